@@ -8,8 +8,15 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/school-monitoring/backend/internal/api"
 	"github.com/school-monitoring/backend/internal/database"
+	"github.com/school-monitoring/backend/internal/services/adjuntos"
+	"github.com/school-monitoring/backend/internal/services/analytics"
+	"github.com/school-monitoring/backend/internal/services/eventbus"
 	"github.com/school-monitoring/backend/internal/services/maintenance"
+	"github.com/school-monitoring/backend/internal/services/monitor"
 	"github.com/school-monitoring/backend/internal/services/notifications"
+	"github.com/school-monitoring/backend/internal/services/orchestrator"
+	"github.com/school-monitoring/backend/internal/services/riesgo"
+	"github.com/school-monitoring/backend/internal/services/rollup"
 	"github.com/school-monitoring/backend/internal/websocket"
 )
 
@@ -39,6 +46,26 @@ func main() {
 	notifWorker := notifications.NewWorker(db, hub)
 	go notifWorker.Run(stop)
 
+	// Bus de eventos (outbox) + orquestador (reglas -> acciones) + cola de reintentos de ejecuciones fallidas
+	bus := eventbus.New(db)
+	orch := orchestrator.New(db, hub, bus)
+	go bus.Run(stop)
+	go orch.RunReintentos(stop)
+
+	// Estado del monitor en memoria: se actualiza con los mensajes del orquestador y difunde diffs por WS
+	estadoMonitor := monitor.NewEstado(db, orch.Notify)
+	orch.Observar(estadoMonitor.Observar)
+	go estadoMonitor.Run(stop)
+
+	// Consolidacion nocturna de asistencia diaria
+	go rollup.RunNightly(db, stop)
+
+	// Puntaje de riesgo (alerta temprana), despues de la consolidacion diaria
+	go riesgo.RunNightly(db, stop)
+
+	// Agregados materializados para analitica (/reportes/series)
+	go analytics.RunRefresco(db, stop)
+
 	// Retención (limpieza periódica)
 	// Por defecto solo en local (para no ejecutar limpieza en cada deploy).
 	appEnv := strings.ToLower(strings.TrimSpace(os.Getenv("APP_ENV")))
@@ -53,8 +80,14 @@ func main() {
 		log.Println("Retention worker disabled")
 	}
 
+	// Almacen de adjuntos (local o S3)
+	almacen, err := adjuntos.AlmacenDesdeEnv()
+	if err != nil {
+		log.Fatal("Failed to configure attachment storage:", err)
+	}
+
 	// Crear router
-	router := api.NewRouter(db, hub)
+	router := api.NewRouter(db, hub, orch, almacen, estadoMonitor)
 
 	// Obtener puerto
 	port := os.Getenv("PORT")
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/api/middleware"
+	"github.com/school-monitoring/backend/internal/auth"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/adjuntos"
+	"gorm.io/gorm"
+)
+
+// AdjuntosHandler maneja los adjuntos de eventos, casos y justificaciones.
+// El acceso se hereda del registro padre: las rutas cuelgan del grupo de la entidad (mismos permisos)
+// y aqui se agregan las reglas propias de cada una:
+//   - justificacion: solo se suben/eliminan adjuntos mientras esta pendiente
+//   - evento y justificacion: elimina quien subio el archivo o quien puede cerrar/revisar
+//   - evento de categoria sensible (salud, convivencia): listar/descargar exige ver_casos
+type AdjuntosHandler struct {
+	db  *gorm.DB
+	svc *adjuntos.Servicio
+}
+
+// NewAdjuntosHandler crea un nuevo handler de adjuntos
+func NewAdjuntosHandler(db *gorm.DB, almacen adjuntos.Almacen) *AdjuntosHandler {
+	return &AdjuntosHandler{db: db, svc: adjuntos.NewServicio(db, almacen)}
+}
+
+// Listar GET /{entidad}/{id}/adjuntos
+func (h *AdjuntosHandler) Listar(entidad string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		padreID, err := h.findPadre(c, entidad, false)
+		if padreID == uuid.Nil {
+			return err
+		}
+		var out []models.Adjunto
+		if err := h.db.Where("entidad_type = ? AND entidad_id = ?", entidad, padreID).
+			Order("created_at DESC").Find(&out).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching attachments"})
+		}
+		return c.JSON(out)
+	}
+}
+
+// Subir POST /{entidad}/{id}/adjuntos (multipart, campo "archivo").
+// El tipo se valida por contenido (no por el header del cliente) y el tamano contra ADJUNTOS_MAX_MB.
+func (h *AdjuntosHandler) Subir(entidad string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims := middleware.GetUserFromContext(c)
+		if claims == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+		}
+		padreID, err := h.findPadre(c, entidad, true)
+		if padreID == uuid.Nil {
+			return err
+		}
+
+		fh, err := c.FormFile("archivo")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "archivo is required"})
+		}
+		maxBytes := adjuntos.MaxBytes()
+		if fh.Size > maxBytes {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": "File too large", "max_bytes": maxBytes})
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid file"})
+		}
+		defer f.Close()
+		contenido, err := io.ReadAll(io.LimitReader(f, maxBytes+1))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid file"})
+		}
+
+		adj, err := h.svc.Subir(entidad, padreID, fh.Filename, contenido, claims.UserID)
+		switch {
+		case errors.Is(err, adjuntos.ErrVacio):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "File is empty"})
+		case errors.Is(err, adjuntos.ErrMuyGrande):
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": "File too large", "max_bytes": maxBytes})
+		case errors.Is(err, adjuntos.ErrTipoInvalido):
+			return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{"error": "File type not allowed"})
+		case err != nil:
+			log.Printf("adjuntos: subir %s %s: %v", entidad, padreID, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error saving attachment"})
+		}
+		_ = models.CrearAuditoria(h.db, "adjuntos", adj.ID, models.AuditoriaInsert, nil, adj, &claims.UserID)
+
+		return c.Status(fiber.StatusCreated).JSON(adj)
+	}
+}
+
+// Descargar GET /{entidad}/{id}/adjuntos/{adjuntoId}; verifica el sha256 antes de entregar
+func (h *AdjuntosHandler) Descargar(entidad string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		adj, err := h.findAdjunto(c, entidad, false)
+		if adj == nil {
+			return err
+		}
+		contenido, err := h.svc.Leer(adj)
+		switch {
+		case errors.Is(err, adjuntos.ErrNoEncontrado):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Attachment content not found"})
+		case errors.Is(err, adjuntos.ErrIntegridad):
+			log.Printf("adjuntos: checksum invalido en %s (%s)", adj.ID, adj.Clave)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Attachment failed integrity check"})
+		case err != nil:
+			log.Printf("adjuntos: leer %s: %v", adj.ID, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error reading attachment"})
+		}
+
+		c.Set(fiber.HeaderContentType, adj.MimeType)
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+strings.ReplaceAll(adj.Nombre, `"`, "")+`"`)
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+		c.Set("X-Checksum-Sha256", adj.Sha256)
+		return c.Send(contenido)
+	}
+}
+
+// Eliminar DELETE /{entidad}/{id}/adjuntos/{adjuntoId}
+func (h *AdjuntosHandler) Eliminar(entidad string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims := middleware.GetUserFromContext(c)
+		if claims == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+		}
+		adj, err := h.findAdjunto(c, entidad, true)
+		if adj == nil {
+			return err
+		}
+		if !puedeEliminarAdjunto(claims, entidad, adj) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only the uploader or a reviewer can delete this attachment"})
+		}
+		if err := h.svc.Eliminar(adj); err != nil {
+			// La metadata ya quedo eliminada; un objeto huerfano en el almacen no afecta al usuario
+			log.Printf("adjuntos: eliminar %s: %v", adj.ID, err)
+		}
+		_ = models.CrearAuditoria(h.db, "adjuntos", adj.ID, models.AuditoriaDelete, adj, nil, &claims.UserID)
+
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// puedeEliminarAdjunto en casos basta gestionar_casos (exigido por la ruta)
+func puedeEliminarAdjunto(claims *auth.Claims, entidad string, adj *models.Adjunto) bool {
+	if adj.SubidoPor == claims.UserID || auth.TienePermiso(claims.Rol, auth.PermisoAdministrar) {
+		return true
+	}
+	switch entidad {
+	case models.AdjuntoEvento:
+		return auth.TienePermiso(claims.Rol, auth.PermisoCerrarEventos)
+	case models.AdjuntoJustificacion:
+		return auth.TienePermiso(claims.Rol, auth.PermisoJustificarAsistencia)
+	}
+	return true
+}
+
+// findAdjunto carga el adjunto de :adjuntoId validando que pertenezca al padre de :id.
+// Si falla responde el error HTTP y retorna nil.
+func (h *AdjuntosHandler) findAdjunto(c *fiber.Ctx, entidad string, escritura bool) (*models.Adjunto, error) {
+	padreID, err := h.findPadre(c, entidad, escritura)
+	if padreID == uuid.Nil {
+		return nil, err
+	}
+	adjID, err := uuid.Parse(c.Params("adjuntoId"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid attachment ID"})
+	}
+	var adj models.Adjunto
+	if err := h.db.First(&adj, "id = ? AND entidad_type = ? AND entidad_id = ?", adjID, entidad, padreID).Error; err != nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Attachment not found"})
+	}
+	return &adj, nil
+}
+
+// findPadre valida que exista el registro padre de :id (y que admita cambios si escritura).
+// Si falla responde el error HTTP y retorna uuid.Nil.
+func (h *AdjuntosHandler) findPadre(c *fiber.Ctx, entidad string, escritura bool) (uuid.UUID, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return uuid.Nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID"})
+	}
+	switch entidad {
+	case models.AdjuntoEvento:
+		var e models.Evento
+		if err := h.db.Select("id", "concepto_id").Preload("Concepto").First(&e, "id = ?", id).Error; err != nil {
+			return uuid.Nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Event not found"})
+		}
+		// Mismo criterio que la hoja de vida: sin ver_casos no se leen adjuntos de eventos sensibles
+		rol := ""
+		if claims := middleware.GetUserFromContext(c); claims != nil {
+			rol = claims.Rol
+		}
+		if !escritura && eventoSensible(&e, rol) {
+			return uuid.Nil, c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+		}
+	case models.AdjuntoCaso:
+		var caso models.Caso
+		if err := h.db.Select("id").First(&caso, "id = ?", id).Error; err != nil {
+			return uuid.Nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Case not found"})
+		}
+	case models.AdjuntoJustificacion:
+		var j models.Justificacion
+		if err := h.db.Select("id", "estado").First(&j, "id = ?", id).Error; err != nil {
+			return uuid.Nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Justification not found"})
+		}
+		if escritura && j.Estado != models.JustificacionPendiente {
+			return uuid.Nil, c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Justification already reviewed"})
+		}
+	default:
+		return uuid.Nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Not found"})
+	}
+	return id, nil
+}
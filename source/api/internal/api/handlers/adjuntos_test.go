@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/api/middleware"
+	"github.com/school-monitoring/backend/internal/auth"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/adjuntos"
+	"github.com/school-monitoring/backend/internal/testutil"
+)
+
+// Los adjuntos de un evento sensible siguen la misma regla que la hoja de vida
+func TestAdjuntosEventoSensible(t *testing.T) {
+	db := testutil.DB(t)
+	almacen, err := adjuntos.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	salud := models.Concepto{Codigo: "TEST_" + uuid.NewString()[:8], Nombre: "Test", Categoria: models.CategoriaSalud}
+	if err := db.Create(&salud).Error; err != nil {
+		t.Fatal(err)
+	}
+	evento := models.Evento{ConceptoID: &salud.ID, Origen: models.OrigenProfesor, Activo: true}
+	if err := db.Create(&evento).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewAdjuntosHandler(db, almacen)
+	get := func(rol string) int {
+		app := fiber.New()
+		app.Use(func(c *fiber.Ctx) error {
+			c.Locals(middleware.LocalsUserKey, &auth.Claims{UserID: uuid.New(), Rol: rol})
+			return c.Next()
+		})
+		app.Get("/eventos/:id/adjuntos", h.Listar(models.AdjuntoEvento))
+		resp, err := app.Test(httptest.NewRequest("GET", "/eventos/"+evento.ID.String()+"/adjuntos", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+	if code := get(models.RolProfesor); code != fiber.StatusForbidden {
+		t.Errorf("profesor: status %d", code)
+	}
+	if code := get(models.RolAsistenteSocial); code != fiber.StatusOK {
+		t.Errorf("asistente social: status %d", code)
+	}
+}
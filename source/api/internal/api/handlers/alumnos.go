@@ -0,0 +1,419 @@
+package handlers
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/api/middleware"
+	"github.com/school-monitoring/backend/internal/auth"
+	"github.com/school-monitoring/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Tipos de entrada de la hoja de vida (timeline)
+const (
+	TimelineEvento           = "evento"
+	TimelineAsistencia       = "asistencia"
+	TimelineAsistenciaCambio = "asistencia_cambio"
+	TimelineEstadoTemporal   = "estado_temporal"
+	TimelineAlerta           = "alerta"
+	TimelineEjecucion        = "accion_ejecucion"
+	TimelineCasoNota         = "caso_nota"
+)
+
+// timelinePermisos define que permiso se necesita para ver el detalle de cada tipo sensible.
+// Sin el permiso la entrada se muestra redactada (se sabe que existe, no su contenido).
+var timelinePermisos = map[string]string{
+	TimelineAlerta:    auth.PermisoVerAlertas,
+	TimelineEjecucion: auth.PermisoVerAuditoria,
+	TimelineCasoNota:  auth.PermisoVerCasos,
+}
+
+// timelineTitulosRedactados titulo generico de una entrada redactada (el original nombra concepto, conteo o accion)
+var timelineTitulosRedactados = map[string]string{
+	TimelineAlerta:         "Alerta",
+	TimelineEjecucion:      "Accion ejecutada",
+	TimelineCasoNota:       "Nota de caso",
+	TimelineEstadoTemporal: "Estado temporal",
+}
+
+// categoriasSensibles eventos cuyo contenido (titulo incluido) solo ve quien tiene ver_casos
+var categoriasSensibles = map[string]bool{
+	models.CategoriaSalud:       true,
+	models.CategoriaConvivencia: true,
+}
+
+// estadosTemporalesSensibles estados temporales que revelan informacion de salud (solo con ver_casos)
+var estadosTemporalesSensibles = map[string]bool{
+	models.EstadoTemporalEnfermeria: true,
+	models.EstadoTemporalSOS:        true,
+}
+
+// estadoTemporalSensible indica si el estado temporal debe redactarse para el rol
+func estadoTemporalSensible(e *models.EstadoTemporal, rol string) bool {
+	return estadosTemporalesSensibles[e.Tipo] && !auth.TienePermiso(rol, auth.PermisoVerCasos)
+}
+
+// eventoSensible indica si el detalle del evento debe redactarse para el rol
+func eventoSensible(e *models.Evento, rol string) bool {
+	return e.Concepto != nil && categoriasSensibles[e.Concepto.Categoria] && !auth.TienePermiso(rol, auth.PermisoVerCasos)
+}
+
+// AlumnosHandler maneja endpoints por alumno
+type AlumnosHandler struct {
+	db *gorm.DB
+}
+
+// NewAlumnosHandler crea un nuevo handler de alumnos
+func NewAlumnosHandler(db *gorm.DB) *AlumnosHandler {
+	return &AlumnosHandler{db: db}
+}
+
+// TimelineItem es una entrada de la hoja de vida del alumno
+type TimelineItem struct {
+	Tipo      string      `json:"tipo"`
+	ID        uuid.UUID   `json:"id"`
+	Fecha     time.Time   `json:"fecha"`
+	Titulo    string      `json:"titulo"`
+	Detalle   interface{} `json:"detalle,omitempty"`
+	Redactado bool        `json:"redactado,omitempty"`
+}
+
+// TimelineResponse respuesta paginada de la hoja de vida
+type TimelineResponse struct {
+	AlumnoID uuid.UUID      `json:"alumno_id"`
+	Items    []TimelineItem `json:"items"`
+	Limit    int            `json:"limit"`
+	Offset   int            `json:"offset"`
+	HasMore  bool           `json:"has_more"`
+}
+
+// Timeline devuelve la hoja de vida del alumno en orden cronologico descendente.
+// GET /alumnos/{id}/timeline?tipos=evento,alerta&desde=&hasta=&limit=&offset=
+func (h *AlumnosHandler) Timeline(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+	}
+
+	alumnoID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid student ID"})
+	}
+	var alumno models.Alumno
+	if err := h.db.First(&alumno, "id = ?", alumnoID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Student not found"})
+	}
+
+	tipos := map[string]bool{}
+	if v := c.Query("tipos"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			tipos[strings.TrimSpace(t)] = true
+		}
+	}
+	incluir := func(t string) bool { return len(tipos) == 0 || tipos[t] }
+
+	var desde, hasta *time.Time
+	if v := c.Query("desde"); v != "" {
+		t, ok := parseFechaHora(v)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid desde, use YYYY-MM-DD or RFC3339"})
+		}
+		desde = &t
+	}
+	if v := c.Query("hasta"); v != "" {
+		t, ok := parseFechaHora(v)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid hasta, use YYYY-MM-DD or RFC3339"})
+		}
+		if len(v) == len("2006-01-02") {
+			t = t.Add(24*time.Hour - time.Nanosecond) // dia completo
+		}
+		hasta = &t
+	}
+
+	limit := clamp(atoi(c.Query("limit")), 1, 200)
+	if c.Query("limit") == "" {
+		limit = 50
+	}
+	offset := clamp(atoi(c.Query("offset")), 0, 5000)
+
+	// Cada fuente aporta a lo mas offset+limit+1 filas (las mas recientes); el merge posterior
+	// queda correcto para la pagina pedida sin cargar todo el historial.
+	n := offset + limit + 1
+	rango := func(q *gorm.DB, col string) *gorm.DB {
+		if desde != nil {
+			q = q.Where(col+" >= ?", *desde)
+		}
+		if hasta != nil {
+			q = q.Where(col+" <= ?", *hasta)
+		}
+		return q.Order(col + " DESC").Limit(n)
+	}
+
+	var items []TimelineItem
+
+	if incluir(TimelineEvento) {
+		var eventos []models.Evento
+		if err := rango(h.db.Preload("Concepto").Where("alumno_id = ?", alumnoID), "created_at").Find(&eventos).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching timeline"})
+		}
+		for _, e := range eventos {
+			titulo := "Evento"
+			if e.Concepto != nil {
+				titulo = e.Concepto.Nombre
+			}
+			if eventoSensible(&e, claims.Rol) {
+				// Solo la categoria: el nombre del concepto ya revela el contenido (ej. "Crisis de angustia")
+				items = append(items, TimelineItem{Tipo: TimelineEvento, ID: e.ID, Fecha: e.CreatedAt, Titulo: "Evento de " + e.Concepto.Categoria, Redactado: true})
+				continue
+			}
+			items = append(items, TimelineItem{Tipo: TimelineEvento, ID: e.ID, Fecha: e.CreatedAt, Titulo: titulo, Detalle: e})
+		}
+	}
+
+	if incluir(TimelineAsistencia) {
+		var asistencias []models.Asistencia
+		q := h.db.Preload("Horario").Preload("Horario.Bloque").Preload("Horario.Asignatura").
+			Where("alumno_id = ? AND estado <> ?", alumnoID, models.EstadoPresente)
+		if err := rango(q, "updated_at").Find(&asistencias).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching timeline"})
+		}
+		for _, a := range asistencias {
+			items = append(items, TimelineItem{Tipo: TimelineAsistencia, ID: a.ID, Fecha: a.UpdatedAt, Titulo: "Asistencia: " + a.Estado, Detalle: a})
+		}
+	}
+
+	if incluir(TimelineAsistenciaCambio) {
+		var auds []models.Auditoria
+		q := h.db.Preload("Usuario").
+			Where("tabla = ? AND accion = ? AND registro_id IN (?)", "asistencias", models.AuditoriaUpdate,
+				h.db.Model(&models.Asistencia{}).Select("id").Where("alumno_id = ?", alumnoID))
+		if err := rango(q, "created_at").Find(&auds).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching timeline"})
+		}
+		for _, a := range auds {
+			var antes, despues models.Asistencia
+			_ = json.Unmarshal(a.DatosAnteriores, &antes)
+			_ = json.Unmarshal(a.DatosNuevos, &despues)
+			if antes.Estado == despues.Estado {
+				continue
+			}
+			items = append(items, TimelineItem{
+				Tipo:   TimelineAsistenciaCambio,
+				ID:     a.ID,
+				Fecha:  a.CreatedAt,
+				Titulo: "Asistencia modificada: " + antes.Estado + " -> " + despues.Estado,
+				Detalle: fiber.Map{
+					"asistencia_id": a.RegistroID,
+					"antes":         antes.Estado,
+					"despues":       despues.Estado,
+					"fecha":         despues.Fecha,
+					"usuario":       a.Usuario,
+				},
+			})
+		}
+	}
+
+	if incluir(TimelineEstadoTemporal) {
+		var estados []models.EstadoTemporal
+		if err := rango(h.db.Where("alumno_id = ?", alumnoID), "inicio").Find(&estados).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching timeline"})
+		}
+		for _, e := range estados {
+			if estadoTemporalSensible(&e, claims.Rol) {
+				items = append(items, TimelineItem{Tipo: TimelineEstadoTemporal, ID: e.ID, Fecha: e.Inicio, Titulo: timelineTitulosRedactados[TimelineEstadoTemporal], Redactado: true})
+				continue
+			}
+			items = append(items, TimelineItem{Tipo: TimelineEstadoTemporal, ID: e.ID, Fecha: e.Inicio, Titulo: "Estado temporal: " + e.Tipo, Detalle: e})
+		}
+	}
+
+	if incluir(TimelineAlerta) {
+		var alertas []models.Alerta
+		if err := rango(h.db.Where("alumno_id = ?", alumnoID), "created_at").Find(&alertas).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching timeline"})
+		}
+		for _, a := range alertas {
+			items = append(items, TimelineItem{Tipo: TimelineAlerta, ID: a.ID, Fecha: a.CreatedAt, Titulo: a.Titulo, Detalle: a})
+		}
+	}
+
+	if incluir(TimelineEjecucion) {
+		var execs []models.AccionEjecucion
+		if err := rango(h.db.Preload("Regla").Preload("Accion").Where("alumno_id = ?", alumnoID), "ejecutado_en").Find(&execs).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching timeline"})
+		}
+		for _, e := range execs {
+			titulo := "Accion ejecutada"
+			if e.Accion != nil {
+				titulo = e.Accion.Nombre
+			}
+			items = append(items, TimelineItem{Tipo: TimelineEjecucion, ID: e.ID, Fecha: e.EjecutadoEn, Titulo: titulo, Detalle: e})
+		}
+	}
+
+	if incluir(TimelineCasoNota) {
+		var notas []models.CasoNota
+		q := h.db.Preload("Usuario").
+			Where("caso_id IN (?)", h.db.Model(&models.Caso{}).Select("id").Where("alumno_id = ?", alumnoID))
+		if err := rango(q, "created_at").Find(&notas).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching timeline"})
+		}
+		for _, nt := range notas {
+			items = append(items, TimelineItem{Tipo: TimelineCasoNota, ID: nt.ID, Fecha: nt.CreatedAt, Titulo: "Nota de caso", Detalle: nt})
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Fecha.After(items[j].Fecha) })
+
+	resp := TimelineResponse{AlumnoID: alumnoID, Limit: limit, Offset: offset, Items: []TimelineItem{}}
+	if offset < len(items) {
+		end := offset + limit
+		if end < len(items) {
+			resp.HasMore = true
+		} else {
+			end = len(items)
+		}
+		resp.Items = items[offset:end]
+	}
+
+	// Redaccion por rol
+	for i := range resp.Items {
+		if permiso, ok := timelinePermisos[resp.Items[i].Tipo]; ok && !auth.TienePermiso(claims.Rol, permiso) {
+			resp.Items[i].Titulo = timelineTitulosRedactados[resp.Items[i].Tipo]
+			resp.Items[i].Detalle = nil
+			resp.Items[i].Redactado = true
+		}
+	}
+
+	return c.JSON(resp)
+}
+
+// AlumnoRequest alta o edicion de un alumno (en la edicion los campos omitidos no cambian)
+type AlumnoRequest struct {
+	CursoID      *uuid.UUID `json:"curso_id"`
+	Nombre       string     `json:"nombre"`
+	Apellido     string     `json:"apellido"`
+	Rut          string     `json:"rut"`
+	CasoEspecial *bool      `json:"caso_especial,omitempty"`
+	Activo       *bool      `json:"activo,omitempty"`
+}
+
+// Create POST /alumnos: crea el alumno y su matricula del ano activo
+func (h *AlumnosHandler) Create(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+
+	var req AlumnoRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	req.Nombre, req.Apellido, req.Rut = strings.TrimSpace(req.Nombre), strings.TrimSpace(req.Apellido), strings.TrimSpace(req.Rut)
+	if req.CursoID == nil || req.Nombre == "" || req.Apellido == "" || req.Rut == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "curso_id, nombre, apellido y rut son requeridos"})
+	}
+	if !h.existeCurso(*req.CursoID) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Course not found"})
+	}
+	if h.existeRut(req.Rut, uuid.Nil) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "RUT already registered"})
+	}
+
+	alumno := models.Alumno{CursoID: *req.CursoID, Nombre: req.Nombre, Apellido: req.Apellido, Rut: req.Rut, Activo: true}
+	if req.CasoEspecial != nil {
+		alumno.CasoEspecial = *req.CasoEspecial
+	}
+	if req.Activo != nil {
+		alumno.Activo = *req.Activo
+	}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&alumno).Error; err != nil {
+			return err
+		}
+		return models.Matricular(tx, alumno.ID, alumno.CursoID, time.Now())
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating student"})
+	}
+	_ = models.CrearAuditoria(h.db, "alumnos", alumno.ID, models.AuditoriaInsert, nil, &alumno, userIDPtr(claims))
+
+	return c.Status(fiber.StatusCreated).JSON(alumno)
+}
+
+// Update PUT /alumnos/{id}: un cambio de curso mueve tambien la matricula del ano activo
+func (h *AlumnosHandler) Update(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid student ID"})
+	}
+	var alumno models.Alumno
+	if err := h.db.First(&alumno, "id = ?", id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Student not found"})
+	}
+	before := alumno
+
+	var req AlumnoRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if v := strings.TrimSpace(req.Nombre); v != "" {
+		alumno.Nombre = v
+	}
+	if v := strings.TrimSpace(req.Apellido); v != "" {
+		alumno.Apellido = v
+	}
+	if v := strings.TrimSpace(req.Rut); v != "" && v != alumno.Rut {
+		if h.existeRut(v, alumno.ID) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "RUT already registered"})
+		}
+		alumno.Rut = v
+	}
+	if req.CasoEspecial != nil {
+		alumno.CasoEspecial = *req.CasoEspecial
+	}
+	if req.Activo != nil {
+		alumno.Activo = *req.Activo
+	}
+	cambioCurso := req.CursoID != nil && *req.CursoID != alumno.CursoID
+	if cambioCurso {
+		if !h.existeCurso(*req.CursoID) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Course not found"})
+		}
+		alumno.CursoID = *req.CursoID
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&alumno).Error; err != nil {
+			return err
+		}
+		if cambioCurso {
+			return models.Matricular(tx, alumno.ID, alumno.CursoID, time.Now())
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating student"})
+	}
+	_ = models.CrearAuditoria(h.db, "alumnos", alumno.ID, models.AuditoriaUpdate, &before, &alumno, userIDPtr(claims))
+
+	return c.JSON(alumno)
+}
+
+func (h *AlumnosHandler) existeCurso(id uuid.UUID) bool {
+	var n int64
+	h.db.Model(&models.Curso{}).Where("id = ?", id).Count(&n)
+	return n > 0
+}
+
+// existeRut indica si otro alumno (distinto de excepto) ya usa el RUT
+func (h *AlumnosHandler) existeRut(rut string, excepto uuid.UUID) bool {
+	var n int64
+	h.db.Unscoped().Model(&models.Alumno{}).Where("rut = ? AND id <> ?", rut, excepto).Count(&n)
+	return n > 0
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/school-monitoring/backend/internal/models"
+)
+
+func TestEventoSensible(t *testing.T) {
+	cases := []struct {
+		name      string
+		categoria string
+		rol       string
+		want      bool
+	}{
+		{"salud sin ver_casos", models.CategoriaSalud, models.RolProfesor, true},
+		{"convivencia sin ver_casos", models.CategoriaConvivencia, models.RolProfesor, true},
+		{"salud con ver_casos", models.CategoriaSalud, models.RolAsistenteSocial, false},
+		{"asistencia sin ver_casos", models.CategoriaAsistencia, models.RolProfesor, false},
+	}
+	for _, tc := range cases {
+		e := models.Evento{Concepto: &models.Concepto{Categoria: tc.categoria}}
+		if got := eventoSensible(&e, tc.rol); got != tc.want {
+			t.Errorf("%s: got %v", tc.name, got)
+		}
+	}
+	if eventoSensible(&models.Evento{}, models.RolProfesor) {
+		t.Error("evento sin concepto no es sensible")
+	}
+}
+
+func TestEstadoTemporalSensible(t *testing.T) {
+	cases := []struct {
+		tipo string
+		rol  string
+		want bool
+	}{
+		{models.EstadoTemporalEnfermeria, models.RolProfesor, true},
+		{models.EstadoTemporalSOS, models.RolProfesor, true},
+		{models.EstadoTemporalBano, models.RolProfesor, false},
+		{models.EstadoTemporalEnfermeria, models.RolAsistenteSocial, false},
+	}
+	for _, tc := range cases {
+		if got := estadoTemporalSensible(&models.EstadoTemporal{Tipo: tc.tipo}, tc.rol); got != tc.want {
+			t.Errorf("%s / %s: got %v", tc.tipo, tc.rol, got)
+		}
+	}
+	for tipo := range timelinePermisos {
+		if timelineTitulosRedactados[tipo] == "" {
+			t.Errorf("%s: sin titulo generico para la redaccion", tipo)
+		}
+	}
+}
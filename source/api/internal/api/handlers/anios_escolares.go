@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/api/middleware"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/anioescolar"
+	"gorm.io/gorm"
+)
+
+// AniosEscolaresHandler maneja anos escolares, periodos, matriculas y el cierre de ano
+type AniosEscolaresHandler struct {
+	db *gorm.DB
+}
+
+// NewAniosEscolaresHandler crea un nuevo handler de anos escolares
+func NewAniosEscolaresHandler(db *gorm.DB) *AniosEscolaresHandler {
+	return &AniosEscolaresHandler{db: db}
+}
+
+// GET /anios-escolares
+func (h *AniosEscolaresHandler) GetAll(c *fiber.Ctx) error {
+	var anios []models.AnioEscolar
+	if err := h.db.Preload("Periodos", func(db *gorm.DB) *gorm.DB { return db.Order("numero") }).
+		Order("anio DESC").Find(&anios).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching school years"})
+	}
+	return c.JSON(anios)
+}
+
+// GET /anios-escolares/actual (incluye el periodo en curso, si lo hay)
+func (h *AniosEscolaresHandler) GetActual(c *fiber.Ctx) error {
+	anio := models.AnioEscolarActivo(h.db)
+	if anio == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No active school year"})
+	}
+	h.db.Where("anio_escolar_id = ?", anio.ID).Order("numero").Find(&anio.Periodos)
+
+	hoy := time.Now().Format("2006-01-02")
+	var periodo *models.Periodo
+	for i, p := range anio.Periodos {
+		if p.FechaInicio.Format("2006-01-02") <= hoy && hoy <= p.FechaFin.Format("2006-01-02") {
+			periodo = &anio.Periodos[i]
+			break
+		}
+	}
+	return c.JSON(fiber.Map{"anio_escolar": anio, "periodo_actual": periodo})
+}
+
+// GET /anios-escolares/:id
+func (h *AniosEscolaresHandler) GetByID(c *fiber.Ctx) error {
+	anio, err := h.find(c)
+	if anio == nil {
+		return err
+	}
+	return c.JSON(anio)
+}
+
+type AnioEscolarRequest struct {
+	Anio        int    `json:"anio"`
+	FechaInicio string `json:"fecha_inicio"` // YYYY-MM-DD
+	FechaFin    string `json:"fecha_fin"`
+	TipoPeriodo string `json:"tipo_periodo"` // semestre (default), trimestre
+	Activo      bool   `json:"activo"`
+}
+
+// POST /anios-escolares (crea los periodos por defecto)
+func (h *AniosEscolaresHandler) Create(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+
+	var req AnioEscolarRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	inicio, errI := time.Parse("2006-01-02", req.FechaInicio)
+	fin, errF := time.Parse("2006-01-02", req.FechaFin)
+	if req.Anio < 2000 || errI != nil || errF != nil || !fin.After(inicio) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "anio, fecha_inicio y fecha_fin (YYYY-MM-DD, fin > inicio) son requeridos"})
+	}
+	if req.TipoPeriodo != "" && req.TipoPeriodo != models.PeriodoSemestre && req.TipoPeriodo != models.PeriodoTrimestre {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "tipo_periodo must be semestre or trimestre"})
+	}
+
+	var existe int64
+	h.db.Model(&models.AnioEscolar{}).Where("anio = ?", req.Anio).Count(&existe)
+	if existe > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "School year already exists"})
+	}
+
+	anio := models.AnioEscolar{Anio: req.Anio, FechaInicio: inicio, FechaFin: fin}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&anio).Error; err != nil {
+			return err
+		}
+		anio.Periodos = models.PeriodosPorDefecto(anio, req.TipoPeriodo)
+		if err := tx.Create(&anio.Periodos).Error; err != nil {
+			return err
+		}
+		if req.Activo {
+			if err := activarAnio(tx, &anio); err != nil {
+				return err
+			}
+		}
+		return models.CrearAuditoria(tx, "anios_escolares", anio.ID, models.AuditoriaInsert, nil, &anio, userIDPtr(claims))
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating school year"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(anio)
+}
+
+// PUT /anios-escolares/:id/activar (un solo ano activo a la vez)
+func (h *AniosEscolaresHandler) Activar(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+
+	anio, err := h.find(c)
+	if anio == nil {
+		return err
+	}
+	if anio.CerradoEn != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "School year is closed"})
+	}
+	before := *anio
+	if err := h.db.Transaction(func(tx *gorm.DB) error { return activarAnio(tx, anio) }); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error activating school year"})
+	}
+	_ = models.CrearAuditoria(h.db, "anios_escolares", anio.ID, models.AuditoriaUpdate, &before, anio, userIDPtr(claims))
+	return c.JSON(anio)
+}
+
+func activarAnio(tx *gorm.DB, anio *models.AnioEscolar) error {
+	if err := tx.Model(&models.AnioEscolar{}).Where("id <> ?", anio.ID).Update("activo", false).Error; err != nil {
+		return err
+	}
+	anio.Activo = true
+	return tx.Model(anio).Update("activo", true).Error
+}
+
+type PeriodoRequest struct {
+	Nombre      string `json:"nombre"`
+	Tipo        string `json:"tipo"`
+	Numero      int    `json:"numero"`
+	FechaInicio string `json:"fecha_inicio"`
+	FechaFin    string `json:"fecha_fin"`
+}
+
+// POST /anios-escolares/:id/periodos
+func (h *AniosEscolaresHandler) CreatePeriodo(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+
+	anio, err := h.find(c)
+	if anio == nil {
+		return err
+	}
+	var req PeriodoRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.Tipo != models.PeriodoSemestre && req.Tipo != models.PeriodoTrimestre {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "tipo must be semestre or trimestre"})
+	}
+	inicio, errI := time.Parse("2006-01-02", req.FechaInicio)
+	fin, errF := time.Parse("2006-01-02", req.FechaFin)
+	if req.Nombre == "" || req.Numero < 1 || errI != nil || errF != nil || fin.Before(inicio) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "nombre, numero, fecha_inicio y fecha_fin (YYYY-MM-DD) son requeridos"})
+	}
+	if inicio.Before(anio.FechaInicio) || fin.After(anio.FechaFin) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "El periodo debe estar dentro del ano escolar"})
+	}
+
+	var traslape int64
+	h.db.Model(&models.Periodo{}).
+		Where("anio_escolar_id = ? AND fecha_inicio <= ? AND fecha_fin >= ?", anio.ID, fin, inicio).
+		Count(&traslape)
+	if traslape > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "El periodo se traslapa con otro existente"})
+	}
+
+	p := models.Periodo{AnioEscolarID: anio.ID, Nombre: req.Nombre, Tipo: req.Tipo, Numero: req.Numero, FechaInicio: inicio, FechaFin: fin}
+	if err := h.db.Create(&p).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating period"})
+	}
+	_ = models.CrearAuditoria(h.db, "periodos", p.ID, models.AuditoriaInsert, nil, &p, userIDPtr(claims))
+	return c.Status(fiber.StatusCreated).JSON(p)
+}
+
+// DELETE /anios-escolares/:id/periodos/:periodoId
+func (h *AniosEscolaresHandler) DeletePeriodo(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+
+	var p models.Periodo
+	if err := h.db.First(&p, "id = ? AND anio_escolar_id = ?", c.Params("periodoId"), c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Period not found"})
+	}
+	var usados int64
+	h.db.Model(&models.Horario{}).Where("periodo_id = ?", p.ID).Count(&usados)
+	if usados > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Period has schedules assigned"})
+	}
+	if err := h.db.Delete(&p).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error deleting period"})
+	}
+	_ = models.CrearAuditoria(h.db, "periodos", p.ID, models.AuditoriaDelete, &p, nil, userIDPtr(claims))
+	return c.JSON(fiber.Map{"message": "Period deleted"})
+}
+
+// GET /anios-escolares/:id/matriculas?curso_id=&estado=
+func (h *AniosEscolaresHandler) GetMatriculas(c *fiber.Ctx) error {
+	anio, err := h.find(c)
+	if anio == nil {
+		return err
+	}
+	q := h.db.Preload("Alumno").Preload("Curso").
+		Joins("JOIN alumnos ON alumnos.id = matriculas.alumno_id").
+		Where("matriculas.anio_escolar_id = ?", anio.ID)
+	if cursoID := c.Query("curso_id"); cursoID != "" {
+		q = q.Where("matriculas.curso_id = ?", cursoID)
+	}
+	if estado := c.Query("estado"); estado != "" {
+		q = q.Where("matriculas.estado = ?", estado)
+	}
+	var out []models.Matricula
+	if err := q.Order("alumnos.apellido, alumnos.nombre").Find(&out).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching enrollments"})
+	}
+	return c.JSON(out)
+}
+
+type RolloverRequest struct {
+	Anio        int    `json:"anio"`         // default: ano actual + 1
+	FechaInicio string `json:"fecha_inicio"` // default: mismas fechas un ano despues
+	FechaFin    string `json:"fecha_fin"`
+	TipoPeriodo string `json:"tipo_periodo"`
+	// curso origen -> curso destino ("" = egresa); por defecto se calcula por nivel y numero
+	MapeoCursos    map[string]string `json:"mapeo_cursos"`
+	Repitentes     []uuid.UUID       `json:"repitentes"`
+	CopiarHorarios bool              `json:"copiar_horarios"`
+}
+
+// POST /anios-escolares/:id/rollover cierra el ano y abre el siguiente (promocion + archivo de horario)
+func (h *AniosEscolaresHandler) Rollover(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+	}
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid school year ID"})
+	}
+
+	var req RolloverRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+	op := anioescolar.Opciones{
+		Anio:           req.Anio,
+		TipoPeriodo:    req.TipoPeriodo,
+		Repitentes:     req.Repitentes,
+		CopiarHorarios: req.CopiarHorarios,
+		UsuarioID:      claims.UserID,
+		MapeoCursos:    map[uuid.UUID]uuid.UUID{},
+	}
+	if req.FechaInicio != "" {
+		if op.FechaInicio, err = time.Parse("2006-01-02", req.FechaInicio); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid fecha_inicio, use YYYY-MM-DD"})
+		}
+	}
+	if req.FechaFin != "" {
+		if op.FechaFin, err = time.Parse("2006-01-02", req.FechaFin); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid fecha_fin, use YYYY-MM-DD"})
+		}
+	}
+	for origen, destino := range req.MapeoCursos {
+		o, err := uuid.Parse(origen)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid course ID in mapeo_cursos"})
+		}
+		d := uuid.Nil
+		if destino != "" {
+			if d, err = uuid.Parse(destino); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid course ID in mapeo_cursos"})
+			}
+		}
+		op.MapeoCursos[o] = d
+	}
+
+	res, err := anioescolar.Rollover(h.db, id, op)
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "School year not found"})
+	case errors.Is(err, anioescolar.ErrAnioCerrado), errors.Is(err, anioescolar.ErrAnioExistente):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, anioescolar.ErrFechas), errors.Is(err, anioescolar.ErrMapeoCursos):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error closing school year"})
+	}
+	return c.JSON(res)
+}
+
+func (h *AniosEscolaresHandler) find(c *fiber.Ctx) (*models.AnioEscolar, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid school year ID"})
+	}
+	var anio models.AnioEscolar
+	if err := h.db.Preload("Periodos", func(db *gorm.DB) *gorm.DB { return db.Order("numero") }).
+		First(&anio, "id = ?", id).Error; err != nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "School year not found"})
+	}
+	return &anio, nil
+}
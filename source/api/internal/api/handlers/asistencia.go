@@ -2,14 +2,21 @@ package handlers
 
 import (
 	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/school-monitoring/backend/internal/api/middleware"
+	"github.com/school-monitoring/backend/internal/auth"
 	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/agenda"
 	"github.com/school-monitoring/backend/internal/services/orchestrator"
+	"github.com/school-monitoring/backend/internal/services/rollup"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // AsistenciaHandler maneja endpoints de asistencia
@@ -28,12 +35,171 @@ type RegistroAsistenciaRequest struct {
 	HorarioID uuid.UUID            `json:"horario_id"`
 	Fecha     string               `json:"fecha"` // YYYY-MM-DD
 	Registros []RegistroAlumno     `json:"registros"`
+
+	// Solo administracion: forzar la edicion pasada la ventana (queda auditado con el motivo)
+	Forzar bool   `json:"forzar,omitempty"`
+	Motivo string `json:"motivo,omitempty"`
+}
+
+// ErrorRegistro error de validacion de una fila de la request
+type ErrorRegistro struct {
+	Indice   int       `json:"indice"`
+	AlumnoID uuid.UUID `json:"alumno_id"`
+	Campo    string    `json:"campo"`
+	Error    string    `json:"error"`
+}
+
+// Politicas de bloqueo del registro de asistencia (ASISTENCIA_BLOQUEO)
+const (
+	BloqueoHoras  = "horas"   // termino del bloque + ASISTENCIA_VENTANA_EDICION_HORAS (default 24)
+	BloqueoFinDia = "fin_dia" // al terminar el dia del bloque
+)
+
+// zonaColegio zona horaria del establecimiento (ver models.ZonaColegio)
+func zonaColegio() *time.Location {
+	return models.ZonaColegio()
+}
+
+// limiteEdicion retorna el instante desde el que un registro ya hecho queda bloqueado.
+// Sin hora de termino del bloque se toma el fin del dia.
+func limiteEdicion(fecha time.Time, bloque *models.BloqueHorario) time.Time {
+	finDia := time.Date(fecha.Year(), fecha.Month(), fecha.Day(), 23, 59, 59, 0, zonaColegio())
+	if os.Getenv("ASISTENCIA_BLOQUEO") == BloqueoFinDia {
+		return finDia
+	}
+	horas := 24
+	if n, err := strconv.Atoi(os.Getenv("ASISTENCIA_VENTANA_EDICION_HORAS")); err == nil && n >= 0 {
+		horas = n
+	}
+	fin := finDia
+	if bloque != nil {
+		if t, ok := horaEnFecha(fecha, bloque.HoraFin); ok {
+			fin = *t
+		}
+	}
+	return fin.Add(time.Duration(horas) * time.Hour)
+}
+
+// registroBloqueado indica si el bloque ya tiene asistencia registrada en la fecha y paso el limite de edicion.
+// El primer registro de un bloque pendiente no se bloquea.
+func registroBloqueado(db *gorm.DB, horario models.Horario, fecha, now time.Time) (bool, time.Time) {
+	limite := limiteEdicion(fecha, horario.Bloque)
+	if !now.After(limite) {
+		return false, limite
+	}
+	var n int64
+	db.Model(&models.Asistencia{}).Where("horario_id = ? AND fecha = ?", horario.ID, fecha).Count(&n)
+	return n > 0, limite
+}
+
+// detalleRegistro horas ya interpretadas de una fila (atraso / retiro)
+type detalleRegistro struct {
+	llegada *time.Time
+	retiro  *time.Time
+}
+
+// validarRegistros valida cada fila contra el curso del bloque y retorna las horas interpretadas
+// junto a la lista de errores por fila (vacia si todo es valido). err es un error de la DB, no de las filas.
+func validarRegistros(db *gorm.DB, horario models.Horario, fecha time.Time, registros []RegistroAlumno, now time.Time) ([]detalleRegistro, []ErrorRegistro, error) {
+	detalles := make([]detalleRegistro, len(registros))
+	var errores []ErrorRegistro
+	agregar := func(i int, r RegistroAlumno, campo, msg string) {
+		errores = append(errores, ErrorRegistro{Indice: i, AlumnoID: r.AlumnoID, Campo: campo, Error: msg})
+	}
+
+	ids := make([]uuid.UUID, 0, len(registros))
+	for _, r := range registros {
+		ids = append(ids, r.AlumnoID)
+	}
+	var alumnosCurso []models.Alumno
+	if len(ids) > 0 {
+		if err := db.Select("id, curso_id, activo").Where("id IN ?", ids).Find(&alumnosCurso).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	alumnoBy := make(map[uuid.UUID]models.Alumno, len(alumnosCurso))
+	for _, a := range alumnosCurso {
+		alumnoBy[a.ID] = a
+	}
+
+	vistos := make(map[uuid.UUID]bool, len(registros))
+	for i, registro := range registros {
+		a, ok := alumnoBy[registro.AlumnoID]
+		switch {
+		case registro.AlumnoID == uuid.Nil:
+			agregar(i, registro, "alumno_id", "alumno_id is required")
+		case !ok:
+			agregar(i, registro, "alumno_id", "Student not found")
+		case a.CursoID != horario.CursoID:
+			agregar(i, registro, "alumno_id", "Student does not belong to the course of this block")
+		case !a.Activo:
+			agregar(i, registro, "alumno_id", "Student is not active")
+		case vistos[registro.AlumnoID]:
+			agregar(i, registro, "alumno_id", "Duplicated student in request")
+		}
+		vistos[registro.AlumnoID] = true
+
+		if !models.EsEstadoAsistenciaValido(registro.Estado) {
+			agregar(i, registro, "estado", "Invalid attendance state (presente, ausente, justificado, atraso, retiro)")
+			continue
+		}
+		switch registro.Estado {
+		case models.EstadoAtraso:
+			if registro.HoraLlegada == "" {
+				detalles[i].llegada = llegadaPorDefecto(horario, fecha, now)
+			} else if t, ok := horaEnFecha(fecha, registro.HoraLlegada); ok {
+				detalles[i].llegada = t
+			} else {
+				agregar(i, registro, "hora_llegada", "Invalid hora_llegada, use HH:MM")
+			}
+		case models.EstadoRetiro:
+			t, ok := horaEnFecha(fecha, registro.HoraRetiro)
+			if !ok {
+				agregar(i, registro, "hora_retiro", "hora_retiro is required for retiro, use HH:MM")
+			}
+			if strings.TrimSpace(registro.RetiradoPorNombre) == "" {
+				agregar(i, registro, "retirado_por_nombre", "retirado_por_nombre is required for retiro")
+			}
+			detalles[i].retiro = t
+		}
+	}
+	return detalles, errores, nil
 }
 
 // RegistroAlumno estructura para cada alumno
 type RegistroAlumno struct {
 	AlumnoID uuid.UUID `json:"alumno_id"`
-	Estado   string    `json:"estado"` // presente, ausente
+	Estado   string    `json:"estado"` // presente, ausente, justificado, atraso, retiro
+
+	HoraLlegada         string `json:"hora_llegada,omitempty"` // HH:MM (atraso)
+	HoraRetiro          string `json:"hora_retiro,omitempty"`  // HH:MM (retiro)
+	RetiradoPorNombre   string `json:"retirado_por_nombre,omitempty"`
+	RetiradoPorRelacion string `json:"retirado_por_relacion,omitempty"`
+}
+
+// horaEnFecha combina una hora HH:MM con el dia calendario de la fecha, ambos en la zona del colegio
+func horaEnFecha(fecha time.Time, hhmm string) (*time.Time, bool) {
+	t, err := time.Parse("15:04", hhmm)
+	if err != nil {
+		return nil, false
+	}
+	out := time.Date(fecha.Year(), fecha.Month(), fecha.Day(), t.Hour(), t.Minute(), 0, 0, zonaColegio())
+	return &out, true
+}
+
+// llegadaPorDefecto hora de llegada de un atraso sin hora_llegada: el momento del registro si es del mismo dia;
+// para una fecha pasada, el inicio del bloque en esa fecha (no la hora en que se digita)
+func llegadaPorDefecto(horario models.Horario, fecha, now time.Time) *time.Time {
+	if now.In(zonaColegio()).Format("2006-01-02") == fecha.Format("2006-01-02") {
+		return &now
+	}
+	if horario.Bloque != nil {
+		if t, ok := horaEnFecha(fecha, horario.Bloque.HoraInicio); ok {
+			return t
+		}
+	}
+	inicio := time.Date(fecha.Year(), fecha.Month(), fecha.Day(), 0, 0, 0, 0, zonaColegio())
+	return &inicio
 }
 
 // RegistrarBloque registra la asistencia de un bloque completo
@@ -56,94 +222,107 @@ func (h *AsistenciaHandler) RegistrarBloque(c *fiber.Ctx) error {
 
 	// Verificar que el horario existe
 	var horario models.Horario
-	if err := h.db.First(&horario, "id = ?", req.HorarioID).Error; err != nil {
+	if err := h.db.Preload("Bloque").First(&horario, "id = ?", req.HorarioID).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Schedule not found"})
 	}
 
-	// Registrar asistencia para cada alumno
-	tx := h.db.Begin()
-
-	// Concepto INASISTENCIA (si hay ausentes, se registran como Evento)
-	var conceptoInasistencia models.Concepto
-	_ = h.db.First(&conceptoInasistencia, "codigo = ?", models.ConceptoInasistencia).Error
-
-	for _, registro := range req.Registros {
-		// Detectar si ya existia asistencia para saber si cambio (para auditoria simple)
-		var prev models.Asistencia
-		prevFound := tx.Where("alumno_id = ? AND horario_id = ? AND fecha = ?",
-			registro.AlumnoID, req.HorarioID, fecha).
-			First(&prev).Error == nil
+	// Calendario escolar y excepciones: no se registra asistencia en dias no lectivos,
+	// en bloques suspendidos o cancelados, ni en un horario de otro dia de la semana
+	efectivo, dia, dictado, err := agenda.Resolver(h.db, horario, fecha)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error checking school calendar"})
+	}
+	if !dia.Lectivo {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Not a school day", "tipo": dia.Tipo, "motivo": dia.Motivo})
+	}
+	if !dictado {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Block is not held on this date", "tipo": dia.Tipo, "motivo": dia.Motivo})
+	}
+	if agenda.Cancelado(efectivo) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Block cancelled on this date", "motivo": efectivo.Excepcion.Motivo})
+	}
 
-		asistencia := models.Asistencia{
-			AlumnoID:      registro.AlumnoID,
-			HorarioID:     req.HorarioID,
-			Fecha:         fecha,
-			Estado:        registro.Estado,
-			RegistradoPor: claims.UserID,
-		}
+	// Autorizacion a nivel de recurso: el profesor solo registra sus bloques (o los que reemplaza ese dia);
+	// quien tiene permiso de administracion puede registrar cualquier bloque.
+	esAdmin := auth.TienePermiso(claims.Rol, auth.PermisoAdministrar)
+	if !esAdmin && efectivo.ProfesorID != claims.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Not the teacher of this block on this date"})
+	}
 
-		// Upsert: actualizar si ya existe
-		if err := tx.Where("alumno_id = ? AND horario_id = ? AND fecha = ?",
-			registro.AlumnoID, req.HorarioID, fecha).
-			Assign(asistencia).
-			FirstOrCreate(&asistencia).Error; err != nil {
+	// Registro bloqueado: los cambios van por solicitud de correccion; solo un admin puede forzar (auditado)
+	now := time.Now().In(zonaColegio())
+	if fecha.Format("2006-01-02") > now.Format("2006-01-02") {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Cannot register attendance for a future date"})
+	}
+	// Bloqueo, validacion y escritura en la misma transaccion y con el mismo now: el bloque queda
+	// tomado para que un registro concurrente no se cuele entre el conteo y el upsert
+	tx := h.db.Begin()
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Horario{}, "id = ?", horario.ID).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error registering attendance"})
+	}
+	bloqueado, limite := registroBloqueado(tx, horario, fecha, now)
+	override := false
+	if bloqueado {
+		if !req.Forzar || !esAdmin {
 			tx.Rollback()
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error registering attendance"})
+			return c.Status(fiber.StatusLocked).JSON(fiber.Map{"error": "Attendance is locked, submit a correction request", "bloqueado_desde": limite})
 		}
-
-		// Auditoria basica (no guardamos "antes" real si no existia)
-		if prevFound {
-			_ = models.CrearAuditoria(tx, "asistencias", asistencia.ID, models.AuditoriaUpdate, &prev, &asistencia, &claims.UserID)
-		} else {
-			_ = models.CrearAuditoria(tx, "asistencias", asistencia.ID, models.AuditoriaInsert, nil, &asistencia, &claims.UserID)
+		if strings.TrimSpace(req.Motivo) == "" {
+			tx.Rollback()
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "motivo is required to override the lock"})
 		}
+		override = true
+	}
 
-		// Orquestacion: si se marca AUSENTE => crear Evento INASISTENCIA (deduplicando por dia)
-		if h.orch != nil && conceptoInasistencia.ID != uuid.Nil && registro.Estado == models.EstadoAusente {
-			dayStart := time.Date(fecha.Year(), fecha.Month(), fecha.Day(), 0, 0, 0, 0, time.UTC)
-			dayEnd := dayStart.Add(24 * time.Hour)
-
-			var existing int64
-			tx.Model(&models.Evento{}).
-				Where("concepto_id = ? AND alumno_id = ? AND created_at >= ? AND created_at < ?",
-					conceptoInasistencia.ID, registro.AlumnoID, dayStart, dayEnd).
-				Count(&existing)
-
-			if existing == 0 {
-				datos, _ := json.Marshal(map[string]interface{}{
-					"horario_id": req.HorarioID,
-					"fecha":      req.Fecha,
-				})
-				cid := conceptoInasistencia.ID
-				evt := models.Evento{
-					ConceptoID:    &cid,
-					AlumnoID:      &registro.AlumnoID,
-					CursoID:       &horario.CursoID,
-					Origen:        models.OrigenProfesor,
-					OrigenUsuario: &claims.UserID,
-					Datos:         datos,
-					Activo:        true,
-				}
-				_ = h.orch.CreateEventoTx(tx, &evt, &claims.UserID)
-			}
-		}
+	// Validacion por fila (alumno del curso, estado conocido, datos de atraso/retiro): se informan todos los errores
+	detalles, errores, err := validarRegistros(tx, horario, fecha, req.Registros, now)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error registering attendance"})
+	}
+	if len(errores) > 0 {
+		tx.Rollback()
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Invalid attendance rows", "errores": errores})
+	}
 
-		// Si el alumno pasa a PRESENTE y antes estaba AUSENTE, cerrar evento INASISTENCIA activo del dia (si existe)
-		if h.orch != nil && conceptoInasistencia.ID != uuid.Nil &&
-			registro.Estado == models.EstadoPresente && prevFound && prev.Estado == models.EstadoAusente {
-			dayStart := time.Date(fecha.Year(), fecha.Month(), fecha.Day(), 0, 0, 0, 0, time.UTC)
-			dayEnd := dayStart.Add(24 * time.Hour)
+	if override {
+		// Registro del override (quien, cuando, por que y sobre que bloque)
+		_ = models.CrearAuditoria(tx, "horarios_asistencia_estado", horario.ID, models.AuditoriaOverride, nil, fiber.Map{
+			"horario_id":      horario.ID,
+			"fecha":           req.Fecha,
+			"motivo":          strings.TrimSpace(req.Motivo),
+			"bloqueado_desde": limite,
+			"registros":       len(req.Registros),
+		}, &claims.UserID)
+	}
 
-			var eventos []models.Evento
-			tx.Where("concepto_id = ? AND alumno_id = ? AND activo = ? AND created_at >= ? AND created_at < ?",
-				conceptoInasistencia.ID, registro.AlumnoID, true, dayStart, dayEnd).
-				Find(&eventos)
-			for _, e := range eventos {
-				_, _ = h.orch.CloseEventoTx(tx, e.ID, claims.UserID)
-			}
+	// Registrar asistencia para cada alumno
+	if err := h.escribirRegistros(tx, horario, fecha, req.Registros, detalles, claims.UserID); err != nil {
+		tx.Rollback()
+		msg := "Error registering attendance"
+		if e, ok := err.(*errEscritura); ok {
+			msg = e.msg
 		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": msg})
+	}
+	// Snapshot por bloque+fecha: conteos desde las filas ya escritas (no desde el body)
+	hae, err := asegurarEstadoBloque(tx, horario, efectivo.ProfesorID, fecha, claims.UserID)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error registering attendance"})
+	}
+	_ = models.CrearAuditoria(tx, "horarios_asistencia_estado", hae.ID, models.AuditoriaInsert, nil, &hae, &claims.UserID)
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error registering attendance"})
+	}
+	if h.orch != nil {
+		// Eventos INASISTENCIA ya confirmados: las reglas se evaluan fuera del request
+		h.orch.Flush()
 	}
-	tx.Commit()
+
+	// Asistencia diaria del curso para la fecha (se vuelve a consolidar con cada bloque)
+	recalcularDiaria(h.db, fecha, fecha, rollup.Filtro{CursoID: &horario.CursoID})
 
 	// WS: presencia del profesor por bloque (para monitor inspectoría)
 	if h.orch != nil {
@@ -160,42 +339,16 @@ func (h *AsistenciaHandler) RegistrarBloque(c *fiber.Ctx) error {
 		// upsert por curso_id
 		h.db.Where("curso_id = ?", horario.CursoID).Assign(ce).FirstOrCreate(&ce)
 
-		// Snapshot por bloque+fecha (conteos)
-		presentes, ausentes, justificados := 0, 0, 0
-		for _, r := range req.Registros {
-			switch r.Estado {
-			case models.EstadoPresente:
-				presentes++
-			case models.EstadoJustificado:
-				justificados++
-			default:
-				ausentes++
-			}
-		}
-		hae := models.HorarioAsistenciaEstado{
-			HorarioID:              req.HorarioID,
-			Fecha:                  fecha,
-			CursoID:                horario.CursoID,
-			BloqueID:               horario.BloqueID,
-			DiaSemana:              horario.DiaSemana,
-			ProfesorID:             horario.ProfesorID,
-			Presentes:              presentes,
-			Ausentes:               ausentes,
-			Justificados:           justificados,
-			UltimaActualizacionEn:  &now,
-			UltimaActualizacionPor: claims.UserID,
-		}
-		h.db.Where("horario_id = ? AND fecha = ?", req.HorarioID, fecha).Assign(hae).FirstOrCreate(&hae)
-		_ = models.CrearAuditoria(h.db, "horarios_asistencia_estado", hae.ID, models.AuditoriaInsert, nil, &hae, &claims.UserID)
-
 		h.orch.Notify("asistencia_bloque_registrada", map[string]interface{}{
 			"curso_id":       horario.CursoID.String(),
 			"horario_id":     req.HorarioID.String(),
 			"registrado_por": claims.UserID.String(),
 			"fecha":          req.Fecha,
-			"presentes":      presentes,
-			"ausentes":       ausentes,
-			"justificados":   justificados,
+			"presentes":      hae.Presentes,
+			"ausentes":       hae.Ausentes,
+			"justificados":   hae.Justificados,
+			"atrasos":        hae.Atrasos,
+			"retiros":        hae.Retiros,
 		})
 	}
 
@@ -258,6 +411,38 @@ type EstadoTemporalRequest struct {
 	Tipo string `json:"tipo"` // bano, enfermeria, sos
 }
 
+// GET /asistencia/pendientes?fecha=&curso_id=&profesor_id=
+// Bloques ya terminados que se dictaban (calendario + excepciones) y no tienen asistencia registrada.
+func (h *AsistenciaHandler) GetPendientes(c *fiber.Ctx) error {
+	now := time.Now().In(zonaColegio())
+	fecha, err := time.Parse("2006-01-02", c.Query("fecha", now.Format("2006-01-02")))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid date format, use YYYY-MM-DD"})
+	}
+	var f agenda.Filtro
+	var ok bool
+	if f.CursoID, ok = uuidQuery(c, "curso_id"); !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid curso_id"})
+	}
+	if f.ProfesorID, ok = uuidQuery(c, "profesor_id"); !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid profesor_id"})
+	}
+
+	corte := "24:00"
+	switch hoy := now.Format("2006-01-02"); {
+	case fecha.Format("2006-01-02") == hoy:
+		corte = now.Format("15:04")
+	case fecha.Format("2006-01-02") > hoy:
+		return c.JSON([]models.Horario{})
+	}
+
+	pendientes, err := agenda.Pendientes(h.db, fecha, f, corte)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching pending attendance"})
+	}
+	return c.JSON(pendientes)
+}
+
 // SetEstadoTemporal establece un estado temporal para un alumno
 func (h *AsistenciaHandler) SetEstadoTemporal(c *fiber.Ctx) error {
 	claims := middleware.GetUserFromContext(c)
@@ -334,7 +519,9 @@ func (h *AsistenciaHandler) SetEstadoTemporal(c *fiber.Ctx) error {
 				var activos []models.Evento
 				h.db.Where("alumno_id = ? AND activo = ? AND concepto_id IN ?", alumnoID, true, conceptoIDs).Find(&activos)
 				for _, e := range activos {
-					_, _ = h.orch.CloseEvento(e.ID, claims.UserID)
+					if _, err := h.orch.CloseEvento(e.ID, claims.UserID, models.MotivoCierreReemplazado); err != nil {
+						return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error closing previous event"})
+					}
 				}
 
 				datos, _ := json.Marshal(map[string]interface{}{
@@ -351,7 +538,9 @@ func (h *AsistenciaHandler) SetEstadoTemporal(c *fiber.Ctx) error {
 					Datos:         datos,
 					Activo:        true,
 				}
-				_ = h.orch.CreateEvento(&evt, &claims.UserID)
+				if err := h.orch.CreateEvento(&evt, &claims.UserID); err != nil {
+					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating event"})
+				}
 			}
 		}
 	}
@@ -392,7 +581,9 @@ func (h *AsistenciaHandler) ClearEstadoTemporal(c *fiber.Ctx) error {
 		var activos []models.Evento
 		h.db.Where("alumno_id = ? AND activo = ? AND concepto_id IN ?", alumnoID, true, conceptoIDs).Find(&activos)
 		for _, e := range activos {
-			_, _ = h.orch.CloseEvento(e.ID, claims.UserID)
+			if _, err := h.orch.CloseEvento(e.ID, claims.UserID, models.MotivoCierreResuelto); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error closing event"})
+			}
 		}
 	}
 
@@ -410,3 +601,192 @@ func (h *AsistenciaHandler) GetEstadosTemporalesActivos(c *fiber.Ctx) error {
 
 	return c.JSON(estados)
 }
+
+// errEscritura error al escribir los registros de un bloque; msg es el mensaje para el cliente
+type errEscritura struct {
+	msg string
+	err error
+}
+
+func (e *errEscritura) Error() string { return e.msg + ": " + e.err.Error() }
+
+// escribirRegistros hace el upsert de las filas de asistencia del bloque (con auditoria antes/despues)
+// y deriva los eventos INASISTENCIA / ATRASO / RETIRO. Lo usan el registro del profesor y las
+// correcciones aprobadas; el llamador maneja la transaccion.
+func (h *AsistenciaHandler) escribirRegistros(tx *gorm.DB, horario models.Horario, fecha time.Time, registros []RegistroAlumno, detalles []detalleRegistro, usuarioID uuid.UUID) error {
+	fechaStr := fecha.Format("2006-01-02")
+
+	// Concepto INASISTENCIA (si hay ausentes, se registran como Evento)
+	var conceptoInasistencia models.Concepto
+	_ = tx.First(&conceptoInasistencia, "codigo = ?", models.ConceptoInasistencia).Error
+
+	// Conceptos ATRASO / RETIRO (un Evento por alumno y dia, para reglas de reiteracion)
+	conceptosDia := map[string]models.Concepto{}
+	var conceptosAtrasoRetiro []models.Concepto
+	tx.Where("codigo IN ?", []string{models.ConceptoAtraso, models.ConceptoRetiro}).Find(&conceptosAtrasoRetiro)
+	for _, cpt := range conceptosAtrasoRetiro {
+		if cpt.Codigo == models.ConceptoAtraso {
+			conceptosDia[models.EstadoAtraso] = cpt
+		} else {
+			conceptosDia[models.EstadoRetiro] = cpt
+		}
+	}
+
+	for i, registro := range registros {
+		// Detectar si ya existia asistencia para saber si cambio (para auditoria simple)
+		var prev models.Asistencia
+		prevFound := tx.Where("alumno_id = ? AND horario_id = ? AND fecha = ?",
+			registro.AlumnoID, horario.ID, fecha).
+			First(&prev).Error == nil
+
+		asistencia := models.Asistencia{
+			AlumnoID:      registro.AlumnoID,
+			HorarioID:     horario.ID,
+			Fecha:         fecha,
+			Estado:        registro.Estado,
+			RegistradoPor: usuarioID,
+
+			HoraLlegada:         detalles[i].llegada,
+			HoraRetiro:          detalles[i].retiro,
+			RetiradoPorNombre:   strings.TrimSpace(registro.RetiradoPorNombre),
+			RetiradoPorRelacion: strings.TrimSpace(registro.RetiradoPorRelacion),
+		}
+
+		// Upsert: actualizar si ya existe. Se escriben todas las columnas del registro (tambien nil / vacio)
+		// para que un atraso o retiro corregido no deje horas ni datos de retiro viejos
+		var err error
+		if prevFound {
+			asistencia.ID, asistencia.CreatedAt = prev.ID, prev.CreatedAt
+			err = tx.Model(&asistencia).
+				Select("estado", "registrado_por", "hora_llegada", "hora_retiro", "retirado_por_nombre", "retirado_por_relacion").
+				Updates(&asistencia).Error
+		} else {
+			err = tx.Create(&asistencia).Error
+		}
+		if err != nil {
+			return &errEscritura{"Error registering attendance", err}
+		}
+
+		// Auditoria basica (no guardamos "antes" real si no existia)
+		if prevFound {
+			_ = models.CrearAuditoria(tx, "asistencias", asistencia.ID, models.AuditoriaUpdate, &prev, &asistencia, &usuarioID)
+		} else {
+			_ = models.CrearAuditoria(tx, "asistencias", asistencia.ID, models.AuditoriaInsert, nil, &asistencia, &usuarioID)
+		}
+
+		// Orquestacion: si se marca AUSENTE => crear Evento INASISTENCIA (deduplicando por dia)
+		if h.orch != nil && conceptoInasistencia.ID != uuid.Nil && registro.Estado == models.EstadoAusente {
+			// Por el dia de la inasistencia (datos.fecha), no por created_at: un registro tardio o
+			// corregido otro dia no duplica el evento
+			var existing int64
+			tx.Model(&models.Evento{}).
+				Where("concepto_id = ? AND alumno_id = ? AND (datos->>'fecha') = ?",
+					conceptoInasistencia.ID, registro.AlumnoID, fechaStr).
+				Count(&existing)
+
+			if existing == 0 {
+				datos, _ := json.Marshal(map[string]interface{}{
+					"horario_id": horario.ID,
+					"fecha":      fechaStr,
+				})
+				cid := conceptoInasistencia.ID
+				evt := models.Evento{
+					ConceptoID:    &cid,
+					AlumnoID:      &registro.AlumnoID,
+					CursoID:       &horario.CursoID,
+					Origen:        models.OrigenProfesor,
+					OrigenUsuario: &usuarioID,
+					Datos:         datos,
+					Activo:        true,
+				}
+				if err := h.orch.CreateEventoTx(tx, &evt, &usuarioID); err != nil {
+					return &errEscritura{"Error creating absence event", err}
+				}
+			}
+		}
+
+		// Atraso/retiro => Evento ATRASO/RETIRO (deduplicando por alumno y fecha)
+		if cpt, ok := conceptosDia[registro.Estado]; ok && h.orch != nil {
+			var existing int64
+			tx.Model(&models.Evento{}).
+				Where("concepto_id = ? AND alumno_id = ? AND (datos->>'fecha') = ?", cpt.ID, registro.AlumnoID, fechaStr).
+				Count(&existing)
+
+			if existing == 0 {
+				payload := map[string]interface{}{
+					"horario_id": horario.ID,
+					"fecha":      fechaStr,
+				}
+				if asistencia.HoraLlegada != nil {
+					payload["hora_llegada"] = asistencia.HoraLlegada.Format("15:04")
+				}
+				if asistencia.HoraRetiro != nil {
+					payload["hora_retiro"] = asistencia.HoraRetiro.Format("15:04")
+					payload["retirado_por_nombre"] = asistencia.RetiradoPorNombre
+					payload["retirado_por_relacion"] = asistencia.RetiradoPorRelacion
+				}
+				datos, _ := json.Marshal(payload)
+				cid := cpt.ID
+				evt := models.Evento{
+					ConceptoID:    &cid,
+					AlumnoID:      &registro.AlumnoID,
+					CursoID:       &horario.CursoID,
+					Origen:        models.OrigenProfesor,
+					OrigenUsuario: &usuarioID,
+					Datos:         datos,
+					Activo:        true,
+				}
+				if err := h.orch.CreateEventoTx(tx, &evt, &usuarioID); err != nil {
+					return &errEscritura{"Error creating attendance event", err}
+				}
+			}
+		}
+
+		// Si el alumno pasa de AUSENTE a PRESENTE (o llego atrasado / se retiro) o a JUSTIFICADO,
+		// cerrar el evento INASISTENCIA activo del dia (si existe)
+		motivoCierre := ""
+		switch registro.Estado {
+		case models.EstadoPresente, models.EstadoAtraso, models.EstadoRetiro:
+			motivoCierre = models.MotivoCierreCorreccion
+		case models.EstadoJustificado:
+			motivoCierre = models.MotivoCierreJustificado
+		}
+		if h.orch != nil && conceptoInasistencia.ID != uuid.Nil &&
+			motivoCierre != "" && prevFound && prev.Estado == models.EstadoAusente {
+			var eventos []models.Evento
+			tx.Where("concepto_id = ? AND alumno_id = ? AND activo = ? AND (datos->>'fecha') = ?",
+				conceptoInasistencia.ID, registro.AlumnoID, true, fechaStr).
+				Find(&eventos)
+			for _, e := range eventos {
+				if _, err := h.orch.CloseEventoTx(tx, e.ID, usuarioID, motivoCierre); err != nil {
+					return &errEscritura{"Error closing absence event", err}
+				}
+			}
+		}
+
+		// Si deja de ser atraso / retiro, cerrar el evento ATRASO / RETIRO del dia, salvo que otro bloque
+		// de ese dia lo siga registrando (el evento es por alumno y fecha)
+		if cpt, ok := conceptosDia[prev.Estado]; ok && h.orch != nil && prevFound && prev.Estado != registro.Estado {
+			var otros int64
+			tx.Model(&models.Asistencia{}).
+				Where("alumno_id = ? AND fecha = ? AND estado = ? AND horario_id <> ?", registro.AlumnoID, fecha, prev.Estado, horario.ID).
+				Count(&otros)
+			if otros == 0 {
+				motivo := motivoCierre
+				if motivo == "" {
+					motivo = models.MotivoCierreCorreccion
+				}
+				var eventos []models.Evento
+				tx.Where("concepto_id = ? AND alumno_id = ? AND activo = ? AND (datos->>'fecha') = ?",
+					cpt.ID, registro.AlumnoID, true, fechaStr).
+					Find(&eventos)
+				for _, e := range eventos {
+					if _, err := h.orch.CloseEventoTx(tx, e.ID, usuarioID, motivo); err != nil {
+						return &errEscritura{"Error closing attendance event", err}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/api/middleware"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/rollup"
+	"gorm.io/gorm"
+)
+
+// Rango maximo de dias para un recalculo a demanda
+const maxDiasRecalculo = 62
+
+// AsistenciaDiariaHandler maneja la asistencia diaria consolidada
+type AsistenciaDiariaHandler struct {
+	db *gorm.DB
+}
+
+// NewAsistenciaDiariaHandler crea un nuevo handler de asistencia diaria
+func NewAsistenciaDiariaHandler(db *gorm.DB) *AsistenciaDiariaHandler {
+	return &AsistenciaDiariaHandler{db: db}
+}
+
+// GET /asistencia-diaria?desde=&hasta=&curso_id=&alumno_id=&estado=
+// Sin fechas retorna el dia de hoy.
+func (h *AsistenciaDiariaHandler) GetAll(c *fiber.Ctx) error {
+	hoy := time.Now().Format("2006-01-02")
+	desde, err := time.Parse("2006-01-02", c.Query("desde", c.Query("fecha", hoy)))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid desde, use YYYY-MM-DD"})
+	}
+	hasta, err := time.Parse("2006-01-02", c.Query("hasta", desde.Format("2006-01-02")))
+	if err != nil || hasta.Before(desde) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid hasta, use YYYY-MM-DD"})
+	}
+
+	q := h.db.Preload("Alumno").Model(&models.AsistenciaDiaria{}).
+		Where("fecha >= ? AND fecha <= ?", desde, hasta)
+	if cursoID := c.Query("curso_id"); cursoID != "" {
+		if id, err := uuid.Parse(cursoID); err == nil {
+			q = q.Where("curso_id = ?", id)
+		}
+	}
+	if alumnoID := c.Query("alumno_id"); alumnoID != "" {
+		if id, err := uuid.Parse(alumnoID); err == nil {
+			q = q.Where("alumno_id = ?", id)
+		}
+	}
+	if estado := c.Query("estado"); estado != "" {
+		q = q.Where("estado = ?", estado)
+	}
+
+	var out []models.AsistenciaDiaria
+	if err := q.Order("fecha, curso_id").Find(&out).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching daily attendance"})
+	}
+	return c.JSON(out)
+}
+
+// RecalcularRequest estructura para recalcular a demanda
+type RecalcularRequest struct {
+	Desde   string     `json:"desde"` // YYYY-MM-DD
+	Hasta   string     `json:"hasta"` // YYYY-MM-DD (default = desde)
+	CursoID *uuid.UUID `json:"curso_id,omitempty"`
+}
+
+// POST /asistencia-diaria/recalcular
+func (h *AsistenciaDiariaHandler) Recalcular(c *fiber.Ctx) error {
+	var req RecalcularRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	desde, err := time.Parse("2006-01-02", req.Desde)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid desde, use YYYY-MM-DD"})
+	}
+	hasta := desde
+	if req.Hasta != "" {
+		if hasta, err = time.Parse("2006-01-02", req.Hasta); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid hasta, use YYYY-MM-DD"})
+		}
+	}
+	if hasta.Before(desde) || hasta.Sub(desde) > maxDiasRecalculo*24*time.Hour {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid range (max 62 days)"})
+	}
+
+	n, err := rollup.Recalcular(h.db, desde, hasta, rollup.Filtro{CursoID: req.CursoID})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error recomputing daily attendance"})
+	}
+	return c.JSON(fiber.Map{
+		"recalculadas": n,
+		"politica":     rollup.PoliticaDesdeEnv(),
+	})
+}
+
+// CorreccionDiariaRequest estructura para corregir el estado diario
+type CorreccionDiariaRequest struct {
+	Estado string `json:"estado"` // presente, ausente, justificado
+	Motivo string `json:"motivo"`
+}
+
+// PUT /asistencia-diaria/{id}
+// La correccion queda auditada y el recalculo automatico deja de sobreescribir la fila.
+func (h *AsistenciaDiariaHandler) Corregir(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid daily attendance ID"})
+	}
+
+	var req CorreccionDiariaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if !models.EsEstadoDiarioValido(req.Estado) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid state (presente, ausente, justificado)"})
+	}
+	if strings.TrimSpace(req.Motivo) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "motivo is required"})
+	}
+
+	var d models.AsistenciaDiaria
+	if err := h.db.First(&d, "id = ?", id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Daily attendance not found"})
+	}
+
+	before := d
+	now := time.Now()
+	d.Estado = req.Estado
+	d.Corregido = true
+	d.CorregidoPor = &claims.UserID
+	d.CorregidoEn = &now
+	d.MotivoCorreccion = strings.TrimSpace(req.Motivo)
+
+	if err := h.db.Save(&d).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating daily attendance"})
+	}
+	_ = models.CrearAuditoria(h.db, "asistencias_diarias", d.ID, models.AuditoriaUpdate, &before, &d, &claims.UserID)
+
+	return c.JSON(d)
+}
+
+// recalcularDiaria refresca la asistencia diaria tras cambios en los registros por bloque (best-effort).
+func recalcularDiaria(db *gorm.DB, desde, hasta time.Time, f rollup.Filtro) {
+	if _, err := rollup.Recalcular(db, desde, hasta, f); err != nil {
+		log.Printf("asistencia diaria: recalculo %s..%s: %v", desde.Format("2006-01-02"), hasta.Format("2006-01-02"), err)
+	}
+}
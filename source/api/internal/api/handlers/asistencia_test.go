@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/orchestrator"
+	"github.com/school-monitoring/backend/internal/testutil"
+	"gorm.io/gorm"
+)
+
+func TestHoraEnFecha(t *testing.T) {
+	// La fecha llega como medianoche UTC (time.Parse de YYYY-MM-DD): se respeta el dia calendario
+	fecha := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	got, ok := horaEnFecha(fecha, "08:15")
+	if !ok {
+		t.Fatal("hora valida rechazada")
+	}
+	want := time.Date(2026, 3, 2, 8, 15, 0, 0, zonaColegio())
+	if !got.Equal(want) || got.Location() != zonaColegio() {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if _, ok := horaEnFecha(fecha, "8h15"); ok {
+		t.Fatal("hora invalida aceptada")
+	}
+}
+
+func TestLlegadaPorDefecto(t *testing.T) {
+	horario := models.Horario{Bloque: &models.BloqueHorario{HoraInicio: "09:00", HoraFin: "09:45"}}
+	fecha := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	mismoDia := time.Date(2026, 3, 2, 9, 20, 0, 0, zonaColegio())
+	diasDespues := time.Date(2026, 3, 5, 16, 0, 0, 0, zonaColegio())
+
+	cases := []struct {
+		name    string
+		horario models.Horario
+		now     time.Time
+		want    time.Time
+	}{
+		{"mismo dia: hora del registro", horario, mismoDia, mismoDia},
+		{"fecha pasada: inicio del bloque", horario, diasDespues, time.Date(2026, 3, 2, 9, 0, 0, 0, zonaColegio())},
+		{"fecha pasada sin bloque: inicio del dia", models.Horario{}, diasDespues, time.Date(2026, 3, 2, 0, 0, 0, 0, zonaColegio())},
+	}
+	for _, tc := range cases {
+		if got := llegadaPorDefecto(tc.horario, fecha, tc.now); !got.Equal(tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// El evento INASISTENCIA se deduplica y se cierra por el dia de la inasistencia (datos.fecha), aunque
+// el registro se haga otro dia; pasar de ausente a justificado tambien lo cierra.
+func TestEscribirRegistrosInasistencia(t *testing.T) {
+	db := testutil.DB(t)
+	horario, alumno, prof := nuevoBloque(t, db, 1)
+	concepto := models.Concepto{Codigo: models.ConceptoInasistencia, Nombre: "Inasistencia"}
+	if err := db.Where("codigo = ?", concepto.Codigo).FirstOrCreate(&concepto).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewAsistenciaHandler(db, orchestrator.New(db, nil, nil))
+	// Fecha pasada: el evento se crea hoy, distinto del dia de la inasistencia
+	fecha := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	escribir := func(estado string) {
+		t.Helper()
+		regs := []RegistroAlumno{{AlumnoID: alumno.ID, Estado: estado}}
+		if err := h.escribirRegistros(db, horario, fecha, regs, make([]detalleRegistro, 1), prof.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	eventos := func() []models.Evento {
+		var out []models.Evento
+		db.Where("concepto_id = ? AND alumno_id = ?", concepto.ID, alumno.ID).Find(&out)
+		return out
+	}
+
+	escribir(models.EstadoAusente)
+	escribir(models.EstadoAusente)
+	evs := eventos()
+	if len(evs) != 1 || !evs[0].Activo {
+		t.Fatalf("esperaba un evento activo, hay %d", len(evs))
+	}
+
+	escribir(models.EstadoJustificado)
+	evs = eventos()
+	if len(evs) != 1 || evs[0].Activo || evs[0].MotivoCierre != models.MotivoCierreJustificado {
+		t.Fatalf("ausente -> justificado debe cerrar el evento: %+v", evs)
+	}
+}
+
+// Atraso corregido a presente: no quedan hora de llegada vieja ni evento ATRASO activo del dia
+func TestEscribirRegistrosCorrigeAtraso(t *testing.T) {
+	db := testutil.DB(t)
+	horario, alumno, prof := nuevoBloque(t, db, 1)
+	concepto := models.Concepto{Codigo: models.ConceptoAtraso, Nombre: "Atraso"}
+	if err := db.Where("codigo = ?", concepto.Codigo).FirstOrCreate(&concepto).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewAsistenciaHandler(db, orchestrator.New(db, nil, nil))
+	fecha := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	llegada := time.Date(2026, 3, 2, 8, 20, 0, 0, zonaColegio())
+	regs := []RegistroAlumno{{AlumnoID: alumno.ID, Estado: models.EstadoAtraso}}
+	if err := h.escribirRegistros(db, horario, fecha, regs, []detalleRegistro{{llegada: &llegada}}, prof.ID); err != nil {
+		t.Fatal(err)
+	}
+	regs[0].Estado = models.EstadoPresente
+	if err := h.escribirRegistros(db, horario, fecha, regs, make([]detalleRegistro, 1), prof.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	var a models.Asistencia
+	if err := db.First(&a, "alumno_id = ? AND horario_id = ?", alumno.ID, horario.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if a.Estado != models.EstadoPresente || a.HoraLlegada != nil {
+		t.Fatalf("asistencia corregida: estado=%s hora_llegada=%v", a.Estado, a.HoraLlegada)
+	}
+	var evs []models.Evento
+	db.Where("concepto_id = ? AND alumno_id = ?", concepto.ID, alumno.ID).Find(&evs)
+	if len(evs) != 1 || evs[0].Activo || evs[0].MotivoCierre != models.MotivoCierreCorreccion {
+		t.Fatalf("atraso -> presente debe cerrar el evento ATRASO: %+v", evs)
+	}
+}
+
+// nuevoBloque crea un horario de todo el dia (curso, asignatura, profesor y bloque propios) con un alumno
+func nuevoBloque(t *testing.T, db *gorm.DB, dia int) (models.Horario, models.Alumno, models.Usuario) {
+	t.Helper()
+	curso := models.Curso{Nombre: "Test " + uuid.NewString()[:8], Nivel: models.NivelBasica}
+	asig := models.Asignatura{Nombre: "Test " + uuid.NewString()[:8]}
+	prof := models.Usuario{Email: uuid.NewString()[:8] + "@test.cl", PasswordHash: "x", Nombre: "Prof", Rol: models.RolProfesor}
+	bloque := models.BloqueHorario{Numero: 99, HoraInicio: "00:00", HoraFin: "23:59"}
+	for _, v := range []interface{}{&curso, &asig, &prof, &bloque} {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	alumno := models.Alumno{CursoID: curso.ID, Nombre: "Ana", Apellido: "Test", Rut: uuid.NewString()[:12], Activo: true}
+	horario := models.Horario{CursoID: curso.ID, AsignaturaID: asig.ID, ProfesorID: prof.ID, BloqueID: bloque.ID, DiaSemana: dia}
+	for _, v := range []interface{}{&alumno, &horario} {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	horario.Bloque = &bloque
+	return horario, alumno, prof
+}
@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/api/middleware"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/calendario"
+	"github.com/school-monitoring/backend/internal/services/rollup"
+	"gorm.io/gorm"
+)
+
+// Rango maximo consultable / recalculado de una vez
+const (
+	maxDiasCalendario   = 400
+	maxDiasRecalculoCal = 120
+)
+
+// CalendarioHandler maneja el calendario escolar (feriados, suspensiones, jornadas especiales)
+type CalendarioHandler struct {
+	db *gorm.DB
+}
+
+// NewCalendarioHandler crea un nuevo handler de calendario
+func NewCalendarioHandler(db *gorm.DB) *CalendarioHandler {
+	return &CalendarioHandler{db: db}
+}
+
+// GET /calendario?desde=&hasta=&curso_id=&tipo=
+func (h *CalendarioHandler) GetAll(c *fiber.Ctx) error {
+	desde, hasta, ok := rangoCalendario(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid date range, use YYYY-MM-DD (max 400 days)"})
+	}
+	q := h.db.Preload("Curso").
+		Where("fecha_inicio <= ? AND fecha_fin >= ?", hasta.Format("2006-01-02"), desde.Format("2006-01-02"))
+	if cursoID := c.Query("curso_id"); cursoID != "" {
+		q = q.Where("(curso_id = ? OR curso_id IS NULL)", cursoID)
+	}
+	if tipo := c.Query("tipo"); tipo != "" {
+		q = q.Where("tipo = ?", tipo)
+	}
+	var entradas []models.CalendarioEntrada
+	if err := q.Order("fecha_inicio, nombre").Find(&entradas).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching calendar"})
+	}
+	return c.JSON(entradas)
+}
+
+// GET /calendario/dias?desde=&hasta=&curso_id= resuelve dia a dia si hay clases y con que horario
+func (h *CalendarioHandler) GetDias(c *fiber.Ctx) error {
+	desde, hasta, ok := rangoCalendario(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid date range, use YYYY-MM-DD (max 400 days)"})
+	}
+	cursoID, ok := uuidQuery(c, "curso_id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid curso_id"})
+	}
+	cal, err := calendario.Cargar(h.db, desde, hasta)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching calendar"})
+	}
+	dias := cal.Dias(desde, hasta, cursoID)
+	lectivos := 0
+	for _, d := range dias {
+		if d.Lectivo {
+			lectivos++
+		}
+	}
+	return c.JSON(fiber.Map{"dias_lectivos": lectivos, "dias": dias})
+}
+
+type CalendarioEntradaRequest struct {
+	Tipo             string     `json:"tipo"`
+	Nombre           string     `json:"nombre"`
+	Descripcion      string     `json:"descripcion"`
+	FechaInicio      string     `json:"fecha_inicio"` // YYYY-MM-DD
+	FechaFin         string     `json:"fecha_fin"`    // opcional (default = fecha_inicio)
+	CursoID          *uuid.UUID `json:"curso_id"`
+	DiaSemanaHorario *int       `json:"dia_semana_horario"`
+	Bloques          []int      `json:"bloques"`
+}
+
+// aplicar valida la request y la copia sobre la entrada
+func (r CalendarioEntradaRequest) aplicar(e *models.CalendarioEntrada) string {
+	if !models.EsTipoCalendarioValido(r.Tipo) {
+		return "tipo must be feriado, suspension, vacaciones, jornada_especial or recuperativo"
+	}
+	if strings.TrimSpace(r.Nombre) == "" {
+		return "nombre is required"
+	}
+	inicio, err := time.Parse("2006-01-02", r.FechaInicio)
+	if err != nil {
+		return "Invalid fecha_inicio, use YYYY-MM-DD"
+	}
+	fin := inicio
+	if r.FechaFin != "" {
+		if fin, err = time.Parse("2006-01-02", r.FechaFin); err != nil || fin.Before(inicio) {
+			return "Invalid fecha_fin, use YYYY-MM-DD (>= fecha_inicio)"
+		}
+	}
+	if r.DiaSemanaHorario != nil && (*r.DiaSemanaHorario < 1 || *r.DiaSemanaHorario > 5) {
+		return "dia_semana_horario must be 1..5"
+	}
+	if r.Tipo == models.CalendarioRecuperativo && r.DiaSemanaHorario == nil {
+		return "dia_semana_horario is required for recuperativo"
+	}
+	for _, b := range r.Bloques {
+		if b < 1 {
+			return "Invalid bloques"
+		}
+	}
+
+	e.Tipo = r.Tipo
+	e.Nombre = strings.TrimSpace(r.Nombre)
+	e.Descripcion = r.Descripcion
+	e.FechaInicio = inicio
+	e.FechaFin = fin
+	e.CursoID = r.CursoID
+	e.DiaSemanaHorario = nil
+	e.Bloques = nil
+	if r.Tipo == models.CalendarioJornadaEspecial || r.Tipo == models.CalendarioRecuperativo {
+		e.DiaSemanaHorario = r.DiaSemanaHorario
+		if len(r.Bloques) > 0 {
+			e.Bloques, _ = json.Marshal(r.Bloques)
+		}
+	}
+	return ""
+}
+
+// POST /calendario
+func (h *CalendarioHandler) Create(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+
+	var req CalendarioEntradaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	e := models.CalendarioEntrada{Origen: models.CalendarioOrigenManual, CreadoPor: userIDPtr(claims)}
+	if msg := req.aplicar(&e); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	if err := h.db.Create(&e).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating calendar entry"})
+	}
+	_ = models.CrearAuditoria(h.db, "calendario_entradas", e.ID, models.AuditoriaInsert, nil, &e, userIDPtr(claims))
+
+	h.recalcular(e.FechaInicio, e.FechaFin, e.CursoID)
+	return c.Status(fiber.StatusCreated).JSON(e)
+}
+
+// PUT /calendario/:id
+func (h *CalendarioHandler) Update(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+
+	var e models.CalendarioEntrada
+	if err := h.db.First(&e, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Calendar entry not found"})
+	}
+	var req CalendarioEntradaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	before := e
+	if msg := req.aplicar(&e); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	if err := h.db.Save(&e).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating calendar entry"})
+	}
+	_ = models.CrearAuditoria(h.db, "calendario_entradas", e.ID, models.AuditoriaUpdate, &before, &e, userIDPtr(claims))
+
+	h.recalcular(before.FechaInicio, before.FechaFin, before.CursoID)
+	h.recalcular(e.FechaInicio, e.FechaFin, e.CursoID)
+	return c.JSON(e)
+}
+
+// DELETE /calendario/:id
+func (h *CalendarioHandler) Delete(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+
+	var e models.CalendarioEntrada
+	if err := h.db.First(&e, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Calendar entry not found"})
+	}
+	if err := h.db.Delete(&e).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error deleting calendar entry"})
+	}
+	_ = models.CrearAuditoria(h.db, "calendario_entradas", e.ID, models.AuditoriaDelete, &e, nil, userIDPtr(claims))
+
+	h.recalcular(e.FechaInicio, e.FechaFin, e.CursoID)
+	return c.JSON(fiber.Map{"message": "Calendar entry deleted"})
+}
+
+// ImportICalResponse resumen de la importacion
+type ImportICalResponse struct {
+	Eventos      int      `json:"eventos"`
+	Creadas      int      `json:"creadas"`
+	Actualizadas int      `json:"actualizadas"`
+	Errores      []string `json:"errores,omitempty"`
+}
+
+// POST /calendario/import/ical?tipo=feriado&curso_id=
+// Body: archivo .ics (text/calendar) o JSON {"ics": "..."}. Idempotente por UID.
+func (h *CalendarioHandler) ImportICal(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+
+	tipoDefecto := c.Query("tipo", models.CalendarioFeriado)
+	if !models.EsTipoCalendarioValido(tipoDefecto) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid tipo"})
+	}
+	cursoID, ok := uuidQuery(c, "curso_id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid curso_id"})
+	}
+
+	cuerpo := c.Body()
+	if strings.HasPrefix(strings.ToLower(c.Get(fiber.HeaderContentType)), fiber.MIMEApplicationJSON) {
+		var req struct {
+			ICS string `json:"ics"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+		cuerpo = []byte(req.ICS)
+	}
+	if len(bytes.TrimSpace(cuerpo)) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Empty calendar"})
+	}
+
+	eventos, err := calendario.LeerICal(bytes.NewReader(cuerpo))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid iCalendar: " + err.Error()})
+	}
+
+	resp := ImportICalResponse{Eventos: len(eventos)}
+	var minFecha, maxFecha time.Time
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		for i, ev := range eventos {
+			if ev.Nombre == "" {
+				ev.Nombre = "Sin titulo"
+			}
+			e := models.CalendarioEntrada{}
+			existe := false
+			if ev.UID != "" {
+				q := tx.Where("uid = ?", ev.UID)
+				if cursoID != nil {
+					q = q.Where("curso_id = ?", *cursoID)
+				} else {
+					q = q.Where("curso_id IS NULL")
+				}
+				existe = q.First(&e).Error == nil
+			}
+			before := e
+
+			e.Tipo = calendario.TipoDeCategorias(ev.Categorias, tipoDefecto)
+			e.Nombre = ev.Nombre
+			e.Descripcion = ev.Descripcion
+			e.FechaInicio = ev.Inicio
+			e.FechaFin = ev.Fin
+			e.CursoID = cursoID
+			e.UID = ev.UID
+			e.Origen = models.CalendarioOrigenICal
+			if e.Tipo == models.CalendarioRecuperativo && e.DiaSemanaHorario == nil {
+				resp.Errores = append(resp.Errores, "evento "+itoa(i+1)+": recuperativo sin dia_semana_horario, revisar manualmente")
+			}
+
+			if existe {
+				if err := tx.Save(&e).Error; err != nil {
+					return err
+				}
+				_ = models.CrearAuditoria(tx, "calendario_entradas", e.ID, models.AuditoriaUpdate, &before, &e, userIDPtr(claims))
+				resp.Actualizadas++
+			} else {
+				e.CreadoPor = userIDPtr(claims)
+				if err := tx.Create(&e).Error; err != nil {
+					return err
+				}
+				_ = models.CrearAuditoria(tx, "calendario_entradas", e.ID, models.AuditoriaInsert, nil, &e, userIDPtr(claims))
+				resp.Creadas++
+			}
+			if minFecha.IsZero() || e.FechaInicio.Before(minFecha) {
+				minFecha = e.FechaInicio
+			}
+			if e.FechaFin.After(maxFecha) {
+				maxFecha = e.FechaFin
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error importing calendar"})
+	}
+	if !minFecha.IsZero() {
+		h.recalcular(minFecha, maxFecha, cursoID)
+	}
+	return c.JSON(resp)
+}
+
+// GET /calendario/ical?desde=&hasta=&curso_id= exporta las entradas como iCalendar
+func (h *CalendarioHandler) ExportICal(c *fiber.Ctx) error {
+	desde, hasta, ok := rangoCalendario(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid date range, use YYYY-MM-DD (max 400 days)"})
+	}
+	q := h.db.Where("fecha_inicio <= ? AND fecha_fin >= ?", hasta.Format("2006-01-02"), desde.Format("2006-01-02"))
+	if cursoID := c.Query("curso_id"); cursoID != "" {
+		q = q.Where("(curso_id = ? OR curso_id IS NULL)", cursoID)
+	} else {
+		q = q.Where("curso_id IS NULL")
+	}
+	var entradas []models.CalendarioEntrada
+	if err := q.Order("fecha_inicio").Find(&entradas).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching calendar"})
+	}
+
+	nombre := "Calendario escolar"
+	if est := models.ObtenerEstablecimiento(h.db); est.Nombre != "" {
+		nombre += " - " + est.Nombre
+	}
+	var buf bytes.Buffer
+	if err := calendario.EscribirICal(&buf, nombre, entradas); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating calendar"})
+	}
+	c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="calendario_escolar.ics"`)
+	return c.Send(buf.Bytes())
+}
+
+// recalcular rehace la asistencia diaria de los dias pasados afectados por un cambio de calendario
+func (h *CalendarioHandler) recalcular(desde, hasta time.Time, cursoID *uuid.UUID) {
+	hoy := time.Now()
+	if desde.After(hoy) {
+		return
+	}
+	if hasta.After(hoy) {
+		hasta = hoy
+	}
+	if hasta.Sub(desde) > maxDiasRecalculoCal*24*time.Hour {
+		desde = hasta.AddDate(0, 0, -maxDiasRecalculoCal)
+	}
+	recalcularDiaria(h.db, desde, hasta, rollup.Filtro{CursoID: cursoID})
+}
+
+// rangoCalendario lee desde/hasta; por defecto el ano calendario en curso
+func rangoCalendario(c *fiber.Ctx) (time.Time, time.Time, bool) {
+	hoy := time.Now()
+	desde, err := time.Parse("2006-01-02", c.Query("desde", time.Date(hoy.Year(), 1, 1, 0, 0, 0, 0, time.UTC).Format("2006-01-02")))
+	if err != nil {
+		return desde, desde, false
+	}
+	hasta, err := time.Parse("2006-01-02", c.Query("hasta", time.Date(hoy.Year(), 12, 31, 0, 0, 0, 0, time.UTC).Format("2006-01-02")))
+	if err != nil || hasta.Before(desde) || hasta.Sub(desde) > maxDiasCalendario*24*time.Hour {
+		return desde, hasta, false
+	}
+	return desde, hasta, true
+}
+
+// uuidQuery lee un uuid opcional de la query (nil si no viene)
+func uuidQuery(c *fiber.Ctx, nombre string) (*uuid.UUID, bool) {
+	v := c.Query(nombre)
+	if v == "" {
+		return nil, true
+	}
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return nil, false
+	}
+	return &id, true
+}
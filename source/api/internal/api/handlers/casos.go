@@ -0,0 +1,444 @@
+package handlers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/api/middleware"
+	"github.com/school-monitoring/backend/internal/auth"
+	"github.com/school-monitoring/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// CasosHandler maneja endpoints de casos (seguimiento psicosocial/convivencia).
+// No emite broadcast WS: la informacion de casos es confidencial.
+type CasosHandler struct {
+	db *gorm.DB
+}
+
+// NewCasosHandler crea un nuevo handler de casos
+func NewCasosHandler(db *gorm.DB) *CasosHandler {
+	return &CasosHandler{db: db}
+}
+
+// GET /casos?estado=&alumno_id=&asignado_a=&limit=&offset=
+func (h *CasosHandler) GetAll(c *fiber.Ctx) error {
+	q := h.db.Preload("Alumno").Preload("Alumno.Curso").Preload("Asignado").Model(&models.Caso{})
+
+	if estado := c.Query("estado"); estado != "" {
+		q = q.Where("estado = ?", estado)
+	}
+	if alumnoID := c.Query("alumno_id"); alumnoID != "" {
+		if id, err := uuid.Parse(alumnoID); err == nil {
+			q = q.Where("alumno_id = ?", id)
+		}
+	}
+	if asignadoA := c.Query("asignado_a"); asignadoA != "" {
+		if id, err := uuid.Parse(asignadoA); err == nil {
+			q = q.Where("asignado_a = ?", id)
+		}
+	}
+
+	limit := clamp(atoi(c.Query("limit")), 1, 200)
+	offset := clamp(atoi(c.Query("offset")), 0, 1000000)
+
+	var out []models.Caso
+	if err := q.Order("updated_at DESC").Limit(limit).Offset(offset).Find(&out).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching cases"})
+	}
+	return c.JSON(out)
+}
+
+// GET /casos/{id}
+func (h *CasosHandler) GetByID(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid case ID"})
+	}
+
+	var caso models.Caso
+	q := h.db.Preload("Alumno").Preload("Alumno.Curso").Preload("Asignado").
+		Preload("Eventos", func(db *gorm.DB) *gorm.DB { return db.Order("created_at DESC") }).
+		Preload("Eventos.Concepto").
+		Preload("Alertas", func(db *gorm.DB) *gorm.DB { return db.Order("created_at DESC") }).
+		Preload("Notas", func(db *gorm.DB) *gorm.DB { return db.Order("created_at DESC") }).
+		Preload("Notas.Usuario").
+		Preload("Tareas", func(db *gorm.DB) *gorm.DB { return db.Order("completada, vence_en") })
+	// Los adjuntos son confidenciales: solo los ve quien puede gestionarlos (mismo permiso que /casos/:id/adjuntos)
+	if claims := middleware.GetUserFromContext(c); claims != nil && auth.TienePermiso(claims.Rol, auth.PermisoGestionarCasos) {
+		q = q.Preload("Adjuntos", func(db *gorm.DB) *gorm.DB { return db.Order("created_at DESC") })
+	}
+	if err := q.First(&caso, "id = ?", id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Case not found"})
+	}
+	return c.JSON(caso)
+}
+
+// CasoRequest estructura para crear/actualizar caso
+type CasoRequest struct {
+	AlumnoID    uuid.UUID   `json:"alumno_id"`
+	Titulo      string      `json:"titulo"`
+	Descripcion string      `json:"descripcion"`
+	Estado      string      `json:"estado"`
+	DerivadoA   string      `json:"derivado_a"`
+	AsignadoA   *uuid.UUID  `json:"asignado_a"`
+	EventoIDs   []uuid.UUID `json:"evento_ids,omitempty"`
+	AlertaIDs   []uuid.UUID `json:"alerta_ids,omitempty"`
+}
+
+// POST /casos
+func (h *CasosHandler) Create(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+	}
+
+	var req CasoRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.AlumnoID == uuid.Nil || strings.TrimSpace(req.Titulo) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "alumno_id and titulo are required"})
+	}
+
+	var alumno models.Alumno
+	if err := h.db.First(&alumno, "id = ?", req.AlumnoID).Error; err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Student not found"})
+	}
+
+	caso := models.Caso{
+		AlumnoID:    req.AlumnoID,
+		Titulo:      strings.TrimSpace(req.Titulo),
+		Descripcion: req.Descripcion,
+		Estado:      models.CasoAbierto,
+		AsignadoA:   req.AsignadoA,
+		CreadoPor:   claims.UserID,
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&caso).Error; err != nil {
+			return err
+		}
+		if err := h.vincular(tx, &caso, req.EventoIDs, req.AlertaIDs); err != nil {
+			return err
+		}
+		return models.CrearAuditoria(tx, "casos", caso.ID, models.AuditoriaInsert, nil, &caso, &claims.UserID)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating case"})
+	}
+
+	h.db.Preload("Alumno").Preload("Asignado").Preload("Eventos").Preload("Alertas").First(&caso, "id = ?", caso.ID)
+	return c.Status(fiber.StatusCreated).JSON(caso)
+}
+
+// PUT /casos/{id}
+func (h *CasosHandler) Update(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid case ID"})
+	}
+
+	var req CasoRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	var caso models.Caso
+	if err := h.db.First(&caso, "id = ?", id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Case not found"})
+	}
+	before := caso
+
+	if req.Titulo != "" {
+		caso.Titulo = strings.TrimSpace(req.Titulo)
+	}
+	if req.Descripcion != "" {
+		caso.Descripcion = req.Descripcion
+	}
+	if req.AsignadoA != nil {
+		caso.AsignadoA = req.AsignadoA
+	}
+	if req.Estado != "" && req.Estado != caso.Estado {
+		if !caso.PuedeTransicionar(req.Estado) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid state transition: " + caso.Estado + " -> " + req.Estado})
+		}
+		if req.Estado == models.CasoDerivado && strings.TrimSpace(req.DerivadoA) == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "derivado_a is required when referring a case"})
+		}
+		caso.Estado = req.Estado
+		switch req.Estado {
+		case models.CasoCerrado:
+			now := time.Now()
+			caso.CerradoEn = &now
+			caso.CerradoPor = &claims.UserID
+		case models.CasoAbierto:
+			caso.CerradoEn = nil
+			caso.CerradoPor = nil
+		}
+	}
+	if req.DerivadoA != "" {
+		caso.DerivadoA = strings.TrimSpace(req.DerivadoA)
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&caso).Error; err != nil {
+			return err
+		}
+		if err := h.vincular(tx, &caso, req.EventoIDs, req.AlertaIDs); err != nil {
+			return err
+		}
+		return models.CrearAuditoria(tx, "casos", caso.ID, models.AuditoriaUpdate, &before, &caso, &claims.UserID)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating case"})
+	}
+
+	h.db.Preload("Alumno").Preload("Asignado").Preload("Eventos").Preload("Alertas").First(&caso, "id = ?", caso.ID)
+	return c.JSON(caso)
+}
+
+// vincular asocia eventos y alertas (del mismo alumno) al caso
+func (h *CasosHandler) vincular(tx *gorm.DB, caso *models.Caso, eventoIDs, alertaIDs []uuid.UUID) error {
+	if len(eventoIDs) > 0 {
+		var eventos []models.Evento
+		if err := tx.Where("id IN ? AND alumno_id = ?", eventoIDs, caso.AlumnoID).Find(&eventos).Error; err != nil {
+			return err
+		}
+		if len(eventos) > 0 {
+			if err := tx.Model(caso).Association("Eventos").Append(&eventos); err != nil {
+				return err
+			}
+		}
+	}
+	if len(alertaIDs) > 0 {
+		var alertas []models.Alerta
+		if err := tx.Where("id IN ? AND alumno_id = ?", alertaIDs, caso.AlumnoID).Find(&alertas).Error; err != nil {
+			return err
+		}
+		if len(alertas) > 0 {
+			if err := tx.Model(caso).Association("Alertas").Append(&alertas); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// NotaCasoRequest estructura para agregar nota
+type NotaCasoRequest struct {
+	Texto string `json:"texto"`
+}
+
+// POST /casos/{id}/notas
+func (h *CasosHandler) AddNota(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+	}
+
+	caso, err := h.findCaso(c)
+	if caso == nil {
+		return err
+	}
+
+	var req NotaCasoRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if strings.TrimSpace(req.Texto) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Note text is required"})
+	}
+
+	nota := models.CasoNota{
+		CasoID:    caso.ID,
+		Texto:     strings.TrimSpace(req.Texto),
+		UsuarioID: claims.UserID,
+	}
+	if err := h.db.Create(&nota).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating note"})
+	}
+	_ = models.CrearAuditoria(h.db, "caso_notas", nota.ID, models.AuditoriaInsert, nil, &nota, &claims.UserID)
+
+	return c.Status(fiber.StatusCreated).JSON(nota)
+}
+
+// TareaCasoRequest estructura para crear/actualizar tarea
+type TareaCasoRequest struct {
+	Titulo      string     `json:"titulo"`
+	Descripcion string     `json:"descripcion"`
+	AsignadoA   *uuid.UUID `json:"asignado_a"`
+	VenceEn     string     `json:"vence_en"` // YYYY-MM-DD o RFC3339
+	Completada  *bool      `json:"completada"`
+}
+
+// POST /casos/{id}/tareas
+func (h *CasosHandler) AddTarea(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+	}
+
+	caso, err := h.findCaso(c)
+	if caso == nil {
+		return err
+	}
+
+	var req TareaCasoRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if strings.TrimSpace(req.Titulo) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Task title is required"})
+	}
+
+	tarea := models.CasoTarea{
+		CasoID:      caso.ID,
+		Titulo:      strings.TrimSpace(req.Titulo),
+		Descripcion: req.Descripcion,
+		AsignadoA:   req.AsignadoA,
+		CreadoPor:   claims.UserID,
+	}
+	if tarea.AsignadoA == nil {
+		tarea.AsignadoA = caso.AsignadoA
+	}
+	if req.VenceEn != "" {
+		t, ok := parseFechaHora(req.VenceEn)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid vence_en, use YYYY-MM-DD or RFC3339"})
+		}
+		tarea.VenceEn = &t
+	}
+
+	if err := h.db.Create(&tarea).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating task"})
+	}
+	_ = models.CrearAuditoria(h.db, "caso_tareas", tarea.ID, models.AuditoriaInsert, nil, &tarea, &claims.UserID)
+
+	return c.Status(fiber.StatusCreated).JSON(tarea)
+}
+
+// PUT /casos/{id}/tareas/{tareaId}
+func (h *CasosHandler) UpdateTarea(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+	}
+
+	caso, err := h.findCaso(c)
+	if caso == nil {
+		return err
+	}
+	tareaID, err := uuid.Parse(c.Params("tareaId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid task ID"})
+	}
+
+	var tarea models.CasoTarea
+	if err := h.db.First(&tarea, "id = ? AND caso_id = ?", tareaID, caso.ID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Task not found"})
+	}
+
+	var req TareaCasoRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	before := tarea
+
+	if req.Titulo != "" {
+		tarea.Titulo = strings.TrimSpace(req.Titulo)
+	}
+	if req.Descripcion != "" {
+		tarea.Descripcion = req.Descripcion
+	}
+	if req.AsignadoA != nil {
+		tarea.AsignadoA = req.AsignadoA
+	}
+	if req.VenceEn != "" {
+		t, ok := parseFechaHora(req.VenceEn)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid vence_en, use YYYY-MM-DD or RFC3339"})
+		}
+		tarea.VenceEn = &t
+	}
+	if req.Completada != nil && *req.Completada != tarea.Completada {
+		tarea.Completada = *req.Completada
+		if tarea.Completada {
+			now := time.Now()
+			tarea.CompletadaEn = &now
+			tarea.CompletadaPor = &claims.UserID
+		} else {
+			tarea.CompletadaEn = nil
+			tarea.CompletadaPor = nil
+		}
+	}
+
+	if err := h.db.Save(&tarea).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating task"})
+	}
+	_ = models.CrearAuditoria(h.db, "caso_tareas", tarea.ID, models.AuditoriaUpdate, &before, &tarea, &claims.UserID)
+
+	return c.JSON(tarea)
+}
+
+// GET /casos/tareas?asignado_a=&vencidas=true
+// Tareas pendientes de casos no cerrados (por defecto las del usuario autenticado).
+func (h *CasosHandler) GetTareasPendientes(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+	}
+
+	asignado := claims.UserID
+	if v := c.Query("asignado_a"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+		}
+		asignado = id
+	}
+
+	q := h.db.Model(&models.CasoTarea{}).
+		Joins("JOIN casos ON casos.id = caso_tareas.caso_id AND casos.deleted_at IS NULL").
+		Where("caso_tareas.completada = ? AND caso_tareas.asignado_a = ? AND casos.estado <> ?", false, asignado, models.CasoCerrado)
+	if c.Query("vencidas") == "true" {
+		q = q.Where("caso_tareas.vence_en < ?", time.Now())
+	}
+
+	var out []models.CasoTarea
+	if err := q.Order("caso_tareas.vence_en").Find(&out).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching tasks"})
+	}
+	return c.JSON(out)
+}
+
+// findCaso carga el caso de :id. Si no existe responde el error HTTP y retorna caso nil.
+func (h *CasosHandler) findCaso(c *fiber.Ctx) (*models.Caso, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid case ID"})
+	}
+	var caso models.Caso
+	if err := h.db.First(&caso, "id = ?", id).Error; err != nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Case not found"})
+	}
+	return &caso, nil
+}
+
+// parseFechaHora acepta YYYY-MM-DD o RFC3339
+func parseFechaHora(s string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/api/middleware"
+	"github.com/school-monitoring/backend/internal/auth"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/testutil"
+)
+
+// El detalle del caso solo incluye los adjuntos para quien tiene gestionar_casos
+func TestCasoAdjuntosPorPermiso(t *testing.T) {
+	db := testutil.DB(t)
+	_, alumno, prof := nuevoBloque(t, db, 1)
+	caso := models.Caso{AlumnoID: alumno.ID, Titulo: "Test", CreadoPor: prof.ID}
+	if err := db.Create(&caso).Error; err != nil {
+		t.Fatal(err)
+	}
+	adj := models.Adjunto{EntidadID: caso.ID, EntidadType: models.AdjuntoCaso, Nombre: "informe.pdf", MimeType: "application/pdf",
+		Tamano: 1, Sha256: "x", Almacen: "local", Clave: "x", SubidoPor: prof.ID}
+	if err := db.Create(&adj).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewCasosHandler(db)
+	adjuntos := func(rol string) int {
+		app := fiber.New()
+		app.Use(func(c *fiber.Ctx) error {
+			c.Locals(middleware.LocalsUserKey, &auth.Claims{UserID: uuid.New(), Rol: rol})
+			return c.Next()
+		})
+		app.Get("/casos/:id", h.GetByID)
+		resp, err := app.Test(httptest.NewRequest("GET", "/casos/"+caso.ID.String(), nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out models.Caso
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		return len(out.Adjuntos)
+	}
+	if n := adjuntos(models.RolInspector); n != 0 {
+		t.Errorf("inspector (solo ver_casos): %d adjuntos", n)
+	}
+	if n := adjuntos(models.RolAsistenteSocial); n != 1 {
+		t.Errorf("asistente social: %d adjuntos", n)
+	}
+}
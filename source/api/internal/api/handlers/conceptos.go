@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/esquema"
 	"gorm.io/gorm"
 )
 
@@ -41,12 +45,63 @@ func (h *ConceptosHandler) GetByID(c *fiber.Ctx) error {
 	return c.JSON(concepto)
 }
 
-// ConceptoRequest estructura para crear/actualizar concepto
+// GetDatosSchema GET /conceptos/{id}/datos-schema
+// JSON Schema de Evento.Datos para renderizar el formulario; sin esquema declarado retorna un objeto libre.
+func (h *ConceptosHandler) GetDatosSchema(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid concept ID"})
+	}
+
+	var concepto models.Concepto
+	if err := h.db.Select("id", "datos_schema").First(&concepto, "id = ?", id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Concept not found"})
+	}
+
+	schema := concepto.DatosSchema
+	if len(schema) == 0 {
+		schema = json.RawMessage(`{"type":"object"}`)
+	}
+	c.Set(fiber.HeaderContentType, "application/schema+json")
+	return c.Send(schema)
+}
+
+// ConceptoRequest estructura para crear/actualizar concepto.
+// DatosSchema: JSON Schema de Evento.Datos (null lo elimina en un update)
 type ConceptoRequest struct {
-	Codigo      string `json:"codigo"`
-	Nombre      string `json:"nombre"`
-	Descripcion string `json:"descripcion"`
-	Activo      *bool  `json:"activo"`
+	Codigo      string          `json:"codigo"`
+	Nombre      string          `json:"nombre"`
+	Descripcion string          `json:"descripcion"`
+	Activo      *bool           `json:"activo"`
+	Severidad   string          `json:"severidad,omitempty"` // baja, media (default), alta
+	Color       string          `json:"color,omitempty"`     // semaforo de sala; default segun severidad
+	Categoria   string          `json:"categoria,omitempty"` // default otro
+	DatosSchema json.RawMessage `json:"datos_schema,omitempty"`
+}
+
+// validarClasificacion verifica severidad, color y categoria enviados (vacios = sin cambio/default)
+func (r *ConceptoRequest) validarClasificacion() string {
+	if r.Severidad != "" && !models.EsSeveridadValida(r.Severidad) {
+		return "Invalid severidad (baja, media, alta)"
+	}
+	if r.Color != "" && !models.EsColorConceptoValido(r.Color) {
+		return "Invalid color (verde, amarillo, rojo)"
+	}
+	if r.Categoria != "" && !models.EsCategoriaValida(r.Categoria) {
+		return "Invalid categoria (asistencia, convivencia, salud, permanencia, otro)"
+	}
+	return ""
+}
+
+// esquemaDatos valida el esquema enviado; nil si viene vacio o null
+func esquemaDatos(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 || bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return nil, nil
+	}
+	if _, err := esquema.Compilar(raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
 }
 
 // Create crea un nuevo concepto
@@ -60,11 +115,24 @@ func (h *ConceptosHandler) Create(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Code and name are required"})
 	}
 
+	if msg := req.validarClasificacion(); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	schema, err := esquemaDatos(req.DatosSchema)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid datos_schema: " + err.Error()})
+	}
+
+	// Severidad, color y categoria vacios se completan al crear (Concepto.Clasificar)
 	concepto := models.Concepto{
 		Codigo:      req.Codigo,
 		Nombre:      req.Nombre,
 		Descripcion: req.Descripcion,
 		Activo:      true,
+		Severidad:   req.Severidad,
+		Color:       req.Color,
+		Categoria:   req.Categoria,
+		DatosSchema: schema,
 	}
 
 	if err := h.db.Create(&concepto).Error; err != nil {
@@ -103,6 +171,25 @@ func (h *ConceptosHandler) Update(c *fiber.Ctx) error {
 	if req.Activo != nil {
 		concepto.Activo = *req.Activo
 	}
+	if msg := req.validarClasificacion(); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	if req.Severidad != "" {
+		concepto.Severidad = req.Severidad
+	}
+	if req.Color != "" {
+		concepto.Color = req.Color
+	}
+	if req.Categoria != "" {
+		concepto.Categoria = req.Categoria
+	}
+	if req.DatosSchema != nil {
+		schema, err := esquemaDatos(req.DatosSchema)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid datos_schema: " + err.Error()})
+		}
+		concepto.DatosSchema = schema
+	}
 
 	if err := h.db.Save(&concepto).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating concept"})
@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/api/middleware"
+	"github.com/school-monitoring/backend/internal/auth"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/agenda"
+	"github.com/school-monitoring/backend/internal/services/rollup"
+	"gorm.io/gorm"
+)
+
+// CorreccionAsistenciaRequest estructura para solicitar la correccion de un bloque bloqueado
+type CorreccionAsistenciaRequest struct {
+	HorarioID uuid.UUID        `json:"horario_id"`
+	Fecha     string           `json:"fecha"` // YYYY-MM-DD
+	Motivo    string           `json:"motivo"`
+	Registros []RegistroAlumno `json:"registros"` // solo las filas a cambiar
+}
+
+// RevisionCorreccionRequest comentario de inspectoria al aprobar o rechazar
+type RevisionCorreccionRequest struct {
+	Comentario string `json:"comentario"`
+}
+
+// GET /asistencia/correcciones?estado=&curso_id=&horario_id=&limit=&offset=
+func (h *AsistenciaHandler) GetCorrecciones(c *fiber.Ctx) error {
+	q := h.db.Preload("Horario").Preload("Horario.Bloque").Preload("Horario.Asignatura").
+		Model(&models.CorreccionAsistencia{})
+
+	if estado := c.Query("estado"); estado != "" {
+		q = q.Where("estado = ?", estado)
+	}
+	cursoID, ok := uuidQuery(c, "curso_id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid curso_id"})
+	}
+	if cursoID != nil {
+		q = q.Where("curso_id = ?", *cursoID)
+	}
+	horarioID, ok := uuidQuery(c, "horario_id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid horario_id"})
+	}
+	if horarioID != nil {
+		q = q.Where("horario_id = ?", *horarioID)
+	}
+
+	limit := clamp(atoi(c.Query("limit")), 1, 200)
+	offset := clamp(atoi(c.Query("offset")), 0, 1000000)
+
+	var out []models.CorreccionAsistencia
+	if err := q.Order("created_at DESC").Limit(limit).Offset(offset).Find(&out).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching correction requests"})
+	}
+	return c.JSON(out)
+}
+
+// GET /asistencia/correcciones/{id}
+func (h *AsistenciaHandler) GetCorreccion(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid correction request ID"})
+	}
+
+	var corr models.CorreccionAsistencia
+	if err := h.db.Preload("Horario").Preload("Horario.Bloque").Preload("Horario.Asignatura").
+		First(&corr, "id = ?", id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Correction request not found"})
+	}
+	return c.JSON(corr)
+}
+
+// POST /asistencia/correcciones
+// Solo para bloques con el registro bloqueado; lo solicita el profesor del bloque (o administracion).
+// Se guarda el estado actual de cada fila para mostrar el antes/despues y detectar cambios posteriores.
+func (h *AsistenciaHandler) CreateCorreccion(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+	}
+
+	var req CorreccionAsistenciaRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if strings.TrimSpace(req.Motivo) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "motivo is required"})
+	}
+	if len(req.Registros) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "registros is required"})
+	}
+	fecha, err := time.Parse("2006-01-02", req.Fecha)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid date format, use YYYY-MM-DD"})
+	}
+
+	var horario models.Horario
+	if err := h.db.Preload("Bloque").First(&horario, "id = ?", req.HorarioID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Schedule not found"})
+	}
+	efectivo, _, dictado, err := agenda.Resolver(h.db, horario, fecha)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error checking school calendar"})
+	}
+	if !dictado || agenda.Cancelado(efectivo) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Block is not held on this date"})
+	}
+	if !auth.TienePermiso(claims.Rol, auth.PermisoAdministrar) && efectivo.ProfesorID != claims.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Not the teacher of this block on this date"})
+	}
+
+	now := time.Now()
+	if bloqueado, _ := registroBloqueado(h.db, horario, fecha, now); !bloqueado {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Attendance is not locked, register it directly"})
+	}
+
+	_, errores, err := validarRegistros(h.db, horario, fecha, req.Registros, now)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating correction request"})
+	}
+	for i, r := range req.Registros {
+		// En una correccion la hora de llegada no puede tomarse del momento de la solicitud
+		if r.Estado == models.EstadoAtraso && r.HoraLlegada == "" {
+			errores = append(errores, ErrorRegistro{Indice: i, AlumnoID: r.AlumnoID, Campo: "hora_llegada", Error: "hora_llegada is required in a correction"})
+		}
+	}
+	if len(errores) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Invalid attendance rows", "errores": errores})
+	}
+
+	actuales, err := asistenciasPorAlumno(h.db, horario.ID, fecha, req.Registros)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching attendance"})
+	}
+	cambios := make([]models.CambioAsistencia, 0, len(req.Registros))
+	for _, r := range req.Registros {
+		cambio := models.CambioAsistencia{
+			AlumnoID:            r.AlumnoID,
+			Estado:              r.Estado,
+			HoraLlegada:         r.HoraLlegada,
+			HoraRetiro:          r.HoraRetiro,
+			RetiradoPorNombre:   strings.TrimSpace(r.RetiradoPorNombre),
+			RetiradoPorRelacion: strings.TrimSpace(r.RetiradoPorRelacion),
+		}
+		if a, ok := actuales[r.AlumnoID]; ok {
+			if igualARegistro(a, cambio) {
+				continue
+			}
+			cambio.EstadoAntes = a.Estado
+		}
+		cambios = append(cambios, cambio)
+	}
+	if len(cambios) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No changes requested"})
+	}
+
+	raw, _ := json.Marshal(cambios)
+	corr := models.CorreccionAsistencia{
+		HorarioID:     horario.ID,
+		Fecha:         fecha,
+		CursoID:       horario.CursoID,
+		Motivo:        strings.TrimSpace(req.Motivo),
+		Cambios:       raw,
+		Estado:        models.CorreccionPendiente,
+		SolicitadoPor: claims.UserID,
+	}
+	if err := h.db.Create(&corr).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating correction request"})
+	}
+	_ = models.CrearAuditoria(h.db, "correcciones_asistencia", corr.ID, models.AuditoriaInsert, nil, &corr, &claims.UserID)
+
+	if h.orch != nil {
+		h.orch.Notify("correccion_asistencia_creada", fiber.Map{
+			"id":         corr.ID.String(),
+			"curso_id":   corr.CursoID.String(),
+			"horario_id": corr.HorarioID.String(),
+			"fecha":      req.Fecha,
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(corr)
+}
+
+// PUT /asistencia/correcciones/{id}/aprobar
+// Aplica todos los cambios en una sola transaccion (filas, auditoria antes/despues, eventos y snapshot del bloque).
+// Si alguna fila cambio desde la solicitud responde 409 y no aplica nada.
+func (h *AsistenciaHandler) AprobarCorreccion(c *fiber.Ctx) error {
+	return h.revisarCorreccion(c, models.CorreccionAprobada)
+}
+
+// PUT /asistencia/correcciones/{id}/rechazar
+func (h *AsistenciaHandler) RechazarCorreccion(c *fiber.Ctx) error {
+	return h.revisarCorreccion(c, models.CorreccionRechazada)
+}
+
+func (h *AsistenciaHandler) revisarCorreccion(c *fiber.Ctx, estado string) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid correction request ID"})
+	}
+
+	var req RevisionCorreccionRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+	if estado == models.CorreccionRechazada && strings.TrimSpace(req.Comentario) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "comentario is required when rejecting"})
+	}
+
+	var corr models.CorreccionAsistencia
+	var errores []ErrorRegistro
+	conflict, desactualizada := false, false
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&corr, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if corr.Estado != models.CorreccionPendiente {
+			conflict = true
+			return nil
+		}
+		before := corr
+		now := time.Now()
+		corr.Estado = estado
+		corr.RevisadoPor = &claims.UserID
+		corr.RevisadoEn = &now
+		corr.Comentario = strings.TrimSpace(req.Comentario)
+
+		if estado == models.CorreccionAprobada {
+			var horario models.Horario
+			if err := tx.Preload("Bloque").First(&horario, "id = ?", corr.HorarioID).Error; err != nil {
+				return err
+			}
+			cambios := corr.ListaCambios()
+			registros := make([]RegistroAlumno, len(cambios))
+			for i, cb := range cambios {
+				registros[i] = RegistroAlumno{
+					AlumnoID:            cb.AlumnoID,
+					Estado:              cb.Estado,
+					HoraLlegada:         cb.HoraLlegada,
+					HoraRetiro:          cb.HoraRetiro,
+					RetiradoPorNombre:   cb.RetiradoPorNombre,
+					RetiradoPorRelacion: cb.RetiradoPorRelacion,
+				}
+			}
+
+			actuales, err := asistenciasPorAlumno(tx, horario.ID, corr.Fecha, registros)
+			if err != nil {
+				return err
+			}
+			for _, cb := range cambios {
+				if actuales[cb.AlumnoID].Estado != cb.EstadoAntes {
+					desactualizada = true
+					return nil
+				}
+			}
+
+			var detalles []detalleRegistro
+			detalles, errores, err = validarRegistros(tx, horario, corr.Fecha, registros, now)
+			if err != nil {
+				return err
+			}
+			if len(errores) > 0 {
+				return nil
+			}
+			if err := h.escribirRegistros(tx, horario, corr.Fecha, registros, detalles, claims.UserID); err != nil {
+				return err
+			}
+			if err := models.RecontarHorarioAsistenciaEstado(tx, horario.ID, corr.Fecha); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Save(&corr).Error; err != nil {
+			return err
+		}
+		return models.CrearAuditoria(tx, "correcciones_asistencia", corr.ID, models.AuditoriaUpdate, &before, &corr, &claims.UserID)
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Correction request not found"})
+		}
+		msg := "Error reviewing correction request"
+		if e, ok := err.(*errEscritura); ok {
+			msg = e.msg
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": msg})
+	}
+	if conflict {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Correction request already reviewed"})
+	}
+	if desactualizada {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Attendance changed since the request was submitted"})
+	}
+	if len(errores) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Invalid attendance rows", "errores": errores})
+	}
+
+	if estado == models.CorreccionAprobada {
+		recalcularDiaria(h.db, corr.Fecha, corr.Fecha, rollup.Filtro{CursoID: &corr.CursoID})
+	}
+
+	if h.orch != nil {
+		h.orch.Flush()
+		h.orch.Notify("correccion_asistencia_"+estado, fiber.Map{
+			"id":         corr.ID.String(),
+			"curso_id":   corr.CursoID.String(),
+			"horario_id": corr.HorarioID.String(),
+			"fecha":      corr.Fecha.Format("2006-01-02"),
+		})
+	}
+
+	return c.JSON(corr)
+}
+
+// asistenciasPorAlumno retorna las filas actuales del bloque y fecha para los alumnos de la request
+func asistenciasPorAlumno(db *gorm.DB, horarioID uuid.UUID, fecha time.Time, registros []RegistroAlumno) (map[uuid.UUID]models.Asistencia, error) {
+	ids := make([]uuid.UUID, 0, len(registros))
+	for _, r := range registros {
+		ids = append(ids, r.AlumnoID)
+	}
+	var rows []models.Asistencia
+	if err := db.Where("horario_id = ? AND fecha = ? AND alumno_id IN ?", horarioID, fecha, ids).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[uuid.UUID]models.Asistencia, len(rows))
+	for _, a := range rows {
+		out[a.AlumnoID] = a
+	}
+	return out, nil
+}
+
+// igualARegistro indica si el cambio pedido no altera la fila actual
+func igualARegistro(a models.Asistencia, cb models.CambioAsistencia) bool {
+	hora := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format("15:04")
+	}
+	return a.Estado == cb.Estado &&
+		hora(a.HoraLlegada) == cb.HoraLlegada &&
+		hora(a.HoraRetiro) == cb.HoraRetiro &&
+		a.RetiradoPorNombre == cb.RetiradoPorNombre &&
+		a.RetiradoPorRelacion == cb.RetiradoPorRelacion
+}
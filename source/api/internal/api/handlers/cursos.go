@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/agenda"
+	"github.com/school-monitoring/backend/internal/services/calendario"
 	"gorm.io/gorm"
 )
 
@@ -74,22 +78,33 @@ func (h *CursosHandler) GetHorario(c *fiber.Ctx) error {
 	return c.JSON(horarios)
 }
 
-// GetHorarioActual obtiene el horario actual del curso (bloque actual)
+// GetHorarioActual obtiene el bloque en curso de hoy segun el calendario escolar
+// (dias no lectivos, jornadas especiales, recuperativos) y las excepciones del dia
 func (h *CursosHandler) GetHorarioActual(c *fiber.Ctx) error {
 	id, err := uuid.Parse(c.Params("id"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid course ID"})
 	}
 
-	// Obtener dia de la semana actual (1=lunes, 5=viernes)
-	// TODO: Implementar logica para obtener bloque actual basado en hora
+	now := time.Now()
+	dia, err := calendario.DiaDe(h.db, now, &id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error checking school calendar"})
+	}
+	if !dia.Lectivo {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Not a school day", "tipo": dia.Tipo, "motivo": dia.Motivo})
+	}
 
-	var horario models.Horario
-	if err := h.db.Preload("Asignatura").Preload("Profesor").Preload("Bloque").
-		Where("curso_id = ?", id).
-		First(&horario).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No current schedule found"})
+	horarios, err := agenda.DelDia(h.db, now, agenda.Filtro{CursoID: &id})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching schedule"})
 	}
 
-	return c.JSON(horario)
+	hora := now.Format("15:04")
+	for _, hh := range horarios {
+		if hh.Bloque != nil && hh.Bloque.HoraInicio <= hora && hora < hh.Bloque.HoraFin {
+			return c.JSON(hh)
+		}
+	}
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No current schedule found"})
 }
@@ -25,6 +25,7 @@ type DashboardResponse struct {
 	EventosActivos       int64                `json:"eventos_activos"`
 	EstadosTemporales    int64                `json:"estados_temporales_activos"`
 	AsistenciaHoy        AsistenciaResumen    `json:"asistencia_hoy"`
+	AsistenciaDiaria     *AsistenciaDiariaResumen `json:"asistencia_diaria,omitempty"`
 	EventosPorTipo       []EventoPorTipo      `json:"eventos_por_tipo"`
 	UltimosEventos       []models.Evento      `json:"ultimos_eventos"`
 	AlumnosEstadoTemp    []models.EstadoTemporal `json:"alumnos_estado_temporal"`
@@ -36,6 +37,18 @@ type AsistenciaResumen struct {
 	Presentes   int64 `json:"presentes"`
 	Ausentes    int64 `json:"ausentes"`
 	Justificados int64 `json:"justificados"`
+	Atrasos     int64 `json:"atrasos"`
+	Retiros     int64 `json:"retiros"`
+}
+
+// AsistenciaDiariaResumen resumen de la ultima asistencia diaria consolidada
+type AsistenciaDiariaResumen struct {
+	Fecha        string `json:"fecha"`
+	Presentes    int64  `json:"presentes"`
+	Ausentes     int64  `json:"ausentes"`
+	Justificados int64  `json:"justificados"`
+	Atrasos      int64  `json:"atrasos"`
+	Retiros      int64  `json:"retiros"`
 }
 
 // EventoPorTipo conteo de eventos por tipo de concepto
@@ -65,6 +78,22 @@ func (h *DashboardHandler) Get(c *fiber.Ctx) error {
 	h.db.Model(&models.Asistencia{}).Where("fecha = ? AND estado = ?", hoy, models.EstadoPresente).Count(&response.AsistenciaHoy.Presentes)
 	h.db.Model(&models.Asistencia{}).Where("fecha = ? AND estado = ?", hoy, models.EstadoAusente).Count(&response.AsistenciaHoy.Ausentes)
 	h.db.Model(&models.Asistencia{}).Where("fecha = ? AND estado = ?", hoy, models.EstadoJustificado).Count(&response.AsistenciaHoy.Justificados)
+	h.db.Model(&models.Asistencia{}).Where("fecha = ? AND estado = ?", hoy, models.EstadoAtraso).Count(&response.AsistenciaHoy.Atrasos)
+	h.db.Model(&models.Asistencia{}).Where("fecha = ? AND estado = ?", hoy, models.EstadoRetiro).Count(&response.AsistenciaHoy.Retiros)
+
+	// Ultima asistencia diaria consolidada (oficial)
+	var ultima models.AsistenciaDiaria
+	if err := h.db.Order("fecha DESC").First(&ultima).Error; err == nil {
+		fecha := ultima.Fecha.Format("2006-01-02")
+		r := AsistenciaDiariaResumen{Fecha: fecha}
+		base := h.db.Model(&models.AsistenciaDiaria{}).Where("fecha = ?", fecha)
+		base.Session(&gorm.Session{}).Where("estado = ?", models.EstadoPresente).Count(&r.Presentes)
+		base.Session(&gorm.Session{}).Where("estado = ?", models.EstadoAusente).Count(&r.Ausentes)
+		base.Session(&gorm.Session{}).Where("estado = ?", models.EstadoJustificado).Count(&r.Justificados)
+		base.Session(&gorm.Session{}).Where("atraso = ?", true).Count(&r.Atrasos)
+		base.Session(&gorm.Session{}).Where("retiro = ?", true).Count(&r.Retiros)
+		response.AsistenciaDiaria = &r
+	}
 
 	// Eventos por tipo (ultimos 7 dias)
 	hace7Dias := time.Now().AddDate(0, 0, -7)
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/school-monitoring/backend/internal/api/middleware"
+	"github.com/school-monitoring/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+var colorHex = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// EstablecimientoHandler maneja los datos institucionales del colegio
+type EstablecimientoHandler struct {
+	db *gorm.DB
+}
+
+// NewEstablecimientoHandler crea un nuevo handler de establecimiento
+func NewEstablecimientoHandler(db *gorm.DB) *EstablecimientoHandler {
+	return &EstablecimientoHandler{db: db}
+}
+
+// GET /establecimiento
+func (h *EstablecimientoHandler) Get(c *fiber.Ctx) error {
+	return c.JSON(models.ObtenerEstablecimiento(h.db))
+}
+
+// PUT /establecimiento (crea la fila si no existe)
+func (h *EstablecimientoHandler) Update(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+	}
+
+	var req models.Establecimiento
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if strings.TrimSpace(req.Nombre) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "nombre is required"})
+	}
+	if req.ColorPrimario == "" {
+		req.ColorPrimario = "#1F4E79"
+	}
+	if !colorHex.MatchString(req.ColorPrimario) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "color_primario must be #RRGGBB"})
+	}
+
+	var e models.Establecimiento
+	existe := h.db.Order("created_at").First(&e).Error == nil
+	before := e
+
+	e.Nombre = strings.TrimSpace(req.Nombre)
+	e.RBD = strings.TrimSpace(req.RBD)
+	e.Direccion = req.Direccion
+	e.Comuna = req.Comuna
+	e.Region = req.Region
+	e.Telefono = req.Telefono
+	e.Email = req.Email
+	e.Director = req.Director
+	e.Lema = req.Lema
+	e.ColorPrimario = req.ColorPrimario
+	// Umbrales del monitor y duraciones maximas de estados temporales: 0 mantiene el valor actual
+	if req.MonitorProfesorVerdeMin > 0 {
+		e.MonitorProfesorVerdeMin = req.MonitorProfesorVerdeMin
+	} else if e.MonitorProfesorVerdeMin <= 0 {
+		e.MonitorProfesorVerdeMin = models.UmbralProfesorVerdeMin
+	}
+	if req.MonitorProfesorAmarilloMin > 0 {
+		e.MonitorProfesorAmarilloMin = req.MonitorProfesorAmarilloMin
+	} else if e.MonitorProfesorAmarilloMin <= 0 {
+		e.MonitorProfesorAmarilloMin = models.UmbralProfesorAmarilloMin
+	}
+	if e.MonitorProfesorAmarilloMin <= e.MonitorProfesorVerdeMin {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "monitor_profesor_amarillo_min must be greater than monitor_profesor_verde_min"})
+	}
+	if req.MonitorBanoMaxMin > 0 {
+		e.MonitorBanoMaxMin = req.MonitorBanoMaxMin
+	} else if e.MonitorBanoMaxMin <= 0 {
+		e.MonitorBanoMaxMin = models.DuracionMaxBanoMin
+	}
+	if req.MonitorEnfermeriaMaxMin > 0 {
+		e.MonitorEnfermeriaMaxMin = req.MonitorEnfermeriaMaxMin
+	} else if e.MonitorEnfermeriaMaxMin <= 0 {
+		e.MonitorEnfermeriaMaxMin = models.DuracionMaxEnfermeriaMin
+	}
+	if req.MonitorSOSMaxMin > 0 {
+		e.MonitorSOSMaxMin = req.MonitorSOSMaxMin
+	} else if e.MonitorSOSMaxMin <= 0 {
+		e.MonitorSOSMaxMin = models.DuracionMaxSOSMin
+	}
+
+	if err := h.db.Save(&e).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error saving school data"})
+	}
+
+	if existe {
+		_ = models.CrearAuditoria(h.db, "establecimiento", e.ID, models.AuditoriaUpdate, &before, &e, &claims.UserID)
+	} else {
+		_ = models.CrearAuditoria(h.db, "establecimiento", e.ID, models.AuditoriaInsert, nil, &e, &claims.UserID)
+	}
+	return c.JSON(e)
+}
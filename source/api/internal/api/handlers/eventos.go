@@ -2,12 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
-	"time"
+	"errors"
+	"log"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/school-monitoring/backend/internal/api/middleware"
 	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/esquema"
 	"github.com/school-monitoring/backend/internal/services/orchestrator"
 	"gorm.io/gorm"
 )
@@ -112,6 +115,9 @@ func (h *EventosHandler) Create(c *fiber.Ctx) error {
 	if err := h.db.First(&concepto, "id = ?", req.ConceptoID).Error; err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Concept not found"})
 	}
+	if errs := validarDatosEvento(&concepto, req.Datos); len(errs) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Invalid datos", "errores": errs})
+	}
 
 	evento := models.Evento{
 		ConceptoID:    &req.ConceptoID,
@@ -137,6 +143,168 @@ func (h *EventosHandler) Create(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusCreated).JSON(evento)
 }
 
+// validarDatosEvento valida Datos contra el esquema del concepto (sin esquema no hay restricciones)
+func validarDatosEvento(concepto *models.Concepto, datos json.RawMessage) []esquema.ErrorCampo {
+	errs, err := esquema.Validar(concepto.DatosSchema, datos)
+	if err != nil {
+		// El esquema se valida al guardarlo; uno invalido no debe impedir registrar eventos
+		log.Printf("eventos: datos_schema invalido en concepto %s: %v", concepto.Codigo, err)
+		return nil
+	}
+	return errs
+}
+
+// Maximo de alumnos por creacion masiva (un curso completo cabe holgado)
+const maxEventosMasivos = 200
+
+// EventoMasivoRequest estructura para crear el mismo evento a varios alumnos.
+// Con curso_id y sin alumno_ids se usan todos los alumnos activos del curso (menos excluir_ids).
+type EventoMasivoRequest struct {
+	ConceptoID uuid.UUID       `json:"concepto_id"`
+	CursoID    *uuid.UUID      `json:"curso_id,omitempty"`
+	AlumnoIDs  []uuid.UUID     `json:"alumno_ids,omitempty"`
+	ExcluirIDs []uuid.UUID     `json:"excluir_ids,omitempty"`
+	Datos      json.RawMessage `json:"datos,omitempty"`
+}
+
+// ResultadoEventoMasivo resultado por alumno
+type ResultadoEventoMasivo struct {
+	AlumnoID  uuid.UUID  `json:"alumno_id"`
+	Resultado string     `json:"resultado"` // creado, omitido
+	EventoID  *uuid.UUID `json:"evento_id,omitempty"`
+	Motivo    string     `json:"motivo,omitempty"`
+}
+
+// CreateMasivo crea un evento por alumno (curso completo o lista) en una sola transaccion.
+// Las reglas se evaluan por cada evento, con la misma deduplicacion que POST /eventos.
+// Los alumnos invalidos se omiten y se informan; un error de base de datos revierte todo.
+func (h *EventosHandler) CreateMasivo(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+	}
+
+	var req EventoMasivoRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.ConceptoID == uuid.Nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Concept ID is required"})
+	}
+	if req.CursoID == nil && len(req.AlumnoIDs) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "curso_id or alumno_ids is required"})
+	}
+
+	var concepto models.Concepto
+	if err := h.db.First(&concepto, "id = ?", req.ConceptoID).Error; err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Concept not found"})
+	}
+	if errs := validarDatosEvento(&concepto, req.Datos); len(errs) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Invalid datos", "errores": errs})
+	}
+
+	ids := req.AlumnoIDs
+	if len(ids) == 0 {
+		var curso models.Curso
+		if err := h.db.First(&curso, "id = ?", *req.CursoID).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Course not found"})
+		}
+		if err := h.db.Model(&models.Alumno{}).Where("curso_id = ? AND activo = ?", curso.ID, true).
+			Order("apellido, nombre").Pluck("id", &ids).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching students"})
+		}
+	}
+	excluir := make(map[uuid.UUID]bool, len(req.ExcluirIDs))
+	for _, id := range req.ExcluirIDs {
+		excluir[id] = true
+	}
+	if len(ids) > maxEventosMasivos {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Too many students in one request", "max": maxEventosMasivos})
+	}
+
+	var alumnos []models.Alumno
+	if len(ids) > 0 {
+		if err := h.db.Select("id, curso_id, activo").Where("id IN ?", ids).Find(&alumnos).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching students"})
+		}
+	}
+	alumnoBy := make(map[uuid.UUID]models.Alumno, len(alumnos))
+	for _, a := range alumnos {
+		alumnoBy[a.ID] = a
+	}
+
+	resultados := make([]ResultadoEventoMasivo, 0, len(ids))
+	creados := 0
+	vistos := make(map[uuid.UUID]bool, len(ids))
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			r := ResultadoEventoMasivo{AlumnoID: id, Resultado: "omitido"}
+			a, ok := alumnoBy[id]
+			switch {
+			case excluir[id]:
+				continue
+			case vistos[id]:
+				r.Motivo = "Duplicated student in request"
+			case !ok:
+				r.Motivo = "Student not found"
+			case !a.Activo:
+				r.Motivo = "Student is not active"
+			case req.CursoID != nil && a.CursoID != *req.CursoID:
+				r.Motivo = "Student does not belong to the course"
+			}
+			vistos[id] = true
+			if r.Motivo != "" {
+				resultados = append(resultados, r)
+				continue
+			}
+
+			alumnoID, cursoID := a.ID, a.CursoID
+			evento := models.Evento{
+				ConceptoID:    &req.ConceptoID,
+				AlumnoID:      &alumnoID,
+				CursoID:       &cursoID,
+				Origen:        models.OrigenProfesor,
+				OrigenUsuario: &claims.UserID,
+				Datos:         req.Datos,
+				Activo:        true,
+			}
+			if h.orch != nil {
+				if err := h.orch.CreateEventoTx(tx, &evento, &claims.UserID); err != nil {
+					return err
+				}
+			} else if err := tx.Create(&evento).Error; err != nil {
+				return err
+			}
+			r.Resultado = "creado"
+			r.EventoID = &evento.ID
+			resultados = append(resultados, r)
+			creados++
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating events"})
+	}
+	if h.orch != nil {
+		h.orch.Flush()
+	}
+
+	status := fiber.StatusCreated
+	if creados == 0 {
+		status = fiber.StatusUnprocessableEntity
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"creados":    creados,
+		"omitidos":   len(resultados) - creados,
+		"resultados": resultados,
+	})
+}
+
+// CerrarEventoRequest estructura para cerrar evento
+type CerrarEventoRequest struct {
+	Motivo string `json:"motivo,omitempty"` // manual (default), resuelto, ...
+}
+
 // Cerrar cierra un evento
 func (h *EventosHandler) Cerrar(c *fiber.Ctx) error {
 	claims := middleware.GetUserFromContext(c)
@@ -149,6 +317,17 @@ func (h *EventosHandler) Cerrar(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid event ID"})
 	}
 
+	// Body opcional
+	var req CerrarEventoRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+	if !models.EsMotivoCierreValido(req.Motivo) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid motivo"})
+	}
+
 	var evento models.Evento
 	if err := h.db.First(&evento, "id = ?", id).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Event not found"})
@@ -158,17 +337,145 @@ func (h *EventosHandler) Cerrar(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Event already closed"})
 	}
 
-	now := time.Now()
-	evento.Activo = false
-	evento.CerradoEn = &now
-	evento.CerradoPor = &claims.UserID
+	if h.orch != nil {
+		out, err := h.orch.CloseEvento(id, claims.UserID, req.Motivo)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error closing event"})
+		}
+		return c.JSON(out)
+	}
 
+	before := evento
+	evento.Cerrar(claims.UserID, req.Motivo)
 	if err := h.db.Save(&evento).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error closing event"})
 	}
+	_ = models.CrearAuditoria(h.db, "eventos", evento.ID, models.AuditoriaUpdate, &before, &evento, &claims.UserID)
 
 	// Cargar relaciones
 	h.db.Preload("Concepto").Preload("Alumno").Preload("Curso").First(&evento, "id = ?", evento.ID)
 
 	return c.JSON(evento)
 }
+
+// Reabrir reabre un evento cerrado
+func (h *EventosHandler) Reabrir(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid event ID"})
+	}
+
+	var evento models.Evento
+	if err := h.db.First(&evento, "id = ?", id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Event not found"})
+	}
+
+	if evento.Activo {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Event is not closed"})
+	}
+
+	if h.orch != nil {
+		out, err := h.orch.ReopenEvento(id, claims.UserID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error reopening event"})
+		}
+		return c.JSON(out)
+	}
+
+	before := evento
+	evento.Reabrir(claims.UserID)
+	if err := h.db.Save(&evento).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error reopening event"})
+	}
+	_ = models.CrearAuditoria(h.db, "eventos", evento.ID, models.AuditoriaUpdate, &before, &evento, &claims.UserID)
+
+	// Cargar relaciones
+	h.db.Preload("Concepto").Preload("Alumno").Preload("Curso").First(&evento, "id = ?", evento.ID)
+
+	return c.JSON(evento)
+}
+
+// NotaEventoRequest estructura para anotar un evento
+type NotaEventoRequest struct {
+	Texto string `json:"texto"`
+}
+
+// Anotar agrega una nota a un evento
+func (h *EventosHandler) Anotar(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid event ID"})
+	}
+
+	var req NotaEventoRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	var nota *models.EventoNota
+	if h.orch != nil {
+		nota, err = h.orch.AnnotateEvento(id, claims.UserID, req.Texto)
+	} else {
+		nota, err = h.anotarSinOrquestador(id, claims.UserID, req.Texto)
+	}
+	if err != nil {
+		if errors.Is(err, orchestrator.ErrNotaVacia) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Note text is required"})
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Event not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error annotating event"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(nota)
+}
+
+// anotarSinOrquestador agrega la nota directo en la DB (sin broadcast), con las mismas validaciones
+func (h *EventosHandler) anotarSinOrquestador(eventoID, usuarioID uuid.UUID, texto string) (*models.EventoNota, error) {
+	texto = strings.TrimSpace(texto)
+	if texto == "" {
+		return nil, orchestrator.ErrNotaVacia
+	}
+	nota := models.EventoNota{EventoID: eventoID, Texto: texto, UsuarioID: usuarioID}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").First(&models.Evento{}, "id = ?", eventoID).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&nota).Error; err != nil {
+			return err
+		}
+		return models.CrearAuditoria(tx, "evento_notas", nota.ID, models.AuditoriaInsert, nil, &nota, &usuarioID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &nota, nil
+}
+
+// GetNotas obtiene las notas de un evento
+func (h *EventosHandler) GetNotas(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid event ID"})
+	}
+
+	var notas []models.EventoNota
+	if err := h.db.Preload("Usuario").
+		Where("evento_id = ?", id).
+		Order("created_at ASC").
+		Find(&notas).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching event notes"})
+	}
+	return c.JSON(notas)
+}
@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/school-monitoring/backend/internal/api/middleware"
 	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/agenda"
 	"gorm.io/gorm"
 )
 
@@ -16,13 +19,27 @@ func NewHorariosHandler(db *gorm.DB) *HorariosHandler {
 	return &HorariosHandler{db: db}
 }
 
-// GetMis devuelve el horario del profesor autenticado
+// GetMis devuelve el horario semanal del profesor autenticado, o el de una fecha (?fecha=YYYY-MM-DD)
+// incluyendo los bloques que reemplaza
 func (h *HorariosHandler) GetMis(c *fiber.Ctx) error {
 	claims := middleware.GetUserFromContext(c)
 	if claims == nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
 	}
 
+	// Con fecha: bloques que efectivamente dicta ese dia (calendario + reemplazos/cambios/cancelaciones)
+	if v := c.Query("fecha"); v != "" {
+		fecha, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid date format, use YYYY-MM-DD"})
+		}
+		horarios, err := agenda.DelDia(h.db, fecha, agenda.Filtro{ProfesorID: &claims.UserID})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching schedules"})
+		}
+		return c.JSON(horarios)
+	}
+
 	q := h.db.Preload("Asignatura").Preload("Bloque").Preload("Curso")
 
 	// Opcional: filtrar por día
@@ -50,6 +67,13 @@ func (h *HorariosHandler) GetAll(c *fiber.Ctx) error {
 	if dia := c.Query("dia_semana"); dia != "" {
 		q = q.Where("dia_semana = ?", dia)
 	}
+	if anioID := c.Query("anio_escolar_id"); anioID != "" {
+		q = q.Where("anio_escolar_id = ?", anioID)
+	}
+	if periodoID := c.Query("periodo_id"); periodoID != "" {
+		// Sin periodo = vale para todo el ano
+		q = q.Where("(periodo_id = ? OR periodo_id IS NULL)", periodoID)
+	}
 
 	var horarios []models.Horario
 	if err := q.Order("curso_id, dia_semana, bloque_id").Find(&horarios).Error; err != nil {
@@ -64,9 +88,13 @@ type HorarioRequest struct {
 	ProfesorID   uuid.UUID `json:"profesor_id"`
 	BloqueID     uuid.UUID `json:"bloque_id"`
 	DiaSemana    int       `json:"dia_semana"`
+	Sala         string    `json:"sala"`
+	// Opcional: restringe el horario a un periodo (por defecto vale para todo el ano activo)
+	PeriodoID *uuid.UUID `json:"periodo_id"`
 }
 
-// Upsert crea o actualiza un horario por (curso_id, dia_semana, bloque_id)
+// Upsert crea o actualiza un horario por (curso_id, dia_semana, bloque_id) dentro del ano y periodo:
+// el mismo bloque puede tener otra asignatura en otro periodo o en el ano siguiente
 func (h *HorariosHandler) Upsert(c *fiber.Ctx) error {
 	claims := middleware.GetUserFromContext(c)
 
@@ -78,8 +106,18 @@ func (h *HorariosHandler) Upsert(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "curso_id, asignatura_id, profesor_id, bloque_id y dia_semana(1..5) son requeridos"})
 	}
 
+	// Ano escolar: el del periodo indicado o el activo
+	anioID := models.IDAnioEscolarActivo(h.db)
+	if req.PeriodoID != nil {
+		var periodo models.Periodo
+		if err := h.db.First(&periodo, "id = ?", *req.PeriodoID).Error; err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "periodo_id invalido"})
+		}
+		anioID = &periodo.AnioEscolarID
+	}
+
 	var existing models.Horario
-	err := h.db.Where("curso_id = ? AND dia_semana = ? AND bloque_id = ?", req.CursoID, req.DiaSemana, req.BloqueID).
+	err := h.db.Scopes(models.ClaveHorario(req.CursoID, req.DiaSemana, req.BloqueID, anioID, req.PeriodoID)).
 		First(&existing).Error
 
 	if err != nil && err != gorm.ErrRecordNotFound {
@@ -88,11 +126,14 @@ func (h *HorariosHandler) Upsert(c *fiber.Ctx) error {
 
 	if err == gorm.ErrRecordNotFound {
 		hh := models.Horario{
-			CursoID:      req.CursoID,
-			AsignaturaID: req.AsignaturaID,
-			ProfesorID:   req.ProfesorID,
-			BloqueID:     req.BloqueID,
-			DiaSemana:    req.DiaSemana,
+			CursoID:       req.CursoID,
+			AsignaturaID:  req.AsignaturaID,
+			ProfesorID:    req.ProfesorID,
+			BloqueID:      req.BloqueID,
+			DiaSemana:     req.DiaSemana,
+			AnioEscolarID: anioID,
+			PeriodoID:     req.PeriodoID,
+			Sala:          req.Sala,
 		}
 		if err := h.db.Create(&hh).Error; err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating schedule"})
@@ -113,6 +154,9 @@ func (h *HorariosHandler) Upsert(c *fiber.Ctx) error {
 	existing.CursoID = req.CursoID
 	existing.BloqueID = req.BloqueID
 	existing.DiaSemana = req.DiaSemana
+	existing.AnioEscolarID = anioID
+	existing.PeriodoID = req.PeriodoID
+	existing.Sala = req.Sala
 
 	if err := h.db.Save(&existing).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating schedule"})
@@ -153,3 +197,139 @@ func (h *HorariosHandler) Delete(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{"message": "Schedule deleted"})
 }
+
+// GET /horarios/excepciones?desde=&hasta=&curso_id=&profesor_id=
+func (h *HorariosHandler) GetExcepciones(c *fiber.Ctx) error {
+	hoy := time.Now()
+	desde, err := time.Parse("2006-01-02", c.Query("desde", hoy.Format("2006-01-02")))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid date format, use YYYY-MM-DD"})
+	}
+	hasta, err := time.Parse("2006-01-02", c.Query("hasta", desde.AddDate(0, 0, 30).Format("2006-01-02")))
+	if err != nil || hasta.Before(desde) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid date range, use YYYY-MM-DD"})
+	}
+
+	q := h.db.Preload("Horario").Preload("Horario.Curso").Preload("Horario.Bloque").Preload("Horario.Profesor").
+		Preload("ProfesorReemplazo").Preload("Asignatura").
+		Joins("JOIN horarios ON horarios.id = horario_excepciones.horario_id").
+		Where("horario_excepciones.fecha >= ? AND horario_excepciones.fecha <= ?", desde.Format("2006-01-02"), hasta.Format("2006-01-02"))
+	if cursoID := c.Query("curso_id"); cursoID != "" {
+		q = q.Where("horarios.curso_id = ?", cursoID)
+	}
+	if profID := c.Query("profesor_id"); profID != "" {
+		q = q.Where("(horarios.profesor_id = ? OR horario_excepciones.profesor_reemplazo_id = ?)", profID, profID)
+	}
+	var out []models.HorarioExcepcion
+	if err := q.Order("horario_excepciones.fecha").Find(&out).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching schedule exceptions"})
+	}
+	return c.JSON(out)
+}
+
+type HorarioExcepcionRequest struct {
+	Fecha               string     `json:"fecha"` // YYYY-MM-DD
+	Tipo                string     `json:"tipo"`  // reemplazo, cancelado, cambio_sala, cambio_asignatura
+	ProfesorReemplazoID *uuid.UUID `json:"profesor_reemplazo_id"`
+	AsignaturaID        *uuid.UUID `json:"asignatura_id"`
+	Sala                string     `json:"sala"`
+	Motivo              string     `json:"motivo"`
+}
+
+// POST /horarios/:id/excepciones crea o reemplaza la excepcion del bloque en la fecha
+func (h *HorariosHandler) UpsertExcepcion(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+
+	var horario models.Horario
+	if err := h.db.Preload("Bloque").First(&horario, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Schedule not found"})
+	}
+	var req HorarioExcepcionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	fecha, err := time.Parse("2006-01-02", req.Fecha)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid date format, use YYYY-MM-DD"})
+	}
+	if !models.EsTipoExcepcionValido(req.Tipo) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "tipo must be reemplazo, cancelado, cambio_sala or cambio_asignatura"})
+	}
+	switch {
+	case req.Tipo == models.ExcepcionReemplazo && req.ProfesorReemplazoID == nil:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "profesor_reemplazo_id is required"})
+	case req.Tipo == models.ExcepcionCambioSala && req.Sala == "":
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "sala is required"})
+	case req.Tipo == models.ExcepcionCambioAsignatura && req.AsignaturaID == nil:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "asignatura_id is required"})
+	}
+	if req.ProfesorReemplazoID != nil {
+		var prof models.Usuario
+		if err := h.db.First(&prof, "id = ? AND activo = ?", *req.ProfesorReemplazoID, true).Error; err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Substitute teacher not found"})
+		}
+	}
+	if req.AsignaturaID != nil {
+		var asig models.Asignatura
+		if err := h.db.First(&asig, "id = ?", *req.AsignaturaID).Error; err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Subject not found"})
+		}
+	}
+
+	// El bloque debe dictarse en esa fecha segun el calendario
+	if _, dia, dictado, err := agenda.Resolver(h.db, horario, fecha); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error checking school calendar"})
+	} else if !dictado {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Block is not held on this date", "tipo": dia.Tipo, "motivo": dia.Motivo})
+	}
+
+	var e models.HorarioExcepcion
+	existe := h.db.Where("horario_id = ? AND fecha = ?", horario.ID, fecha.Format("2006-01-02")).First(&e).Error == nil
+	before := e
+
+	e.HorarioID = horario.ID
+	e.Fecha = fecha
+	e.Tipo = req.Tipo
+	e.ProfesorReemplazoID = nil
+	e.AsignaturaID = nil
+	e.Sala = ""
+	e.Motivo = req.Motivo
+	if req.Tipo != models.ExcepcionCancelado {
+		e.ProfesorReemplazoID = req.ProfesorReemplazoID
+		e.AsignaturaID = req.AsignaturaID
+		e.Sala = req.Sala
+	}
+	if !existe {
+		e.CreadoPor = userIDPtr(claims)
+	}
+	if err := h.db.Save(&e).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error saving schedule exception"})
+	}
+	if existe {
+		_ = models.CrearAuditoria(h.db, "horario_excepciones", e.ID, models.AuditoriaUpdate, &before, &e, userIDPtr(claims))
+	} else {
+		_ = models.CrearAuditoria(h.db, "horario_excepciones", e.ID, models.AuditoriaInsert, nil, &e, userIDPtr(claims))
+	}
+
+	h.db.Preload("ProfesorReemplazo").Preload("Asignatura").First(&e, "id = ?", e.ID)
+	status := fiber.StatusCreated
+	if existe {
+		status = fiber.StatusOK
+	}
+	return c.Status(status).JSON(e)
+}
+
+// DELETE /horarios/excepciones/:id (el bloque vuelve al horario semanal)
+func (h *HorariosHandler) DeleteExcepcion(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+
+	var e models.HorarioExcepcion
+	if err := h.db.First(&e, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Schedule exception not found"})
+	}
+	if err := h.db.Delete(&e).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error deleting schedule exception"})
+	}
+	_ = models.CrearAuditoria(h.db, "horario_excepciones", e.ID, models.AuditoriaDelete, &e, nil, userIDPtr(claims))
+	return c.JSON(fiber.Map{"message": "Schedule exception deleted"})
+}
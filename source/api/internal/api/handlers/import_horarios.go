@@ -85,6 +85,9 @@ func (h *ImportHandler) ImportHorariosCSV(c *fiber.Ctx) error {
 		bloqueByNum[b.Numero] = b
 	}
 
+	// Los horarios nuevos quedan en el ano escolar activo
+	anioID := models.IDAnioEscolarActivo(h.db)
+
 	// Ejecutar en transaccion
 	err = h.db.Transaction(func(tx *gorm.DB) error {
 		for idx, row := range records {
@@ -181,9 +184,9 @@ func (h *ImportHandler) ImportHorariosCSV(c *fiber.Ctx) error {
 				}
 			}
 
-			// Upsert Horario por (curso, dia, bloque)
+			// Upsert Horario por (curso, dia, bloque) en el ano activo, para todo el ano
 			var existing models.Horario
-			err := tx.Where("curso_id = ? AND dia_semana = ? AND bloque_id = ?", curso.ID, dia, bloque.ID).
+			err := tx.Scopes(models.ClaveHorario(curso.ID, dia, bloque.ID, anioID, nil)).
 				First(&existing).Error
 			if err != nil && err != gorm.ErrRecordNotFound {
 				resp.RowsError++
@@ -193,11 +196,12 @@ func (h *ImportHandler) ImportHorariosCSV(c *fiber.Ctx) error {
 
 			if err == gorm.ErrRecordNotFound {
 				hh := models.Horario{
-					CursoID:      curso.ID,
-					AsignaturaID: asig.ID,
-					ProfesorID:   prof.ID,
-					BloqueID:     bloque.ID,
-					DiaSemana:    dia,
+					CursoID:       curso.ID,
+					AsignaturaID:  asig.ID,
+					ProfesorID:    prof.ID,
+					BloqueID:      bloque.ID,
+					DiaSemana:     dia,
+					AnioEscolarID: anioID,
 				}
 				if err := tx.Create(&hh).Error; err != nil {
 					resp.RowsError++
@@ -213,6 +217,9 @@ func (h *ImportHandler) ImportHorariosCSV(c *fiber.Ctx) error {
 			before := existing
 			existing.AsignaturaID = asig.ID
 			existing.ProfesorID = prof.ID
+			if existing.AnioEscolarID == nil {
+				existing.AnioEscolarID = anioID
+			}
 			if err := tx.Save(&existing).Error; err != nil {
 				resp.RowsError++
 				resp.Errores = append(resp.Errores, "fila "+itoa(idx+1)+": error actualizando horario")
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/api/middleware"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/orchestrator"
+	"github.com/school-monitoring/backend/internal/services/rollup"
+	"gorm.io/gorm"
+)
+
+// JustificacionesHandler maneja solicitudes de justificacion de inasistencias
+type JustificacionesHandler struct {
+	db   *gorm.DB
+	orch *orchestrator.Orchestrator
+}
+
+// NewJustificacionesHandler crea un nuevo handler de justificaciones
+func NewJustificacionesHandler(db *gorm.DB, orch *orchestrator.Orchestrator) *JustificacionesHandler {
+	return &JustificacionesHandler{db: db, orch: orch}
+}
+
+// GET /justificaciones?estado=&alumno_id=&limit=&offset=
+func (h *JustificacionesHandler) GetAll(c *fiber.Ctx) error {
+	q := h.db.Preload("Alumno").Preload("Alumno.Curso").
+		Preload("Adjuntos").
+		Model(&models.Justificacion{})
+
+	if estado := c.Query("estado"); estado != "" {
+		q = q.Where("estado = ?", estado)
+	}
+	if alumnoID := c.Query("alumno_id"); alumnoID != "" {
+		if id, err := uuid.Parse(alumnoID); err == nil {
+			q = q.Where("alumno_id = ?", id)
+		}
+	}
+
+	limit := clamp(atoi(c.Query("limit")), 1, 200)
+	offset := clamp(atoi(c.Query("offset")), 0, 1000000)
+
+	var out []models.Justificacion
+	if err := q.Order("created_at DESC").Limit(limit).Offset(offset).Find(&out).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching justifications"})
+	}
+	return c.JSON(out)
+}
+
+// GET /justificaciones/{id}
+func (h *JustificacionesHandler) GetByID(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid justification ID"})
+	}
+
+	var j models.Justificacion
+	if err := h.db.Preload("Alumno").Preload("Alumno.Curso").
+		Preload("Adjuntos").
+		First(&j, "id = ?", id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Justification not found"})
+	}
+	return c.JSON(j)
+}
+
+// JustificacionRequest estructura para solicitar una justificacion
+type JustificacionRequest struct {
+	AlumnoID        uuid.UUID `json:"alumno_id"`
+	Tipo            string    `json:"tipo"` // certificado_medico, nota_apoderado, otro
+	Motivo          string    `json:"motivo"`
+	FechaDesde      string    `json:"fecha_desde"` // YYYY-MM-DD
+	FechaHasta      string    `json:"fecha_hasta"` // YYYY-MM-DD
+	Origen          string    `json:"origen"`      // personal (default), apoderado
+	ApoderadoNombre string    `json:"apoderado_nombre"`
+}
+
+// POST /justificaciones
+// La registra el personal del colegio, a nombre propio o del apoderado que la presenta.
+func (h *JustificacionesHandler) Create(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+	}
+
+	var req JustificacionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.AlumnoID == uuid.Nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "alumno_id is required"})
+	}
+	if !models.EsTipoJustificacionValido(req.Tipo) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid type (certificado_medico, nota_apoderado, otro)"})
+	}
+	desde, err := time.Parse("2006-01-02", req.FechaDesde)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid fecha_desde, use YYYY-MM-DD"})
+	}
+	hasta := desde
+	if req.FechaHasta != "" {
+		if hasta, err = time.Parse("2006-01-02", req.FechaHasta); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid fecha_hasta, use YYYY-MM-DD"})
+		}
+	}
+	if hasta.Before(desde) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "fecha_hasta must not be before fecha_desde"})
+	}
+	if req.Origen != "" && req.Origen != models.JustificacionOrigenPersonal && req.Origen != models.JustificacionOrigenApoderado {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid origin (personal, apoderado)"})
+	}
+	if req.Origen == models.JustificacionOrigenApoderado && strings.TrimSpace(req.ApoderadoNombre) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "apoderado_nombre is required for guardian submissions"})
+	}
+
+	var alumno models.Alumno
+	if err := h.db.First(&alumno, "id = ?", req.AlumnoID).Error; err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Student not found"})
+	}
+
+	j := models.Justificacion{
+		AlumnoID:        req.AlumnoID,
+		Tipo:            req.Tipo,
+		Motivo:          req.Motivo,
+		FechaDesde:      desde,
+		FechaHasta:      hasta,
+		Estado:          models.JustificacionPendiente,
+		Origen:          req.Origen,
+		ApoderadoNombre: strings.TrimSpace(req.ApoderadoNombre),
+		SolicitadoPor:   claims.UserID,
+	}
+	if err := h.db.Create(&j).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating justification"})
+	}
+	_ = models.CrearAuditoria(h.db, "justificacions", j.ID, models.AuditoriaInsert, nil, &j, &claims.UserID)
+
+	if h.orch != nil {
+		h.orch.Notify("justificacion_creada", fiber.Map{
+			"id":        j.ID.String(),
+			"alumno_id": j.AlumnoID.String(),
+			"curso_id":  alumno.CursoID.String(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(j)
+}
+
+// RevisionJustificacionRequest estructura para aprobar/rechazar
+type RevisionJustificacionRequest struct {
+	Comentario string `json:"comentario"`
+}
+
+// PUT /justificaciones/{id}/aprobar
+// Aplica la justificacion de forma retroactiva en una sola transaccion:
+// asistencias ausentes del rango -> justificado, cierre de eventos INASISTENCIA y recuento de snapshots por bloque.
+func (h *JustificacionesHandler) Aprobar(c *fiber.Ctx) error {
+	return h.revisar(c, models.JustificacionAprobada)
+}
+
+// PUT /justificaciones/{id}/rechazar
+func (h *JustificacionesHandler) Rechazar(c *fiber.Ctx) error {
+	return h.revisar(c, models.JustificacionRechazada)
+}
+
+func (h *JustificacionesHandler) revisar(c *fiber.Ctx, estado string) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid justification ID"})
+	}
+
+	var req RevisionJustificacionRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+	if estado == models.JustificacionRechazada && strings.TrimSpace(req.Comentario) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "comentario is required when rejecting"})
+	}
+
+	var j models.Justificacion
+	conflict := false
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&j, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if j.Estado != models.JustificacionPendiente {
+			conflict = true
+			return nil
+		}
+		before := j
+		now := time.Now()
+		j.Estado = estado
+		j.RevisadoPor = &claims.UserID
+		j.RevisadoEn = &now
+		j.Comentario = strings.TrimSpace(req.Comentario)
+
+		if estado == models.JustificacionAprobada {
+			n, err := h.aplicar(tx, &j, claims.UserID)
+			if err != nil {
+				return err
+			}
+			j.AsistenciasJustificadas = n
+		}
+
+		if err := tx.Save(&j).Error; err != nil {
+			return err
+		}
+		return models.CrearAuditoria(tx, "justificacions", j.ID, models.AuditoriaUpdate, &before, &j, &claims.UserID)
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Justification not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error reviewing justification"})
+	}
+	if conflict {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Justification already reviewed"})
+	}
+
+	if estado == models.JustificacionAprobada {
+		recalcularDiaria(h.db, j.FechaDesde, j.FechaHasta, rollup.Filtro{AlumnoID: &j.AlumnoID})
+	}
+
+	if h.orch != nil {
+		h.orch.Flush()
+		h.orch.Notify("justificacion_"+estado, fiber.Map{
+			"id":                       j.ID.String(),
+			"alumno_id":                j.AlumnoID.String(),
+			"asistencias_justificadas": j.AsistenciasJustificadas,
+		})
+	}
+
+	return c.JSON(j)
+}
+
+// aplicar justifica retroactivamente las asistencias del rango y retorna cuantas cambiaron.
+func (h *JustificacionesHandler) aplicar(tx *gorm.DB, j *models.Justificacion, usuarioID uuid.UUID) (int, error) {
+	var asistencias []models.Asistencia
+	if err := tx.Where("alumno_id = ? AND fecha >= ? AND fecha <= ? AND estado = ?",
+		j.AlumnoID, j.FechaDesde, j.FechaHasta, models.EstadoAusente).
+		Find(&asistencias).Error; err != nil {
+		return 0, err
+	}
+
+	type bloqueFecha struct {
+		HorarioID uuid.UUID
+		Fecha     string
+	}
+	afectados := map[bloqueFecha]time.Time{}
+	for i := range asistencias {
+		a := asistencias[i]
+		before := a
+		a.Estado = models.EstadoJustificado
+		a.RegistradoPor = usuarioID
+		if err := tx.Save(&a).Error; err != nil {
+			return 0, err
+		}
+		_ = models.CrearAuditoria(tx, "asistencias", a.ID, models.AuditoriaUpdate, &before, &a, &usuarioID)
+		afectados[bloqueFecha{a.HorarioID, a.Fecha.Format("2006-01-02")}] = a.Fecha
+	}
+
+	for k, fecha := range afectados {
+		if err := models.RecontarHorarioAsistenciaEstado(tx, k.HorarioID, fecha); err != nil {
+			return 0, err
+		}
+	}
+
+	// Cerrar eventos INASISTENCIA activos del rango (datos.fecha = dia de la inasistencia)
+	if h.orch != nil {
+		var concepto models.Concepto
+		if err := tx.First(&concepto, "codigo = ?", models.ConceptoInasistencia).Error; err == nil {
+			var eventos []models.Evento
+			if err := tx.Where("concepto_id = ? AND alumno_id = ? AND activo = ? AND (datos->>'fecha') >= ? AND (datos->>'fecha') <= ?",
+				concepto.ID, j.AlumnoID, true, j.FechaDesde.Format("2006-01-02"), j.FechaHasta.Format("2006-01-02")).
+				Find(&eventos).Error; err != nil {
+				return 0, err
+			}
+			for _, e := range eventos {
+				if _, err := h.orch.CloseEventoTx(tx, e.ID, usuarioID, models.MotivoCierreJustificado); err != nil {
+					return 0, err
+				}
+			}
+		}
+	}
+
+	return len(asistencias), nil
+}
@@ -1,148 +1,83 @@
 package handlers
 
 import (
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/school-monitoring/backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/services/monitor"
 	"gorm.io/gorm"
 )
 
 type MonitorHandler struct {
-	db *gorm.DB
+	db     *gorm.DB
+	estado *monitor.Estado
 }
 
-func NewMonitorHandler(db *gorm.DB) *MonitorHandler {
-	return &MonitorHandler{db: db}
+// NewMonitorHandler con estado != nil el snapshot sale de memoria; sin el se calcula en cada request
+func NewMonitorHandler(db *gorm.DB, estado *monitor.Estado) *MonitorHandler {
+	return &MonitorHandler{db: db, estado: estado}
 }
 
-type MonitorCurso struct {
-	CursoID                 string     `json:"curso_id"`
-	Nombre                  string     `json:"nombre"`
-	Nivel                   string     `json:"nivel"`
-	SalaSemaforo            string     `json:"sala_semaforo"`    // verde, amarillo, rojo, gris
-	ProfesorSemaforo        string     `json:"profesor_semaforo"` // verde, amarillo, gris
-	EventosActivos          int        `json:"eventos_activos"`
-	EstadosTemporalesActivos int       `json:"estados_temporales_activos"`
-	UltimaAsistenciaEn      *time.Time `json:"ultima_asistencia_en,omitempty"`
-}
+type MonitorCurso = monitor.Curso
 
-type MonitorSnapshot struct {
-	UpdatedAt time.Time     `json:"updated_at"`
-	Cursos    []MonitorCurso `json:"cursos"`
-}
+type MonitorBloque = monitor.Bloque
+
+type MonitorSnapshot = monitor.Snapshot
 
+// Snapshot GET /monitor/snapshot. Los cambios posteriores llegan por WS como monitor_curso_actualizado
+// (con version correlativa a la del snapshot).
 func (h *MonitorHandler) Snapshot(c *fiber.Ctx) error {
-	var cursos []models.Curso
-	if err := h.db.Order("nivel, nombre").Find(&cursos).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching courses"})
+	if h.estado != nil {
+		out, err := h.estado.Snapshot()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching monitor state"})
+		}
+		return c.JSON(out)
 	}
 
-	// Eventos activos agrupados por curso y severidad
-	type evAgg struct {
-		CursoID string
-		Codigo  string
-		Cnt     int
+	now := time.Now()
+	cursos, err := monitor.Calcular(h.db, now, nil)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching monitor state"})
 	}
-	var evRows []evAgg
-	h.db.Table("eventos").
-		Select("curso_id as curso_id, conceptos.codigo as codigo, COUNT(*) as cnt").
-		Joins("JOIN conceptos ON eventos.concepto_id = conceptos.id").
-		Where("eventos.activo = true AND eventos.curso_id IS NOT NULL").
-		Group("curso_id, conceptos.codigo").
-		Scan(&evRows)
+	return c.JSON(MonitorSnapshot{UpdatedAt: now, Cursos: cursos})
+}
 
-	byCurso := map[string]map[string]int{}
-	for _, row := range evRows {
-		if _, ok := byCurso[row.CursoID]; !ok {
-			byCurso[row.CursoID] = map[string]int{}
-		}
-		byCurso[row.CursoID][row.Codigo] = row.Cnt
+// Alumnos GET /monitor/alumnos?curso_id=&ubicacion=&excedidos=true
+// Ubicacion actual de cada alumno (sala, bano, enfermeria, sos, ausente, retirado, sin_registro), tiempo en el
+// estado temporal con su maximo, asistencia del dia por bloque y eventos abiertos. Los cambios posteriores
+// se avisan por WS como monitor_alumnos_actualizados (solo curso_id y version): el cliente vuelve a pedir aca.
+func (h *MonitorHandler) Alumnos(c *fiber.Ctx) error {
+	f := monitor.FiltroAlumnos{
+		CursoID:      strings.TrimSpace(c.Query("curso_id")),
+		Ubicacion:    strings.TrimSpace(c.Query("ubicacion")),
+		SoloExcedido: c.Query("excedidos") == "true",
 	}
-
-	// Estados temporales activos agrupados por curso
-	type stAgg struct {
-		CursoID string
-		Cnt     int
+	if f.CursoID != "" {
+		if _, err := uuid.Parse(f.CursoID); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid curso_id"})
+		}
 	}
-	var stRows []stAgg
-	h.db.Table("estado_temporals").
-		Select("alumnos.curso_id as curso_id, COUNT(*) as cnt").
-		Joins("JOIN alumnos ON estado_temporals.alumno_id = alumnos.id").
-		Where("estado_temporals.fin IS NULL").
-		Group("alumnos.curso_id").
-		Scan(&stRows)
-	stByCurso := map[string]int{}
-	for _, row := range stRows {
-		stByCurso[row.CursoID] = row.Cnt
+	switch f.Ubicacion {
+	case "", monitor.UbicacionSala, monitor.UbicacionBano, monitor.UbicacionEnfermeria, monitor.UbicacionSOS,
+		monitor.UbicacionAusente, monitor.UbicacionRetirado, monitor.UbicacionSinRegistro:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ubicacion"})
 	}
 
-	// Estado curso (presencia profesor)
-	var estados []models.CursoEstado
-	h.db.Find(&estados)
-	ceByCurso := map[string]*models.CursoEstado{}
-	for i := range estados {
-		ce := estados[i]
-		id := ce.CursoID.String()
-		ceByCurso[id] = &ce
+	var (
+		out monitor.SnapshotAlumnos
+		err error
+	)
+	if h.estado != nil {
+		out, err = h.estado.Alumnos(f)
+	} else {
+		out, err = monitor.CalcularSnapshotAlumnos(h.db, f)
 	}
-
-	out := MonitorSnapshot{UpdatedAt: time.Now()}
-	for _, c := range cursos {
-		cid := c.ID.String()
-		// sala semaforo
-		codes := byCurso[cid]
-		sala := "verde"
-		if codes != nil {
-			if codes[models.ConceptoSOS] > 0 || codes[models.ConceptoComportamiento] > 0 || codes[models.ConceptoDisciplinario] > 0 {
-				sala = "rojo"
-			} else if codes[models.ConceptoBano] > 0 || codes[models.ConceptoEnfermeria] > 0 || codes[models.ConceptoInasistencia] > 0 {
-				sala = "amarillo"
-			}
-		}
-
-		// profesor semaforo por ultima asistencia
-		prof := "gris"
-		var last *time.Time
-		if ce := ceByCurso[cid]; ce != nil && ce.UltimaAsistenciaEn != nil {
-			last = ce.UltimaAsistenciaEn
-			min := time.Since(*ce.UltimaAsistenciaEn).Minutes()
-			if min <= 75 {
-				prof = "verde"
-			} else if min <= 150 {
-				prof = "amarillo"
-			} else {
-				prof = "gris"
-			}
-		}
-
-		evtCount := 0
-		if codes != nil {
-			for _, n := range codes {
-				evtCount += n
-			}
-		}
-
-		stCount := stByCurso[cid]
-
-		// Regla de "sin data": si no hay eventos, no hay estados temporales y no hay asistencia registrada => gris
-		if evtCount == 0 && stCount == 0 && last == nil {
-			sala = "gris"
-		}
-
-		out.Cursos = append(out.Cursos, MonitorCurso{
-			CursoID:                 cid,
-			Nombre:                  c.Nombre,
-			Nivel:                   c.Nivel,
-			SalaSemaforo:            sala,
-			ProfesorSemaforo:        prof,
-			EventosActivos:          evtCount,
-			EstadosTemporalesActivos: stCount,
-			UltimaAsistenciaEn:      last,
-		})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching student status"})
 	}
-
 	return c.JSON(out)
 }
-
-
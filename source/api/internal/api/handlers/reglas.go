@@ -47,6 +47,7 @@ type ReglaRequest struct {
 	Nombre     string          `json:"nombre"`
 	ConceptoID uuid.UUID       `json:"concepto_id"`
 	Condicion  json.RawMessage `json:"condicion"`
+	Disparador string          `json:"disparador,omitempty"` // creado (default), cerrado
 	AccionID   uuid.UUID       `json:"accion_id"`
 	Activo     *bool           `json:"activo"`
 }
@@ -61,6 +62,16 @@ func (h *ReglasHandler) Create(c *fiber.Ctx) error {
 	if req.Nombre == "" || req.ConceptoID == uuid.Nil || req.AccionID == uuid.Nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Name, concept_id and action_id are required"})
 	}
+	if req.Disparador == "" {
+		req.Disparador = models.DisparadorCreado
+	}
+	if !models.EsDisparadorValido(req.Disparador) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid trigger (creado, cerrado)"})
+	}
+
+	if err := validarCondicion(req.Condicion); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid condition: " + err.Error()})
+	}
 
 	// Verificar que concepto y accion existen
 	var concepto models.Concepto
@@ -77,6 +88,7 @@ func (h *ReglasHandler) Create(c *fiber.Ctx) error {
 		Nombre:     req.Nombre,
 		ConceptoID: req.ConceptoID,
 		Condicion:  req.Condicion,
+		Disparador: req.Disparador,
 		AccionID:   req.AccionID,
 		Activo:     true,
 	}
@@ -115,8 +127,17 @@ func (h *ReglasHandler) Update(c *fiber.Ctx) error {
 		regla.ConceptoID = req.ConceptoID
 	}
 	if req.Condicion != nil {
+		if err := validarCondicion(req.Condicion); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid condition: " + err.Error()})
+		}
 		regla.Condicion = req.Condicion
 	}
+	if req.Disparador != "" {
+		if !models.EsDisparadorValido(req.Disparador) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid trigger (creado, cerrado)"})
+		}
+		regla.Disparador = req.Disparador
+	}
 	if req.AccionID != uuid.Nil {
 		regla.AccionID = req.AccionID
 	}
@@ -147,3 +168,20 @@ func (h *ReglasHandler) Delete(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{"message": "Rule deleted"})
 }
+
+// validarCondicion verifica que la condicion se pueda interpretar y que sus filtros de datos sean validos
+func validarCondicion(raw json.RawMessage) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	var cond models.CondicionRegla
+	if err := json.Unmarshal(raw, &cond); err != nil {
+		return err
+	}
+	for _, f := range cond.Datos {
+		if err := f.Validar(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/pdf"
+	"github.com/school-monitoring/backend/internal/services/analytics"
+	"github.com/school-monitoring/backend/internal/services/export"
+	"gorm.io/gorm"
+)
+
+// Rango maximo de una serie (un anio escolar con holgura)
+const maxDiasSerie = 400
+
+// ReportesHandler maneja reportes y exportaciones oficiales
+type ReportesHandler struct {
+	db *gorm.DB
+}
+
+// NewReportesHandler crea un nuevo handler de reportes
+func NewReportesHandler(db *gorm.DB) *ReportesHandler {
+	return &ReportesHandler{db: db}
+}
+
+// GET /reportes/asistencia-mensual?mes=YYYY-MM&curso_id=&formato=csv|xlsx
+// Matriz alumno x dia del mes (libro de clases) con RUT, totales y porcentajes.
+// Sin curso_id exporta todos los cursos. La respuesta se escribe en streaming.
+// Lee la asistencia diaria ya consolidada (cada registro la recalcula, ademas del proceso nocturno y
+// POST /asistencia-diaria/recalcular): un GET no escribe.
+func (h *ReportesHandler) AsistenciaMensual(c *fiber.Ctx) error {
+	mes, err := time.Parse("2006-01", c.Query("mes", time.Now().Format("2006-01")))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid mes, use YYYY-MM"})
+	}
+	formato := c.Query("formato", export.FormatoCSV)
+	if formato != export.FormatoCSV && formato != export.FormatoXLSX {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid format (csv, xlsx)"})
+	}
+
+	var cursoID *uuid.UUID
+	nombre := "todos"
+	if v := c.Query("curso_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid course ID"})
+		}
+		var curso models.Curso
+		if err := h.db.First(&curso, "id = ?", id).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Course not found"})
+		}
+		cursoID = &id
+		nombre = curso.Nombre
+	}
+
+	archivo := fmt.Sprintf("asistencia_%s_%s.%s", mes.Format("2006-01"), nombreArchivo(nombre), formato)
+	c.Set(fiber.HeaderContentType, export.ContentType(formato))
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+archivo+`"`)
+
+	db := h.db
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		t, err := export.NuevaTabla(formato, w, "Asistencia "+mes.Format("2006-01"))
+		if err != nil {
+			log.Printf("export asistencia mensual: %v", err)
+			return
+		}
+		if err := export.AsistenciaMensual(db, t, mes.Year(), mes.Month(), cursoID); err != nil {
+			log.Printf("export asistencia mensual: %v", err)
+		}
+		if err := t.Close(); err != nil {
+			log.Printf("export asistencia mensual: %v", err)
+		}
+		_ = w.Flush()
+	})
+	return nil
+}
+
+// nombreArchivo deja solo caracteres seguros para Content-Disposition
+func nombreArchivo(s string) string {
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			out = append(out, r)
+		case r == ' ':
+			out = append(out, '_')
+		}
+	}
+	return string(out)
+}
+
+// enviarPDF serializa el documento como descarga
+func enviarPDF(c *fiber.Ctx, doc *pdf.Documento, archivo string) error {
+	var buf bytes.Buffer
+	if err := doc.Escribir(&buf); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating PDF"})
+	}
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+archivo+`"`)
+	return c.Send(buf.Bytes())
+}
+
+// rangoFechas lee desde/hasta (YYYY-MM-DD), o los toma de periodo_id / anio_escolar_id.
+// Por defecto desde el inicio del ano escolar activo (o el 1 de enero) hasta hoy.
+func rangoFechas(db *gorm.DB, c *fiber.Ctx) (time.Time, time.Time, bool) {
+	hoy := time.Now()
+	defDesde := fmt.Sprintf("%d-01-01", hoy.Year())
+	defHasta := hoy.Format("2006-01-02")
+
+	if periodoID := c.Query("periodo_id"); periodoID != "" {
+		var p models.Periodo
+		if err := db.First(&p, "id = ?", periodoID).Error; err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		defDesde, defHasta = p.FechaInicio.Format("2006-01-02"), p.FechaFin.Format("2006-01-02")
+	} else if anioID := c.Query("anio_escolar_id"); anioID != "" {
+		var a models.AnioEscolar
+		if err := db.First(&a, "id = ?", anioID).Error; err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		defDesde, defHasta = a.FechaInicio.Format("2006-01-02"), a.FechaFin.Format("2006-01-02")
+	} else if a := models.AnioEscolarActivo(db); a != nil && !a.FechaInicio.After(hoy) {
+		defDesde = a.FechaInicio.Format("2006-01-02")
+	}
+
+	desde, err := time.Parse("2006-01-02", c.Query("desde", defDesde))
+	if err != nil {
+		return desde, desde, false
+	}
+	hasta, err := time.Parse("2006-01-02", c.Query("hasta", defHasta))
+	if err != nil || hasta.Before(desde) {
+		return desde, hasta, false
+	}
+	return desde, hasta, true
+}
+
+func (h *ReportesHandler) findAlumno(c *fiber.Ctx) (*models.Alumno, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid student ID"})
+	}
+	var alumno models.Alumno
+	if err := h.db.Preload("Curso").First(&alumno, "id = ?", id).Error; err != nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Student not found"})
+	}
+	return &alumno, nil
+}
+
+// GET /reportes/pdf/cursos/{id}/asistencia?mes=YYYY-MM
+func (h *ReportesHandler) PDFAsistenciaCurso(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid course ID"})
+	}
+	mes, err := time.Parse("2006-01", c.Query("mes", time.Now().Format("2006-01")))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid mes, use YYYY-MM"})
+	}
+	var curso models.Curso
+	if err := h.db.First(&curso, "id = ?", id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Course not found"})
+	}
+
+	doc, err := export.PDFAsistenciaCurso(h.db, curso, mes.Year(), mes.Month())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating report"})
+	}
+	return enviarPDF(c, doc, fmt.Sprintf("asistencia_%s_%s.pdf", mes.Format("2006-01"), nombreArchivo(curso.Nombre)))
+}
+
+// GET /reportes/pdf/alumnos/{id}/certificado?desde=&hasta=
+func (h *ReportesHandler) PDFCertificadoAsistencia(c *fiber.Ctx) error {
+	alumno, err := h.findAlumno(c)
+	if alumno == nil {
+		return err
+	}
+	desde, hasta, ok := rangoFechas(h.db, c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid date range, use YYYY-MM-DD or a valid periodo_id"})
+	}
+	doc, err := export.PDFCertificadoAsistencia(h.db, *alumno, desde, hasta)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating report"})
+	}
+	return enviarPDF(c, doc, "certificado_asistencia_"+nombreArchivo(alumno.Rut)+".pdf")
+}
+
+// GET /reportes/pdf/alumnos/{id}/historial?desde=&hasta=
+func (h *ReportesHandler) PDFHistorialAlumno(c *fiber.Ctx) error {
+	alumno, err := h.findAlumno(c)
+	if alumno == nil {
+		return err
+	}
+	desde, hasta, ok := rangoFechas(h.db, c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid date range, use YYYY-MM-DD or a valid periodo_id"})
+	}
+
+	doc, err := export.PDFHistorialAlumno(h.db, *alumno, desde, hasta)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating report"})
+	}
+	return enviarPDF(c, doc, "historial_"+nombreArchivo(alumno.Rut)+".pdf")
+}
+
+// GET /reportes/pdf/alertas?desde=&hasta=&curso_id=
+func (h *ReportesHandler) PDFResolucionAlertas(c *fiber.Ctx) error {
+	desde, hasta, ok := rangoFechas(h.db, c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid date range, use YYYY-MM-DD or a valid periodo_id"})
+	}
+	var cursoID *uuid.UUID
+	if v := c.Query("curso_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid course ID"})
+		}
+		cursoID = &id
+	}
+
+	doc, err := export.PDFResolucionAlertas(h.db, desde, hasta, cursoID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating report"})
+	}
+	return enviarPDF(c, doc, fmt.Sprintf("alertas_%s_%s.pdf", desde.Format("20060102"), hasta.Format("20060102")))
+}
+
+// GET /reportes/series/{metrica}?desde=&hasta=&granularidad=dia|semana|mes&agrupar=&curso_id=&nivel=&concepto_id=
+// metrica: eventos, inasistencias, atrasos, alertas, sla_alertas. Lee los agregados diarios materializados.
+func (h *ReportesHandler) Serie(c *fiber.Ctx) error {
+	metrica := c.Params("metrica")
+	if !models.EsMetricaValida(metrica) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid metric (eventos, inasistencias, atrasos, alertas, sla_alertas)"})
+	}
+	desde, hasta, ok := rangoFechas(h.db, c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid date range, use YYYY-MM-DD or a valid periodo_id"})
+	}
+	if hasta.Sub(desde) > maxDiasSerie*24*time.Hour {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Date range too large (max 400 days)"})
+	}
+	q := analytics.Consulta{
+		Metrica:      metrica,
+		Desde:        desde,
+		Hasta:        hasta,
+		Granularidad: c.Query("granularidad", analytics.GranularidadDia),
+		Agrupar:      c.Query("agrupar"),
+		Nivel:        c.Query("nivel"),
+	}
+	if !analytics.EsGranularidadValida(q.Granularidad) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid granularity (dia, semana, mes)"})
+	}
+	if !analytics.EsGrupoValido(q.Agrupar) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid grouping (curso, nivel, concepto, asignatura, profesor, bloque)"})
+	}
+	if v := c.Query("curso_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid course ID"})
+		}
+		q.CursoID = &id
+	}
+	if v := c.Query("concepto_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid concept ID"})
+		}
+		q.ConceptoID = &id
+	}
+
+	puntos, err := analytics.Serie(h.db, q)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching series"})
+	}
+	return c.JSON(fiber.Map{
+		"metrica":      q.Metrica,
+		"granularidad": q.Granularidad,
+		"agrupar":      q.Agrupar,
+		"desde":        desde.Format("2006-01-02"),
+		"hasta":        hasta.Format("2006-01-02"),
+		"puntos":       puntos,
+	})
+}
+
+// POST /reportes/agregados/refrescar {desde, hasta}
+// Regenera los agregados del rango (ej. tras importar datos historicos).
+func (h *ReportesHandler) RefrescarAgregados(c *fiber.Ctx) error {
+	var req RecalcularRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	desde, err := time.Parse("2006-01-02", req.Desde)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid desde, use YYYY-MM-DD"})
+	}
+	hasta := time.Now()
+	if req.Hasta != "" {
+		if hasta, err = time.Parse("2006-01-02", req.Hasta); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid hasta, use YYYY-MM-DD"})
+		}
+	}
+	if hasta.Before(desde) || hasta.Sub(desde) > maxDiasSerie*24*time.Hour {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid range (max 400 days)"})
+	}
+	if err := analytics.Refrescar(h.db, desde, hasta); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error refreshing aggregates"})
+	}
+	return c.JSON(fiber.Map{"message": "Aggregates refreshed", "desde": desde.Format("2006-01-02"), "hasta": hasta.Format("2006-01-02")})
+}
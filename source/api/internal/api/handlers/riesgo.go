@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/api/middleware"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/riesgo"
+	"gorm.io/gorm"
+)
+
+// RiesgoHandler maneja el puntaje de riesgo (alerta temprana) de alumnos
+type RiesgoHandler struct {
+	db *gorm.DB
+}
+
+// NewRiesgoHandler crea un nuevo handler de riesgo
+func NewRiesgoHandler(db *gorm.DB) *RiesgoHandler {
+	return &RiesgoHandler{db: db}
+}
+
+// GET /riesgo?curso_id=&nivel=&limit=&offset=
+// Ranking descendente por puntaje (por curso si se indica curso_id).
+func (h *RiesgoHandler) Ranking(c *fiber.Ctx) error {
+	q := h.db.Preload("Alumno").Preload("Alumno.Curso").Model(&models.RiesgoAlumno{})
+	if cursoID := c.Query("curso_id"); cursoID != "" {
+		id, err := uuid.Parse(cursoID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid course ID"})
+		}
+		q = q.Where("curso_id = ?", id)
+	}
+	if nivel := c.Query("nivel"); nivel != "" {
+		q = q.Where("nivel = ?", nivel)
+	}
+
+	limit := clamp(atoi(c.Query("limit")), 1, 500)
+	offset := clamp(atoi(c.Query("offset")), 0, 1000000)
+
+	var out []models.RiesgoAlumno
+	if err := q.Order("puntaje DESC, alumno_id").Limit(limit).Offset(offset).Find(&out).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching risk scores"})
+	}
+	return c.JSON(out)
+}
+
+// GET /riesgo/alumnos/{id}
+func (h *RiesgoHandler) GetByAlumno(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid student ID"})
+	}
+	var r models.RiesgoAlumno
+	if err := h.db.Preload("Alumno").Preload("Alumno.Curso").First(&r, "alumno_id = ?", id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Risk score not computed for this student"})
+	}
+	return c.JSON(r)
+}
+
+// RecalcularRiesgoRequest estructura para recalcular a demanda
+type RecalcularRiesgoRequest struct {
+	CursoID  *uuid.UUID `json:"curso_id,omitempty"`
+	AlumnoID *uuid.UUID `json:"alumno_id,omitempty"`
+}
+
+// POST /riesgo/recalcular
+func (h *RiesgoHandler) Recalcular(c *fiber.Ctx) error {
+	var req RecalcularRiesgoRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+	n, err := riesgo.Calcular(h.db, riesgo.Filtro{CursoID: req.CursoID, AlumnoID: req.AlumnoID})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error computing risk scores"})
+	}
+	return c.JSON(fiber.Map{"recalculados": n})
+}
+
+// GET /riesgo/configuracion
+func (h *RiesgoHandler) GetConfiguracion(c *fiber.Ctx) error {
+	return c.JSON(models.ObtenerConfiguracionRiesgo(h.db))
+}
+
+// PUT /riesgo/configuracion
+func (h *RiesgoHandler) UpdateConfiguracion(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+	}
+
+	// Los campos omitidos conservan el valor vigente (un PUT sin pesos_concepto no los borra)
+	var cfg models.ConfiguracionRiesgo
+	existe := h.db.Order("created_at").First(&cfg).Error == nil
+	if !existe {
+		cfg = models.ConfiguracionRiesgoDefault()
+	}
+	before := cfg
+	req := cfg
+	// Copia propia: json.Unmarshal sobre un RawMessage reutiliza su arreglo y alteraria before
+	req.PesosConcepto = append(json.RawMessage(nil), cfg.PesosConcepto...)
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.PesoAsistencia < 0 || req.PesoEventos < 0 || req.PesoAlertas < 0 || req.PesoCasos < 0 ||
+		req.PesoAsistencia+req.PesoEventos+req.PesoAlertas+req.PesoCasos <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Weights must be non-negative and not all zero"})
+	}
+	if req.VentanaDias <= 0 || req.UmbralAsistencia <= 0 || req.UmbralAsistencia > 100 ||
+		req.SaturacionEventos <= 0 || req.SaturacionAlertas <= 0 || req.UmbralMedio > req.UmbralAlto {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid window, thresholds or saturation values"})
+	}
+	if len(req.PesosConcepto) > 0 {
+		var pesos map[string]float64
+		if err := json.Unmarshal(req.PesosConcepto, &pesos); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "pesos_concepto must be an object of code -> weight"})
+		}
+	}
+
+	req.ID = cfg.ID
+	req.CreatedAt = cfg.CreatedAt
+	cfg = req
+
+	if err := h.db.Save(&cfg).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error saving risk configuration"})
+	}
+	if existe {
+		_ = models.CrearAuditoria(h.db, "configuracion_riesgo", cfg.ID, models.AuditoriaUpdate, &before, &cfg, &claims.UserID)
+	} else {
+		_ = models.CrearAuditoria(h.db, "configuracion_riesgo", cfg.ID, models.AuditoriaInsert, nil, &cfg, &claims.UserID)
+	}
+	return c.JSON(cfg)
+}
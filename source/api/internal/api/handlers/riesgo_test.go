@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/api/middleware"
+	"github.com/school-monitoring/backend/internal/auth"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/testutil"
+)
+
+// Un PUT parcial conserva los campos omitidos (en particular pesos_concepto)
+func TestUpdateConfiguracionRiesgoParcial(t *testing.T) {
+	db := testutil.DB(t)
+	admin := models.Usuario{Email: "riesgo-" + uuid.NewString()[:8] + "@test.cl", PasswordHash: "x", Nombre: "Admin", Rol: models.RolAdmin}
+	if err := db.Create(&admin).Error; err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middleware.LocalsUserKey, &auth.Claims{UserID: admin.ID, Rol: admin.Rol})
+		return c.Next()
+	})
+	app.Put("/riesgo/configuracion", NewRiesgoHandler(db).UpdateConfiguracion)
+
+	put := func(body string) (int, models.ConfiguracionRiesgo) {
+		req := httptest.NewRequest("PUT", "/riesgo/configuracion", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cfg models.ConfiguracionRiesgo
+		_ = json.NewDecoder(resp.Body).Decode(&cfg)
+		return resp.StatusCode, cfg
+	}
+
+	if code, _ := put(`{"pesos_concepto": {"SOS": 5}}`); code != fiber.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	code, cfg := put(`{"umbral_alto": 70}`)
+	if code != fiber.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	var pesos map[string]float64
+	if err := json.Unmarshal(cfg.PesosConcepto, &pesos); err != nil || pesos["SOS"] != 5 {
+		t.Fatalf("pesos_concepto perdidos: %s", cfg.PesosConcepto)
+	}
+	def := models.ConfiguracionRiesgoDefault()
+	if cfg.UmbralAlto != 70 || cfg.PesoAsistencia != def.PesoAsistencia || cfg.VentanaDias != def.VentanaDias {
+		t.Fatalf("configuracion: %+v", cfg)
+	}
+
+	if code, _ := put(`{"umbral_medio": 90}`); code != fiber.StatusBadRequest {
+		t.Fatalf("umbral_medio > umbral_alto: status %d", code)
+	}
+}
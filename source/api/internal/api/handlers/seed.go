@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/school-monitoring/backend/internal/auth"
 	"github.com/school-monitoring/backend/internal/models"
 	"gorm.io/gorm"
@@ -63,6 +66,23 @@ func (h *SeedHandler) Seed(c *fiber.Ctx) error {
 	// Recargar cursos con IDs
 	h.db.Find(&cursos)
 
+	// Ano escolar vigente (marzo a diciembre) con dos semestres
+	hoy := time.Now()
+	anio := models.AnioEscolar{
+		Anio:        hoy.Year(),
+		FechaInicio: time.Date(hoy.Year(), time.March, 1, 0, 0, 0, 0, time.UTC),
+		FechaFin:    time.Date(hoy.Year(), time.December, 20, 0, 0, 0, 0, time.UTC),
+		Activo:      true,
+	}
+	if err := h.db.Where("anio = ?", anio.Anio).FirstOrCreate(&anio).Error; err == nil {
+		var nPeriodos int64
+		h.db.Model(&models.Periodo{}).Where("anio_escolar_id = ?", anio.ID).Count(&nPeriodos)
+		if nPeriodos == 0 {
+			periodos := models.PeriodosPorDefecto(anio, models.PeriodoSemestre)
+			h.db.Create(&periodos)
+		}
+	}
+
 	// Crear alumnos para cada curso
 	nombres := []string{"Santiago", "Martina", "Mateo", "Sofia", "Benjamin", "Valentina", "Lucas", "Isabella", "Agustin", "Emma"}
 	apellidos := []string{"Gonzalez", "Rodriguez", "Martinez", "Lopez", "Garcia", "Hernandez", "Perez", "Sanchez", "Ramirez", "Torres"}
@@ -78,6 +98,13 @@ func (h *SeedHandler) Seed(c *fiber.Ctx) error {
 			}
 			// Upsert por RUT para asegurar que el alumno quede asociado al curso correcto
 			h.db.Where("rut = ?", alumno.Rut).Assign(alumno).FirstOrCreate(&alumno)
+
+			if anio.ID != uuid.Nil {
+				mat := models.Matricula{AlumnoID: alumno.ID, AnioEscolarID: anio.ID, CursoID: curso.ID, Estado: models.MatriculaActiva, FechaAlta: anio.FechaInicio}
+				h.db.Where("alumno_id = ? AND anio_escolar_id = ?", alumno.ID, anio.ID).
+					Assign(models.Matricula{CursoID: curso.ID}).
+					FirstOrCreate(&mat)
+			}
 		}
 	}
 
@@ -135,6 +162,9 @@ func (h *SeedHandler) Seed(c *fiber.Ctx) error {
 						BloqueID:     bloque.ID,
 						DiaSemana:    dia,
 					}
+					if anio.ID != uuid.Nil {
+						hh.AnioEscolarID = &anio.ID
+					}
 
 					// Unico por (curso, dia, bloque)
 					h.db.Where("curso_id = ? AND dia_semana = ? AND bloque_id = ?", curso.ID, dia, bloque.ID).
@@ -153,11 +183,18 @@ func (h *SeedHandler) Seed(c *fiber.Ctx) error {
 		{Codigo: models.ConceptoSOS, Nombre: "SOS", Descripcion: "Situacion de emergencia"},
 		{Codigo: models.ConceptoComportamiento, Nombre: "Comportamiento", Descripcion: "Incidente de comportamiento"},
 		{Codigo: models.ConceptoDisciplinario, Nombre: "Disciplinario", Descripcion: "Problema disciplinario"},
+		{Codigo: models.ConceptoAtraso, Nombre: "Atraso", Descripcion: "Alumno llega tarde a clases"},
+		{Codigo: models.ConceptoRetiro, Nombre: "Retiro", Descripcion: "Alumno retirado antes del termino de la jornada"},
 	}
 
 	for i := range conceptos {
 		h.db.FirstOrCreate(&conceptos[i], models.Concepto{Codigo: conceptos[i].Codigo})
 	}
+	// Esquemas de Datos demo (solo si el concepto aun no declara uno)
+	for codigo, schema := range esquemasDatosDemo {
+		h.db.Model(&models.Concepto{}).Where("codigo = ? AND datos_schema IS NULL", codigo).
+			Update("datos_schema", json.RawMessage(schema))
+	}
 
 	// Recargar conceptos con IDs
 	h.db.Find(&conceptos)
@@ -186,6 +223,15 @@ func (h *SeedHandler) Seed(c *fiber.Ctx) error {
 	var accionAlertaAsistente models.Accion
 	h.db.First(&accionAlertaAsistente, "codigo = ?", "ALERTA_ASISTENTE")
 
+	var conceptoAtraso models.Concepto
+	h.db.First(&conceptoAtraso, "codigo = ?", models.ConceptoAtraso)
+
+	var accionAlertaInspector models.Accion
+	h.db.First(&accionAlertaInspector, "codigo = ?", "ALERTA_INSPECTOR")
+
+	var conceptoComportamiento models.Concepto
+	h.db.First(&conceptoComportamiento, "codigo = ?", models.ConceptoComportamiento)
+
 	reglas := []models.Regla{
 		{
 			Nombre:     "Notificar por inasistencia",
@@ -199,6 +245,18 @@ func (h *SeedHandler) Seed(c *fiber.Ctx) error {
 			Condicion:  []byte(`{"tipo": "cantidad", "campo": "inasistencias", "operador": ">=", "valor": 2, "dias": 7}`),
 			AccionID:   accionAlertaAsistente.ID,
 		},
+		{
+			Nombre:     "Alerta por 3 atrasos en 30 dias",
+			ConceptoID: conceptoAtraso.ID,
+			Condicion:  []byte(`{"tipo": "cantidad", "campo": "atrasos", "operador": ">=", "valor": 3, "dias": 30, "distinct_dias": true}`),
+			AccionID:   accionAlertaInspector.ID,
+		},
+		{
+			Nombre:     "Alerta por comportamiento grave",
+			ConceptoID: conceptoComportamiento.ID,
+			Condicion:  []byte(`{"tipo": "siempre", "datos": [{"campo": "severidad", "operador": "in", "valor": ["grave", "gravisima"]}]}`),
+			AccionID:   accionAlertaInspector.ID,
+		},
 	}
 
 	for i := range reglas {
@@ -212,9 +270,9 @@ func (h *SeedHandler) Seed(c *fiber.Ctx) error {
 		"alumnos":     "120 alumnos creados",
 		"asignaturas": "9 asignaturas creadas",
 		"bloques":     "5 bloques horarios creados",
-		"conceptos":   "6 conceptos creados",
+		"conceptos":   "8 conceptos creados",
 		"acciones":    "3 acciones creadas",
-		"reglas":      "2 reglas creadas",
+		"reglas":      "4 reglas creadas",
 	})
 }
 
@@ -224,3 +282,39 @@ func generarRut(curso string, indice int) string {
 	key := strings.ToUpper(strings.ReplaceAll(curso, " ", ""))
 	return fmt.Sprintf("DEMO-%s-%02d", key, indice+1)
 }
+
+// esquemasDatosDemo JSON Schema de Evento.Datos para los conceptos que se registran a mano
+var esquemasDatosDemo = map[string]string{
+	models.ConceptoComportamiento: `{
+		"type": "object",
+		"properties": {
+			"severidad": {"type": "string", "title": "Severidad", "enum": ["leve", "grave", "gravisima"]},
+			"lugar": {"type": "string", "title": "Lugar", "maxLength": 120},
+			"testigos": {"type": "array", "title": "Testigos", "items": {"type": "string", "maxLength": 120}, "maxItems": 10},
+			"descripcion": {"type": "string", "title": "Descripcion", "maxLength": 2000}
+		}
+	}`,
+	models.ConceptoDisciplinario: `{
+		"type": "object",
+		"properties": {
+			"severidad": {"type": "string", "title": "Severidad", "enum": ["leve", "grave", "gravisima"]},
+			"medida": {"type": "string", "title": "Medida aplicada", "maxLength": 200},
+			"descripcion": {"type": "string", "title": "Descripcion", "maxLength": 2000}
+		}
+	}`,
+	models.ConceptoSOS: `{
+		"type": "object",
+		"properties": {
+			"ubicacion": {"type": "string", "title": "Ubicacion", "maxLength": 120},
+			"tipo": {"type": "string", "title": "Tipo", "enum": ["salud", "seguridad", "convivencia", "otro"]},
+			"descripcion": {"type": "string", "title": "Descripcion", "maxLength": 2000}
+		}
+	}`,
+	models.ConceptoEnfermeria: `{
+		"type": "object",
+		"properties": {
+			"motivo": {"type": "string", "title": "Motivo", "maxLength": 200},
+			"requiere_retiro": {"type": "boolean", "title": "Requiere retiro"}
+		}
+	}`,
+}
@@ -0,0 +1,502 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/api/middleware"
+	"github.com/school-monitoring/backend/internal/auth"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/agenda"
+	"github.com/school-monitoring/backend/internal/services/orchestrator"
+	"github.com/school-monitoring/backend/internal/services/rollup"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Sincronizacion offline de la app movil (POST /sync).
+//
+// Cada mutacion trae un UUID generado por el cliente y el momento de captura (cliente_en). Se aplican
+// en el orden recibido, cada una en su propia transaccion; reenviar una mutacion ya procesada retorna
+// el resultado guardado (repetida=true) sin volver a aplicarla.
+//
+// Politica de conflictos (gana la version mas nueva; ante empate, el servidor):
+//   - asistencia: si la fila del servidor se modifico despues de cliente_en queda en conflicto y se
+//     retorna la fila vigente. El bloqueo de edicion se evalua con la hora del servidor: lo capturado
+//     offline debe sincronizarse antes del bloqueo, despues va por solicitud de correccion.
+//   - evento_crear: idempotente por ID (el ID de la mutacion es el ID del evento); no hay conflicto.
+//   - evento_cerrar: conflicto si el evento ya esta cerrado o se reabrio despues de cliente_en.
+//   - cliente_en en el futuro se toma como la hora del servidor; anterior a horizonteOfflineSync se
+//     rechaza (el evento creado offline conserva cliente_en como created_at, acotado a ese horizonte).
+//
+// El delta retorna lo modificado en el servidor desde el token del cliente (asistencias de sus
+// bloques, eventos de sus cursos y calendario). Si alguna coleccion llega al maximo, mas=true y el
+// cliente debe volver a llamar con el token nuevo; puede recibir filas repetidas (upsert por id).
+const (
+	maxMutacionesSync = 500
+	maxDeltaSync      = 1000
+	diasDeltaInicial  = 7
+	// Antiguedad maxima de una captura offline; lo anterior debe ingresarse por los flujos normales
+	horizonteOfflineSync = 7 * 24 * time.Hour
+	// Margen para no saltarse filas de transacciones que aun no confirman al leer el delta
+	margenTokenSync = 2 * time.Second
+)
+
+// SyncHandler maneja la sincronizacion offline
+type SyncHandler struct {
+	db         *gorm.DB
+	orch       *orchestrator.Orchestrator
+	asistencia *AsistenciaHandler
+}
+
+// NewSyncHandler crea un nuevo handler de sincronizacion
+func NewSyncHandler(db *gorm.DB, orch *orchestrator.Orchestrator) *SyncHandler {
+	return &SyncHandler{db: db, orch: orch, asistencia: NewAsistenciaHandler(db, orch)}
+}
+
+// SyncRequest lote de mutaciones offline
+type SyncRequest struct {
+	Token         string            `json:"token,omitempty"` // token de la ultima sincronizacion (vacio = primera)
+	DispositivoID string            `json:"dispositivo_id,omitempty"`
+	Mutaciones    []MutacionRequest `json:"mutaciones"`
+}
+
+// MutacionRequest una mutacion capturada en el dispositivo
+type MutacionRequest struct {
+	ID        uuid.UUID       `json:"id"`
+	Tipo      string          `json:"tipo"`       // asistencia, evento_crear, evento_cerrar
+	ClienteEn time.Time       `json:"cliente_en"` // RFC3339
+	Datos     json.RawMessage `json:"datos"`
+}
+
+// ResultadoMutacion resultado por mutacion
+type ResultadoMutacion struct {
+	ID        uuid.UUID       `json:"id"`
+	Resultado string          `json:"resultado"` // aplicado, conflicto, rechazado, error
+	Repetida  bool            `json:"repetida,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Errores   []ErrorRegistro `json:"errores,omitempty"`
+	Servidor  interface{}     `json:"servidor,omitempty"` // version vigente en el servidor (conflicto)
+}
+
+// mutacionAsistencia datos de una mutacion de asistencia (una fila)
+type mutacionAsistencia struct {
+	HorarioID uuid.UUID `json:"horario_id"`
+	Fecha     string    `json:"fecha"` // YYYY-MM-DD
+	RegistroAlumno
+}
+
+// mutacionEventoCerrar datos para cerrar un evento
+type mutacionEventoCerrar struct {
+	EventoID uuid.UUID `json:"evento_id"`
+	Motivo   string    `json:"motivo,omitempty"`
+}
+
+// SyncCambios delta de cambios del servidor
+type SyncCambios struct {
+	Asistencias []models.Asistencia        `json:"asistencias"`
+	Eventos     []models.Evento            `json:"eventos"`
+	Calendario  []models.CalendarioEntrada `json:"calendario"`
+	Eliminados  map[string][]uuid.UUID     `json:"eliminados,omitempty"` // asistencias / calendario
+}
+
+// bloqueSync bloque y fecha con asistencia sincronizada (para consolidar al final del lote)
+type bloqueSync struct {
+	cursoID   uuid.UUID
+	horarioID uuid.UUID
+	fecha     time.Time
+}
+
+// POST /sync
+func (h *SyncHandler) Sync(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not authenticated"})
+	}
+
+	var req SyncRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if len(req.Mutaciones) > maxMutacionesSync {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": "Too many mutations in one batch", "max": maxMutacionesSync})
+	}
+	inicio := time.Now()
+	desde := inicio.AddDate(0, 0, -diasDeltaInicial)
+	if req.Token != "" {
+		us, err := strconv.ParseInt(req.Token, 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid sync token"})
+		}
+		desde = time.UnixMicro(us)
+	}
+
+	resultados := make([]ResultadoMutacion, 0, len(req.Mutaciones))
+	bloques := map[bloqueSync]bool{}
+	for _, m := range req.Mutaciones {
+		r, b := h.procesar(claims, req.DispositivoID, m)
+		if b != nil {
+			bloques[*b] = true
+		}
+		resultados = append(resultados, r)
+	}
+
+	if h.orch != nil && len(req.Mutaciones) > 0 {
+		h.orch.Flush()
+	}
+	consolidados := map[bloqueSync]bool{}
+	for b := range bloques {
+		dia := bloqueSync{cursoID: b.cursoID, fecha: b.fecha}
+		if !consolidados[dia] {
+			consolidados[dia] = true
+			recalcularDiaria(h.db, b.fecha, b.fecha, rollup.Filtro{CursoID: &b.cursoID})
+		}
+		if h.orch != nil {
+			h.orch.Notify("asistencia_sincronizada", fiber.Map{
+				"curso_id":   b.cursoID.String(),
+				"horario_id": b.horarioID.String(),
+				"fecha":      b.fecha.Format("2006-01-02"),
+				"usuario_id": claims.UserID.String(),
+			})
+		}
+	}
+
+	cambios, hasta, mas, err := h.delta(claims, desde, inicio.Add(-margenTokenSync))
+	if err != nil {
+		log.Printf("sync: delta usuario %s: %v", claims.UserID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching server changes", "resultados": resultados})
+	}
+
+	return c.JSON(fiber.Map{
+		"resultados":  resultados,
+		"cambios":     cambios,
+		"token":       strconv.FormatInt(hasta.UnixMicro(), 10),
+		"mas":         mas,
+		"servidor_en": inicio,
+	})
+}
+
+// procesar aplica una mutacion y guarda su resultado (salvo errores transitorios)
+func (h *SyncHandler) procesar(claims *auth.Claims, dispositivo string, m MutacionRequest) (ResultadoMutacion, *bloqueSync) {
+	if m.ID == uuid.Nil {
+		return ResultadoMutacion{Resultado: models.SyncRechazado, Error: "id is required"}, nil
+	}
+
+	var previa models.SyncMutacion
+	if err := h.db.First(&previa, "id = ?", m.ID).Error; err == nil {
+		if previa.UsuarioID != claims.UserID {
+			return ResultadoMutacion{ID: m.ID, Resultado: models.SyncRechazado, Error: "Mutation id already used"}, nil
+		}
+		var out ResultadoMutacion
+		_ = json.Unmarshal(previa.Detalle, &out)
+		out.ID = m.ID
+		out.Repetida = true
+		return out, nil
+	}
+
+	clienteEn, vigente := momentoCliente(m.ClienteEn, time.Now())
+
+	var out ResultadoMutacion
+	var bloque *bloqueSync
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		switch {
+		case m.ClienteEn.IsZero():
+			out = rechazo("cliente_en is required")
+		case !vigente:
+			out = rechazo("cliente_en is older than the offline window")
+		case m.Tipo == models.MutacionAsistencia:
+			out, bloque, err = h.aplicarAsistencia(tx, claims, m, clienteEn)
+		case m.Tipo == models.MutacionEventoCrear:
+			out, err = h.aplicarEventoCrear(tx, claims, m, clienteEn)
+		case m.Tipo == models.MutacionEventoCerrar:
+			out, err = h.aplicarEventoCerrar(tx, claims, m, clienteEn)
+		default:
+			out = rechazo("Unknown mutation type (asistencia, evento_crear, evento_cerrar)")
+		}
+		if err != nil {
+			return err
+		}
+		out.ID = m.ID
+
+		detalle, _ := json.Marshal(out)
+		return tx.Create(&models.SyncMutacion{
+			ID:            m.ID,
+			UsuarioID:     claims.UserID,
+			DispositivoID: dispositivo,
+			Tipo:          m.Tipo,
+			ClienteEn:     clienteEn,
+			Resultado:     out.Resultado,
+			Detalle:       detalle,
+		}).Error
+	})
+	if err != nil {
+		log.Printf("sync: mutacion %s (%s): %v", m.ID, m.Tipo, err)
+		return ResultadoMutacion{ID: m.ID, Resultado: models.SyncError, Error: "Error applying mutation, retry"}, nil
+	}
+	if out.Resultado != models.SyncAplicado {
+		bloque = nil
+	}
+	return out, bloque
+}
+
+// momentoCliente acota cliente_en a la hora del servidor; vigente=false si es anterior al horizonte offline
+func momentoCliente(clienteEn, now time.Time) (time.Time, bool) {
+	if clienteEn.After(now) {
+		return now, true
+	}
+	return clienteEn, !clienteEn.Before(now.Add(-horizonteOfflineSync))
+}
+
+func rechazo(msg string) ResultadoMutacion {
+	return ResultadoMutacion{Resultado: models.SyncRechazado, Error: msg}
+}
+
+// aplicarAsistencia aplica una fila de asistencia con las mismas reglas que POST /asistencia/bloque
+func (h *SyncHandler) aplicarAsistencia(tx *gorm.DB, claims *auth.Claims, m MutacionRequest, clienteEn time.Time) (ResultadoMutacion, *bloqueSync, error) {
+	if !auth.TienePermiso(claims.Rol, auth.PermisoRegistrarAsistencia) {
+		return rechazo("Insufficient permissions"), nil, nil
+	}
+	var d mutacionAsistencia
+	if err := json.Unmarshal(m.Datos, &d); err != nil {
+		return rechazo("Invalid datos"), nil, nil
+	}
+	fecha, err := time.Parse("2006-01-02", d.Fecha)
+	if err != nil {
+		return rechazo("Invalid date format, use YYYY-MM-DD"), nil, nil
+	}
+	// Fila del horario tomada hasta el commit: el bloqueo y la escritura ven el mismo estado
+	var horario models.Horario
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Bloque").First(&horario, "id = ?", d.HorarioID).Error; err != nil {
+		return rechazo("Schedule not found"), nil, nil
+	}
+
+	efectivo, dia, dictado, err := agenda.Resolver(tx, horario, fecha)
+	if err != nil {
+		return ResultadoMutacion{}, nil, err
+	}
+	if !dia.Lectivo || !dictado || agenda.Cancelado(efectivo) {
+		return rechazo("Block is not held on this date"), nil, nil
+	}
+	if !auth.TienePermiso(claims.Rol, auth.PermisoAdministrar) && efectivo.ProfesorID != claims.UserID {
+		return rechazo("Not the teacher of this block on this date"), nil, nil
+	}
+	now := time.Now().In(zonaColegio())
+	if fecha.Format("2006-01-02") > now.Format("2006-01-02") {
+		return rechazo("Cannot register attendance for a future date"), nil, nil
+	}
+	if bloqueado, _ := registroBloqueado(tx, horario, fecha, now); bloqueado {
+		return rechazo("Attendance is locked, submit a correction request"), nil, nil
+	}
+
+	// Atraso sin hora de llegada: se toma el momento de la captura en el dispositivo
+	registros := []RegistroAlumno{d.RegistroAlumno}
+	detalles, errores, err := validarRegistros(tx, horario, fecha, registros, clienteEn)
+	if err != nil {
+		return ResultadoMutacion{}, nil, err
+	}
+	if len(errores) > 0 {
+		out := rechazo("Invalid attendance rows")
+		out.Errores = errores
+		return out, nil, nil
+	}
+
+	var actual models.Asistencia
+	if err := tx.Where("alumno_id = ? AND horario_id = ? AND fecha = ?", d.AlumnoID, horario.ID, fecha).
+		First(&actual).Error; err == nil && !actual.UpdatedAt.Before(clienteEn) {
+		return ResultadoMutacion{Resultado: models.SyncConflicto, Error: "Server has a newer version", Servidor: actual}, nil, nil
+	}
+
+	if err := h.asistencia.escribirRegistros(tx, horario, fecha, registros, detalles, claims.UserID); err != nil {
+		return ResultadoMutacion{}, nil, err
+	}
+	if _, err := asegurarEstadoBloque(tx, horario, efectivo.ProfesorID, fecha, claims.UserID); err != nil {
+		return ResultadoMutacion{}, nil, err
+	}
+	return ResultadoMutacion{Resultado: models.SyncAplicado}, &bloqueSync{cursoID: horario.CursoID, horarioID: horario.ID, fecha: fecha}, nil
+}
+
+// aplicarEventoCrear crea el evento con el ID de la mutacion y la hora de captura
+func (h *SyncHandler) aplicarEventoCrear(tx *gorm.DB, claims *auth.Claims, m MutacionRequest, clienteEn time.Time) (ResultadoMutacion, error) {
+	if !auth.TienePermiso(claims.Rol, auth.PermisoCrearEventos) {
+		return rechazo("Insufficient permissions"), nil
+	}
+	var d EventoRequest
+	if err := json.Unmarshal(m.Datos, &d); err != nil {
+		return rechazo("Invalid datos"), nil
+	}
+	if d.ConceptoID == uuid.Nil {
+		return rechazo("Concept ID is required"), nil
+	}
+	var concepto models.Concepto
+	if err := tx.First(&concepto, "id = ?", d.ConceptoID).Error; err != nil {
+		return rechazo("Concept not found"), nil
+	}
+	if errs := validarDatosEvento(&concepto, d.Datos); len(errs) > 0 {
+		return rechazo("Invalid datos: " + errs[0].Campo + " " + errs[0].Error), nil
+	}
+	var existe int64
+	tx.Unscoped().Model(&models.Evento{}).Where("id = ?", m.ID).Count(&existe)
+	if existe > 0 {
+		return rechazo("Event ID already exists"), nil
+	}
+
+	evento := models.Evento{
+		ID:            m.ID,
+		ConceptoID:    &d.ConceptoID,
+		AlumnoID:      d.AlumnoID,
+		CursoID:       d.CursoID,
+		Origen:        models.OrigenProfesor,
+		OrigenUsuario: &claims.UserID,
+		Datos:         d.Datos,
+		Activo:        true,
+		CreatedAt:     clienteEn,
+	}
+	if h.orch != nil {
+		if err := h.orch.CreateEventoTx(tx, &evento, &claims.UserID); err != nil {
+			return ResultadoMutacion{}, err
+		}
+	} else if err := tx.Create(&evento).Error; err != nil {
+		return ResultadoMutacion{}, err
+	}
+	return ResultadoMutacion{Resultado: models.SyncAplicado}, nil
+}
+
+// aplicarEventoCerrar cierra el evento salvo que el servidor tenga un estado posterior
+func (h *SyncHandler) aplicarEventoCerrar(tx *gorm.DB, claims *auth.Claims, m MutacionRequest, clienteEn time.Time) (ResultadoMutacion, error) {
+	if !auth.TienePermiso(claims.Rol, auth.PermisoCrearEventos) && !auth.TienePermiso(claims.Rol, auth.PermisoCerrarEventos) {
+		return rechazo("Insufficient permissions"), nil
+	}
+	var d mutacionEventoCerrar
+	if err := json.Unmarshal(m.Datos, &d); err != nil {
+		return rechazo("Invalid datos"), nil
+	}
+	if !models.EsMotivoCierreValido(d.Motivo) {
+		return rechazo("Invalid motivo"), nil
+	}
+	var evento models.Evento
+	if err := tx.First(&evento, "id = ?", d.EventoID).Error; err != nil {
+		return rechazo("Event not found"), nil
+	}
+	if !evento.Activo {
+		return ResultadoMutacion{Resultado: models.SyncConflicto, Error: "Event already closed", Servidor: evento}, nil
+	}
+	if evento.ReabiertoEn != nil && evento.ReabiertoEn.After(clienteEn) {
+		return ResultadoMutacion{Resultado: models.SyncConflicto, Error: "Event was reopened after this change", Servidor: evento}, nil
+	}
+
+	if h.orch != nil {
+		if _, err := h.orch.CloseEventoTx(tx, evento.ID, claims.UserID, d.Motivo); err != nil {
+			return ResultadoMutacion{}, err
+		}
+	} else {
+		before := evento
+		evento.Cerrar(claims.UserID, d.Motivo)
+		if err := tx.Save(&evento).Error; err != nil {
+			return ResultadoMutacion{}, err
+		}
+		_ = models.CrearAuditoria(tx, "eventos", evento.ID, models.AuditoriaUpdate, &before, &evento, &claims.UserID)
+	}
+	return ResultadoMutacion{Resultado: models.SyncAplicado}, nil
+}
+
+// asegurarEstadoBloque crea el snapshot del bloque si aun no existe, registra quien lo actualizo y
+// recalcula sus conteos desde las filas de asistencia. Retorna el snapshot ya recontado.
+func asegurarEstadoBloque(tx *gorm.DB, horario models.Horario, profesorID uuid.UUID, fecha time.Time, usuarioID uuid.UUID) (models.HorarioAsistenciaEstado, error) {
+	now := time.Now()
+	hae := models.HorarioAsistenciaEstado{
+		HorarioID: horario.ID,
+		Fecha:     fecha,
+		CursoID:   horario.CursoID,
+		BloqueID:  horario.BloqueID,
+		DiaSemana: horario.DiaSemana,
+	}
+	if err := tx.Where("horario_id = ? AND fecha = ?", horario.ID, fecha).
+		Assign(models.HorarioAsistenciaEstado{ProfesorID: profesorID, UltimaActualizacionEn: &now, UltimaActualizacionPor: usuarioID}).
+		FirstOrCreate(&hae).Error; err != nil {
+		return hae, err
+	}
+	if err := models.RecontarHorarioAsistenciaEstado(tx, horario.ID, fecha); err != nil {
+		return hae, err
+	}
+	err := tx.First(&hae, "id = ?", hae.ID).Error
+	return hae, err
+}
+
+// delta lee los cambios en (desde, hasta]. Si una coleccion llega al maximo, el token retrocede a su
+// ultima fila incluida y mas=true.
+func (h *SyncHandler) delta(claims *auth.Claims, desde, hasta time.Time) (SyncCambios, time.Time, bool, error) {
+	out := SyncCambios{Eliminados: map[string][]uuid.UUID{}}
+	token := hasta
+	mas := false
+	recortar := func(n int, ultima func() time.Time) {
+		if n > maxDeltaSync {
+			mas = true
+			if t := ultima().Add(-time.Microsecond); t.Before(token) {
+				token = t
+			}
+		}
+	}
+
+	// Bloques del usuario: titular o reemplazante
+	propios := h.db.Model(&models.Horario{}).Select("id").Where("profesor_id = ?", claims.UserID)
+	reemplazos := h.db.Model(&models.HorarioExcepcion{}).Select("horario_id").Where("profesor_reemplazo_id = ?", claims.UserID)
+
+	if err := h.db.Where("updated_at > ? AND updated_at <= ?", desde, hasta).
+		Where("(horario_id IN (?) OR horario_id IN (?))", propios, reemplazos).
+		Order("updated_at").Limit(maxDeltaSync + 1).Find(&out.Asistencias).Error; err != nil {
+		return out, token, false, err
+	}
+	recortar(len(out.Asistencias), func() time.Time { return out.Asistencias[maxDeltaSync-1].UpdatedAt })
+	if len(out.Asistencias) > maxDeltaSync {
+		out.Asistencias = out.Asistencias[:maxDeltaSync]
+	}
+
+	qe := h.db.Preload("Concepto").Where("updated_at > ? AND updated_at <= ?", desde, hasta)
+	if !auth.TienePermiso(claims.Rol, auth.PermisoVerMonitor) {
+		cursos := h.db.Model(&models.Horario{}).Select("curso_id").Where("profesor_id = ?", claims.UserID)
+		qe = qe.Where("(curso_id IN (?) OR origen_usuario = ?)", cursos, claims.UserID)
+	}
+	if err := qe.Order("updated_at").Limit(maxDeltaSync + 1).Find(&out.Eventos).Error; err != nil {
+		return out, token, false, err
+	}
+	recortar(len(out.Eventos), func() time.Time { return out.Eventos[maxDeltaSync-1].UpdatedAt })
+	if len(out.Eventos) > maxDeltaSync {
+		out.Eventos = out.Eventos[:maxDeltaSync]
+	}
+
+	if err := h.db.Where("updated_at > ? AND updated_at <= ?", desde, hasta).
+		Order("updated_at").Limit(maxDeltaSync + 1).Find(&out.Calendario).Error; err != nil {
+		return out, token, false, err
+	}
+	recortar(len(out.Calendario), func() time.Time { return out.Calendario[maxDeltaSync-1].UpdatedAt })
+	if len(out.Calendario) > maxDeltaSync {
+		out.Calendario = out.Calendario[:maxDeltaSync]
+	}
+
+	// Borrados logicos del periodo
+	var ids []uuid.UUID
+	if err := h.db.Unscoped().Model(&models.Asistencia{}).
+		Where("deleted_at > ? AND deleted_at <= ?", desde, hasta).
+		Where("(horario_id IN (?) OR horario_id IN (?))", propios, reemplazos).
+		Pluck("id", &ids).Error; err != nil {
+		return out, token, false, err
+	}
+	if len(ids) > 0 {
+		out.Eliminados["asistencias"] = ids
+	}
+	ids = nil
+	if err := h.db.Unscoped().Model(&models.CalendarioEntrada{}).
+		Where("deleted_at > ? AND deleted_at <= ?", desde, hasta).Pluck("id", &ids).Error; err != nil {
+		return out, token, false, err
+	}
+	if len(ids) > 0 {
+		out.Eliminados["calendario"] = ids
+	}
+
+	return out, token, mas, nil
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/auth"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/testutil"
+)
+
+func TestMomentoCliente(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name    string
+		cliente time.Time
+		want    time.Time
+		vigente bool
+	}{
+		{"pasado reciente", now.Add(-time.Hour), now.Add(-time.Hour), true},
+		{"futuro se acota al servidor", now.Add(time.Hour), now, true},
+		{"limite del horizonte", now.Add(-horizonteOfflineSync), now.Add(-horizonteOfflineSync), true},
+		{"anterior al horizonte", now.Add(-horizonteOfflineSync - time.Second), now.Add(-horizonteOfflineSync - time.Second), false},
+	}
+	for _, tc := range cases {
+		got, vigente := momentoCliente(tc.cliente, now)
+		if !got.Equal(tc.want) || vigente != tc.vigente {
+			t.Errorf("%s: %s %v", tc.name, got, vigente)
+		}
+	}
+}
+
+func mutacion(tipo string, clienteEn time.Time, datos interface{}) MutacionRequest {
+	raw, _ := json.Marshal(datos)
+	return MutacionRequest{ID: uuid.New(), Tipo: tipo, ClienteEn: clienteEn, Datos: raw}
+}
+
+// Asistencia: gana la version mas nueva; una captura anterior a la ultima escritura queda en conflicto
+func TestSyncAsistenciaUltimaEscrituraGana(t *testing.T) {
+	db := testutil.DB(t)
+	hoy := time.Now().In(zonaColegio())
+	dia := models.DiaSemanaDeFecha(hoy)
+	if dia > 5 {
+		t.Skip("fin de semana: no hay bloques que registrar")
+	}
+	horario, alumno, prof := nuevoBloque(t, db, dia)
+	h := NewSyncHandler(db, nil)
+	claims := &auth.Claims{UserID: prof.ID, Rol: prof.Rol}
+	fila := func(estado string) mutacionAsistencia {
+		return mutacionAsistencia{HorarioID: horario.ID, Fecha: hoy.Format("2006-01-02"), RegistroAlumno: RegistroAlumno{AlumnoID: alumno.ID, Estado: estado}}
+	}
+
+	primera := mutacion(models.MutacionAsistencia, time.Now().Add(-time.Hour), fila(models.EstadoAusente))
+	if r, _ := h.procesar(claims, "", primera); r.Resultado != models.SyncAplicado {
+		t.Fatalf("primera: %+v", r)
+	}
+	vieja := mutacion(models.MutacionAsistencia, time.Now().Add(-2*time.Hour), fila(models.EstadoPresente))
+	if r, _ := h.procesar(claims, "", vieja); r.Resultado != models.SyncConflicto || r.Servidor == nil {
+		t.Fatalf("captura anterior a la escritura del servidor: %+v", r)
+	}
+	nueva := mutacion(models.MutacionAsistencia, time.Now().Add(time.Minute), fila(models.EstadoPresente))
+	if r, _ := h.procesar(claims, "", nueva); r.Resultado != models.SyncAplicado {
+		t.Fatalf("captura posterior: %+v", r)
+	}
+	var a models.Asistencia
+	if err := db.First(&a, "alumno_id = ? AND horario_id = ?", alumno.ID, horario.ID).Error; err != nil || a.Estado != models.EstadoPresente {
+		t.Fatalf("asistencia: %s %v", a.Estado, err)
+	}
+
+	// Reenvio: se retorna el resultado guardado sin volver a aplicar
+	if r, _ := h.procesar(claims, "", vieja); !r.Repetida || r.Resultado != models.SyncConflicto {
+		t.Fatalf("reenvio: %+v", r)
+	}
+}
+
+// Eventos: cierre en conflicto si se reabrio despues de la captura; cliente_en fuera de horizonte o
+// motivo desconocido se rechazan; created_at nunca queda en el futuro.
+func TestSyncEventos(t *testing.T) {
+	db := testutil.DB(t)
+	_, alumno, prof := nuevoBloque(t, db, 1)
+	concepto := models.Concepto{Codigo: "TEST_" + uuid.NewString()[:8], Nombre: "Test"}
+	if err := db.Create(&concepto).Error; err != nil {
+		t.Fatal(err)
+	}
+	h := NewSyncHandler(db, nil)
+	claims := &auth.Claims{UserID: prof.ID, Rol: prof.Rol}
+	crear := EventoRequest{ConceptoID: concepto.ID, AlumnoID: &alumno.ID, CursoID: &alumno.CursoID}
+
+	antigua := mutacion(models.MutacionEventoCrear, time.Now().Add(-horizonteOfflineSync-time.Hour), crear)
+	if r, _ := h.procesar(claims, "", antigua); r.Resultado != models.SyncRechazado {
+		t.Fatalf("fuera del horizonte: %+v", r)
+	}
+
+	futura := mutacion(models.MutacionEventoCrear, time.Now().Add(24*time.Hour), crear)
+	if r, _ := h.procesar(claims, "", futura); r.Resultado != models.SyncAplicado {
+		t.Fatalf("crear: %+v", r)
+	}
+	var evento models.Evento
+	if err := db.First(&evento, "id = ?", futura.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if evento.CreatedAt.After(time.Now()) {
+		t.Fatalf("created_at en el futuro: %s", evento.CreatedAt)
+	}
+
+	cerrar := func(clienteEn time.Time, motivo string) ResultadoMutacion {
+		r, _ := h.procesar(claims, "", mutacion(models.MutacionEventoCerrar, clienteEn, mutacionEventoCerrar{EventoID: evento.ID, Motivo: motivo}))
+		return r
+	}
+	if r := cerrar(time.Now(), "porque si"); r.Resultado != models.SyncRechazado {
+		t.Fatalf("motivo invalido: %+v", r)
+	}
+	reabierto := time.Now()
+	if err := db.Model(&evento).Update("reabierto_en", reabierto).Error; err != nil {
+		t.Fatal(err)
+	}
+	if r := cerrar(reabierto.Add(-time.Minute), models.MotivoCierreResuelto); r.Resultado != models.SyncConflicto {
+		t.Fatalf("cierre anterior a la reapertura: %+v", r)
+	}
+	if r := cerrar(time.Now(), models.MotivoCierreResuelto); r.Resultado != models.SyncAplicado {
+		t.Fatalf("cierre: %+v", r)
+	}
+	if r := cerrar(time.Now(), ""); r.Resultado != models.SyncConflicto {
+		t.Fatalf("evento ya cerrado: %+v", r)
+	}
+}
@@ -1,20 +1,24 @@
 package handlers
 
 import (
+	"errors"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/api/middleware"
 	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/orchestrator"
 	"gorm.io/gorm"
 )
 
 type TrazabilidadHandler struct {
-	db *gorm.DB
+	db   *gorm.DB
+	orch *orchestrator.Orchestrator
 }
 
-func NewTrazabilidadHandler(db *gorm.DB) *TrazabilidadHandler {
-	return &TrazabilidadHandler{db: db}
+func NewTrazabilidadHandler(db *gorm.DB, orch *orchestrator.Orchestrator) *TrazabilidadHandler {
+	return &TrazabilidadHandler{db: db, orch: orch}
 }
 
 // GET /auditorias?tabla=&registro_id=&usuario_id=&limit=&offset=
@@ -107,6 +111,56 @@ func (h *TrazabilidadHandler) AccionesEjecuciones(c *fiber.Ctx) error {
 	return c.JSON(out)
 }
 
+// GET /acciones-ejecuciones/fallidas?regla_id=&pendientes=true&limit=&offset=
+func (h *TrazabilidadHandler) EjecucionesFallidas(c *fiber.Ctx) error {
+	q := h.db.Preload("Regla").Preload("Accion").Preload("Evento").Model(&models.AccionEjecucion{}).
+		Where("resultado = ?", models.EjecucionResultadoError)
+
+	if reglaID := c.Query("regla_id"); reglaID != "" {
+		if id, err := uuid.Parse(reglaID); err == nil {
+			q = q.Where("regla_id = ?", id)
+		}
+	}
+	// pendientes=true: solo las que aun tienen reintento automatico agendado
+	if c.Query("pendientes") == "true" {
+		q = q.Where("siguiente_intento_en IS NOT NULL")
+	}
+
+	limit := clamp(atoi(c.Query("limit")), 1, 200)
+	offset := clamp(atoi(c.Query("offset")), 0, 1000000)
+
+	var out []models.AccionEjecucion
+	if err := q.Order("ejecutado_en DESC").Limit(limit).Offset(offset).Find(&out).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error fetching failed executions"})
+	}
+	return c.JSON(out)
+}
+
+// POST /acciones-ejecuciones/{id}/reintentar
+func (h *TrazabilidadHandler) ReintentarEjecucion(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid execution ID"})
+	}
+
+	exec, err := h.orch.ReintentarEjecucion(id, userIDPtr(claims))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Execution not found"})
+		}
+		if errors.Is(err, orchestrator.ErrEjecucionNoFallida) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Execution is not in error state"})
+		}
+		if errors.Is(err, orchestrator.ErrEjecucionHuerfana) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Rule, action or event no longer exists; execution marked as exhausted", "exec": exec})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error retrying execution"})
+	}
+	return c.JSON(exec)
+}
+
 func clamp(n, min, max int) int {
 	if n < min {
 		return min
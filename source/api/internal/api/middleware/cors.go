@@ -8,7 +8,7 @@ import (
 func CORSMiddleware(c *fiber.Ctx) error {
 	c.Set("Access-Control-Allow-Origin", "*")
 	c.Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-	c.Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, X-Request-Id")
+	c.Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, X-Request-Id, Idempotency-Key")
 	c.Set("Access-Control-Max-Age", "86400")
 
 	if c.Method() == fiber.MethodOptions {
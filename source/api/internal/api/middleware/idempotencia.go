@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HeaderIdempotencyKey header con la clave generada por el cliente (un UUID por accion del usuario)
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+const maxLargoClaveIdempotencia = 255
+
+// Un request en_proceso mas antiguo que esto se da por abandonado (ej. caida del servidor) y la clave se libera
+const abandonoIdempotencia = 5 * time.Minute
+
+// IdempotencyMiddleware evita ejecutar dos veces el mismo request (doble toque, reintentos por timeout).
+// Sin header no hace nada. Con header, por usuario y clave:
+//   - primera vez: ejecuta el handler y guarda status + body por IDEMPOTENCY_TTL_HORAS (default 24)
+//   - repetido con el mismo request: retorna la respuesta guardada (header Idempotent-Replayed: true)
+//   - repetido con otro metodo, ruta o body: 422
+//   - repetido mientras el primero sigue en curso: 409
+//
+// Las respuestas 5xx no se guardan para que el cliente pueda reintentar. Debe ir despues de AuthMiddleware.
+func IdempotencyMiddleware(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		clave := c.Get(HeaderIdempotencyKey)
+		if clave == "" {
+			return c.Next()
+		}
+		if len(clave) > maxLargoClaveIdempotencia {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Idempotency-Key too long"})
+		}
+
+		usuarioID := uuid.Nil
+		if claims := GetUserFromContext(c); claims != nil {
+			usuarioID = claims.UserID
+		}
+		suma := sha256.New()
+		suma.Write([]byte(c.Method() + " " + c.Path() + "\n"))
+		suma.Write(c.Body())
+		hash := hex.EncodeToString(suma.Sum(nil))
+
+		now := time.Now()
+		reg := models.IdempotenciaClave{
+			UsuarioID:   usuarioID,
+			Clave:       clave,
+			Metodo:      c.Method(),
+			Ruta:        c.Path(),
+			HashRequest: hash,
+			Estado:      models.IdempotenciaEnProceso,
+			ExpiraEn:    now.Add(ttlIdempotencia()),
+		}
+
+		reservada, err := reservarClave(db, &reg, now)
+		if err != nil {
+			// Sin tabla disponible se atiende el request igual (sin proteccion ante duplicados)
+			log.Printf("idempotencia: reservar clave %q: %v", clave, err)
+			return c.Next()
+		}
+		if !reservada {
+			var previa models.IdempotenciaClave
+			if err := db.Where("usuario_id = ? AND clave = ?", usuarioID, clave).First(&previa).Error; err != nil {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A request with this Idempotency-Key is still in progress"})
+			}
+			switch {
+			case previa.HashRequest != hash:
+				return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Idempotency-Key already used for a different request"})
+			case previa.Estado != models.IdempotenciaCompletada:
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A request with this Idempotency-Key is still in progress"})
+			}
+			c.Set("Idempotent-Replayed", "true")
+			if previa.ContentType != "" {
+				c.Set(fiber.HeaderContentType, previa.ContentType)
+			}
+			return c.Status(previa.StatusCode).Send(previa.Respuesta)
+		}
+
+		if err := c.Next(); err != nil {
+			db.Delete(&models.IdempotenciaClave{}, "id = ?", reg.ID)
+			return err
+		}
+
+		status := c.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError {
+			db.Delete(&models.IdempotenciaClave{}, "id = ?", reg.ID)
+			return nil
+		}
+		if err := db.Model(&models.IdempotenciaClave{}).Where("id = ?", reg.ID).Updates(map[string]interface{}{
+			"estado":       models.IdempotenciaCompletada,
+			"status_code":  status,
+			"content_type": string(c.Response().Header.ContentType()),
+			"respuesta":    append([]byte(nil), c.Response().Body()...),
+		}).Error; err != nil {
+			log.Printf("idempotencia: guardar respuesta %q: %v", clave, err)
+		}
+		return nil
+	}
+}
+
+// reservarClave inserta la clave en estado en_proceso; false si ya existe una vigente.
+// Una clave vencida o abandonada se reemplaza.
+func reservarClave(db *gorm.DB, reg *models.IdempotenciaClave, now time.Time) (bool, error) {
+	for intento := 0; intento < 2; intento++ {
+		res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(reg)
+		if res.Error != nil {
+			return false, res.Error
+		}
+		if res.RowsAffected > 0 {
+			return true, nil
+		}
+		borrada := db.Where("usuario_id = ? AND clave = ?", reg.UsuarioID, reg.Clave).
+			Where("(expira_en < ? OR (estado = ? AND created_at < ?))", now, models.IdempotenciaEnProceso, now.Add(-abandonoIdempotencia)).
+			Delete(&models.IdempotenciaClave{})
+		if borrada.Error != nil || borrada.RowsAffected == 0 {
+			return false, borrada.Error
+		}
+		reg.ID = uuid.Nil
+	}
+	return false, nil
+}
+
+func ttlIdempotencia() time.Duration {
+	if n, err := strconv.Atoi(os.Getenv("IDEMPOTENCY_TTL_HORAS")); err == nil && n > 0 {
+		return time.Duration(n) * time.Hour
+	}
+	return 24 * time.Hour
+}
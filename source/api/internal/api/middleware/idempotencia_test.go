@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/auth"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/testutil"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	db := testutil.DB(t)
+	usuario := uuid.New()
+	ejecuciones := 0
+	fallar := false
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		id := usuario
+		if u := c.Get("X-Usuario"); u != "" {
+			id = uuid.MustParse(u)
+		}
+		c.Locals(LocalsUserKey, &auth.Claims{UserID: id, Rol: models.RolAdmin})
+		return c.Next()
+	})
+	app.Post("/recurso", IdempotencyMiddleware(db), func(c *fiber.Ctx) error {
+		ejecuciones++
+		if fallar {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "boom"})
+		}
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{"n": ejecuciones})
+	})
+
+	type respuesta struct {
+		status   int
+		body     string
+		replayed bool
+	}
+	post := func(clave, body string, headers ...string) respuesta {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/recurso", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if clave != "" {
+			req.Header.Set(HeaderIdempotencyKey, clave)
+		}
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(resp.Body)
+		return respuesta{resp.StatusCode, string(b), resp.Header.Get("Idempotent-Replayed") == "true"}
+	}
+
+	t.Run("sin clave se ejecuta siempre", func(t *testing.T) {
+		antes := ejecuciones
+		post("", `{}`)
+		post("", `{}`)
+		if ejecuciones != antes+2 {
+			t.Fatalf("ejecuciones: %d", ejecuciones-antes)
+		}
+	})
+
+	t.Run("repeticion retorna la respuesta guardada", func(t *testing.T) {
+		clave := uuid.NewString()
+		primera := post(clave, `{"a":1}`)
+		antes := ejecuciones
+		segunda := post(clave, `{"a":1}`)
+		if primera.status != fiber.StatusCreated || primera.replayed {
+			t.Fatalf("primera: %+v", primera)
+		}
+		if segunda.status != primera.status || segunda.body != primera.body || !segunda.replayed || ejecuciones != antes {
+			t.Fatalf("repeticion: %+v (primera %+v), ejecuciones +%d", segunda, primera, ejecuciones-antes)
+		}
+	})
+
+	t.Run("misma clave con otro body", func(t *testing.T) {
+		clave := uuid.NewString()
+		post(clave, `{"a":1}`)
+		if r := post(clave, `{"a":2}`); r.status != fiber.StatusUnprocessableEntity {
+			t.Fatalf("status %d", r.status)
+		}
+	})
+
+	t.Run("la clave es por usuario", func(t *testing.T) {
+		clave := uuid.NewString()
+		post(clave, `{}`)
+		antes := ejecuciones
+		if r := post(clave, `{}`, "X-Usuario", uuid.NewString()); r.replayed || ejecuciones != antes+1 {
+			t.Fatalf("otro usuario: %+v", r)
+		}
+	})
+
+	t.Run("en curso", func(t *testing.T) {
+		clave := uuid.NewString()
+		suma := sha256.Sum256([]byte("POST /recurso\n{}"))
+		enCurso := models.IdempotenciaClave{UsuarioID: usuario, Clave: clave, Metodo: "POST", Ruta: "/recurso",
+			HashRequest: hex.EncodeToString(suma[:]), Estado: models.IdempotenciaEnProceso, ExpiraEn: time.Now().Add(time.Hour)}
+		if err := db.Create(&enCurso).Error; err != nil {
+			t.Fatal(err)
+		}
+		antes := ejecuciones
+		if r := post(clave, `{"otro":1}`); r.status != fiber.StatusUnprocessableEntity {
+			t.Fatalf("otro body: status %d", r.status)
+		}
+		if r := post(clave, `{}`); r.status != fiber.StatusConflict || ejecuciones != antes {
+			t.Fatalf("status %d, ejecuciones +%d", r.status, ejecuciones-antes)
+		}
+	})
+
+	t.Run("en curso abandonada se libera", func(t *testing.T) {
+		clave := uuid.NewString()
+		abandonada := models.IdempotenciaClave{UsuarioID: usuario, Clave: clave, Metodo: "POST", Ruta: "/recurso",
+			HashRequest: "x", Estado: models.IdempotenciaEnProceso, ExpiraEn: time.Now().Add(time.Hour),
+			CreatedAt: time.Now().Add(-abandonoIdempotencia - time.Minute)}
+		if err := db.Create(&abandonada).Error; err != nil {
+			t.Fatal(err)
+		}
+		antes := ejecuciones
+		if r := post(clave, `{}`); r.status != fiber.StatusCreated || ejecuciones != antes+1 {
+			t.Fatalf("status %d, ejecuciones +%d", r.status, ejecuciones-antes)
+		}
+	})
+
+	t.Run("vencida se libera", func(t *testing.T) {
+		clave := uuid.NewString()
+		vencida := models.IdempotenciaClave{UsuarioID: usuario, Clave: clave, Metodo: "POST", Ruta: "/recurso",
+			HashRequest: "x", Estado: models.IdempotenciaCompletada, StatusCode: 201, ExpiraEn: time.Now().Add(-time.Minute)}
+		if err := db.Create(&vencida).Error; err != nil {
+			t.Fatal(err)
+		}
+		if r := post(clave, `{"b":1}`); r.status != fiber.StatusCreated || r.replayed {
+			t.Fatalf("vencida: %+v", r)
+		}
+	})
+
+	t.Run("5xx no se guarda", func(t *testing.T) {
+		clave := uuid.NewString()
+		fallar = true
+		if r := post(clave, `{}`); r.status != fiber.StatusInternalServerError {
+			t.Fatalf("status %d", r.status)
+		}
+		fallar = false
+		antes := ejecuciones
+		if r := post(clave, `{}`); r.status != fiber.StatusCreated || r.replayed || ejecuciones != antes+1 {
+			t.Fatalf("reintento: %+v", r)
+		}
+	})
+
+	t.Run("clave demasiado larga", func(t *testing.T) {
+		if r := post(strings.Repeat("k", maxLargoClaveIdempotencia+1), `{}`); r.status != fiber.StatusBadRequest {
+			t.Fatalf("status %d", r.status)
+		}
+	})
+}
@@ -7,14 +7,21 @@ import (
 	"github.com/school-monitoring/backend/internal/api/middleware"
 	"github.com/school-monitoring/backend/internal/auth"
 	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/adjuntos"
+	"github.com/school-monitoring/backend/internal/services/monitor"
 	"github.com/school-monitoring/backend/internal/services/orchestrator"
 	"github.com/school-monitoring/backend/internal/websocket"
 	"gorm.io/gorm"
 )
 
 // NewRouter crea y configura el router principal (Fiber).
-func NewRouter(db *gorm.DB, hub *websocket.Hub) *fiber.App {
-	app := fiber.New()
+func NewRouter(db *gorm.DB, hub *websocket.Hub, orch *orchestrator.Orchestrator, almacen adjuntos.Almacen, estadoMonitor *monitor.Estado) *fiber.App {
+	// El limite de body debe admitir un adjunto del tamano maximo (multipart incluido)
+	bodyLimit := fiber.DefaultBodyLimit
+	if n := int(adjuntos.MaxBytes()) + 1024*1024; n > bodyLimit {
+		bodyLimit = n
+	}
+	app := fiber.New(fiber.Config{BodyLimit: bodyLimit})
 
 	// Middleware global
 	app.Use(middleware.RequestIDMiddleware)
@@ -22,7 +29,6 @@ func NewRouter(db *gorm.DB, hub *websocket.Hub) *fiber.App {
 	app.Use(middleware.JSONMiddleware)
 
 	// Inicializar handlers
-	orch := orchestrator.New(db, hub)
 	authHandler := handlers.NewAuthHandler(db)
 	cursosHandler := handlers.NewCursosHandler(db)
 	asistenciaHandler := handlers.NewAsistenciaHandler(db, orch)
@@ -37,9 +43,20 @@ func NewRouter(db *gorm.DB, hub *websocket.Hub) *fiber.App {
 	bloquesHandler := handlers.NewBloquesHandler(db)
 	horariosHandler := handlers.NewHorariosHandler(db)
 	importHandler := handlers.NewImportHandler(db)
-	trazabilidadHandler := handlers.NewTrazabilidadHandler(db)
-	monitorHandler := handlers.NewMonitorHandler(db)
+	trazabilidadHandler := handlers.NewTrazabilidadHandler(db, orch)
+	monitorHandler := handlers.NewMonitorHandler(db, estadoMonitor)
 	alertasHandler := handlers.NewAlertasHandler(db)
+	casosHandler := handlers.NewCasosHandler(db)
+	alumnosHandler := handlers.NewAlumnosHandler(db)
+	justificacionesHandler := handlers.NewJustificacionesHandler(db, orch)
+	asistenciaDiariaHandler := handlers.NewAsistenciaDiariaHandler(db)
+	reportesHandler := handlers.NewReportesHandler(db)
+	establecimientoHandler := handlers.NewEstablecimientoHandler(db)
+	riesgoHandler := handlers.NewRiesgoHandler(db)
+	aniosHandler := handlers.NewAniosEscolaresHandler(db)
+	calendarioHandler := handlers.NewCalendarioHandler(db)
+	syncHandler := handlers.NewSyncHandler(db, orch)
+	adjuntosHandler := handlers.NewAdjuntosHandler(db, almacen)
 
 	// API v1
 	api := app.Group("/api/v1")
@@ -52,6 +69,9 @@ func NewRouter(db *gorm.DB, hub *websocket.Hub) *fiber.App {
 	// Rutas protegidas
 	protected := api.Group("", middleware.AuthMiddleware)
 
+	// Idempotency-Key en los endpoints que la app reintenta (doble toque, timeouts)
+	idem := middleware.IdempotencyMiddleware(db)
+
 	// Auth
 	protected.Post("/auth/logout", authHandler.Logout)
 	protected.Post("/auth/refresh", authHandler.RefreshToken)
@@ -73,23 +93,62 @@ func NewRouter(db *gorm.DB, hub *websocket.Hub) *fiber.App {
 	catalogos := protected.Group("", middleware.PermissionMiddleware(auth.PermisoVerCursos))
 	catalogos.Get("/asignaturas", asignaturasHandler.GetAll)
 	catalogos.Get("/bloques", bloquesHandler.GetAll)
+	catalogos.Get("/establecimiento", establecimientoHandler.Get)
 
 	// Asistencia
 	asistenciaRoutes := protected.Group("/asistencia", middleware.PermissionMiddleware(auth.PermisoRegistrarAsistencia, auth.PermisoVerAsistencia))
-	asistenciaRoutes.Post("/bloque", asistenciaHandler.RegistrarBloque)
+	asistenciaRoutes.Post("/bloque", idem, asistenciaHandler.RegistrarBloque)
 	asistenciaRoutes.Get("/curso/:id/fecha/:fecha", asistenciaHandler.GetByCursoFecha)
+	asistenciaRoutes.Get("/pendientes", middleware.PermissionMiddleware(auth.PermisoVerAsistencia, auth.PermisoVerMonitor), asistenciaHandler.GetPendientes)
 	asistenciaRoutes.Get("/horario/:id/fecha/:fecha", asistenciaHandler.GetByHorarioFecha)
 
+	// Correcciones de bloques con el registro bloqueado: las solicita el profesor, las revisa inspectoria
+	revisarCorreccion := middleware.PermissionMiddleware(auth.PermisoJustificarAsistencia)
+	asistenciaRoutes.Get("/correcciones", asistenciaHandler.GetCorrecciones)
+	asistenciaRoutes.Get("/correcciones/:id", asistenciaHandler.GetCorreccion)
+	asistenciaRoutes.Post("/correcciones", middleware.PermissionMiddleware(auth.PermisoRegistrarAsistencia), idem, asistenciaHandler.CreateCorreccion)
+	asistenciaRoutes.Put("/correcciones/:id/aprobar", revisarCorreccion, asistenciaHandler.AprobarCorreccion)
+	asistenciaRoutes.Put("/correcciones/:id/rechazar", revisarCorreccion, asistenciaHandler.RechazarCorreccion)
+
+	// Asistencia diaria consolidada (oficial); recalculo y correcciones para inspectoria
+	diaria := protected.Group("/asistencia-diaria", middleware.PermissionMiddleware(auth.PermisoVerAsistencia))
+	corregirDiaria := middleware.PermissionMiddleware(auth.PermisoJustificarAsistencia)
+	diaria.Get("", asistenciaDiariaHandler.GetAll)
+	diaria.Post("/recalcular", corregirDiaria, asistenciaDiariaHandler.Recalcular)
+	diaria.Put("/:id", corregirDiaria, asistenciaDiariaHandler.Corregir)
+
+	// Justificaciones de inasistencia: las registra el personal (propias o del apoderado), las revisa inspectoria
+	justif := protected.Group("/justificaciones", middleware.PermissionMiddleware(auth.PermisoVerAsistencia, auth.PermisoJustificarAsistencia))
+	revisarJustif := middleware.PermissionMiddleware(auth.PermisoJustificarAsistencia)
+	justif.Get("", justificacionesHandler.GetAll)
+	justif.Get("/:id", justificacionesHandler.GetByID)
+	justif.Post("", justificacionesHandler.Create)
+	justif.Get("/:id/adjuntos", adjuntosHandler.Listar(models.AdjuntoJustificacion))
+	justif.Post("/:id/adjuntos", idem, adjuntosHandler.Subir(models.AdjuntoJustificacion))
+	justif.Get("/:id/adjuntos/:adjuntoId", adjuntosHandler.Descargar(models.AdjuntoJustificacion))
+	justif.Delete("/:id/adjuntos/:adjuntoId", adjuntosHandler.Eliminar(models.AdjuntoJustificacion))
+	justif.Put("/:id/aprobar", revisarJustif, justificacionesHandler.Aprobar)
+	justif.Put("/:id/rechazar", revisarJustif, justificacionesHandler.Rechazar)
+
 	// Estados temporales de alumnos
 	estTemp := protected.Group("", middleware.PermissionMiddleware(auth.PermisoRegistrarAsistencia, auth.PermisoCrearEventos, auth.PermisoVerAsistencia))
-	estTemp.Put("/alumnos/:id/estado-temporal", asistenciaHandler.SetEstadoTemporal)
-	estTemp.Delete("/alumnos/:id/estado-temporal", asistenciaHandler.ClearEstadoTemporal)
+	estTemp.Put("/alumnos/:id/estado-temporal", idem, asistenciaHandler.SetEstadoTemporal)
+	estTemp.Delete("/alumnos/:id/estado-temporal", idem, asistenciaHandler.ClearEstadoTemporal)
 	estTemp.Get("/estados-temporales", asistenciaHandler.GetEstadosTemporalesActivos)
 
+	// Hoja de vida del alumno (detalle sensible redactado segun permisos). Alta y cambio de curso
+	// mantienen la matricula del ano activo.
+	alumnosRoutes := protected.Group("/alumnos", middleware.PermissionMiddleware(auth.PermisoVerAlumnos))
+	alumnosRoutes.Get("/:id/timeline", alumnosHandler.Timeline)
+	gestionAlumnos := middleware.PermissionMiddleware(auth.PermisoAdministrar, auth.PermisoImportarDatos)
+	alumnosRoutes.Post("", gestionAlumnos, idem, alumnosHandler.Create)
+	alumnosRoutes.Put("/:id", gestionAlumnos, alumnosHandler.Update)
+
 	// Conceptos (backoffice)
 	conceptosRoutes := protected.Group("/conceptos")
 	conceptosRoutes.Get("", conceptosHandler.GetAll)
 	conceptosRoutes.Get("/:id", conceptosHandler.GetByID)
+	conceptosRoutes.Get("/:id/datos-schema", conceptosHandler.GetDatosSchema)
 
 	conceptosAdmin := conceptosRoutes.Group("", middleware.RoleMiddleware(models.RolAdmin, models.RolBackoffice))
 	conceptosAdmin.Post("", conceptosHandler.Create)
@@ -121,22 +180,92 @@ func NewRouter(db *gorm.DB, hub *websocket.Hub) *fiber.App {
 	eventosRoutes.Get("", eventosHandler.GetAll)
 	eventosRoutes.Get("/activos", eventosHandler.GetActivos)
 	eventosRoutes.Get("/alumno/:id", eventosHandler.GetByAlumno)
-	eventosRoutes.Post("", eventosHandler.Create)
+	eventosRoutes.Post("", idem, eventosHandler.Create)
+	eventosRoutes.Post("/masivo", middleware.PermissionMiddleware(auth.PermisoCrearEventos), idem, eventosHandler.CreateMasivo)
 	eventosRoutes.Put("/:id/cerrar", eventosHandler.Cerrar)
+	eventosRoutes.Put("/:id/reabrir", middleware.PermissionMiddleware(auth.PermisoCerrarEventos), eventosHandler.Reabrir)
+	eventosRoutes.Get("/:id/notas", eventosHandler.GetNotas)
+	eventosRoutes.Post("/:id/notas", eventosHandler.Anotar)
+	// Evidencia (fotos, documentos, declaraciones firmadas)
+	eventosRoutes.Get("/:id/adjuntos", adjuntosHandler.Listar(models.AdjuntoEvento))
+	eventosRoutes.Post("/:id/adjuntos", middleware.PermissionMiddleware(auth.PermisoCrearEventos, auth.PermisoCerrarEventos), idem, adjuntosHandler.Subir(models.AdjuntoEvento))
+	eventosRoutes.Get("/:id/adjuntos/:adjuntoId", adjuntosHandler.Descargar(models.AdjuntoEvento))
+	eventosRoutes.Delete("/:id/adjuntos/:adjuntoId", adjuntosHandler.Eliminar(models.AdjuntoEvento))
 
 	// Dashboard
 	dash := protected.Group("", middleware.PermissionMiddleware(auth.PermisoVerReportes, auth.PermisoVerEventos))
 	dash.Get("/dashboard", dashboardHandler.Get)
 
-	// Monitor snapshot (inspectoría/admin/backoffice)
-	monitor := protected.Group("/monitor", middleware.PermissionMiddleware(auth.PermisoVerMonitor))
-	monitor.Get("/snapshot", monitorHandler.Snapshot)
+	// Monitor snapshot y estado por alumno (inspectoría/admin/backoffice)
+	monitorRoutes := protected.Group("/monitor", middleware.PermissionMiddleware(auth.PermisoVerMonitor))
+	monitorRoutes.Get("/snapshot", monitorHandler.Snapshot)
+	monitorRoutes.Get("/alumnos", monitorHandler.Alumnos)
 
 	// Alertas operativas (inspectoría/admin/backoffice)
 	alertas := protected.Group("/alertas", middleware.PermissionMiddleware(auth.PermisoVerAlertas, auth.PermisoCerrarAlertas))
 	alertas.Get("", alertasHandler.GetAll)
 	alertas.Put("/:id/cerrar", alertasHandler.Cerrar)
 
+	// Casos (asistente social/convivencia). Lectura: ver_casos. Escritura y adjuntos confidenciales: gestionar_casos.
+	casos := protected.Group("/casos", middleware.PermissionMiddleware(auth.PermisoVerCasos, auth.PermisoGestionarCasos))
+	gestionCasos := middleware.PermissionMiddleware(auth.PermisoGestionarCasos)
+	casos.Get("", casosHandler.GetAll)
+	casos.Get("/tareas", casosHandler.GetTareasPendientes)
+	casos.Get("/:id", casosHandler.GetByID)
+	casos.Post("", gestionCasos, casosHandler.Create)
+	casos.Put("/:id", gestionCasos, casosHandler.Update)
+	casos.Post("/:id/notas", gestionCasos, casosHandler.AddNota)
+	casos.Post("/:id/tareas", gestionCasos, casosHandler.AddTarea)
+	casos.Put("/:id/tareas/:tareaId", gestionCasos, casosHandler.UpdateTarea)
+	casos.Get("/:id/adjuntos", gestionCasos, adjuntosHandler.Listar(models.AdjuntoCaso))
+	casos.Post("/:id/adjuntos", gestionCasos, idem, adjuntosHandler.Subir(models.AdjuntoCaso))
+	casos.Get("/:id/adjuntos/:adjuntoId", gestionCasos, adjuntosHandler.Descargar(models.AdjuntoCaso))
+	casos.Delete("/:id/adjuntos/:adjuntoId", gestionCasos, adjuntosHandler.Eliminar(models.AdjuntoCaso))
+
+	// Riesgo / alerta temprana (ausentismo cronico): datos sensibles, solo equipos de apoyo y gestion
+	riesgoRoutes := protected.Group("/riesgo", middleware.PermissionMiddleware(auth.PermisoVerCasos, auth.PermisoVerReportes))
+	riesgoRoutes.Get("", riesgoHandler.Ranking)
+	riesgoRoutes.Get("/alumnos/:id", riesgoHandler.GetByAlumno)
+	riesgoRoutes.Get("/configuracion", riesgoHandler.GetConfiguracion)
+	riesgoRoutes.Post("/recalcular", middleware.PermissionMiddleware(auth.PermisoGestionarCasos, auth.PermisoAdministrar), riesgoHandler.Recalcular)
+	riesgoRoutes.Put("/configuracion", middleware.PermissionMiddleware(auth.PermisoAdministrar), riesgoHandler.UpdateConfiguracion)
+
+	// Reportes y exportaciones oficiales (Mineduc)
+	reportes := protected.Group("/reportes", middleware.PermissionMiddleware(auth.PermisoVerReportes))
+	reportes.Get("/asistencia-mensual", reportesHandler.AsistenciaMensual)
+	reportes.Get("/pdf/cursos/:id/asistencia", reportesHandler.PDFAsistenciaCurso)
+	reportes.Get("/pdf/alumnos/:id/certificado", reportesHandler.PDFCertificadoAsistencia)
+	reportes.Get("/pdf/alumnos/:id/historial", reportesHandler.PDFHistorialAlumno)
+	reportes.Get("/pdf/alertas", reportesHandler.PDFResolucionAlertas)
+	reportes.Get("/series/:metrica", reportesHandler.Serie)
+	reportes.Post("/agregados/refrescar", middleware.PermissionMiddleware(auth.PermisoAdministrar), reportesHandler.RefrescarAgregados)
+
+	// Anos escolares y periodos; cierre de ano (promocion + archivo de horario) solo administracion
+	anios := protected.Group("/anios-escolares", middleware.PermissionMiddleware(auth.PermisoVerCursos))
+	gestionAnios := middleware.PermissionMiddleware(auth.PermisoGestionarHorarios, auth.PermisoAdministrar)
+	anios.Get("", aniosHandler.GetAll)
+	anios.Get("/actual", aniosHandler.GetActual)
+	anios.Get("/:id", aniosHandler.GetByID)
+	anios.Get("/:id/matriculas", middleware.PermissionMiddleware(auth.PermisoVerAlumnos), aniosHandler.GetMatriculas)
+	anios.Post("", gestionAnios, aniosHandler.Create)
+	anios.Put("/:id/activar", gestionAnios, aniosHandler.Activar)
+	anios.Post("/:id/periodos", gestionAnios, aniosHandler.CreatePeriodo)
+	anios.Delete("/:id/periodos/:periodoId", gestionAnios, aniosHandler.DeletePeriodo)
+	anios.Post("/:id/rollover", middleware.PermissionMiddleware(auth.PermisoAdministrar), aniosHandler.Rollover)
+
+	// Calendario escolar (feriados, suspensiones, vacaciones, jornadas especiales) + iCal
+	cal := protected.Group("/calendario", middleware.PermissionMiddleware(auth.PermisoVerCursos))
+	cal.Get("", calendarioHandler.GetAll)
+	cal.Get("/dias", calendarioHandler.GetDias)
+	cal.Get("/ical", calendarioHandler.ExportICal)
+	cal.Post("", gestionAnios, calendarioHandler.Create)
+	cal.Post("/import/ical", gestionAnios, idem, calendarioHandler.ImportICal)
+	cal.Put("/:id", gestionAnios, calendarioHandler.Update)
+	cal.Delete("/:id", gestionAnios, calendarioHandler.Delete)
+
+	// Sincronizacion offline de la app movil (asistencia y eventos capturados sin conexion + delta)
+	protected.Post("/sync", middleware.PermissionMiddleware(auth.PermisoRegistrarAsistencia, auth.PermisoCrearEventos, auth.PermisoCerrarEventos), syncHandler.Sync)
+
 	// Admin (usuarios + horarios)
 	admin := protected.Group("", middleware.PermissionMiddleware(auth.PermisoAdministrar, auth.PermisoGestionarUsuarios, auth.PermisoGestionarHorarios, auth.PermisoImportarDatos, auth.PermisoVerAuditoria))
 
@@ -148,13 +277,21 @@ func NewRouter(db *gorm.DB, hub *websocket.Hub) *fiber.App {
 	admin.Get("/horarios", horariosHandler.GetAll)
 	admin.Post("/horarios", horariosHandler.Upsert)
 	admin.Delete("/horarios/:id", horariosHandler.Delete)
+	admin.Get("/horarios/excepciones", horariosHandler.GetExcepciones)
+	admin.Post("/horarios/:id/excepciones", middleware.PermissionMiddleware(auth.PermisoGestionarHorarios, auth.PermisoAdministrar), horariosHandler.UpsertExcepcion)
+	admin.Delete("/horarios/excepciones/:id", middleware.PermissionMiddleware(auth.PermisoGestionarHorarios, auth.PermisoAdministrar), horariosHandler.DeleteExcepcion)
+
+	// Datos institucionales (encabezado de reportes)
+	admin.Put("/establecimiento", middleware.PermissionMiddleware(auth.PermisoAdministrar), establecimientoHandler.Update)
 
 	// Importaciones
-	admin.Post("/import/horarios", importHandler.ImportHorariosCSV)
+	admin.Post("/import/horarios", idem, importHandler.ImportHorariosCSV)
 
 	// Trazabilidad
 	admin.Get("/auditorias", trazabilidadHandler.Auditorias)
 	admin.Get("/acciones-ejecuciones", trazabilidadHandler.AccionesEjecuciones)
+	admin.Get("/acciones-ejecuciones/fallidas", trazabilidadHandler.EjecucionesFallidas)
+	admin.Post("/acciones-ejecuciones/:id/reintentar", middleware.RoleMiddleware(models.RolAdmin, models.RolBackoffice), trazabilidadHandler.ReintentarEjecucion)
 
 	// WebSocket
 	app.Get("/ws", fiberws.New(func(conn *fiberws.Conn) {
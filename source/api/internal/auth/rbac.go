@@ -10,6 +10,7 @@ const (
 	PermisoVerAlumnos         = "ver_alumnos"
 	PermisoRegistrarAsistencia = "registrar_asistencia"
 	PermisoVerAsistencia      = "ver_asistencia"
+	PermisoJustificarAsistencia = "justificar_asistencia"
 	PermisoCrearEventos       = "crear_eventos"
 	PermisoVerEventos         = "ver_eventos"
 	PermisoCerrarEventos      = "cerrar_eventos"
@@ -36,6 +37,7 @@ var permisosPorRol = map[string][]string{
 		PermisoVerAlumnos,
 		PermisoRegistrarAsistencia,
 		PermisoVerAsistencia,
+		PermisoJustificarAsistencia,
 		PermisoCrearEventos,
 		PermisoVerEventos,
 		PermisoCerrarEventos,
@@ -66,6 +68,7 @@ var permisosPorRol = map[string][]string{
 		PermisoVerCursos,
 		PermisoVerAlumnos,
 		PermisoVerAsistencia,
+		PermisoJustificarAsistencia,
 		PermisoVerEventos,
 		PermisoCerrarEventos,
 		PermisoVerAlertas,
@@ -96,17 +96,39 @@ func Initialize() (*gorm.DB, error) {
 	if os.Getenv("DB_RESET") == "true" {
 		log.Println("DB_RESET=true: Dropping all tables...")
 		DB.Exec("DROP TABLE IF EXISTS auditorias CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS eventos_outbox CASCADE")
 		DB.Exec("DROP TABLE IF EXISTS notification_outboxes CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS asistencias_diarias CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS establecimiento CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS agregados_diarios CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS riesgos_alumnos CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS configuracion_riesgo CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS adjuntos CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS idempotencia_claves CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS sync_mutaciones CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS correcciones_asistencia CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS justificacions CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS caso_tareas CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS caso_notas CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS caso_alertas CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS caso_eventos CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS casos CASCADE")
 		DB.Exec("DROP TABLE IF EXISTS alertas CASCADE")
 		DB.Exec("DROP TABLE IF EXISTS horarios_asistencia_estado CASCADE")
 		DB.Exec("DROP TABLE IF EXISTS cursos_estado CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS evento_notas CASCADE")
 		DB.Exec("DROP TABLE IF EXISTS eventos CASCADE")
 		DB.Exec("DROP TABLE IF EXISTS reglas CASCADE")
 		DB.Exec("DROP TABLE IF EXISTS acciones CASCADE")
 		DB.Exec("DROP TABLE IF EXISTS conceptos CASCADE")
 		DB.Exec("DROP TABLE IF EXISTS estados_temporales CASCADE")
 		DB.Exec("DROP TABLE IF EXISTS asistencias CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS horario_excepciones CASCADE")
 		DB.Exec("DROP TABLE IF EXISTS horarios CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS matriculas CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS calendario_entradas CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS periodos CASCADE")
+		DB.Exec("DROP TABLE IF EXISTS anios_escolares CASCADE")
 		DB.Exec("DROP TABLE IF EXISTS bloque_horarios CASCADE")
 		DB.Exec("DROP TABLE IF EXISTS asignaturas CASCADE")
 		DB.Exec("DROP TABLE IF EXISTS alumnos CASCADE")
@@ -123,28 +145,8 @@ func Initialize() (*gorm.DB, error) {
 	}
 
 	if autoMigrate {
-		err = DB.AutoMigrate(
-			&models.Usuario{},
-			&models.Curso{},
-			&models.CursoEstado{},
-			&models.Alumno{},
-			&models.Asignatura{},
-			&models.BloqueHorario{},
-			&models.Horario{},
-			&models.Asistencia{},
-			&models.HorarioAsistenciaEstado{},
-			&models.EstadoTemporal{},
-			&models.Concepto{},
-			&models.Accion{},
-			&models.Regla{},
-			&models.Evento{},
-			&models.AccionEjecucion{},
-			&models.Alerta{},
-			&models.NotificationOutbox{},
-			&models.Auditoria{},
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		if err := Migrar(DB); err != nil {
+			return nil, err
 		}
 		log.Println("Database migration completed")
 	} else {
@@ -154,6 +156,61 @@ func Initialize() (*gorm.DB, error) {
 	return DB, nil
 }
 
+// Migrar crea/actualiza el esquema (AutoMigrate) y completa datos derivados de columnas nuevas
+func Migrar(db *gorm.DB) error {
+	err := db.AutoMigrate(
+		&models.Usuario{},
+		&models.Curso{},
+		&models.CursoEstado{},
+		&models.Alumno{},
+		&models.AnioEscolar{},
+		&models.Periodo{},
+		&models.Matricula{},
+		&models.CalendarioEntrada{},
+		&models.Asignatura{},
+		&models.BloqueHorario{},
+		&models.Horario{},
+		&models.HorarioExcepcion{},
+		&models.Asistencia{},
+		&models.HorarioAsistenciaEstado{},
+		&models.EstadoTemporal{},
+		&models.AsistenciaDiaria{},
+		&models.Establecimiento{},
+		&models.AgregadoDiario{},
+		&models.ConfiguracionRiesgo{},
+		&models.RiesgoAlumno{},
+		&models.Justificacion{},
+		&models.CorreccionAsistencia{},
+		&models.SyncMutacion{},
+		&models.IdempotenciaClave{},
+		&models.Adjunto{},
+		&models.Concepto{},
+		&models.Accion{},
+		&models.Regla{},
+		&models.Evento{},
+		&models.EventoNota{},
+		&models.AccionEjecucion{},
+		&models.Alerta{},
+		&models.Caso{},
+		&models.CasoNota{},
+		&models.CasoTarea{},
+		&models.NotificationOutbox{},
+		&models.EventoOutbox{},
+		&models.Auditoria{},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+	if err := db.Exec(models.IndiceHorarioUnico).Error; err != nil {
+		log.Printf("Warning: could not create unique schedule index: %v", err)
+	}
+	// Severidad/color/categoria de conceptos creados antes de que existieran esas columnas
+	if err := models.ClasificarConceptos(db); err != nil {
+		log.Printf("Warning: could not classify concepts: %v", err)
+	}
+	return nil
+}
+
 // GetDB retorna la instancia de la base de datos
 func GetDB() *gorm.DB {
 	return DB
@@ -8,9 +8,17 @@ import (
 	"gorm.io/gorm"
 )
 
+// Resultados de ejecucion
+const (
+	EjecucionResultadoOK    = "ok"
+	EjecucionResultadoError = "error"
+)
+
 // AccionEjecucion registra una accion ejecutada por una regla (trazabilidad y deduplicacion)
 type AccionEjecucion struct {
 	ID        uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	// Clave de idempotencia (regla:accion:evento). Nullable para filas anteriores al bus de eventos.
+	Clave     *string        `gorm:"uniqueIndex" json:"clave,omitempty"`
 	ReglaID   uuid.UUID      `gorm:"type:uuid;not null;index" json:"regla_id"`
 	Regla     *Regla         `gorm:"foreignKey:ReglaID" json:"regla,omitempty"`
 	AccionID  uuid.UUID      `gorm:"type:uuid;not null;index" json:"accion_id"`
@@ -25,6 +33,10 @@ type AccionEjecucion struct {
 	ScopeKey    string     `gorm:"index" json:"scope_key,omitempty"`       // ej: "alumno:<uuid>" o "curso:<uuid>"
 	VentanaInicio *time.Time `json:"ventana_inicio,omitempty"`
 	VentanaFin    *time.Time `json:"ventana_fin,omitempty"`
+	// Reintentos de side effects fallidos (cola de reintentos)
+	Intentos           int        `gorm:"not null;default:0" json:"intentos"`
+	UltimoError        string     `gorm:"type:text" json:"ultimo_error,omitempty"`
+	SiguienteIntentoEn *time.Time `gorm:"index" json:"siguiente_intento_en,omitempty"`
 	EjecutadoEn time.Time    `gorm:"not null" json:"ejecutado_en"`
 	CreatedAt time.Time      `json:"created_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
@@ -40,4 +52,19 @@ func (a *AccionEjecucion) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// MarcarError registra un intento fallido. El siguiente intento lo agenda quien reintenta.
+func (a *AccionEjecucion) MarcarError(err error) {
+	a.Resultado = EjecucionResultadoError
+	a.Intentos++
+	a.UltimoError = err.Error()
+	a.SiguienteIntentoEn = nil
+}
 
+// MarcarOK registra un intento exitoso (cierra la cola de reintentos).
+func (a *AccionEjecucion) MarcarOK() {
+	a.Resultado = EjecucionResultadoOK
+	a.Intentos++
+	a.UltimoError = ""
+	a.SiguienteIntentoEn = nil
+	a.EjecutadoEn = time.Now()
+}
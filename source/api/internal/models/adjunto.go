@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Entidades que pueden tener adjuntos (EntidadType)
+const (
+	AdjuntoEvento        = "evento"
+	AdjuntoCaso          = "caso"
+	AdjuntoJustificacion = "justificacion"
+)
+
+// Adjunto es la metadata de un archivo (foto, documento, declaracion firmada) ligado a un evento,
+// caso o justificacion. El contenido vive en el almacen configurado (Almacen + Clave), no en la DB.
+type Adjunto struct {
+	ID          uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	EntidadID   uuid.UUID      `gorm:"type:uuid;not null;index:idx_adjuntos_entidad" json:"entidad_id"`
+	EntidadType string         `gorm:"not null;index:idx_adjuntos_entidad" json:"entidad"` // evento, caso, justificacion
+	Nombre      string         `gorm:"not null" json:"nombre"`
+	MimeType    string         `gorm:"not null" json:"mime_type"`
+	Tamano      int64          `gorm:"not null" json:"tamano"`
+	Sha256      string         `gorm:"not null" json:"sha256"`
+	Almacen     string         `gorm:"not null" json:"-"` // local, s3
+	Clave       string         `gorm:"not null" json:"-"` // ruta/objeto dentro del almacen
+	SubidoPor   uuid.UUID      `gorm:"type:uuid;not null" json:"subido_por"`
+	CreatedAt   time.Time      `json:"created_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// BeforeCreate genera UUID antes de crear
+func (a *Adjunto) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Metricas agregadas para analitica
+const (
+	MetricaEventos       = "eventos"
+	MetricaInasistencias = "inasistencias"
+	MetricaAtrasos       = "atrasos"
+	MetricaAlertas       = "alertas"
+	MetricaSLAAlertas    = "sla_alertas" // alertas cerradas; SumaMinutos = tiempo total de resolucion
+)
+
+// AgregadoDiario es un conteo pre-calculado por dia y dimensiones (agregado materializado).
+// Se regenera por rango de fechas; las consultas de series solo leen esta tabla.
+type AgregadoDiario struct {
+	ID      uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Fecha   time.Time `gorm:"type:date;not null;index:idx_agregados_metrica_fecha,priority:2" json:"fecha"`
+	Metrica string    `gorm:"not null;index:idx_agregados_metrica_fecha,priority:1" json:"metrica"`
+
+	CursoID      *uuid.UUID `gorm:"type:uuid" json:"curso_id,omitempty"`
+	Nivel        string     `json:"nivel,omitempty"`
+	ConceptoID   *uuid.UUID `gorm:"type:uuid" json:"concepto_id,omitempty"`
+	AsignaturaID *uuid.UUID `gorm:"type:uuid" json:"asignatura_id,omitempty"`
+	ProfesorID   *uuid.UUID `gorm:"type:uuid" json:"profesor_id,omitempty"`
+	BloqueID     *uuid.UUID `gorm:"type:uuid" json:"bloque_id,omitempty"`
+
+	Cantidad    int64   `gorm:"not null;default:0" json:"cantidad"`
+	SumaMinutos float64 `gorm:"not null;default:0" json:"suma_minutos"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName nombre de tabla
+func (AgregadoDiario) TableName() string {
+	return "agregados_diarios"
+}
+
+// EsMetricaValida indica si la metrica es soportada
+func EsMetricaValida(m string) bool {
+	switch m {
+	case MetricaEventos, MetricaInasistencias, MetricaAtrasos, MetricaAlertas, MetricaSLAAlertas:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Tipos de periodo
+const (
+	PeriodoSemestre  = "semestre"
+	PeriodoTrimestre = "trimestre"
+)
+
+// Estados de matricula
+const (
+	MatriculaActiva    = "activa"
+	MatriculaPromovida = "promovida"
+	MatriculaRepitente = "repitente"
+	MatriculaEgresada  = "egresada"
+	MatriculaRetirada  = "retirada"
+)
+
+// AnioEscolar ano lectivo; solo uno activo a la vez
+type AnioEscolar struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Anio        int        `gorm:"uniqueIndex;not null" json:"anio"`
+	FechaInicio time.Time  `gorm:"type:date;not null" json:"fecha_inicio"`
+	FechaFin    time.Time  `gorm:"type:date;not null" json:"fecha_fin"`
+	Activo      bool       `gorm:"default:false;index" json:"activo"`
+	CerradoEn   *time.Time `json:"cerrado_en,omitempty"`
+	CerradoPor  *uuid.UUID `gorm:"type:uuid" json:"cerrado_por,omitempty"`
+	Periodos    []Periodo  `gorm:"foreignKey:AnioEscolarID" json:"periodos,omitempty"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName nombre de tabla
+func (AnioEscolar) TableName() string {
+	return "anios_escolares"
+}
+
+// BeforeCreate genera UUID antes de crear
+func (a *AnioEscolar) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// Periodo semestre/trimestre dentro de un ano escolar
+type Periodo struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	AnioEscolarID uuid.UUID `gorm:"type:uuid;not null;index" json:"anio_escolar_id"`
+	Nombre        string    `gorm:"not null" json:"nombre"` // "1er Semestre"
+	Tipo          string    `gorm:"not null" json:"tipo"`   // semestre, trimestre
+	Numero        int       `gorm:"not null" json:"numero"`
+	FechaInicio   time.Time `gorm:"type:date;not null" json:"fecha_inicio"`
+	FechaFin      time.Time `gorm:"type:date;not null" json:"fecha_fin"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName nombre de tabla
+func (Periodo) TableName() string {
+	return "periodos"
+}
+
+// BeforeCreate genera UUID antes de crear
+func (p *Periodo) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// Matricula inscripcion de un alumno en un curso para un ano escolar.
+// Alumno.CursoID sigue siendo el curso vigente; la matricula conserva la historia.
+type Matricula struct {
+	ID            uuid.UUID    `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	AlumnoID      uuid.UUID    `gorm:"type:uuid;not null;uniqueIndex:idx_matricula_alumno_anio" json:"alumno_id"`
+	Alumno        *Alumno      `gorm:"foreignKey:AlumnoID" json:"alumno,omitempty"`
+	AnioEscolarID uuid.UUID    `gorm:"type:uuid;not null;uniqueIndex:idx_matricula_alumno_anio;index" json:"anio_escolar_id"`
+	AnioEscolar   *AnioEscolar `gorm:"foreignKey:AnioEscolarID" json:"anio_escolar,omitempty"`
+	CursoID       uuid.UUID    `gorm:"type:uuid;not null;index" json:"curso_id"`
+	Curso         *Curso       `gorm:"foreignKey:CursoID" json:"curso,omitempty"`
+	Estado        string       `gorm:"not null;default:'activa'" json:"estado"` // activa, promovida, repitente, egresada, retirada
+	FechaAlta     time.Time    `gorm:"type:date;not null" json:"fecha_alta"`
+	FechaBaja     *time.Time   `gorm:"type:date" json:"fecha_baja,omitempty"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName nombre de tabla
+func (Matricula) TableName() string {
+	return "matriculas"
+}
+
+// BeforeCreate genera UUID antes de crear
+func (m *Matricula) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	if m.Estado == "" {
+		m.Estado = MatriculaActiva
+	}
+	return nil
+}
+
+// AnioEscolarActivo retorna el ano escolar activo (nil si no hay ninguno configurado)
+func AnioEscolarActivo(db *gorm.DB) *AnioEscolar {
+	var a AnioEscolar
+	if err := db.Where("activo = ?", true).Order("anio DESC").First(&a).Error; err != nil {
+		return nil
+	}
+	return &a
+}
+
+// IDAnioEscolarActivo retorna el id del ano activo o nil (para asignar en horarios/matriculas)
+func IDAnioEscolarActivo(db *gorm.DB) *uuid.UUID {
+	if a := AnioEscolarActivo(db); a != nil {
+		return &a.ID
+	}
+	return nil
+}
+
+// Matricular inscribe al alumno en el curso para el ano activo; si ya tenia matricula ese ano la mueve al curso
+// (vuelve a quedar activa). Sin ano activo no hace nada: el cierre de ano matricula a quienes no la tengan.
+func Matricular(db *gorm.DB, alumnoID, cursoID uuid.UUID, fecha time.Time) error {
+	anio := AnioEscolarActivo(db)
+	if anio == nil {
+		return nil
+	}
+	m := Matricula{AlumnoID: alumnoID, AnioEscolarID: anio.ID, CursoID: cursoID, Estado: MatriculaActiva, FechaAlta: fecha}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "alumno_id"}, {Name: "anio_escolar_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"curso_id", "estado", "fecha_baja", "updated_at", "deleted_at"}),
+	}).Create(&m).Error
+}
+
+// PeriodosPorDefecto divide el ano en n periodos (2 semestres o 3 trimestres) de igual duracion
+func PeriodosPorDefecto(anio AnioEscolar, tipo string) []Periodo {
+	n, nombre := 2, "Semestre"
+	if tipo == PeriodoTrimestre {
+		n, nombre = 3, "Trimestre"
+	} else {
+		tipo = PeriodoSemestre
+	}
+	ordinal := []string{"1er", "2do", "3er"}
+	dias := int(anio.FechaFin.Sub(anio.FechaInicio).Hours()/24) + 1
+	out := make([]Periodo, 0, n)
+	inicio := anio.FechaInicio
+	for i := 0; i < n; i++ {
+		fin := anio.FechaInicio.AddDate(0, 0, dias*(i+1)/n-1)
+		if i == n-1 {
+			fin = anio.FechaFin
+		}
+		out = append(out, Periodo{
+			AnioEscolarID: anio.ID,
+			Nombre:        ordinal[i] + " " + nombre,
+			Tipo:          tipo,
+			Numero:        i + 1,
+			FechaInicio:   inicio,
+			FechaFin:      fin,
+		})
+		inicio = fin.AddDate(0, 0, 1)
+	}
+	return out
+}
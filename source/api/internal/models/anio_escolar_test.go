@@ -0,0 +1,41 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/testutil"
+)
+
+// El alta crea la matricula del ano activo y un cambio de curso la mueve (una por alumno y ano)
+func TestMatricular(t *testing.T) {
+	db := testutil.DB(t)
+	anio := models.AnioEscolar{Anio: 2999, FechaInicio: time.Date(2999, 3, 1, 0, 0, 0, 0, time.UTC), FechaFin: time.Date(2999, 12, 20, 0, 0, 0, 0, time.UTC), Activo: true}
+	c1 := models.Curso{Nombre: "1 Basico T", Nivel: models.NivelBasica}
+	c2 := models.Curso{Nombre: "2 Basico T", Nivel: models.NivelBasica}
+	for _, v := range []interface{}{&anio, &c1, &c2} {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	alumno := models.Alumno{CursoID: c1.ID, Nombre: "Test", Apellido: "Test", Rut: "T-" + uuid.NewString()[:8], Activo: true}
+	if err := db.Create(&alumno).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := models.Matricular(db, alumno.ID, c1.ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Matricular(db, alumno.ID, c2.ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	var mats []models.Matricula
+	if err := db.Where("alumno_id = ?", alumno.ID).Find(&mats).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(mats) != 1 || mats[0].CursoID != c2.ID || mats[0].AnioEscolarID != anio.ID || mats[0].Estado != models.MatriculaActiva {
+		t.Fatalf("matriculas: %+v", mats)
+	}
+}
@@ -12,6 +12,8 @@ const (
 	EstadoPresente    = "presente"
 	EstadoAusente     = "ausente"
 	EstadoJustificado = "justificado"
+	EstadoAtraso      = "atraso" // llego tarde (presente desde HoraLlegada)
+	EstadoRetiro      = "retiro" // retirado antes del termino de la jornada
 )
 
 // Tipos de estado temporal
@@ -29,8 +31,15 @@ type Asistencia struct {
 	HorarioID     uuid.UUID      `gorm:"type:uuid;not null" json:"horario_id"`
 	Horario       *Horario       `gorm:"foreignKey:HorarioID" json:"horario,omitempty"`
 	Fecha         time.Time      `gorm:"type:date;not null" json:"fecha"`
-	Estado        string         `gorm:"not null;default:'ausente'" json:"estado"` // presente, ausente, justificado
+	Estado        string         `gorm:"not null;default:'ausente'" json:"estado"` // presente, ausente, justificado, atraso, retiro
 	RegistradoPor uuid.UUID      `gorm:"type:uuid" json:"registrado_por"`
+
+	// Atraso: hora de llegada. Retiro: hora de salida y quien retira al alumno.
+	HoraLlegada         *time.Time `json:"hora_llegada,omitempty"`
+	HoraRetiro          *time.Time `json:"hora_retiro,omitempty"`
+	RetiradoPorNombre   string     `json:"retirado_por_nombre,omitempty"`
+	RetiradoPorRelacion string     `json:"retirado_por_relacion,omitempty"` // apoderado, madre, padre, otro...
+
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
@@ -50,6 +59,15 @@ type EstadoTemporal struct {
 	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// EsEstadoAsistenciaValido indica si el estado de asistencia es soportado
+func EsEstadoAsistenciaValido(estado string) bool {
+	switch estado {
+	case EstadoPresente, EstadoAusente, EstadoJustificado, EstadoAtraso, EstadoRetiro:
+		return true
+	}
+	return false
+}
+
 // BeforeCreate genera UUID antes de crear
 func (a *Asistencia) BeforeCreate(tx *gorm.DB) error {
 	if a.ID == uuid.Nil {
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Politicas de calculo de asistencia diaria
+const (
+	PoliticaPrimerBloque  = "primer_bloque"  // presente si asistio al primer bloque registrado del dia
+	PoliticaMinimoBloques = "minimo_bloques" // presente si asistio a >= N bloques
+)
+
+// AsistenciaDiaria es el estado oficial de asistencia de un alumno en un dia,
+// calculado desde los registros por bloque (Asistencia) segun la politica vigente.
+type AsistenciaDiaria struct {
+	ID       uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	AlumnoID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_asistencia_diaria_alumno_fecha" json:"alumno_id"`
+	Alumno   *Alumno   `gorm:"foreignKey:AlumnoID" json:"alumno,omitempty"`
+	CursoID  uuid.UUID `gorm:"type:uuid;not null;index" json:"curso_id"`
+	Fecha    time.Time `gorm:"type:date;not null;uniqueIndex:idx_asistencia_diaria_alumno_fecha;index" json:"fecha"`
+	Estado   string    `gorm:"not null" json:"estado"` // presente, ausente, justificado
+
+	Atraso bool `gorm:"default:false" json:"atraso"`
+	Retiro bool `gorm:"default:false" json:"retiro"`
+
+	BloquesRegistrados int    `gorm:"not null;default:0" json:"bloques_registrados"`
+	BloquesPresentes   int    `gorm:"not null;default:0" json:"bloques_presentes"`
+	Politica           string `json:"politica"`
+	CalculadoEn        time.Time `json:"calculado_en"`
+
+	// Correccion manual: el recalculo no sobreescribe filas corregidas
+	Corregido        bool       `gorm:"default:false" json:"corregido"`
+	CorregidoPor     *uuid.UUID `gorm:"type:uuid" json:"corregido_por,omitempty"`
+	CorregidoEn      *time.Time `json:"corregido_en,omitempty"`
+	MotivoCorreccion string     `gorm:"type:text" json:"motivo_correccion,omitempty"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName nombre de tabla
+func (AsistenciaDiaria) TableName() string {
+	return "asistencias_diarias"
+}
+
+// BeforeCreate genera UUID antes de crear
+func (a *AsistenciaDiaria) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// EsEstadoDiarioValido indica si el estado es valido para la asistencia diaria
+func EsEstadoDiarioValido(estado string) bool {
+	return estado == EstadoPresente || estado == EstadoAusente || estado == EstadoJustificado
+}
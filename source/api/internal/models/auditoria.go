@@ -13,6 +13,8 @@ const (
 	AuditoriaInsert = "INSERT"
 	AuditoriaUpdate = "UPDATE"
 	AuditoriaDelete = "DELETE"
+	// Accion fuera de la regla habitual autorizada por un administrador (ej. editar asistencia bloqueada)
+	AuditoriaOverride = "OVERRIDE"
 )
 
 // Auditoria representa un registro de auditoria
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Tipos de entrada del calendario escolar
+const (
+	CalendarioFeriado         = "feriado"          // no lectivo
+	CalendarioSuspension      = "suspension"       // no lectivo (paro, emergencia, jornada de reflexion)
+	CalendarioVacaciones      = "vacaciones"       // no lectivo
+	CalendarioJornadaEspecial = "jornada_especial" // lectivo con bloques reducidos u horario de otro dia
+	CalendarioRecuperativo    = "recuperativo"     // lectivo en fin de semana (recuperacion de clases)
+)
+
+// Origenes de una entrada
+const (
+	CalendarioOrigenManual = "manual"
+	CalendarioOrigenICal   = "ical"
+)
+
+// CalendarioEntrada dia o rango de dias que altera el calendario lunes-viernes por defecto.
+// CursoID nil = aplica a todo el establecimiento.
+type CalendarioEntrada struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Tipo        string     `gorm:"not null;index" json:"tipo"`
+	Nombre      string     `gorm:"not null" json:"nombre"`
+	Descripcion string     `json:"descripcion,omitempty"`
+	FechaInicio time.Time  `gorm:"type:date;not null;index" json:"fecha_inicio"`
+	FechaFin    time.Time  `gorm:"type:date;not null;index" json:"fecha_fin"` // inclusiva
+	CursoID     *uuid.UUID `gorm:"type:uuid;index" json:"curso_id,omitempty"`
+	Curso       *Curso     `gorm:"foreignKey:CursoID" json:"curso,omitempty"`
+	// Jornada especial / recuperativo: horario semanal que se usa (1..5) y bloques que se dictan (vacio = todos)
+	DiaSemanaHorario *int            `json:"dia_semana_horario,omitempty"`
+	Bloques          json.RawMessage `gorm:"type:jsonb" json:"bloques,omitempty"` // [1,2,3]
+	UID              string          `gorm:"index" json:"uid,omitempty"`          // UID iCal (import/export idempotente)
+	Origen           string          `gorm:"not null;default:'manual'" json:"origen"`
+	CreadoPor        *uuid.UUID      `gorm:"type:uuid" json:"creado_por,omitempty"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName nombre de tabla
+func (CalendarioEntrada) TableName() string {
+	return "calendario_entradas"
+}
+
+// BeforeCreate genera UUID antes de crear
+func (e *CalendarioEntrada) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	if e.Origen == "" {
+		e.Origen = CalendarioOrigenManual
+	}
+	return nil
+}
+
+// EsTipoCalendarioValido indica si el tipo es uno de los soportados
+func EsTipoCalendarioValido(tipo string) bool {
+	switch tipo {
+	case CalendarioFeriado, CalendarioSuspension, CalendarioVacaciones, CalendarioJornadaEspecial, CalendarioRecuperativo:
+		return true
+	}
+	return false
+}
+
+// EsNoLectivo indica si el tipo suspende las clases
+func (e *CalendarioEntrada) EsNoLectivo() bool {
+	return e.Tipo == CalendarioFeriado || e.Tipo == CalendarioSuspension || e.Tipo == CalendarioVacaciones
+}
+
+// NumerosBloque retorna los bloques restringidos (nil = todos)
+func (e *CalendarioEntrada) NumerosBloque() []int {
+	if len(e.Bloques) == 0 {
+		return nil
+	}
+	var out []int
+	_ = json.Unmarshal(e.Bloques, &out)
+	return out
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Estados de caso
+const (
+	CasoAbierto     = "abierto"
+	CasoSeguimiento = "seguimiento"
+	CasoDerivado    = "derivado"
+	CasoCerrado     = "cerrado"
+)
+
+// Caso agrupa el seguimiento psicosocial/convivencia de un alumno:
+// eventos y alertas relacionados, notas, tareas de seguimiento y adjuntos confidenciales.
+type Caso struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	AlumnoID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"alumno_id"`
+	Alumno      *Alumno    `gorm:"foreignKey:AlumnoID" json:"alumno,omitempty"`
+	Titulo      string     `gorm:"not null" json:"titulo"`
+	Descripcion string     `gorm:"type:text" json:"descripcion"`
+	Estado      string     `gorm:"not null;index;default:'abierto'" json:"estado"` // abierto, seguimiento, derivado, cerrado
+	DerivadoA   string     `json:"derivado_a,omitempty"`                           // institucion/red externa (si derivado)
+	AsignadoA   *uuid.UUID `gorm:"type:uuid;index" json:"asignado_a,omitempty"`    // profesional a cargo
+	Asignado    *Usuario   `gorm:"foreignKey:AsignadoA" json:"asignado,omitempty"`
+	CreadoPor   uuid.UUID  `gorm:"type:uuid;not null" json:"creado_por"`
+	CerradoEn   *time.Time `json:"cerrado_en,omitempty"`
+	CerradoPor  *uuid.UUID `gorm:"type:uuid" json:"cerrado_por,omitempty"`
+
+	Eventos  []Evento    `gorm:"many2many:caso_eventos" json:"eventos,omitempty"`
+	Alertas  []Alerta    `gorm:"many2many:caso_alertas" json:"alertas,omitempty"`
+	Notas    []CasoNota  `gorm:"foreignKey:CasoID" json:"notas,omitempty"`
+	Tareas   []CasoTarea `gorm:"foreignKey:CasoID" json:"tareas,omitempty"`
+	Adjuntos []Adjunto   `gorm:"polymorphic:Entidad;polymorphicValue:caso" json:"adjuntos,omitempty"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (c *Caso) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	if c.Estado == "" {
+		c.Estado = CasoAbierto
+	}
+	return nil
+}
+
+// transicionesCaso define los cambios de estado permitidos
+var transicionesCaso = map[string][]string{
+	CasoAbierto:     {CasoSeguimiento, CasoDerivado, CasoCerrado},
+	CasoSeguimiento: {CasoDerivado, CasoCerrado},
+	CasoDerivado:    {CasoSeguimiento, CasoCerrado},
+	CasoCerrado:     {CasoAbierto},
+}
+
+// PuedeTransicionar verifica si el caso puede pasar al estado indicado
+func (c *Caso) PuedeTransicionar(estado string) bool {
+	for _, e := range transicionesCaso[c.Estado] {
+		if e == estado {
+			return true
+		}
+	}
+	return false
+}
+
+// CasoNota es una nota de seguimiento del caso
+type CasoNota struct {
+	ID        uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	CasoID    uuid.UUID      `gorm:"type:uuid;not null;index" json:"caso_id"`
+	Texto     string         `gorm:"type:text;not null" json:"texto"`
+	UsuarioID uuid.UUID      `gorm:"type:uuid;not null" json:"usuario_id"`
+	Usuario   *Usuario       `gorm:"foreignKey:UsuarioID" json:"usuario,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (n *CasoNota) BeforeCreate(tx *gorm.DB) error {
+	if n.ID == uuid.Nil {
+		n.ID = uuid.New()
+	}
+	return nil
+}
+
+// CasoTarea es una tarea de seguimiento con fecha de vencimiento
+type CasoTarea struct {
+	ID            uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	CasoID        uuid.UUID      `gorm:"type:uuid;not null;index" json:"caso_id"`
+	Titulo        string         `gorm:"not null" json:"titulo"`
+	Descripcion   string         `gorm:"type:text" json:"descripcion,omitempty"`
+	AsignadoA     *uuid.UUID     `gorm:"type:uuid;index" json:"asignado_a,omitempty"`
+	VenceEn       *time.Time     `gorm:"index" json:"vence_en,omitempty"`
+	Completada    bool           `gorm:"default:false" json:"completada"`
+	CompletadaEn  *time.Time     `json:"completada_en,omitempty"`
+	CompletadaPor *uuid.UUID     `gorm:"type:uuid" json:"completada_por,omitempty"`
+	CreadoPor     uuid.UUID      `gorm:"type:uuid;not null" json:"creado_por"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (t *CasoTarea) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// Vencida indica si la tarea esta pendiente y paso su fecha limite
+func (t *CasoTarea) Vencida(now time.Time) bool {
+	return !t.Completada && t.VenceEn != nil && t.VenceEn.Before(now)
+}
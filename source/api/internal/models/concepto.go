@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,18 +16,50 @@ const (
 	ConceptoSOS            = "SOS"
 	ConceptoComportamiento = "COMPORTAMIENTO"
 	ConceptoDisciplinario  = "DISCIPLINARIO"
+	ConceptoAtraso         = "ATRASO"
+	ConceptoRetiro         = "RETIRO"
+)
+
+// Severidad de un concepto
+const (
+	SeveridadBaja  = "baja"
+	SeveridadMedia = "media"
+	SeveridadAlta  = "alta"
+)
+
+// Colores de semaforo. Concepto.Color es el color al que lleva la sala mientras tenga eventos activos
+// (verde = no afecta); gris solo se usa en el monitor (sin datos).
+const (
+	SemaforoVerde    = "verde"
+	SemaforoAmarillo = "amarillo"
+	SemaforoRojo     = "rojo"
+	SemaforoGris     = "gris"
+)
+
+// Categorias de conceptos
+const (
+	CategoriaAsistencia  = "asistencia"
+	CategoriaConvivencia = "convivencia"
+	CategoriaSalud       = "salud"
+	CategoriaPermanencia = "permanencia" // salidas de la sala (bano, etc.)
+	CategoriaOtro        = "otro"
 )
 
 // Concepto representa un evento estandarizado del establecimiento
 type Concepto struct {
-	ID          uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	Codigo      string         `gorm:"uniqueIndex;not null" json:"codigo"` // "INASISTENCIA", "BANO", etc
-	Nombre      string         `gorm:"not null" json:"nombre"`
-	Descripcion string         `gorm:"type:text" json:"descripcion"`
-	Activo      bool           `gorm:"default:true" json:"activo"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Codigo      string    `gorm:"uniqueIndex;not null" json:"codigo"` // "INASISTENCIA", "BANO", etc
+	Nombre      string    `gorm:"not null" json:"nombre"`
+	Descripcion string    `gorm:"type:text" json:"descripcion"`
+	Activo      bool      `gorm:"default:true" json:"activo"`
+	Severidad   string    `gorm:"not null;default:''" json:"severidad"` // baja, media, alta
+	Color       string    `gorm:"not null;default:''" json:"color"`     // semaforo de sala: verde, amarillo, rojo
+	Categoria   string    `gorm:"not null;default:''" json:"categoria"` // asistencia, convivencia, salud, permanencia, otro
+	// DatosSchema JSON Schema de Evento.Datos para este concepto (validacion y formularios dinamicos)
+	DatosSchema json.RawMessage `gorm:"type:jsonb" json:"datos_schema,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt  `gorm:"index" json:"-"`
 }
 
 // BeforeCreate genera UUID antes de crear
@@ -34,5 +67,90 @@ func (c *Concepto) BeforeCreate(tx *gorm.DB) error {
 	if c.ID == uuid.Nil {
 		c.ID = uuid.New()
 	}
+	c.Clasificar()
+	return nil
+}
+
+// EsSeveridadValida verifica la severidad
+func EsSeveridadValida(s string) bool {
+	return s == SeveridadBaja || s == SeveridadMedia || s == SeveridadAlta
+}
+
+// EsColorConceptoValido verifica el color de semaforo de un concepto
+func EsColorConceptoValido(c string) bool {
+	return c == SemaforoVerde || c == SemaforoAmarillo || c == SemaforoRojo
+}
+
+// EsCategoriaValida verifica la categoria
+func EsCategoriaValida(c string) bool {
+	switch c {
+	case CategoriaAsistencia, CategoriaConvivencia, CategoriaSalud, CategoriaPermanencia, CategoriaOtro:
+		return true
+	}
+	return false
+}
+
+// ColorPorSeveridad color de semaforo por defecto: alta -> rojo, media -> amarillo, baja -> verde
+func ColorPorSeveridad(severidad string) string {
+	switch severidad {
+	case SeveridadAlta:
+		return SemaforoRojo
+	case SeveridadBaja:
+		return SemaforoVerde
+	}
+	return SemaforoAmarillo
+}
+
+// clasificacionPredefinida severidad, color y categoria de los conceptos predefinidos
+// (equivale al semaforo fijo que usaba el monitor antes de configurarse por concepto)
+var clasificacionPredefinida = map[string][3]string{
+	ConceptoSOS:            {SeveridadAlta, SemaforoRojo, CategoriaConvivencia},
+	ConceptoComportamiento: {SeveridadAlta, SemaforoRojo, CategoriaConvivencia},
+	ConceptoDisciplinario:  {SeveridadAlta, SemaforoRojo, CategoriaConvivencia},
+	ConceptoBano:           {SeveridadMedia, SemaforoAmarillo, CategoriaPermanencia},
+	ConceptoEnfermeria:     {SeveridadMedia, SemaforoAmarillo, CategoriaSalud},
+	ConceptoInasistencia:   {SeveridadMedia, SemaforoAmarillo, CategoriaAsistencia},
+	ConceptoAtraso:         {SeveridadBaja, SemaforoVerde, CategoriaAsistencia},
+	ConceptoRetiro:         {SeveridadBaja, SemaforoVerde, CategoriaAsistencia},
+}
+
+// Clasificar completa severidad, color y categoria vacios: los predefinidos segun su codigo,
+// el resto severidad media, color segun severidad y categoria otro
+func (c *Concepto) Clasificar() {
+	def, ok := clasificacionPredefinida[c.Codigo]
+	if !ok {
+		def = [3]string{SeveridadMedia, "", CategoriaOtro}
+	}
+	if c.Severidad == "" {
+		c.Severidad = def[0]
+	}
+	if c.Color == "" {
+		c.Color = def[1]
+		if c.Color == "" {
+			c.Color = ColorPorSeveridad(c.Severidad)
+		}
+	}
+	if c.Categoria == "" {
+		c.Categoria = def[2]
+	}
+}
+
+// ClasificarConceptos completa la clasificacion de los conceptos que aun no la tienen (post migracion)
+func ClasificarConceptos(db *gorm.DB) error {
+	var pendientes []Concepto
+	if err := db.Unscoped().Where("severidad = '' OR color = '' OR categoria = ''").Find(&pendientes).Error; err != nil {
+		return err
+	}
+	for i := range pendientes {
+		c := &pendientes[i]
+		c.Clasificar()
+		if err := db.Unscoped().Model(&Concepto{}).Where("id = ?", c.ID).Updates(map[string]interface{}{
+			"severidad": c.Severidad,
+			"color":     c.Color,
+			"categoria": c.Categoria,
+		}).Error; err != nil {
+			return err
+		}
+	}
 	return nil
 }
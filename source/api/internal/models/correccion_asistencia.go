@@ -0,0 +1,71 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Estados de una solicitud de correccion de asistencia
+const (
+	CorreccionPendiente = "pendiente"
+	CorreccionAprobada  = "aprobada"
+	CorreccionRechazada = "rechazada"
+)
+
+// CambioAsistencia una fila de la correccion: estado anterior (al solicitar) y estado pedido
+type CambioAsistencia struct {
+	AlumnoID            uuid.UUID `json:"alumno_id"`
+	EstadoAntes         string    `json:"estado_antes,omitempty"` // vacio = sin registro
+	Estado              string    `json:"estado"`
+	HoraLlegada         string    `json:"hora_llegada,omitempty"` // HH:MM (atraso)
+	HoraRetiro          string    `json:"hora_retiro,omitempty"`  // HH:MM (retiro)
+	RetiradoPorNombre   string    `json:"retirado_por_nombre,omitempty"`
+	RetiradoPorRelacion string    `json:"retirado_por_relacion,omitempty"`
+}
+
+// CorreccionAsistencia solicitud para cambiar la asistencia de un bloque ya bloqueado.
+// La revisa inspectoria; al aprobarse los cambios se aplican en una sola transaccion.
+type CorreccionAsistencia struct {
+	ID            uuid.UUID       `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	HorarioID     uuid.UUID       `gorm:"type:uuid;not null;index" json:"horario_id"`
+	Horario       *Horario        `gorm:"foreignKey:HorarioID" json:"horario,omitempty"`
+	Fecha         time.Time       `gorm:"type:date;not null;index" json:"fecha"`
+	CursoID       uuid.UUID       `gorm:"type:uuid;not null;index" json:"curso_id"`
+	Motivo        string          `gorm:"type:text;not null" json:"motivo"`
+	Cambios       json.RawMessage `gorm:"type:jsonb;not null" json:"cambios"` // []CambioAsistencia
+	Estado        string          `gorm:"not null;index;default:'pendiente'" json:"estado"`
+	SolicitadoPor uuid.UUID       `gorm:"type:uuid;not null;index" json:"solicitado_por"`
+	RevisadoPor   *uuid.UUID      `gorm:"type:uuid" json:"revisado_por,omitempty"`
+	RevisadoEn    *time.Time      `json:"revisado_en,omitempty"`
+	Comentario    string          `gorm:"type:text" json:"comentario,omitempty"` // comentario de revision
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName nombre de tabla
+func (CorreccionAsistencia) TableName() string {
+	return "correcciones_asistencia"
+}
+
+// BeforeCreate genera UUID antes de crear
+func (c *CorreccionAsistencia) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	if c.Estado == "" {
+		c.Estado = CorreccionPendiente
+	}
+	return nil
+}
+
+// ListaCambios decodifica los cambios solicitados
+func (c *CorreccionAsistencia) ListaCambios() []CambioAsistencia {
+	var out []CambioAsistencia
+	_ = json.Unmarshal(c.Cambios, &out)
+	return out
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Umbrales por defecto del semaforo de presencia del profesor (minutos)
+const (
+	UmbralProfesorVerdeMin    = 75
+	UmbralProfesorAmarilloMin = 150
+)
+
+// Duracion maxima por defecto de cada estado temporal antes de marcarlo excedido (minutos)
+const (
+	DuracionMaxBanoMin       = 10
+	DuracionMaxEnfermeriaMin = 30
+	DuracionMaxSOSMin        = 5
+)
+
+// Establecimiento datos institucionales del colegio (fila unica), usados en reportes y certificados
+type Establecimiento struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Nombre        string    `gorm:"not null" json:"nombre"`
+	RBD           string    `json:"rbd"` // Rol Base de Datos (Mineduc)
+	Direccion     string    `json:"direccion"`
+	Comuna        string    `json:"comuna"`
+	Region        string    `json:"region"`
+	Telefono      string    `json:"telefono"`
+	Email         string    `json:"email"`
+	Director      string    `json:"director"`
+	Lema          string    `json:"lema"`
+	ColorPrimario string    `gorm:"default:'#1F4E79'" json:"color_primario"` // hex, barra de encabezado de reportes
+	// Monitor: semaforo de presencia del profesor segun minutos desde la ultima asistencia registrada
+	// (hasta MonitorProfesorVerdeMin verde, hasta MonitorProfesorAmarilloMin amarillo, despues gris).
+	// Monitor por alumno: minutos que puede durar cada estado temporal antes de destacarlo como excedido
+	MonitorProfesorVerdeMin    int            `gorm:"not null;default:75" json:"monitor_profesor_verde_min"`
+	MonitorProfesorAmarilloMin int            `gorm:"not null;default:150" json:"monitor_profesor_amarillo_min"`
+	MonitorBanoMaxMin          int            `gorm:"not null;default:10" json:"monitor_bano_max_min"`
+	MonitorEnfermeriaMaxMin    int            `gorm:"not null;default:30" json:"monitor_enfermeria_max_min"`
+	MonitorSOSMaxMin           int            `gorm:"not null;default:5" json:"monitor_sos_max_min"`
+	CreatedAt                  time.Time      `json:"created_at"`
+	UpdatedAt                  time.Time      `json:"updated_at"`
+	DeletedAt                  gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName nombre de tabla
+func (Establecimiento) TableName() string {
+	return "establecimiento"
+}
+
+// BeforeCreate genera UUID antes de crear
+func (e *Establecimiento) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// DuracionMaxEstadoTemporal minutos permitidos para el tipo de estado temporal (0 si el tipo no tiene limite)
+func (e Establecimiento) DuracionMaxEstadoTemporal(tipo string) int {
+	n, def := 0, 0
+	switch tipo {
+	case EstadoTemporalBano:
+		n, def = e.MonitorBanoMaxMin, DuracionMaxBanoMin
+	case EstadoTemporalEnfermeria:
+		n, def = e.MonitorEnfermeriaMaxMin, DuracionMaxEnfermeriaMin
+	case EstadoTemporalSOS:
+		n, def = e.MonitorSOSMaxMin, DuracionMaxSOSMin
+	}
+	if n <= 0 {
+		return def
+	}
+	return n
+}
+
+// ObtenerEstablecimiento retorna los datos del colegio (valores por defecto si aun no se configuran)
+func ObtenerEstablecimiento(db *gorm.DB) Establecimiento {
+	var e Establecimiento
+	if err := db.Order("created_at").First(&e).Error; err != nil {
+		return Establecimiento{Nombre: "Establecimiento", ColorPrimario: "#1F4E79",
+			MonitorProfesorVerdeMin: UmbralProfesorVerdeMin, MonitorProfesorAmarilloMin: UmbralProfesorAmarilloMin,
+			MonitorBanoMaxMin: DuracionMaxBanoMin, MonitorEnfermeriaMaxMin: DuracionMaxEnfermeriaMin, MonitorSOSMaxMin: DuracionMaxSOSMin}
+	}
+	return e
+}
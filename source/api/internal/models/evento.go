@@ -14,6 +14,15 @@ const (
 	OrigenSistema  = "sistema"
 )
 
+// Motivos de cierre de eventos
+const (
+	MotivoCierreManual      = "manual"      // cierre desde UI/API
+	MotivoCierreResuelto    = "resuelto"    // situacion resuelta (ej: alumno volvio de enfermeria)
+	MotivoCierreReemplazado = "reemplazado" // otro evento del mismo tipo lo reemplaza
+	MotivoCierreCorreccion  = "correccion"  // correccion de asistencia (ausente -> presente)
+	MotivoCierreJustificado = "justificado" // inasistencia justificada
+)
+
 // Evento representa la ocurrencia concreta de un concepto
 type Evento struct {
 	ID            uuid.UUID       `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
@@ -32,6 +41,11 @@ type Evento struct {
 	Activo        bool            `gorm:"default:true" json:"activo"`
 	CerradoEn     *time.Time      `json:"cerrado_en,omitempty"`
 	CerradoPor    *uuid.UUID      `gorm:"type:uuid" json:"cerrado_por,omitempty"`
+	MotivoCierre  string          `json:"motivo_cierre,omitempty"`
+	ReabiertoEn   *time.Time      `json:"reabierto_en,omitempty"`
+	ReabiertoPor  *uuid.UUID      `gorm:"type:uuid" json:"reabierto_por,omitempty"`
+	Notas         []EventoNota    `gorm:"foreignKey:EventoID" json:"notas,omitempty"`
+	Adjuntos      []Adjunto       `gorm:"polymorphic:Entidad;polymorphicValue:evento" json:"adjuntos,omitempty"`
 	CreatedAt     time.Time       `json:"created_at"`
 	UpdatedAt     time.Time       `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt  `gorm:"index" json:"-"`
@@ -46,9 +60,33 @@ func (e *Evento) BeforeCreate(tx *gorm.DB) error {
 }
 
 // Cerrar cierra el evento
-func (e *Evento) Cerrar(usuarioID uuid.UUID) {
+func (e *Evento) Cerrar(usuarioID uuid.UUID, motivo string) {
 	now := time.Now()
+	if motivo == "" {
+		motivo = MotivoCierreManual
+	}
 	e.Activo = false
 	e.CerradoEn = &now
 	e.CerradoPor = &usuarioID
+	e.MotivoCierre = motivo
+}
+
+// EsMotivoCierreValido indica si el motivo de cierre es soportado (vacio = manual)
+func EsMotivoCierreValido(motivo string) bool {
+	switch motivo {
+	case "", MotivoCierreManual, MotivoCierreResuelto, MotivoCierreReemplazado, MotivoCierreCorreccion, MotivoCierreJustificado:
+		return true
+	}
+	return false
+}
+
+// Reabrir vuelve a activar un evento cerrado
+func (e *Evento) Reabrir(usuarioID uuid.UUID) {
+	now := time.Now()
+	e.Activo = true
+	e.CerradoEn = nil
+	e.CerradoPor = nil
+	e.MotivoCierre = ""
+	e.ReabiertoEn = &now
+	e.ReabiertoPor = &usuarioID
 }
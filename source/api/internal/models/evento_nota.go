@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EventoNota es una anotacion libre sobre un evento (seguimiento, contexto, resolucion)
+type EventoNota struct {
+	ID        uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	EventoID  uuid.UUID      `gorm:"type:uuid;not null;index" json:"evento_id"`
+	Texto     string         `gorm:"type:text;not null" json:"texto"`
+	UsuarioID uuid.UUID      `gorm:"type:uuid;not null" json:"usuario_id"`
+	Usuario   *Usuario       `gorm:"foreignKey:UsuarioID" json:"usuario,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (n *EventoNota) BeforeCreate(tx *gorm.DB) error {
+	if n.ID == uuid.Nil {
+		n.ID = uuid.New()
+	}
+	return nil
+}
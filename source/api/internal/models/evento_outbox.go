@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Estados de un mensaje del bus de eventos
+const (
+	EventoOutboxPendiente  = "pendiente"
+	EventoOutboxProcesando = "procesando"
+	EventoOutboxProcesado  = "procesado"
+	EventoOutboxError      = "error"
+)
+
+// EventoOutbox es la cola durable del bus interno de eventos (transactional outbox).
+// Se escribe en la misma transaccion que el evento y la consumen los workers del bus
+// con semantica at-least-once (lease via bloqueado_hasta).
+type EventoOutbox struct {
+	ID                 uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Topico             string     `gorm:"not null;index" json:"topico"` // evento_creado, ...
+	EventoID           uuid.UUID  `gorm:"type:uuid;not null;index" json:"evento_id"`
+	UsuarioID          *uuid.UUID `gorm:"type:uuid" json:"usuario_id,omitempty"`
+	CerradoEn          *time.Time `json:"cerrado_en,omitempty"` // evento_cerrado: cierre que se publico
+	MotivoCierre       string     `json:"motivo_cierre,omitempty"`
+	Estado             string     `gorm:"not null;index;default:'pendiente'" json:"estado"` // pendiente, procesando, procesado, error
+	Intentos           int        `gorm:"not null;default:0" json:"intentos"`
+	SiguienteIntentoEn *time.Time `json:"siguiente_intento_en,omitempty"`
+	BloqueadoHasta     *time.Time `json:"bloqueado_hasta,omitempty"`
+	UltimoError        string     `gorm:"type:text" json:"ultimo_error,omitempty"`
+	ProcesadoEn        *time.Time `json:"procesado_en,omitempty"`
+	CreatedAt          time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+func (EventoOutbox) TableName() string {
+	return "eventos_outbox"
+}
+
+func (e *EventoOutbox) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	if e.Estado == "" {
+		e.Estado = EventoOutboxPendiente
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package models
+
+import "testing"
+
+func TestEsMotivoCierreValido(t *testing.T) {
+	cases := map[string]bool{
+		"":                      true,
+		MotivoCierreManual:      true,
+		MotivoCierreResuelto:    true,
+		MotivoCierreReemplazado: true,
+		MotivoCierreCorreccion:  true,
+		MotivoCierreJustificado: true,
+		"otro":                  false,
+		"MANUAL":                false,
+	}
+	for motivo, want := range cases {
+		if got := EsMotivoCierreValido(motivo); got != want {
+			t.Errorf("EsMotivoCierreValido(%q) = %v, want %v", motivo, got, want)
+		}
+	}
+}
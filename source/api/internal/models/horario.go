@@ -30,9 +30,37 @@ type Horario struct {
 	BloqueID     uuid.UUID      `gorm:"type:uuid;not null" json:"bloque_id"`
 	Bloque       *BloqueHorario `gorm:"foreignKey:BloqueID" json:"bloque,omitempty"`
 	DiaSemana    int            `gorm:"not null" json:"dia_semana"` // 1=lunes, 5=viernes
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+	Sala         string         `json:"sala,omitempty"`
+	// Ano escolar (y opcionalmente periodo) al que pertenece; nil = horario previo a la gestion de anos.
+	// Al cerrar el ano (rollover) los horarios se archivan con soft delete.
+	AnioEscolarID *uuid.UUID     `gorm:"type:uuid;index" json:"anio_escolar_id,omitempty"`
+	PeriodoID     *uuid.UUID     `gorm:"type:uuid;index" json:"periodo_id,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Excepcion aplicada al resolver el horario de una fecha (no se persiste)
+	Excepcion *HorarioExcepcion `gorm:"-" json:"excepcion,omitempty"`
+	// Profesor del horario semanal cuando hay reemplazo (no se persiste)
+	ProfesorTitularID *uuid.UUID `gorm:"-" json:"profesor_titular_id,omitempty"`
+}
+
+// IndiceHorarioUnico un horario por (curso, dia, bloque) dentro de cada ano y periodo. Los NULL (sin ano /
+// todo el ano) cuentan como un valor mas y los horarios archivados (soft delete) no participan.
+const IndiceHorarioUnico = `CREATE UNIQUE INDEX IF NOT EXISTS idx_horarios_curso_dia_bloque_periodo ON horarios (curso_id, dia_semana, bloque_id,
+	COALESCE(anio_escolar_id, '00000000-0000-0000-0000-000000000000'::uuid), COALESCE(periodo_id, '00000000-0000-0000-0000-000000000000'::uuid))
+	WHERE deleted_at IS NULL`
+
+// ClaveHorario filtra el horario de (curso, dia, bloque) en un ano y periodo (periodo nil = todo el ano).
+// Un horario sin ano (previo a la gestion de anos) se considera del ano indicado; se prefiere el del ano exacto.
+func ClaveHorario(cursoID uuid.UUID, dia int, bloqueID uuid.UUID, anioID, periodoID *uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where("curso_id = ? AND dia_semana = ? AND bloque_id = ? AND periodo_id IS NOT DISTINCT FROM ?", cursoID, dia, bloqueID, periodoID)
+		if anioID == nil {
+			return db.Where("anio_escolar_id IS NULL")
+		}
+		return db.Where("(anio_escolar_id = ? OR anio_escolar_id IS NULL)", *anioID).Order("anio_escolar_id IS NULL")
+	}
 }
 
 // BeforeCreate genera UUID antes de crear
@@ -59,6 +87,17 @@ func DiaSemanaTexto(dia int) string {
 		3: "Miercoles",
 		4: "Jueves",
 		5: "Viernes",
+		6: "Sabado",
+		7: "Domingo",
 	}
 	return dias[dia]
 }
+
+// DiaSemanaDeFecha retorna el dia de la semana ISO (1=lunes .. 7=domingo).
+// Si el dia es lectivo lo decide el calendario escolar (services/calendario), no esta funcion.
+func DiaSemanaDeFecha(t time.Time) int {
+	if wd := int(t.Weekday()); wd != 0 {
+		return wd
+	}
+	return 7
+}
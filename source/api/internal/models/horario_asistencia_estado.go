@@ -24,6 +24,8 @@ type HorarioAsistenciaEstado struct {
 	Presentes    int `gorm:"not null;default:0" json:"presentes"`
 	Ausentes     int `gorm:"not null;default:0" json:"ausentes"`
 	Justificados int `gorm:"not null;default:0" json:"justificados"`
+	Atrasos      int `gorm:"not null;default:0" json:"atrasos"`
+	Retiros      int `gorm:"not null;default:0" json:"retiros"`
 
 	UltimaActualizacionEn *time.Time `json:"ultima_actualizacion_en,omitempty"`
 	UltimaActualizacionPor uuid.UUID `gorm:"type:uuid" json:"ultima_actualizacion_por"`
@@ -44,4 +46,45 @@ func (h *HorarioAsistenciaEstado) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
-
+// RecontarHorarioAsistenciaEstado recalcula los conteos del snapshot (horario, fecha) desde las filas de asistencia.
+// No crea el snapshot si no existe (solo se crea al registrar el bloque).
+func RecontarHorarioAsistenciaEstado(tx *gorm.DB, horarioID uuid.UUID, fecha time.Time) error {
+	type row struct {
+		Estado string
+		N      int
+	}
+	var rows []row
+	if err := tx.Model(&Asistencia{}).
+		Select("estado, COUNT(*) as n").
+		Where("horario_id = ? AND fecha = ?", horarioID, fecha).
+		Group("estado").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+	presentes, ausentes, justificados, atrasos, retiros := 0, 0, 0, 0, 0
+	for _, r := range rows {
+		switch r.Estado {
+		case EstadoPresente:
+			presentes += r.N
+		case EstadoJustificado:
+			justificados += r.N
+		case EstadoAtraso:
+			atrasos += r.N
+		case EstadoRetiro:
+			retiros += r.N
+		default:
+			ausentes += r.N
+		}
+	}
+	now := time.Now()
+	return tx.Model(&HorarioAsistenciaEstado{}).
+		Where("horario_id = ? AND fecha = ?", horarioID, fecha).
+		Updates(map[string]interface{}{
+			"presentes":               presentes,
+			"ausentes":                ausentes,
+			"justificados":            justificados,
+			"atrasos":                 atrasos,
+			"retiros":                 retiros,
+			"ultima_actualizacion_en": now,
+		}).Error
+}
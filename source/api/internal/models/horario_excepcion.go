@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Tipos de excepcion de horario
+const (
+	ExcepcionReemplazo        = "reemplazo"         // otro profesor cubre el bloque
+	ExcepcionCancelado        = "cancelado"         // el bloque no se dicta ese dia
+	ExcepcionCambioSala       = "cambio_sala"       // se dicta en otra sala
+	ExcepcionCambioAsignatura = "cambio_asignatura" // se dicta otra asignatura (permuta)
+)
+
+// HorarioExcepcion cambio puntual de un bloque del horario semanal en una fecha.
+// Una por (horario, fecha); los campos informados se aplican sobre el horario
+// (ej. un reemplazo puede venir tambien con cambio de sala).
+type HorarioExcepcion struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	HorarioID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_horario_excepcion_fecha" json:"horario_id"`
+	Horario   *Horario  `gorm:"foreignKey:HorarioID" json:"horario,omitempty"`
+	Fecha     time.Time `gorm:"type:date;not null;uniqueIndex:idx_horario_excepcion_fecha;index" json:"fecha"`
+	Tipo      string    `gorm:"not null" json:"tipo"`
+
+	ProfesorReemplazoID *uuid.UUID  `gorm:"type:uuid;index" json:"profesor_reemplazo_id,omitempty"`
+	ProfesorReemplazo   *Usuario    `gorm:"foreignKey:ProfesorReemplazoID" json:"profesor_reemplazo,omitempty"`
+	AsignaturaID        *uuid.UUID  `gorm:"type:uuid" json:"asignatura_id,omitempty"`
+	Asignatura          *Asignatura `gorm:"foreignKey:AsignaturaID" json:"asignatura,omitempty"`
+	Sala                string      `json:"sala,omitempty"`
+	Motivo              string      `json:"motivo,omitempty"`
+	CreadoPor           *uuid.UUID  `gorm:"type:uuid" json:"creado_por,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName nombre de tabla
+func (HorarioExcepcion) TableName() string {
+	return "horario_excepciones"
+}
+
+// BeforeCreate genera UUID antes de crear
+func (e *HorarioExcepcion) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// EsTipoExcepcionValido indica si el tipo es uno de los soportados
+func EsTipoExcepcionValido(tipo string) bool {
+	switch tipo {
+	case ExcepcionReemplazo, ExcepcionCancelado, ExcepcionCambioSala, ExcepcionCambioAsignatura:
+		return true
+	}
+	return false
+}
+
+// Aplicar retorna una copia del horario con la excepcion aplicada (profesor, asignatura y sala efectivos)
+func (e *HorarioExcepcion) Aplicar(h Horario) Horario {
+	if e.Tipo == ExcepcionCancelado {
+		return h
+	}
+	if e.ProfesorReemplazoID != nil {
+		h.ProfesorID = *e.ProfesorReemplazoID
+		h.Profesor = e.ProfesorReemplazo
+	}
+	if e.AsignaturaID != nil {
+		h.AsignaturaID = *e.AsignaturaID
+		h.Asignatura = e.Asignatura
+	}
+	if e.Sala != "" {
+		h.Sala = e.Sala
+	}
+	return h
+}
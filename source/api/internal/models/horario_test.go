@@ -0,0 +1,58 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/testutil"
+	"gorm.io/gorm"
+)
+
+// El mismo (curso, dia, bloque) puede tener un horario por periodo; el indice unico impide duplicarlo
+func TestClaveHorarioPorPeriodo(t *testing.T) {
+	db := testutil.DB(t)
+	curso := models.Curso{Nombre: "Test " + uuid.NewString()[:8], Nivel: models.NivelBasica}
+	asig := models.Asignatura{Nombre: "Test " + uuid.NewString()[:8]}
+	prof := models.Usuario{Email: uuid.NewString()[:8] + "@test.cl", PasswordHash: "x", Nombre: "Prof", Rol: models.RolProfesor}
+	bloque := models.BloqueHorario{Numero: 99, HoraInicio: "08:00", HoraFin: "08:45"}
+	anio := models.AnioEscolar{Anio: 2999, FechaInicio: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), FechaFin: time.Date(2026, 12, 20, 0, 0, 0, 0, time.UTC)}
+	for _, v := range []interface{}{&curso, &asig, &prof, &bloque, &anio} {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	periodos := models.PeriodosPorDefecto(anio, models.PeriodoSemestre)
+	if err := db.Create(&periodos).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	nuevo := func(periodoID *uuid.UUID) models.Horario {
+		return models.Horario{CursoID: curso.ID, AsignaturaID: asig.ID, ProfesorID: prof.ID, BloqueID: bloque.ID, DiaSemana: 1, AnioEscolarID: &anio.ID, PeriodoID: periodoID}
+	}
+	h1 := nuevo(&periodos[0].ID)
+	h2 := nuevo(&periodos[1].ID)
+	if err := db.Create(&h1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&h2).Error; err != nil {
+		t.Fatalf("otro periodo, mismo bloque: %v", err)
+	}
+
+	var got models.Horario
+	if err := db.Scopes(models.ClaveHorario(curso.ID, 1, bloque.ID, &anio.ID, &periodos[1].ID)).First(&got).Error; err != nil || got.ID != h2.ID {
+		t.Fatalf("clave periodo 2: %v %v", got.ID, err)
+	}
+	if err := db.Scopes(models.ClaveHorario(curso.ID, 1, bloque.ID, &anio.ID, nil)).First(&got).Error; err == nil {
+		t.Fatal("sin periodo no debe coincidir con horarios de un periodo")
+	}
+
+	if err := db.Exec(models.IndiceHorarioUnico).Error; err != nil {
+		t.Fatal(err)
+	}
+	dup := nuevo(&periodos[0].ID)
+	if err := db.Transaction(func(tx *gorm.DB) error { return tx.Create(&dup).Error }); err == nil {
+		t.Fatal("horario duplicado en el mismo periodo")
+	}
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Estados de una clave de idempotencia
+const (
+	IdempotenciaEnProceso  = "en_proceso"
+	IdempotenciaCompletada = "completada"
+)
+
+// IdempotenciaClave respuesta guardada para un header Idempotency-Key (por usuario) hasta ExpiraEn.
+// Un reintento con la misma clave y el mismo request recibe la respuesta original sin re-ejecutar.
+type IdempotenciaClave struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UsuarioID   uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_idempotencia_usuario_clave" json:"usuario_id"`
+	Clave       string    `gorm:"not null;size:255;uniqueIndex:idx_idempotencia_usuario_clave" json:"clave"`
+	Metodo      string    `gorm:"not null" json:"metodo"`
+	Ruta        string    `gorm:"not null" json:"ruta"`
+	HashRequest string    `gorm:"not null" json:"hash_request"` // sha256 de metodo + ruta + body
+	Estado      string    `gorm:"not null;default:'en_proceso'" json:"estado"`
+	StatusCode  int       `json:"status_code"`
+	ContentType string    `json:"content_type,omitempty"`
+	Respuesta   []byte    `gorm:"type:bytea" json:"-"`
+	ExpiraEn    time.Time `gorm:"not null;index" json:"expira_en"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName nombre de tabla
+func (IdempotenciaClave) TableName() string {
+	return "idempotencia_claves"
+}
+
+// BeforeCreate genera UUID antes de crear
+func (i *IdempotenciaClave) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Tipos de justificacion
+const (
+	JustificacionCertificadoMedico = "certificado_medico"
+	JustificacionNotaApoderado     = "nota_apoderado"
+	JustificacionOtro              = "otro"
+)
+
+// Estados de justificacion
+const (
+	JustificacionPendiente = "pendiente"
+	JustificacionAprobada  = "aprobada"
+	JustificacionRechazada = "rechazada"
+)
+
+// Origen de la solicitud
+const (
+	JustificacionOrigenPersonal  = "personal"
+	JustificacionOrigenApoderado = "apoderado"
+)
+
+// Justificacion es una solicitud para justificar inasistencias de un alumno en un rango de fechas.
+// Al aprobarse, las asistencias ausentes del rango pasan a justificado.
+type Justificacion struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	AlumnoID        uuid.UUID  `gorm:"type:uuid;not null;index" json:"alumno_id"`
+	Alumno          *Alumno    `gorm:"foreignKey:AlumnoID" json:"alumno,omitempty"`
+	Tipo            string     `gorm:"not null" json:"tipo"` // certificado_medico, nota_apoderado, otro
+	Motivo          string     `gorm:"type:text" json:"motivo"`
+	FechaDesde      time.Time  `gorm:"type:date;not null;index" json:"fecha_desde"`
+	FechaHasta      time.Time  `gorm:"type:date;not null;index" json:"fecha_hasta"`
+	Estado          string     `gorm:"not null;index;default:'pendiente'" json:"estado"` // pendiente, aprobada, rechazada
+	Origen          string     `gorm:"not null;default:'personal'" json:"origen"`        // personal, apoderado
+	ApoderadoNombre string     `json:"apoderado_nombre,omitempty"`
+	SolicitadoPor   uuid.UUID  `gorm:"type:uuid;not null" json:"solicitado_por"`
+	RevisadoPor     *uuid.UUID `gorm:"type:uuid" json:"revisado_por,omitempty"`
+	RevisadoEn      *time.Time `json:"revisado_en,omitempty"`
+	Comentario      string     `gorm:"type:text" json:"comentario,omitempty"` // comentario de revision
+	// Resultado de la aprobacion
+	AsistenciasJustificadas int `gorm:"not null;default:0" json:"asistencias_justificadas"`
+
+	Adjuntos []Adjunto `gorm:"polymorphic:Entidad;polymorphicValue:justificacion" json:"adjuntos,omitempty"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (j *Justificacion) BeforeCreate(tx *gorm.DB) error {
+	if j.ID == uuid.Nil {
+		j.ID = uuid.New()
+	}
+	if j.Estado == "" {
+		j.Estado = JustificacionPendiente
+	}
+	if j.Origen == "" {
+		j.Origen = JustificacionOrigenPersonal
+	}
+	return nil
+}
+
+// EsTipoJustificacionValido verifica si el tipo es valido
+func EsTipoJustificacionValido(tipo string) bool {
+	switch tipo {
+	case JustificacionCertificadoMedico, JustificacionNotaApoderado, JustificacionOtro:
+		return true
+	}
+	return false
+}
@@ -2,12 +2,21 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// Disparadores de reglas (momento del ciclo de vida del evento en que se evaluan)
+const (
+	DisparadorCreado  = "creado"
+	DisparadorCerrado = "cerrado"
+)
+
 // Regla representa una condicion que dispara una accion
 type Regla struct {
 	ID         uuid.UUID       `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
@@ -15,6 +24,7 @@ type Regla struct {
 	ConceptoID uuid.UUID       `gorm:"type:uuid;not null" json:"concepto_id"`
 	Concepto   *Concepto       `gorm:"foreignKey:ConceptoID" json:"concepto,omitempty"`
 	Condicion  json.RawMessage `gorm:"type:jsonb;not null" json:"condicion"`
+	Disparador string          `gorm:"not null;default:'creado'" json:"disparador"` // creado, cerrado
 	AccionID   uuid.UUID       `gorm:"type:uuid;not null" json:"accion_id"`
 	Accion     *Accion         `gorm:"foreignKey:AccionID" json:"accion,omitempty"`
 	Activo     bool            `gorm:"default:true" json:"activo"`
@@ -28,22 +38,89 @@ func (r *Regla) BeforeCreate(tx *gorm.DB) error {
 	if r.ID == uuid.Nil {
 		r.ID = uuid.New()
 	}
+	if r.Disparador == "" {
+		r.Disparador = DisparadorCreado
+	}
 	return nil
 }
 
 // CondicionRegla estructura para definir condiciones
 type CondicionRegla struct {
 	// V1 (compat)
-	Tipo     string `json:"tipo"`     // cantidad, tiempo, caso_especial
-	Campo    string `json:"campo"`    // inasistencias, eventos
+	Tipo     string `json:"tipo"`     // cantidad, tiempo, caso_especial, siempre, riesgo
+	Campo    string `json:"campo"`    // inasistencias, eventos; riesgo: puntaje, porcentaje_asistencia
 	Operador string `json:"operador"` // >=, <=, ==
 	Valor    int    `json:"valor"`    // cantidad
-	Dias     int    `json:"dias"`     // periodo en dias
+	Dias     int    `json:"dias"`     // periodo en dias lectivos (segun calendario escolar)
 
 	// V2 (extensiones)
 	Scope         string `json:"scope,omitempty"`           // alumno, curso
 	ConceptoCodigo string `json:"concepto_codigo,omitempty"` // si se quiere contar un concepto distinto al de la regla
 	DistinctDias  bool   `json:"distinct_dias,omitempty"`   // cuenta dias distintos (reincidencia no consecutiva)
+	DiasCorridos  bool   `json:"dias_corridos,omitempty"`   // Dias en dias calendario (incluye feriados y vacaciones)
+
+	// Reglas de cierre: limitar a ciertos motivos (vacio = cualquiera)
+	MotivosCierre []string `json:"motivos_cierre,omitempty"`
+
+	// Filtros sobre Evento.Datos (todos deben cumplirse). El evento que dispara debe cumplirlos
+	// y en reglas de cantidad solo se cuentan los eventos que los cumplen.
+	Datos []FiltroDatos `json:"datos,omitempty"`
+}
+
+// Operadores de filtros sobre Datos
+const (
+	FiltroIgual      = "=="
+	FiltroDistinto   = "!="
+	FiltroMayor      = ">"
+	FiltroMayorIgual = ">="
+	FiltroMenor      = "<"
+	FiltroMenorIgual = "<="
+	FiltroEn         = "in"
+	FiltroExiste     = "existe"
+)
+
+// FiltroDatos condicion sobre un campo de Evento.Datos (ej. {"campo": "severidad", "operador": "in", "valor": ["grave", "gravisima"]}).
+// Campo admite rutas con puntos ("ubicacion.sala"). Comparaciones >, <, etc. con numeros o textos (ej. fechas ISO).
+type FiltroDatos struct {
+	Campo    string      `json:"campo"`
+	Operador string      `json:"operador"`
+	Valor    interface{} `json:"valor,omitempty"`
+}
+
+var reCampoDatos = regexp.MustCompile(`^[A-Za-z0-9_]+(\.[A-Za-z0-9_]+)*$`)
+
+// Ruta segmentos del campo
+func (f FiltroDatos) Ruta() []string {
+	return strings.Split(f.Campo, ".")
+}
+
+// Validar verifica campo, operador y tipo de valor
+func (f FiltroDatos) Validar() error {
+	if !reCampoDatos.MatchString(f.Campo) {
+		return fmt.Errorf("invalid datos field %q", f.Campo)
+	}
+	switch f.Operador {
+	case FiltroIgual, FiltroDistinto:
+	case FiltroMayor, FiltroMayorIgual, FiltroMenor, FiltroMenorIgual:
+		switch f.Valor.(type) {
+		case float64, string:
+		default:
+			return fmt.Errorf("datos filter %q: %s requires a number or string value", f.Campo, f.Operador)
+		}
+	case FiltroEn:
+		if _, ok := f.Valor.([]interface{}); !ok {
+			return fmt.Errorf("datos filter %q: in requires an array value", f.Campo)
+		}
+	case FiltroExiste:
+	default:
+		return fmt.Errorf("datos filter %q: unsupported operator %q", f.Campo, f.Operador)
+	}
+	return nil
+}
+
+// EsDisparadorValido verifica si el disparador es valido
+func EsDisparadorValido(d string) bool {
+	return d == DisparadorCreado || d == DisparadorCerrado
 }
 
 // ParseCondicion parsea la condicion JSON
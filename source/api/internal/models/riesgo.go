@@ -0,0 +1,118 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Niveles de riesgo
+const (
+	RiesgoBajo  = "bajo"
+	RiesgoMedio = "medio"
+	RiesgoAlto  = "alto"
+)
+
+// ConfiguracionRiesgo pesos y umbrales del puntaje de riesgo (fila unica)
+type ConfiguracionRiesgo struct {
+	ID uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+
+	// Pesos relativos de cada factor (se normalizan por su suma)
+	PesoAsistencia float64 `gorm:"not null" json:"peso_asistencia"`
+	PesoEventos    float64 `gorm:"not null" json:"peso_eventos"`
+	PesoAlertas    float64 `gorm:"not null" json:"peso_alertas"`
+	PesoCasos      float64 `gorm:"not null" json:"peso_casos"`
+
+	VentanaDias       int     `gorm:"not null" json:"ventana_dias"`       // periodo evaluado
+	UmbralAsistencia  float64 `gorm:"not null" json:"umbral_asistencia"`  // % bajo el cual el factor asistencia es maximo
+	SaturacionEventos float64 `gorm:"not null" json:"saturacion_eventos"` // puntos de eventos para factor maximo
+	SaturacionAlertas int     `gorm:"not null" json:"saturacion_alertas"` // alertas abiertas para factor maximo
+	UmbralMedio       float64 `gorm:"not null" json:"umbral_medio"`
+	UmbralAlto        float64 `gorm:"not null" json:"umbral_alto"`
+
+	// Peso por codigo de concepto para el factor eventos (ej. {"DISCIPLINARIO": 3}); default 1
+	PesosConcepto json.RawMessage `gorm:"type:jsonb" json:"pesos_concepto,omitempty"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName nombre de tabla
+func (ConfiguracionRiesgo) TableName() string {
+	return "configuracion_riesgo"
+}
+
+// BeforeCreate genera UUID antes de crear
+func (c *ConfiguracionRiesgo) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// ConfiguracionRiesgoDefault valores por defecto
+func ConfiguracionRiesgoDefault() ConfiguracionRiesgo {
+	return ConfiguracionRiesgo{
+		PesoAsistencia:    40,
+		PesoEventos:       25,
+		PesoAlertas:       20,
+		PesoCasos:         15,
+		VentanaDias:       30,
+		UmbralAsistencia:  85,
+		SaturacionEventos: 10,
+		SaturacionAlertas: 3,
+		UmbralMedio:       30,
+		UmbralAlto:        60,
+		PesosConcepto:     json.RawMessage(`{"INASISTENCIA": 0, "ATRASO": 1, "RETIRO": 1, "COMPORTAMIENTO": 2, "DISCIPLINARIO": 3, "SOS": 3}`),
+	}
+}
+
+// ObtenerConfiguracionRiesgo retorna la configuracion vigente (defaults si no existe)
+func ObtenerConfiguracionRiesgo(db *gorm.DB) ConfiguracionRiesgo {
+	var c ConfiguracionRiesgo
+	if err := db.Order("created_at").First(&c).Error; err != nil {
+		return ConfiguracionRiesgoDefault()
+	}
+	return c
+}
+
+// FactorRiesgo explica el aporte de un factor al puntaje
+type FactorRiesgo struct {
+	Factor  string  `json:"factor"`  // asistencia, eventos, alertas, casos
+	Valor   float64 `json:"valor"`   // factor normalizado 0..1
+	Aporte  float64 `json:"aporte"`  // puntos aportados al puntaje (0..100)
+	Detalle string  `json:"detalle"` // explicacion legible
+}
+
+// RiesgoAlumno ultimo puntaje de riesgo calculado por alumno
+type RiesgoAlumno struct {
+	ID       uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	AlumnoID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"alumno_id"`
+	Alumno   *Alumno   `gorm:"foreignKey:AlumnoID" json:"alumno,omitempty"`
+	CursoID  uuid.UUID `gorm:"type:uuid;not null;index" json:"curso_id"`
+
+	Puntaje              float64         `gorm:"not null;index" json:"puntaje"` // 0..100
+	Nivel                string          `gorm:"not null" json:"nivel"`         // bajo, medio, alto
+	PorcentajeAsistencia *float64        `json:"porcentaje_asistencia,omitempty"`
+	Factores             json.RawMessage `gorm:"type:jsonb" json:"factores"` // []FactorRiesgo
+	CalculadoEn          time.Time       `json:"calculado_en"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName nombre de tabla
+func (RiesgoAlumno) TableName() string {
+	return "riesgos_alumnos"
+}
+
+// BeforeCreate genera UUID antes de crear
+func (r *RiesgoAlumno) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
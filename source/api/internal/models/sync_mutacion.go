@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tipos de mutacion que acepta la sincronizacion offline
+const (
+	MutacionAsistencia   = "asistencia"   // fila de asistencia de un alumno en un bloque
+	MutacionEventoCrear  = "evento_crear" // el ID de la mutacion pasa a ser el ID del evento
+	MutacionEventoCerrar = "evento_cerrar"
+)
+
+// Resultado de una mutacion
+const (
+	SyncAplicado  = "aplicado"
+	SyncConflicto = "conflicto" // el servidor tiene una version mas nueva; se conserva la del servidor
+	SyncRechazado = "rechazado" // invalida o sin permiso; reintentar no cambia el resultado
+	SyncError     = "error"     // falla transitoria del servidor; no se guarda y el cliente debe reintentar
+)
+
+// SyncMutacion mutacion offline ya procesada. El ID es el UUID generado por el cliente: reenviar
+// la misma mutacion retorna el resultado guardado sin volver a aplicarla.
+type SyncMutacion struct {
+	ID            uuid.UUID       `gorm:"type:uuid;primary_key" json:"id"`
+	UsuarioID     uuid.UUID       `gorm:"type:uuid;not null;index" json:"usuario_id"`
+	DispositivoID string          `gorm:"index" json:"dispositivo_id,omitempty"`
+	Tipo          string          `gorm:"not null" json:"tipo"`
+	ClienteEn     time.Time       `gorm:"not null" json:"cliente_en"` // momento de la captura en el dispositivo
+	Resultado     string          `gorm:"not null;index" json:"resultado"`
+	Detalle       json.RawMessage `gorm:"type:jsonb" json:"detalle,omitempty"` // respuesta entregada al cliente
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// TableName nombre de tabla
+func (SyncMutacion) TableName() string {
+	return "sync_mutaciones"
+}
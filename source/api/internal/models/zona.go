@@ -0,0 +1,27 @@
+package models
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	zonaOnce sync.Once
+	zona     *time.Location
+)
+
+// ZonaColegio zona horaria del establecimiento (ZONA_HORARIA, ej. America/Santiago; por defecto la del servidor).
+// Las fechas de asistencia son dias calendario del colegio: las horas HH:MM se interpretan en esta zona.
+func ZonaColegio() *time.Location {
+	zonaOnce.Do(func() {
+		zona = time.Local
+		if v := strings.TrimSpace(os.Getenv("ZONA_HORARIA")); v != "" {
+			if l, err := time.LoadLocation(v); err == nil {
+				zona = l
+			}
+		}
+	})
+	return zona
+}
@@ -0,0 +1,247 @@
+// Package pdf es un generador PDF minimo en Go puro (sin servicios ni binarios externos).
+// Soporta paginas A4/Carta, texto con las fuentes base Helvetica/Helvetica-Bold (WinAnsi,
+// suficiente para espanol), lineas y rectangulos. Pensado para reportes tabulares simples.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Tamanos de pagina en puntos (1/72")
+var (
+	A4    = Tamano{595.28, 841.89}
+	Carta = Tamano{612, 792}
+)
+
+// Tamano de pagina (ancho, alto) en puntos
+type Tamano struct {
+	Ancho, Alto float64
+}
+
+// Horizontal retorna el tamano rotado
+func (t Tamano) Horizontal() Tamano {
+	return Tamano{t.Alto, t.Ancho}
+}
+
+// Documento PDF en construccion. Las coordenadas son desde la esquina superior izquierda.
+type Documento struct {
+	tamano  Tamano
+	paginas []*bytes.Buffer
+	actual  *bytes.Buffer
+
+	negrita bool
+	size    float64
+
+	Titulo string
+	Autor  string
+}
+
+// Nuevo crea un documento vacio del tamano indicado
+func Nuevo(t Tamano) *Documento {
+	return &Documento{tamano: t, size: 10}
+}
+
+// Tamano retorna el tamano de pagina
+func (d *Documento) Tamano() Tamano { return d.tamano }
+
+// Paginas retorna la cantidad de paginas creadas
+func (d *Documento) Paginas() int { return len(d.paginas) }
+
+// NuevaPagina agrega una pagina y la deja como actual
+func (d *Documento) NuevaPagina() {
+	d.actual = &bytes.Buffer{}
+	d.paginas = append(d.paginas, d.actual)
+}
+
+// IrAPagina vuelve a una pagina ya creada (1-based) para agregar contenido (ej. pie "n de N")
+func (d *Documento) IrAPagina(n int) {
+	if n >= 1 && n <= len(d.paginas) {
+		d.actual = d.paginas[n-1]
+	}
+}
+
+// Fuente define la fuente para los textos siguientes
+func (d *Documento) Fuente(negrita bool, size float64) {
+	d.negrita = negrita
+	d.size = size
+}
+
+// ColorRelleno define el color de relleno (y de texto) en RGB 0-255
+func (d *Documento) ColorRelleno(r, g, b int) {
+	fmt.Fprintf(d.actual, "%s %s %s rg\n", num(float64(r)/255), num(float64(g)/255), num(float64(b)/255))
+}
+
+// ColorLinea define el color de trazo en RGB 0-255
+func (d *Documento) ColorLinea(r, g, b int) {
+	fmt.Fprintf(d.actual, "%s %s %s RG\n", num(float64(r)/255), num(float64(g)/255), num(float64(b)/255))
+}
+
+// Texto escribe s con la linea base en (x, y)
+func (d *Documento) Texto(x, y float64, s string) {
+	f := "F1"
+	if d.negrita {
+		f = "F2"
+	}
+	fmt.Fprintf(d.actual, "BT /%s %s Tf %s %s Td (%s) Tj ET\n", f, num(d.size), num(x), num(d.tamano.Alto-y), escapar(winAnsi(s)))
+}
+
+// TextoDerecha escribe s alineado a la derecha en x
+func (d *Documento) TextoDerecha(x, y float64, s string) {
+	d.Texto(x-d.Ancho(s), y, s)
+}
+
+// TextoCentrado escribe s centrado en x
+func (d *Documento) TextoCentrado(x, y float64, s string) {
+	d.Texto(x-d.Ancho(s)/2, y, s)
+}
+
+// Linea traza una linea de (x1,y1) a (x2,y2)
+func (d *Documento) Linea(x1, y1, x2, y2, grosor float64) {
+	fmt.Fprintf(d.actual, "%s w %s %s m %s %s l S\n", num(grosor), num(x1), num(d.tamano.Alto-y1), num(x2), num(d.tamano.Alto-y2))
+}
+
+// Rect dibuja un rectangulo (relleno o solo borde)
+func (d *Documento) Rect(x, y, w, h float64, relleno bool) {
+	op := "S"
+	if relleno {
+		op = "f"
+	}
+	fmt.Fprintf(d.actual, "%s %s %s %s re %s\n", num(x), num(d.tamano.Alto-y-h), num(w), num(h), op)
+}
+
+// Ancho retorna el ancho de s con la fuente actual
+func (d *Documento) Ancho(s string) float64 {
+	return AnchoTexto(s, d.negrita, d.size)
+}
+
+// Recortar acorta s (con "...") para que quepa en ancho
+func (d *Documento) Recortar(s string, ancho float64) string {
+	if d.Ancho(s) <= ancho {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && d.Ancho(string(r)+"...") > ancho {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "..."
+}
+
+// Partir divide s en lineas que caben en ancho (por palabras)
+func (d *Documento) Partir(s string, ancho float64) []string {
+	var lineas []string
+	linea := ""
+	palabra := ""
+	flush := func() {
+		if palabra == "" {
+			return
+		}
+		cand := palabra
+		if linea != "" {
+			cand = linea + " " + palabra
+		}
+		if d.Ancho(cand) <= ancho || linea == "" {
+			linea = cand
+		} else {
+			lineas = append(lineas, linea)
+			linea = palabra
+		}
+		palabra = ""
+	}
+	for _, r := range s {
+		switch r {
+		case ' ', '\t':
+			flush()
+		case '\n':
+			flush()
+			lineas = append(lineas, linea)
+			linea = ""
+		default:
+			palabra += string(r)
+		}
+	}
+	flush()
+	if linea != "" || len(lineas) == 0 {
+		lineas = append(lineas, linea)
+	}
+	return lineas
+}
+
+// Escribir serializa el documento
+func (d *Documento) Escribir(w io.Writer) error {
+	if len(d.paginas) == 0 {
+		d.NuevaPagina()
+	}
+	var out bytes.Buffer
+	offsets := []int{}
+	obj := func(contenido string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), contenido)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+
+	// 1 catalogo, 2 arbol de paginas, 3-4 fuentes, 5 info; luego (pagina, contenido) por pagina
+	n := len(d.paginas)
+	kids := ""
+	for i := 0; i < n; i++ {
+		kids += strconv.Itoa(6+i*2) + " 0 R "
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids, n))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	obj(fmt.Sprintf("<< /Title (%s) /Author (%s) /Producer (school-monitoring) >>", escapar(winAnsi(d.Titulo)), escapar(winAnsi(d.Autor))))
+
+	for i, p := range d.paginas {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			num(d.tamano.Ancho), num(d.tamano.Alto), 7+i*2))
+
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		if _, err := zw.Write(p.Bytes()); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", len(offsets), z.Len())
+		out.Write(z.Bytes())
+		out.WriteString("\nendstream\nendobj\n")
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+func num(f float64) string {
+	return strconv.FormatFloat(math.Round(f*1000)/1000, 'f', -1, 64)
+}
+
+func escapar(b []byte) string {
+	var out bytes.Buffer
+	for _, c := range b {
+		switch c {
+		case '(', ')', '\\':
+			out.WriteByte('\\')
+			out.WriteByte(c)
+		case '\r', '\n':
+			out.WriteByte(' ')
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
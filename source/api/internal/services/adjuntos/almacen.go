@@ -0,0 +1,51 @@
+package adjuntos
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Backends de almacenamiento (ADJUNTOS_STORAGE)
+const (
+	AlmacenLocal = "local"
+	AlmacenS3    = "s3"
+)
+
+// ErrNoEncontrado el objeto no existe en el almacen
+var ErrNoEncontrado = errors.New("objeto no encontrado en el almacen")
+
+// Almacen guarda el contenido de los adjuntos; la metadata vive en la tabla adjuntos.
+// Las claves las genera el servicio (entidad/aaaa/mm/uuid) y nunca vienen del cliente.
+type Almacen interface {
+	Nombre() string
+	Guardar(clave string, contenido []byte, mime string) error
+	Leer(clave string) ([]byte, error)
+	Eliminar(clave string) error
+}
+
+// AlmacenDesdeEnv crea el backend configurado:
+//   - local (default): ADJUNTOS_DIR (default ./data/adjuntos)
+//   - s3: ADJUNTOS_S3_ENDPOINT, ADJUNTOS_S3_BUCKET, ADJUNTOS_S3_REGION, ADJUNTOS_S3_ACCESS_KEY, ADJUNTOS_S3_SECRET_KEY
+//     (cualquier servicio compatible con S3, ej. MinIO en docker-compose)
+func AlmacenDesdeEnv() (Almacen, error) {
+	switch tipo := strings.ToLower(strings.TrimSpace(os.Getenv("ADJUNTOS_STORAGE"))); tipo {
+	case "", AlmacenLocal:
+		dir := os.Getenv("ADJUNTOS_DIR")
+		if dir == "" {
+			dir = "./data/adjuntos"
+		}
+		return NewLocal(dir)
+	case AlmacenS3:
+		return NewS3(S3Config{
+			Endpoint:  os.Getenv("ADJUNTOS_S3_ENDPOINT"),
+			Bucket:    os.Getenv("ADJUNTOS_S3_BUCKET"),
+			Region:    os.Getenv("ADJUNTOS_S3_REGION"),
+			AccessKey: os.Getenv("ADJUNTOS_S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("ADJUNTOS_S3_SECRET_KEY"),
+		})
+	default:
+		return nil, fmt.Errorf("ADJUNTOS_STORAGE desconocido: %q (local, s3)", tipo)
+	}
+}
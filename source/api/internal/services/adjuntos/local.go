@@ -0,0 +1,79 @@
+package adjuntos
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local guarda los adjuntos en un directorio del servidor
+type Local struct {
+	dir string
+}
+
+// NewLocal crea (si no existe) el directorio base
+func NewLocal(dir string) (*Local, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(abs, 0o750); err != nil {
+		return nil, fmt.Errorf("crear directorio de adjuntos: %w", err)
+	}
+	return &Local{dir: abs}, nil
+}
+
+// Nombre del backend
+func (l *Local) Nombre() string { return AlmacenLocal }
+
+// Guardar escribe a un archivo temporal y lo renombra (no deja archivos a medias)
+func (l *Local) Guardar(clave string, contenido []byte, mime string) error {
+	ruta, err := l.ruta(clave)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(ruta), 0o750); err != nil {
+		return err
+	}
+	tmp := ruta + ".tmp"
+	if err := os.WriteFile(tmp, contenido, 0o640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ruta)
+}
+
+// Leer retorna el contenido del objeto
+func (l *Local) Leer(clave string) ([]byte, error) {
+	ruta, err := l.ruta(clave)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(ruta)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoEncontrado
+	}
+	return b, err
+}
+
+// Eliminar borra el objeto (no falla si ya no existe)
+func (l *Local) Eliminar(clave string) error {
+	ruta, err := l.ruta(clave)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(ruta); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// ruta resuelve la clave dentro del directorio base, rechazando rutas que escapen de el
+func (l *Local) ruta(clave string) (string, error) {
+	ruta := filepath.Join(l.dir, filepath.FromSlash(clave))
+	if !strings.HasPrefix(ruta, l.dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("clave de adjunto invalida: %q", clave)
+	}
+	return ruta, nil
+}
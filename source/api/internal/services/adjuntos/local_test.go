@@ -0,0 +1,70 @@
+package adjuntos
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalGuardarLeerEliminar(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLocal(filepath.Join(dir, "adjuntos"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clave := "evento/2026/03/abc"
+	if err := l.Guardar(clave, []byte("hola"), "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := l.Leer(clave)
+	if err != nil || string(got) != "hola" {
+		t.Fatalf("leer: %q %v", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "adjuntos", "evento", "2026", "03", "abc.tmp")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("quedo el archivo temporal: %v", err)
+	}
+
+	if err := l.Eliminar(clave); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Leer(clave); !errors.Is(err, ErrNoEncontrado) {
+		t.Fatalf("leer eliminado: %v", err)
+	}
+	if err := l.Eliminar(clave); err != nil {
+		t.Fatalf("eliminar dos veces: %v", err)
+	}
+}
+
+// Una clave no puede salir del directorio base
+func TestLocalRutaFueraDelDirectorio(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLocal(filepath.Join(dir, "adjuntos"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "secreto"), []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, clave := range []string{"../secreto", "evento/../../secreto", "", ".", "evento/../.."} {
+		if err := l.Guardar(clave, []byte("x"), ""); err == nil {
+			t.Errorf("guardar %q: esperaba error", clave)
+		}
+		if _, err := l.Leer(clave); err == nil {
+			t.Errorf("leer %q: esperaba error", clave)
+		}
+		if err := l.Eliminar(clave); err == nil {
+			t.Errorf("eliminar %q: esperaba error", clave)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "secreto")); err != nil {
+		t.Fatalf("el archivo fuera del directorio fue modificado: %v", err)
+	}
+	// Una ruta absoluta se interpreta dentro del directorio
+	if err := l.Guardar("/evento/abs", []byte("x"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "adjuntos", "evento", "abs")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,169 @@
+package adjuntos
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3Config parametros de un almacen compatible con S3
+type S3Config struct {
+	Endpoint  string // ej. http://minio:9000 o https://s3.us-east-1.amazonaws.com
+	Bucket    string
+	Region    string // default us-east-1
+	AccessKey string
+	SecretKey string
+}
+
+// S3 almacen compatible con S3 (AWS, MinIO, etc.) usando path-style y firma SigV4
+type S3 struct {
+	cfg    S3Config
+	base   *url.URL
+	client *http.Client
+}
+
+// NewS3 valida la configuracion; no verifica que el bucket exista
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("almacen s3 requiere ADJUNTOS_S3_ENDPOINT, ADJUNTOS_S3_BUCKET, ADJUNTOS_S3_ACCESS_KEY y ADJUNTOS_S3_SECRET_KEY")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	base, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("ADJUNTOS_S3_ENDPOINT invalido: %q", cfg.Endpoint)
+	}
+	return &S3{cfg: cfg, base: base, client: &http.Client{Timeout: 60 * time.Second}}, nil
+}
+
+// Nombre del backend
+func (s *S3) Nombre() string { return AlmacenS3 }
+
+// Guardar sube el objeto (PUT)
+func (s *S3) Guardar(clave string, contenido []byte, mime string) error {
+	resp, err := s.hacer(http.MethodPut, clave, contenido, mime)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return errorS3(resp)
+	}
+	return nil
+}
+
+// Leer descarga el objeto (GET)
+func (s *S3) Leer(clave string) ([]byte, error) {
+	resp, err := s.hacer(http.MethodGet, clave, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNoEncontrado
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, errorS3(resp)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Eliminar borra el objeto (DELETE; S3 responde 204 aunque no exista)
+func (s *S3) Eliminar(clave string) error {
+	resp, err := s.hacer(http.MethodDelete, clave, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotFound {
+		return errorS3(resp)
+	}
+	return nil
+}
+
+func (s *S3) hacer(metodo, clave string, body []byte, mime string) (*http.Response, error) {
+	u := *s.base
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + s.cfg.Bucket + "/" + strings.TrimLeft(clave, "/")
+	req, err := http.NewRequest(metodo, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if mime != "" {
+		req.Header.Set("Content-Type", mime)
+	}
+	s.firmar(req, body, time.Now().UTC())
+	return s.client.Do(req)
+}
+
+// firmar agrega los headers de AWS Signature Version 4
+func (s *S3) firmar(req *http.Request, body []byte, now time.Time) {
+	fecha := now.Format("20060102")
+	instante := now.Format("20060102T150405Z")
+	hashBody := sha256Hex(body)
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", instante)
+	req.Header.Set("X-Amz-Content-Sha256", hashBody)
+
+	nombres := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	valores := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": hashBody,
+		"x-amz-date":           instante,
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		nombres = []string{"content-type", "host", "x-amz-content-sha256", "x-amz-date"}
+		valores["content-type"] = ct
+	}
+	var canonHeaders strings.Builder
+	for _, n := range nombres {
+		canonHeaders.WriteString(n + ":" + strings.TrimSpace(valores[n]) + "\n")
+	}
+	firmados := strings.Join(nombres, ";")
+
+	canonico := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		"",
+		canonHeaders.String(),
+		firmados,
+		hashBody,
+	}, "\n")
+
+	alcance := fecha + "/" + s.cfg.Region + "/s3/aws4_request"
+	aFirmar := "AWS4-HMAC-SHA256\n" + instante + "\n" + alcance + "\n" + sha256Hex([]byte(canonico))
+
+	clave := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), fecha)
+	clave = hmacSHA256(clave, s.cfg.Region)
+	clave = hmacSHA256(clave, "s3")
+	clave = hmacSHA256(clave, "aws4_request")
+	firma := hex.EncodeToString(hmacSHA256(clave, aFirmar))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, alcance, firmados, firma))
+}
+
+func errorS3(resp *http.Response) error {
+	detalle, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("s3 %s: %s", resp.Status, strings.TrimSpace(string(detalle)))
+}
+
+func sha256Hex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(clave []byte, dato string) []byte {
+	m := hmac.New(sha256.New, clave)
+	m.Write([]byte(dato))
+	return m.Sum(nil)
+}
@@ -0,0 +1,140 @@
+package adjuntos
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// s3Falso almacen en memoria que valida los headers de firma de cada request
+type s3Falso struct {
+	t       *testing.T
+	mu      sync.Mutex
+	objetos map[string][]byte
+	tipos   map[string]string
+	fallar  bool
+}
+
+func (f *s3Falso) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	suma := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(suma[:]) {
+		f.t.Errorf("%s %s: x-amz-content-sha256 no coincide con el body", r.Method, r.URL.Path)
+	}
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AK/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request") ||
+		!strings.Contains(auth, "SignedHeaders=") || !strings.Contains(auth, "Signature=") {
+		f.t.Errorf("authorization: %q", auth)
+	}
+	if f.fallar {
+		http.Error(w, "<Error><Code>InternalError</Code></Error>", http.StatusInternalServerError)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		f.objetos[r.URL.Path] = body
+		f.tipos[r.URL.Path] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		b, ok := f.objetos[r.URL.Path]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(b)
+	case http.MethodDelete:
+		delete(f.objetos, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3GuardarLeerEliminar(t *testing.T) {
+	falso := &s3Falso{t: t, objetos: map[string][]byte{}, tipos: map[string]string{}}
+	srv := httptest.NewServer(falso)
+	defer srv.Close()
+
+	s, err := NewS3(S3Config{Endpoint: srv.URL + "/prefijo/", Bucket: "adjuntos", Region: "eu-west-1", AccessKey: "AK", SecretKey: "SK"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clave := "caso/2026/03/abc"
+	if err := s.Guardar(clave, []byte("%PDF-1.4"), "application/pdf"); err != nil {
+		t.Fatal(err)
+	}
+	ruta := "/prefijo/adjuntos/" + clave
+	if string(falso.objetos[ruta]) != "%PDF-1.4" || falso.tipos[ruta] != "application/pdf" {
+		t.Fatalf("objetos: %v tipos: %v", falso.objetos, falso.tipos)
+	}
+	got, err := s.Leer(clave)
+	if err != nil || string(got) != "%PDF-1.4" {
+		t.Fatalf("leer: %q %v", got, err)
+	}
+	if err := s.Eliminar(clave); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Leer(clave); !errors.Is(err, ErrNoEncontrado) {
+		t.Fatalf("leer eliminado: %v", err)
+	}
+
+	falso.fallar = true
+	if err := s.Guardar(clave, []byte("x"), ""); err == nil || !strings.Contains(err.Error(), "InternalError") {
+		t.Fatalf("error del servidor: %v", err)
+	}
+	if _, err := s.Leer(clave); err == nil || errors.Is(err, ErrNoEncontrado) {
+		t.Fatalf("leer con error del servidor: %v", err)
+	}
+}
+
+func TestNewS3Config(t *testing.T) {
+	ok := S3Config{Endpoint: "http://minio:9000", Bucket: "b", AccessKey: "a", SecretKey: "s"}
+	s, err := NewS3(ok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.cfg.Region != "us-east-1" {
+		t.Errorf("region por defecto: %q", s.cfg.Region)
+	}
+	sinBucket := ok
+	sinBucket.Bucket = ""
+	sinEsquema := ok
+	sinEsquema.Endpoint = "minio:9000"
+	for _, cfg := range []S3Config{sinBucket, sinEsquema, {}} {
+		if _, err := NewS3(cfg); err == nil {
+			t.Errorf("config invalida aceptada: %+v", cfg)
+		}
+	}
+}
+
+// La firma depende solo del request y del instante, y cambia con la clave secreta
+func TestS3Firma(t *testing.T) {
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	firma := func(secreto string) string {
+		s, _ := NewS3(S3Config{Endpoint: "http://minio:9000", Bucket: "b", AccessKey: "a", SecretKey: secreto})
+		req, _ := http.NewRequest(http.MethodPut, "http://minio:9000/b/k", nil)
+		req.Header.Set("Content-Type", "text/plain")
+		s.firmar(req, []byte("x"), now)
+		if req.Header.Get("X-Amz-Date") != "20260302T100000Z" {
+			t.Fatalf("x-amz-date: %q", req.Header.Get("X-Amz-Date"))
+		}
+		return req.Header.Get("Authorization")
+	}
+	a := firma("s")
+	if a != firma("s") {
+		t.Error("la firma no es deterministica")
+	}
+	if a == firma("otro") {
+		t.Error("la firma no depende de la clave secreta")
+	}
+	if !strings.Contains(a, "SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date") {
+		t.Errorf("headers firmados: %q", a)
+	}
+}
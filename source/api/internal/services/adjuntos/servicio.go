@@ -0,0 +1,182 @@
+package adjuntos
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Errores de validacion (el handler los traduce a 4xx)
+var (
+	ErrVacio           = errors.New("archivo vacio")
+	ErrMuyGrande       = errors.New("archivo excede el tamano maximo")
+	ErrTipoInvalido    = errors.New("tipo de archivo no permitido")
+	ErrIntegridad      = errors.New("el contenido no coincide con el checksum registrado")
+	ErrEntidadInvalida = errors.New("entidad de adjunto invalida")
+)
+
+// Tipos permitidos segun el contenido (http.DetectContentType), no segun el header del cliente
+var mimePermitidos = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"text/plain":      true,
+}
+
+// Documentos de oficina (zip): se aceptan por extension cuando el contenido es un zip
+var mimeOffice = map[string]string{
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".odt":  "application/vnd.oasis.opendocument.text",
+}
+
+// MaxBytes tamano maximo por archivo: ADJUNTOS_MAX_MB (default 10)
+func MaxBytes() int64 {
+	if n, err := strconv.Atoi(os.Getenv("ADJUNTOS_MAX_MB")); err == nil && n > 0 {
+		return int64(n) * 1024 * 1024
+	}
+	return 10 * 1024 * 1024
+}
+
+// DetectarMime valida tamano y tipo del contenido y retorna el MIME a registrar
+func DetectarMime(nombre string, contenido []byte) (string, error) {
+	if len(contenido) == 0 {
+		return "", ErrVacio
+	}
+	if int64(len(contenido)) > MaxBytes() {
+		return "", ErrMuyGrande
+	}
+	mime := http.DetectContentType(contenido)
+	if i := strings.Index(mime, ";"); i >= 0 {
+		mime = mime[:i]
+	}
+	if mimePermitidos[mime] {
+		return mime, nil
+	}
+	if mime == "application/zip" {
+		if office, ok := mimeOffice[strings.ToLower(filepath.Ext(nombre))]; ok {
+			return office, nil
+		}
+	}
+	return "", ErrTipoInvalido
+}
+
+// EsEntidadValida verifica el tipo de entidad
+func EsEntidadValida(entidad string) bool {
+	switch entidad {
+	case models.AdjuntoEvento, models.AdjuntoCaso, models.AdjuntoJustificacion:
+		return true
+	}
+	return false
+}
+
+// Servicio sube, lee y elimina adjuntos (metadata en DB + contenido en el almacen)
+type Servicio struct {
+	db      *gorm.DB
+	almacen Almacen
+}
+
+// NewServicio crea el servicio de adjuntos
+func NewServicio(db *gorm.DB, almacen Almacen) *Servicio {
+	return &Servicio{db: db, almacen: almacen}
+}
+
+// Subir valida el archivo, lo guarda en el almacen y registra la metadata con su sha256.
+// Si falla el registro en DB se elimina el objeto recien guardado.
+func (s *Servicio) Subir(entidad string, entidadID uuid.UUID, nombre string, contenido []byte, usuarioID uuid.UUID) (*models.Adjunto, error) {
+	if !EsEntidadValida(entidad) {
+		return nil, ErrEntidadInvalida
+	}
+	mime, err := DetectarMime(nombre, contenido)
+	if err != nil {
+		return nil, err
+	}
+	adj := models.Adjunto{
+		ID:          uuid.New(),
+		EntidadID:   entidadID,
+		EntidadType: entidad,
+		Nombre:      limpiarNombre(nombre),
+		MimeType:    mime,
+		Tamano:      int64(len(contenido)),
+		Sha256:      checksum(contenido),
+		Almacen:     s.almacen.Nombre(),
+		SubidoPor:   usuarioID,
+	}
+	adj.Clave = clave(entidad, adj.ID, time.Now())
+	if err := s.almacen.Guardar(adj.Clave, contenido, mime); err != nil {
+		return nil, fmt.Errorf("guardar en almacen: %w", err)
+	}
+	if err := s.db.Create(&adj).Error; err != nil {
+		_ = s.almacen.Eliminar(adj.Clave)
+		return nil, err
+	}
+	return &adj, nil
+}
+
+// Leer retorna el contenido verificando el checksum registrado
+func (s *Servicio) Leer(adj *models.Adjunto) ([]byte, error) {
+	if adj.Almacen != s.almacen.Nombre() {
+		return nil, fmt.Errorf("adjunto guardado en almacen %q, configurado %q", adj.Almacen, s.almacen.Nombre())
+	}
+	contenido, err := s.almacen.Leer(adj.Clave)
+	if err != nil {
+		return nil, err
+	}
+	if checksum(contenido) != adj.Sha256 {
+		return nil, ErrIntegridad
+	}
+	return contenido, nil
+}
+
+// Eliminar borra la metadata (soft delete, queda en auditoria) y el objeto del almacen
+func (s *Servicio) Eliminar(adj *models.Adjunto) error {
+	if err := s.db.Delete(adj).Error; err != nil {
+		return err
+	}
+	if adj.Almacen == s.almacen.Nombre() {
+		return s.almacen.Eliminar(adj.Clave)
+	}
+	return nil
+}
+
+// clave del objeto: entidad/aaaa/mm/uuid (no depende del nombre enviado por el cliente)
+func clave(entidad string, id uuid.UUID, t time.Time) string {
+	return path.Join(entidad, t.Format("2006"), t.Format("01"), id.String())
+}
+
+func checksum(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+// limpiarNombre deja solo el nombre base, sin rutas ni caracteres de control
+func limpiarNombre(nombre string) string {
+	nombre = filepath.Base(strings.ReplaceAll(nombre, `\`, "/"))
+	nombre = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, nombre)
+	if nombre == "" || nombre == "." || nombre == "/" {
+		return "adjunto"
+	}
+	if r := []rune(nombre); len(r) > 200 {
+		nombre = string(r[:200])
+	}
+	return nombre
+}
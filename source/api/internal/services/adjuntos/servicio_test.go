@@ -0,0 +1,51 @@
+package adjuntos
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestDetectarMime(t *testing.T) {
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	w, _ := zw.Create("word/document.xml")
+	_, _ = w.Write([]byte("<xml/>"))
+	_ = zw.Close()
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	cases := []struct {
+		name      string
+		nombre    string
+		contenido []byte
+		mime      string
+		err       error
+	}{
+		{"vacio", "a.txt", nil, "", ErrVacio},
+		{"png", "foto.jpg", png, "image/png", nil}, // manda el contenido, no la extension
+		{"pdf", "doc.pdf", []byte("%PDF-1.4\n%..."), "application/pdf", nil},
+		{"texto", "nota.txt", []byte("hola mundo"), "text/plain", nil},
+		{"docx", "informe.DOCX", zipBuf.Bytes(), mimeOffice[".docx"], nil},
+		{"zip generico", "datos.zip", zipBuf.Bytes(), "", ErrTipoInvalido},
+		{"html", "pagina.txt", []byte("<html><script>alert(1)</script></html>"), "", ErrTipoInvalido},
+		{"ejecutable", "informe.pdf", []byte("MZ\x90\x00\x03\x00\x00\x00"), "", ErrTipoInvalido},
+	}
+	for _, tc := range cases {
+		mime, err := DetectarMime(tc.nombre, tc.contenido)
+		if mime != tc.mime || !errors.Is(err, tc.err) {
+			t.Errorf("%s: %q %v", tc.name, mime, err)
+		}
+	}
+}
+
+func TestDetectarMimeTamanoMaximo(t *testing.T) {
+	t.Setenv("ADJUNTOS_MAX_MB", "1")
+	limite := bytes.Repeat([]byte("a"), 1024*1024)
+	if _, err := DetectarMime("a.txt", limite); err != nil {
+		t.Fatalf("en el limite: %v", err)
+	}
+	if _, err := DetectarMime("a.txt", append(limite, 'a')); !errors.Is(err, ErrMuyGrande) {
+		t.Fatalf("sobre el limite: %v", err)
+	}
+}
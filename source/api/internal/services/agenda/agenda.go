@@ -0,0 +1,234 @@
+package agenda
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/calendario"
+	"gorm.io/gorm"
+)
+
+// Filtro acota los bloques del dia (campos nil = sin filtro).
+// ProfesorID incluye tanto los bloques de los que es titular como los que reemplaza.
+type Filtro struct {
+	CursoID    *uuid.UUID
+	ProfesorID *uuid.UUID
+}
+
+// DelDia resuelve los bloques que se dictan en la fecha: horario semanal del dia que indica el
+// calendario escolar (feriados, jornadas especiales, recuperativos) con las excepciones puntuales
+// aplicadas (reemplazo, cambio de sala o asignatura). Los bloques cancelados se incluyen con su
+// excepcion para que el titular lo vea; usar Cancelado para descartarlos.
+func DelDia(db *gorm.DB, fecha time.Time, f Filtro) ([]models.Horario, error) {
+	f0 := fecha.Format("2006-01-02")
+	cal, err := calendario.Cargar(db, fecha, fecha)
+	if err != nil {
+		return nil, err
+	}
+
+	// Solo los dias de la semana que rigen la fecha y los horarios cuyo periodo la cubre; seDicta
+	// vuelve a verificar por curso (bloques de jornada especial, entradas propias del curso)
+	dias := cal.DiasHorario(fecha, f.CursoID)
+	if len(dias) == 0 {
+		return []models.Horario{}, nil
+	}
+	fueraDePeriodo := db.Model(&models.Periodo{}).Select("id").Where("fecha_inicio > ? OR fecha_fin < ?", f0, f0)
+	q := db.Preload("Asignatura").Preload("Profesor").Preload("Bloque").Preload("Curso").
+		Where("horarios.dia_semana IN ?", dias).
+		Where("(horarios.periodo_id IS NULL OR horarios.periodo_id NOT IN (?))", fueraDePeriodo)
+	if f.CursoID != nil {
+		q = q.Where("horarios.curso_id = ?", *f.CursoID)
+	}
+	if f.ProfesorID != nil {
+		reemplazos := db.Model(&models.HorarioExcepcion{}).Select("horario_id").
+			Where("fecha = ? AND profesor_reemplazo_id = ? AND tipo <> ?", f0, *f.ProfesorID, models.ExcepcionCancelado)
+		q = q.Where("(horarios.profesor_id = ? OR horarios.id IN (?))", *f.ProfesorID, reemplazos)
+	}
+	var horarios []models.Horario
+	if err := q.Find(&horarios).Error; err != nil {
+		return nil, err
+	}
+
+	periodos, err := periodosDe(db, horarios)
+	if err != nil {
+		return nil, err
+	}
+
+	vigentes := make([]models.Horario, 0, len(horarios))
+	ids := make([]uuid.UUID, 0, len(horarios))
+	for _, h := range horarios {
+		if !seDicta(cal, periodos, h, fecha) {
+			continue
+		}
+		vigentes = append(vigentes, h)
+		ids = append(ids, h.ID)
+	}
+
+	excepciones := map[uuid.UUID]*models.HorarioExcepcion{}
+	if len(ids) > 0 {
+		var rows []models.HorarioExcepcion
+		if err := db.Preload("ProfesorReemplazo").Preload("Asignatura").
+			Where("horario_id IN ? AND fecha = ?", ids, f0).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		for i := range rows {
+			excepciones[rows[i].HorarioID] = &rows[i]
+		}
+	}
+
+	out := make([]models.Horario, 0, len(vigentes))
+	for _, h := range vigentes {
+		efectivo := aplicar(h, excepciones[h.ID])
+		// El titular reemplazado sigue viendo su bloque (marcado); otro profesor solo si es el reemplazante
+		if f.ProfesorID != nil && efectivo.ProfesorID != *f.ProfesorID && h.ProfesorID != *f.ProfesorID {
+			continue
+		}
+		out = append(out, efectivo)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		ni, nj := numeroBloque(out[i]), numeroBloque(out[j])
+		if ni != nj {
+			return ni < nj
+		}
+		return out[i].CursoID.String() < out[j].CursoID.String()
+	})
+	return out, nil
+}
+
+// Resolver aplica calendario y excepciones a un horario en una fecha. ok=false si el bloque no
+// corresponde a esa fecha (dia no lectivo, otro dia de la semana, bloque suspendido o fuera de su periodo).
+func Resolver(db *gorm.DB, h models.Horario, fecha time.Time) (efectivo models.Horario, dia calendario.Dia, ok bool, err error) {
+	cal, err := calendario.Cargar(db, fecha, fecha)
+	if err != nil {
+		return h, dia, false, err
+	}
+	dia = cal.Dia(fecha, &h.CursoID)
+	if h.Bloque == nil {
+		var b models.BloqueHorario
+		if err := db.First(&b, "id = ?", h.BloqueID).Error; err == nil {
+			h.Bloque = &b
+		}
+	}
+	periodos, err := periodosDe(db, []models.Horario{h})
+	if err != nil {
+		return h, dia, false, err
+	}
+	if !seDicta(cal, periodos, h, fecha) {
+		return h, dia, false, nil
+	}
+
+	var e models.HorarioExcepcion
+	switch err := db.Where("horario_id = ? AND fecha = ?", h.ID, fecha.Format("2006-01-02")).First(&e).Error; err {
+	case nil:
+		return aplicar(h, &e), dia, true, nil
+	case gorm.ErrRecordNotFound:
+		return h, dia, true, nil
+	default:
+		return h, dia, false, err
+	}
+}
+
+// Cancelado indica si el bloque resuelto fue cancelado para la fecha
+func Cancelado(h models.Horario) bool {
+	return h.Excepcion != nil && h.Excepcion.Tipo == models.ExcepcionCancelado
+}
+
+// Pendientes retorna los bloques dictados en la fecha, terminados antes de corte (HH:MM), sin
+// asistencia registrada. Para fechas pasadas usar corte "24:00".
+func Pendientes(db *gorm.DB, fecha time.Time, f Filtro, corte string) ([]models.Horario, error) {
+	bloques, err := DelDia(db, fecha, f)
+	if err != nil {
+		return nil, err
+	}
+	candidatos := make([]models.Horario, 0, len(bloques))
+	ids := make([]uuid.UUID, 0, len(bloques))
+	for _, h := range bloques {
+		if Cancelado(h) || h.Bloque == nil || h.Bloque.HoraFin > corte {
+			continue
+		}
+		candidatos = append(candidatos, h)
+		ids = append(ids, h.ID)
+	}
+	if len(ids) == 0 {
+		return candidatos, nil
+	}
+
+	var registrados []uuid.UUID
+	if err := db.Model(&models.HorarioAsistenciaEstado{}).
+		Where("horario_id IN ? AND fecha = ?", ids, fecha.Format("2006-01-02")).
+		Pluck("horario_id", &registrados).Error; err != nil {
+		return nil, err
+	}
+	hecho := make(map[uuid.UUID]bool, len(registrados))
+	for _, id := range registrados {
+		hecho[id] = true
+	}
+	out := candidatos[:0]
+	for _, h := range candidatos {
+		if !hecho[h.ID] {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+func aplicar(h models.Horario, e *models.HorarioExcepcion) models.Horario {
+	if e == nil {
+		return h
+	}
+	efectivo := e.Aplicar(h)
+	efectivo.Excepcion = e
+	if efectivo.ProfesorID != h.ProfesorID {
+		titular := h.ProfesorID
+		efectivo.ProfesorTitularID = &titular
+	}
+	return efectivo
+}
+
+func seDicta(cal *calendario.Calendario, periodos map[uuid.UUID]models.Periodo, h models.Horario, fecha time.Time) bool {
+	dia := cal.Dia(fecha, &h.CursoID)
+	if !dia.Lectivo || dia.DiaSemanaHorario != h.DiaSemana {
+		return false
+	}
+	if h.Bloque != nil && !dia.SeDicta(h.Bloque.Numero) {
+		return false
+	}
+	if h.PeriodoID != nil {
+		p, ok := periodos[*h.PeriodoID]
+		f := fecha.Format("2006-01-02")
+		if ok && (f < p.FechaInicio.Format("2006-01-02") || f > p.FechaFin.Format("2006-01-02")) {
+			return false
+		}
+	}
+	return true
+}
+
+func periodosDe(db *gorm.DB, horarios []models.Horario) (map[uuid.UUID]models.Periodo, error) {
+	var ids []uuid.UUID
+	for _, h := range horarios {
+		if h.PeriodoID != nil {
+			ids = append(ids, *h.PeriodoID)
+		}
+	}
+	out := map[uuid.UUID]models.Periodo{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	var rows []models.Periodo
+	if err := db.Where("id IN ?", ids).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range rows {
+		out[p.ID] = p
+	}
+	return out, nil
+}
+
+func numeroBloque(h models.Horario) int {
+	if h.Bloque == nil {
+		return 0
+	}
+	return h.Bloque.Numero
+}
@@ -0,0 +1,144 @@
+package analytics
+
+import (
+	"log"
+	"time"
+
+	"github.com/school-monitoring/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Horario del bloque asociado al evento (eventos de asistencia guardan horario_id en datos)
+const joinHorarioEvento = `LEFT JOIN horarios h ON h.id = CASE WHEN e.datos->>'horario_id' ~ '^[0-9a-fA-F-]{36}$' THEN (e.datos->>'horario_id')::uuid END`
+
+const insertAgregado = `INSERT INTO agregados_diarios (fecha, metrica, curso_id, nivel, concepto_id, asignatura_id, profesor_id, bloque_id, cantidad, suma_minutos, created_at) `
+
+// Una consulta por metrica; reciben (desde, hasta) como [desde, hasta) en fechas
+var consultasAgregado = map[string]string{
+	models.MetricaEventos: insertAgregado + `
+		SELECT e.created_at::date, 'eventos', e.curso_id, COALESCE(c.nivel, ''), e.concepto_id, h.asignatura_id, h.profesor_id, h.bloque_id, COUNT(*), 0, now()
+		FROM eventos e
+		LEFT JOIN cursos c ON c.id = e.curso_id
+		` + joinHorarioEvento + `
+		WHERE e.deleted_at IS NULL AND e.created_at >= ? AND e.created_at < ?
+		GROUP BY 1, 3, 4, 5, 6, 7, 8`,
+
+	models.MetricaInasistencias: insertAgregado + `
+		SELECT a.fecha, 'inasistencias', h.curso_id, COALESCE(c.nivel, ''), NULL::uuid, h.asignatura_id, h.profesor_id, h.bloque_id, COUNT(*), 0, now()
+		FROM asistencias a
+		JOIN horarios h ON h.id = a.horario_id
+		LEFT JOIN cursos c ON c.id = h.curso_id
+		WHERE a.deleted_at IS NULL AND a.estado = 'ausente' AND a.fecha >= ? AND a.fecha < ?
+		GROUP BY 1, 3, 4, 6, 7, 8`,
+
+	models.MetricaAtrasos: insertAgregado + `
+		SELECT a.fecha, 'atrasos', h.curso_id, COALESCE(c.nivel, ''), NULL::uuid, h.asignatura_id, h.profesor_id, h.bloque_id, COUNT(*), 0, now()
+		FROM asistencias a
+		JOIN horarios h ON h.id = a.horario_id
+		LEFT JOIN cursos c ON c.id = h.curso_id
+		WHERE a.deleted_at IS NULL AND a.estado = 'atraso' AND a.fecha >= ? AND a.fecha < ?
+		GROUP BY 1, 3, 4, 6, 7, 8`,
+
+	models.MetricaAlertas: insertAgregado + `
+		SELECT al.created_at::date, 'alertas', al.curso_id, COALESCE(c.nivel, ''), e.concepto_id, h.asignatura_id, h.profesor_id, h.bloque_id, COUNT(*), 0, now()
+		FROM alertas al
+		LEFT JOIN cursos c ON c.id = al.curso_id
+		LEFT JOIN eventos e ON e.id = al.evento_id
+		` + joinHorarioEvento + `
+		WHERE al.deleted_at IS NULL AND al.created_at >= ? AND al.created_at < ?
+		GROUP BY 1, 3, 4, 5, 6, 7, 8`,
+
+	models.MetricaSLAAlertas: insertAgregado + `
+		SELECT al.cerrado_en::date, 'sla_alertas', al.curso_id, COALESCE(c.nivel, ''), e.concepto_id, h.asignatura_id, h.profesor_id, h.bloque_id,
+			COUNT(*), SUM(EXTRACT(EPOCH FROM (al.cerrado_en - al.created_at)) / 60), now()
+		FROM alertas al
+		LEFT JOIN cursos c ON c.id = al.curso_id
+		LEFT JOIN eventos e ON e.id = al.evento_id
+		` + joinHorarioEvento + `
+		WHERE al.deleted_at IS NULL AND al.estado = 'cerrada' AND al.cerrado_en IS NOT NULL
+			AND al.cerrado_en >= ? AND al.cerrado_en < ?
+		GROUP BY 1, 3, 4, 5, 6, 7, 8`,
+}
+
+// Refrescar regenera los agregados de [desde, hasta] (fechas inclusivas, dias del colegio) en una transaccion.
+func Refrescar(db *gorm.DB, desde, hasta time.Time) error {
+	zona := models.ZonaColegio()
+	ini := time.Date(desde.Year(), desde.Month(), desde.Day(), 0, 0, 0, 0, zona)
+	fin := time.Date(hasta.Year(), hasta.Month(), hasta.Day(), 0, 0, 0, 0, zona).AddDate(0, 0, 1)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("fecha >= ? AND fecha < ?", ini.Format("2006-01-02"), fin.Format("2006-01-02")).
+			Delete(&models.AgregadoDiario{}).Error; err != nil {
+			return err
+		}
+		for _, m := range []string{models.MetricaEventos, models.MetricaInasistencias, models.MetricaAtrasos, models.MetricaAlertas, models.MetricaSLAAlertas} {
+			desdeArg, hastaArg := interface{}(ini), interface{}(fin)
+			if m == models.MetricaInasistencias || m == models.MetricaAtrasos {
+				// asistencias.fecha es date
+				desdeArg, hastaArg = ini.Format("2006-01-02"), fin.Format("2006-01-02")
+			}
+			if err := tx.Exec(consultasAgregado[m], desdeArg, hastaArg).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// retrocesoSinPeriodo dias que se recalculan cada noche si hoy no cae en un periodo
+const retrocesoSinPeriodo = 31
+
+// inicioRefrescoNocturno primer dia que se recalcula cada noche: el inicio del periodo abierto (ahi caen
+// justificaciones, correcciones aprobadas y sync offline de dias pasados) o, sin periodo, el ultimo mes.
+func inicioRefrescoNocturno(db *gorm.DB, hoy time.Time) time.Time {
+	desde := hoy.AddDate(0, 0, -retrocesoSinPeriodo)
+	var periodo models.Periodo
+	if err := db.Where("fecha_inicio <= ? AND fecha_fin >= ?", hoy.Format("2006-01-02"), hoy.Format("2006-01-02")).
+		Order("fecha_inicio").First(&periodo).Error; err == nil {
+		desde = periodo.FechaInicio
+	}
+	return desde
+}
+
+// RunRefresco mantiene al dia los agregados de hoy y ayer cada 15 minutos y, una vez por dia (al arrancar
+// y al cambiar de dia), recalcula todo el periodo abierto para recoger cambios sobre fechas pasadas.
+// Si la tabla esta vacia (primer arranque) calcula el ultimo anio.
+func RunRefresco(db *gorm.DB, stop <-chan struct{}) {
+	var n int64
+	db.Model(&models.AgregadoDiario{}).Count(&n)
+	if n == 0 {
+		hoy := time.Now().In(models.ZonaColegio())
+		if err := Refrescar(db, hoy.AddDate(-1, 0, 0), hoy); err != nil {
+			log.Printf("analytics: backfill: %v", err)
+		}
+	}
+
+	ultimoNocturno := ""
+	refrescar := func() {
+		hoy := time.Now().In(models.ZonaColegio())
+		desde := hoy.AddDate(0, 0, -1)
+		dia := hoy.Format("2006-01-02")
+		if dia != ultimoNocturno {
+			if d := inicioRefrescoNocturno(db, hoy); d.Before(desde) {
+				desde = d
+			}
+		}
+		if err := Refrescar(db, desde, hoy); err != nil {
+			log.Printf("analytics: refresco: %v", err)
+			return
+		}
+		ultimoNocturno = dia
+	}
+	refrescar()
+
+	ticker := time.NewTicker(15 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			refrescar()
+		}
+	}
+}
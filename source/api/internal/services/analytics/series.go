@@ -0,0 +1,164 @@
+package analytics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Granularidades soportadas
+const (
+	GranularidadDia    = "dia"
+	GranularidadSemana = "semana"
+	GranularidadMes    = "mes"
+)
+
+var truncPorGranularidad = map[string]string{
+	GranularidadDia:    "day",
+	GranularidadSemana: "week",
+	GranularidadMes:    "month",
+}
+
+// Columna de agregados_diarios por dimension de agrupacion
+var columnaPorGrupo = map[string]string{
+	"curso":      "curso_id",
+	"nivel":      "nivel",
+	"concepto":   "concepto_id",
+	"asignatura": "asignatura_id",
+	"profesor":   "profesor_id",
+	"bloque":     "bloque_id",
+}
+
+// Consulta parametros de una serie
+type Consulta struct {
+	Metrica      string
+	Desde, Hasta time.Time
+	Granularidad string
+	Agrupar      string // "" = total
+	CursoID      *uuid.UUID
+	Nivel        string
+	ConceptoID   *uuid.UUID
+}
+
+// Punto de una serie
+type Punto struct {
+	Periodo         string   `json:"periodo"` // inicio del periodo, YYYY-MM-DD
+	GrupoID         string   `json:"grupo_id,omitempty"`
+	Grupo           string   `json:"grupo,omitempty"`
+	Cantidad        int64    `json:"cantidad"`
+	PromedioMinutos *float64 `json:"promedio_minutos,omitempty"` // solo sla_alertas
+}
+
+// EsGrupoValido indica si la dimension de agrupacion es soportada
+func EsGrupoValido(g string) bool {
+	_, ok := columnaPorGrupo[g]
+	return g == "" || ok
+}
+
+// EsGranularidadValida indica si la granularidad es soportada
+func EsGranularidadValida(g string) bool {
+	_, ok := truncPorGranularidad[g]
+	return ok
+}
+
+// Serie retorna la serie temporal de la metrica leyendo solo los agregados materializados.
+func Serie(db *gorm.DB, q Consulta) ([]Punto, error) {
+	trunc, ok := truncPorGranularidad[q.Granularidad]
+	if !ok {
+		return nil, fmt.Errorf("granularidad no soportada: %s", q.Granularidad)
+	}
+	col, ok := columnaPorGrupo[q.Agrupar]
+	grupo := "''"
+	if ok {
+		grupo = "COALESCE(" + col + "::text, '')"
+	}
+
+	type fila struct {
+		Periodo     time.Time
+		GrupoID     string
+		Cantidad    int64
+		SumaMinutos float64
+	}
+	var filas []fila
+	sel := fmt.Sprintf("date_trunc('%s', fecha)::date as periodo, %s as grupo_id, SUM(cantidad) as cantidad, SUM(suma_minutos) as suma_minutos", trunc, grupo)
+	tx := db.Model(&models.AgregadoDiario{}).Select(sel).
+		Where("metrica = ? AND fecha >= ? AND fecha <= ?", q.Metrica, q.Desde.Format("2006-01-02"), q.Hasta.Format("2006-01-02"))
+	if q.CursoID != nil {
+		tx = tx.Where("curso_id = ?", *q.CursoID)
+	}
+	if q.Nivel != "" {
+		tx = tx.Where("nivel = ?", q.Nivel)
+	}
+	if q.ConceptoID != nil {
+		tx = tx.Where("concepto_id = ?", *q.ConceptoID)
+	}
+	if err := tx.Group("1, 2").Order("1, 2").Scan(&filas).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(filas))
+	for _, f := range filas {
+		ids = append(ids, f.GrupoID)
+	}
+	nombres := nombresGrupo(db, q.Agrupar, ids)
+	out := make([]Punto, 0, len(filas))
+	for _, f := range filas {
+		p := Punto{Periodo: f.Periodo.Format("2006-01-02"), GrupoID: f.GrupoID, Cantidad: f.Cantidad}
+		if q.Agrupar != "" {
+			p.Grupo = nombres[f.GrupoID]
+			if p.Grupo == "" {
+				p.Grupo = "Sin " + q.Agrupar
+			}
+		}
+		if q.Metrica == models.MetricaSLAAlertas && f.Cantidad > 0 {
+			prom := f.SumaMinutos / float64(f.Cantidad)
+			p.PromedioMinutos = &prom
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// nombresGrupo resuelve etiquetas legibles para los ids del grupo
+func nombresGrupo(db *gorm.DB, agrupar string, grupos []string) map[string]string {
+	out := map[string]string{}
+	if agrupar == "nivel" {
+		for _, g := range grupos {
+			out[g] = g
+		}
+		return out
+	}
+	ids := []string{}
+	for _, g := range grupos {
+		if g != "" {
+			ids = append(ids, g)
+		}
+	}
+	if len(ids) == 0 {
+		return out
+	}
+	type par struct {
+		ID     string
+		Nombre string
+	}
+	var pares []par
+	switch agrupar {
+	case "curso":
+		db.Model(&models.Curso{}).Select("id::text as id, nombre").Where("id IN ?", ids).Scan(&pares)
+	case "concepto":
+		db.Model(&models.Concepto{}).Select("id::text as id, nombre").Where("id IN ?", ids).Scan(&pares)
+	case "asignatura":
+		db.Model(&models.Asignatura{}).Select("id::text as id, nombre").Where("id IN ?", ids).Scan(&pares)
+	case "profesor":
+		db.Model(&models.Usuario{}).Select("id::text as id, nombre").Where("id IN ?", ids).Scan(&pares)
+	case "bloque":
+		db.Model(&models.BloqueHorario{}).Select("id::text as id, 'Bloque ' || numero || ' (' || hora_inicio || ')' as nombre").Where("id IN ?", ids).Scan(&pares)
+	}
+	for _, p := range pares {
+		out[p.ID] = p.Nombre
+	}
+	return out
+}
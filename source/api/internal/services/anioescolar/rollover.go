@@ -0,0 +1,320 @@
+package anioescolar
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Errores de validacion del cierre de ano
+var (
+	ErrAnioCerrado   = errors.New("el ano escolar ya esta cerrado")
+	ErrAnioExistente = errors.New("el ano escolar destino ya existe")
+	ErrFechas        = errors.New("rango de fechas invalido")
+	ErrMapeoCursos   = errors.New("el mapeo de cursos referencia un curso inexistente")
+)
+
+// Orden de niveles para calcular el curso siguiente
+var ordenNivel = map[string]int{
+	models.NivelBasica: 1,
+	models.NivelMedia:  2,
+}
+
+// Opciones del cierre de ano (rollover)
+type Opciones struct {
+	Anio        int
+	FechaInicio time.Time
+	FechaFin    time.Time
+	TipoPeriodo string
+	// Curso destino explicito por curso origen; uuid.Nil = el curso egresa
+	MapeoCursos map[uuid.UUID]uuid.UUID
+	Repitentes  []uuid.UUID
+	// Copia el horario vigente al nuevo ano (si no, se parte sin horario)
+	CopiarHorarios bool
+	UsuarioID      uuid.UUID
+}
+
+// Resultado resumen del cierre
+type Resultado struct {
+	AnioEscolar        models.AnioEscolar `json:"anio_escolar"`
+	Promovidos         int                `json:"promovidos"`
+	Repitentes         int                `json:"repitentes"`
+	Egresados          int                `json:"egresados"`
+	HorariosArchivados int64              `json:"horarios_archivados"`
+	HorariosCopiados   int                `json:"horarios_copiados"`
+}
+
+// CursoSiguiente calcula el curso al que se promueve cada curso: mismo paralelo (letra final),
+// siguiente numero dentro del nivel y luego el primero del nivel siguiente. nil = egresa.
+func CursoSiguiente(cursos []models.Curso) map[uuid.UUID]*uuid.UUID {
+	type clave struct {
+		curso    models.Curso
+		rango    int
+		paralelo string
+	}
+	claves := make([]clave, 0, len(cursos))
+	for _, c := range cursos {
+		claves = append(claves, clave{curso: c, rango: ordenNivel[c.Nivel]*100 + numeroCurso(c.Nombre), paralelo: paraleloCurso(c.Nombre)})
+	}
+	sort.SliceStable(claves, func(i, j int) bool { return claves[i].rango < claves[j].rango })
+
+	out := make(map[uuid.UUID]*uuid.UUID, len(cursos))
+	for i, c := range claves {
+		out[c.curso.ID] = nil
+		for _, sig := range claves[i+1:] {
+			if sig.rango > c.rango && sig.paralelo == c.paralelo {
+				id := sig.curso.ID
+				out[c.curso.ID] = &id
+				break
+			}
+		}
+	}
+	return out
+}
+
+// destinos curso al que pasa cada curso: CursoSiguiente con el mapeo explicito encima.
+// Origen y destino del mapeo deben ser cursos existentes (destino uuid.Nil = el curso egresa).
+func destinos(cursos []models.Curso, mapeo map[uuid.UUID]uuid.UUID) (map[uuid.UUID]*uuid.UUID, error) {
+	siguiente := CursoSiguiente(cursos)
+	for origen, destino := range mapeo {
+		if _, ok := siguiente[origen]; !ok {
+			return nil, ErrMapeoCursos
+		}
+		if destino == uuid.Nil {
+			siguiente[origen] = nil
+			continue
+		}
+		if _, ok := siguiente[destino]; !ok {
+			return nil, ErrMapeoCursos
+		}
+		d := destino
+		siguiente[origen] = &d
+	}
+	return siguiente, nil
+}
+
+// numeroCurso extrae el numero inicial del nombre ("3 Basico A" -> 3)
+func numeroCurso(nombre string) int {
+	campos := strings.Fields(nombre)
+	if len(campos) == 0 {
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.TrimRight(campos[0], "°º"))
+	return n
+}
+
+// paraleloCurso extrae la letra del paralelo ("3 Basico A" -> "A"); vacio si no tiene
+func paraleloCurso(nombre string) string {
+	campos := strings.Fields(nombre)
+	if len(campos) < 3 {
+		return ""
+	}
+	ultimo := campos[len(campos)-1]
+	if len(ultimo) == 1 {
+		return strings.ToUpper(ultimo)
+	}
+	return ""
+}
+
+// Rollover cierra el ano escolar indicado y abre el siguiente en una sola transaccion:
+// promueve (o mantiene repitentes / egresa) a los alumnos, crea las matriculas nuevas,
+// archiva el horario del ano cerrado y activa el ano nuevo con sus periodos.
+func Rollover(db *gorm.DB, anioID uuid.UUID, op Opciones) (*Resultado, error) {
+	var res Resultado
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var viejo models.AnioEscolar
+		if err := tx.First(&viejo, "id = ?", anioID).Error; err != nil {
+			return err
+		}
+		if viejo.CerradoEn != nil {
+			return ErrAnioCerrado
+		}
+
+		if op.Anio == 0 {
+			op.Anio = viejo.Anio + 1
+		}
+		if op.FechaInicio.IsZero() {
+			op.FechaInicio = viejo.FechaInicio.AddDate(op.Anio-viejo.Anio, 0, 0)
+		}
+		if op.FechaFin.IsZero() {
+			op.FechaFin = viejo.FechaFin.AddDate(op.Anio-viejo.Anio, 0, 0)
+		}
+		if !op.FechaFin.After(op.FechaInicio) || !op.FechaInicio.After(viejo.FechaInicio) {
+			return ErrFechas
+		}
+		var existe int64
+		tx.Model(&models.AnioEscolar{}).Where("anio = ?", op.Anio).Count(&existe)
+		if existe > 0 {
+			return ErrAnioExistente
+		}
+
+		// Ano nuevo con sus periodos
+		nuevo := models.AnioEscolar{Anio: op.Anio, FechaInicio: op.FechaInicio, FechaFin: op.FechaFin}
+		if err := tx.Create(&nuevo).Error; err != nil {
+			return err
+		}
+		periodos := models.PeriodosPorDefecto(nuevo, op.TipoPeriodo)
+		if err := tx.Create(&periodos).Error; err != nil {
+			return err
+		}
+
+		// Alumnos activos sin matricula en el ano cerrado (datos previos a la gestion de anos)
+		if err := tx.Exec(`
+			INSERT INTO matriculas (id, alumno_id, anio_escolar_id, curso_id, estado, fecha_alta, created_at, updated_at)
+			SELECT gen_random_uuid(), a.id, ?, a.curso_id, ?, ?, NOW(), NOW()
+			FROM alumnos a
+			WHERE a.activo = true AND a.deleted_at IS NULL
+			  AND NOT EXISTS (SELECT 1 FROM matriculas m WHERE m.alumno_id = a.id AND m.anio_escolar_id = ? AND m.deleted_at IS NULL)`,
+			viejo.ID, models.MatriculaActiva, viejo.FechaInicio, viejo.ID).Error; err != nil {
+			return err
+		}
+
+		var cursos []models.Curso
+		if err := tx.Find(&cursos).Error; err != nil {
+			return err
+		}
+		siguiente, err := destinos(cursos, op.MapeoCursos)
+		if err != nil {
+			return err
+		}
+		repite := make(map[uuid.UUID]bool, len(op.Repitentes))
+		for _, id := range op.Repitentes {
+			repite[id] = true
+		}
+
+		var matriculas []models.Matricula
+		if err := tx.Where("anio_escolar_id = ? AND estado = ?", viejo.ID, models.MatriculaActiva).
+			Find(&matriculas).Error; err != nil {
+			return err
+		}
+
+		// Agrupar cambios para actualizar en bloque
+		promovidos := map[uuid.UUID][]uuid.UUID{} // curso destino -> alumnos
+		porEstado := map[string][]uuid.UUID{}     // estado final de la matricula vieja -> matriculas
+		var egresados []uuid.UUID
+		nuevas := make([]models.Matricula, 0, len(matriculas))
+		for _, m := range matriculas {
+			destino := &m.CursoID
+			estado := models.MatriculaRepitente
+			if !repite[m.AlumnoID] {
+				destino = siguiente[m.CursoID]
+				estado = models.MatriculaPromovida
+				if destino == nil {
+					estado = models.MatriculaEgresada
+				}
+			}
+			porEstado[estado] = append(porEstado[estado], m.ID)
+
+			switch estado {
+			case models.MatriculaEgresada:
+				egresados = append(egresados, m.AlumnoID)
+				res.Egresados++
+				continue
+			case models.MatriculaRepitente:
+				res.Repitentes++
+			default:
+				promovidos[*destino] = append(promovidos[*destino], m.AlumnoID)
+				res.Promovidos++
+			}
+			nuevas = append(nuevas, models.Matricula{
+				AlumnoID:      m.AlumnoID,
+				AnioEscolarID: nuevo.ID,
+				CursoID:       *destino,
+				Estado:        models.MatriculaActiva,
+				FechaAlta:     nuevo.FechaInicio,
+			})
+		}
+
+		fechaBaja := viejo.FechaFin
+		for estado, ids := range porEstado {
+			if err := tx.Model(&models.Matricula{}).Where("id IN ?", ids).
+				Updates(map[string]interface{}{"estado": estado, "fecha_baja": fechaBaja}).Error; err != nil {
+				return err
+			}
+		}
+		for cursoID, alumnos := range promovidos {
+			if err := tx.Model(&models.Alumno{}).Where("id IN ?", alumnos).Update("curso_id", cursoID).Error; err != nil {
+				return err
+			}
+		}
+		if len(egresados) > 0 {
+			if err := tx.Model(&models.Alumno{}).Where("id IN ?", egresados).Update("activo", false).Error; err != nil {
+				return err
+			}
+		}
+		if len(nuevas) > 0 {
+			if err := tx.CreateInBatches(&nuevas, 500).Error; err != nil {
+				return err
+			}
+		}
+
+		// Horario: los registros sin ano pertenecen al ano que se cierra; se copian (opcional) y se archivan
+		if err := tx.Model(&models.Horario{}).Where("anio_escolar_id IS NULL").
+			Update("anio_escolar_id", viejo.ID).Error; err != nil {
+			return err
+		}
+		if op.CopiarHorarios {
+			var vigentes []models.Horario
+			if err := tx.Where("anio_escolar_id = ?", viejo.ID).Find(&vigentes).Error; err != nil {
+				return err
+			}
+			copias := make([]models.Horario, 0, len(vigentes))
+			for _, h := range vigentes {
+				copias = append(copias, models.Horario{
+					CursoID:       h.CursoID,
+					AsignaturaID:  h.AsignaturaID,
+					ProfesorID:    h.ProfesorID,
+					BloqueID:      h.BloqueID,
+					DiaSemana:     h.DiaSemana,
+					Sala:          h.Sala,
+					AnioEscolarID: &nuevo.ID,
+				})
+			}
+			if len(copias) > 0 {
+				if err := tx.CreateInBatches(&copias, 500).Error; err != nil {
+					return err
+				}
+			}
+			res.HorariosCopiados = len(copias)
+		}
+		archivados := tx.Where("anio_escolar_id = ?", viejo.ID).Delete(&models.Horario{})
+		if archivados.Error != nil {
+			return archivados.Error
+		}
+		res.HorariosArchivados = archivados.RowsAffected
+
+		// Cerrar el ano viejo y activar el nuevo
+		antes := viejo
+		ahora := time.Now()
+		viejo.Activo = false
+		viejo.CerradoEn = &ahora
+		viejo.CerradoPor = &op.UsuarioID
+		if err := tx.Save(&viejo).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.AnioEscolar{}).Where("id <> ?", nuevo.ID).Update("activo", false).Error; err != nil {
+			return err
+		}
+		nuevo.Activo = true
+		if err := tx.Model(&nuevo).Update("activo", true).Error; err != nil {
+			return err
+		}
+		nuevo.Periodos = periodos
+
+		_ = models.CrearAuditoria(tx, "anios_escolares", viejo.ID, models.AuditoriaUpdate, &antes, &viejo, &op.UsuarioID)
+		_ = models.CrearAuditoria(tx, "anios_escolares", nuevo.ID, models.AuditoriaInsert, nil, &nuevo, &op.UsuarioID)
+
+		res.AnioEscolar = nuevo
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
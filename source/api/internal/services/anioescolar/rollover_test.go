@@ -0,0 +1,172 @@
+package anioescolar
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/testutil"
+)
+
+func curso(nombre, nivel string) models.Curso {
+	return models.Curso{ID: uuid.New(), Nombre: nombre, Nivel: nivel}
+}
+
+func TestNumeroYParaleloCurso(t *testing.T) {
+	cases := []struct {
+		nombre   string
+		numero   int
+		paralelo string
+	}{
+		{"3 Basico A", 3, "A"},
+		{"3° Basico b", 3, "B"},
+		{"4 Medio", 4, ""},
+		{"1 Medio AB", 1, ""},
+		{"", 0, ""},
+	}
+	for _, tc := range cases {
+		if n := numeroCurso(tc.nombre); n != tc.numero {
+			t.Errorf("numeroCurso(%q) = %d, esperaba %d", tc.nombre, n, tc.numero)
+		}
+		if p := paraleloCurso(tc.nombre); p != tc.paralelo {
+			t.Errorf("paraleloCurso(%q) = %q, esperaba %q", tc.nombre, p, tc.paralelo)
+		}
+	}
+}
+
+func TestCursoSiguiente(t *testing.T) {
+	b1A, b2A, b1B := curso("1 Basico A", models.NivelBasica), curso("2 Basico A", models.NivelBasica), curso("1 Basico B", models.NivelBasica)
+	b8A, m1A, m4A := curso("8 Basico A", models.NivelBasica), curso("1 Medio A", models.NivelMedia), curso("4 Medio A", models.NivelMedia)
+	sig := CursoSiguiente([]models.Curso{m4A, b2A, b1B, m1A, b1A, b8A})
+
+	cases := []struct {
+		name    string
+		origen  models.Curso
+		destino *models.Curso
+	}{
+		{"mismo paralelo, numero siguiente", b1A, &b2A},
+		{"siguiente existente aunque salte numeros", b2A, &b8A},
+		{"basica pasa a media", b8A, &m1A},
+		{"sin curso siguiente en el paralelo egresa", b1B, nil},
+		{"ultimo curso egresa", m4A, nil},
+	}
+	for _, tc := range cases {
+		got := sig[tc.origen.ID]
+		switch {
+		case tc.destino == nil && got != nil:
+			t.Errorf("%s: esperaba egreso, got %v", tc.name, *got)
+		case tc.destino != nil && (got == nil || *got != tc.destino.ID):
+			t.Errorf("%s: got %v, esperaba %s", tc.name, got, tc.destino.Nombre)
+		}
+	}
+}
+
+func TestDestinosMapeo(t *testing.T) {
+	b1A, b2A, b2B := curso("1 Basico A", models.NivelBasica), curso("2 Basico A", models.NivelBasica), curso("2 Basico B", models.NivelBasica)
+	cursos := []models.Curso{b1A, b2A, b2B}
+
+	sig, err := destinos(cursos, map[uuid.UUID]uuid.UUID{b1A.ID: b2B.ID, b2A.ID: uuid.Nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig[b1A.ID] == nil || *sig[b1A.ID] != b2B.ID {
+		t.Errorf("mapeo explicito ignorado: %v", sig[b1A.ID])
+	}
+	if sig[b2A.ID] != nil {
+		t.Errorf("destino uuid.Nil debe egresar: %v", sig[b2A.ID])
+	}
+
+	for name, mapeo := range map[string]map[uuid.UUID]uuid.UUID{
+		"destino inexistente": {b1A.ID: uuid.New()},
+		"origen inexistente":  {uuid.New(): b2A.ID},
+	} {
+		if _, err := destinos(cursos, mapeo); !errors.Is(err, ErrMapeoCursos) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
+
+func TestRollover(t *testing.T) {
+	db := testutil.DB(t)
+	// Desactivar otros anos del ambiente de pruebas (se revierte al final)
+	db.Model(&models.AnioEscolar{}).Where("activo = ?", true).Update("activo", false)
+
+	viejo := models.AnioEscolar{Anio: 2998, FechaInicio: time.Date(2998, 3, 1, 0, 0, 0, 0, time.UTC), FechaFin: time.Date(2998, 12, 20, 0, 0, 0, 0, time.UTC), Activo: true}
+	c1 := models.Curso{Nombre: "1 Basico Z", Nivel: models.NivelBasica}
+	c2 := models.Curso{Nombre: "2 Basico Z", Nivel: models.NivelBasica}
+	asig := models.Asignatura{Nombre: "Test " + uuid.NewString()[:8]}
+	prof := models.Usuario{Email: uuid.NewString()[:8] + "@test.cl", PasswordHash: "x", Nombre: "Prof", Rol: models.RolProfesor}
+	bloque := models.BloqueHorario{Numero: 98, HoraInicio: "08:00", HoraFin: "08:45"}
+	for _, v := range []interface{}{&viejo, &c1, &c2, &asig, &prof, &bloque} {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	alumno := func(cursoID uuid.UUID) models.Alumno {
+		a := models.Alumno{CursoID: cursoID, Nombre: "Test", Apellido: "Test", Rut: "T-" + uuid.NewString()[:8], Activo: true}
+		if err := db.Create(&a).Error; err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	promovido, repitente, egresado := alumno(c1.ID), alumno(c1.ID), alumno(c2.ID)
+	horario := models.Horario{CursoID: c1.ID, AsignaturaID: asig.ID, ProfesorID: prof.ID, BloqueID: bloque.ID, DiaSemana: 1, AnioEscolarID: &viejo.ID}
+	if err := db.Create(&horario).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 2 Basico Z no tiene curso siguiente: egresa. Mapeo a un curso inexistente se rechaza sin cambios.
+	if _, err := Rollover(db, viejo.ID, Opciones{MapeoCursos: map[uuid.UUID]uuid.UUID{c1.ID: uuid.New()}}); !errors.Is(err, ErrMapeoCursos) {
+		t.Fatalf("mapeo invalido: err = %v", err)
+	}
+	res, err := Rollover(db, viejo.ID, Opciones{Repitentes: []uuid.UUID{repitente.ID}, CopiarHorarios: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Otros alumnos activos del ambiente tambien se procesan: se validan solo los del test
+	if res.AnioEscolar.Anio != 2999 || !res.AnioEscolar.Activo || len(res.AnioEscolar.Periodos) != 2 {
+		t.Fatalf("ano nuevo: %+v", res.AnioEscolar)
+	}
+
+	cursoNuevo := func(a models.Alumno) (uuid.UUID, bool) {
+		var m models.Matricula
+		err := db.Where("alumno_id = ? AND anio_escolar_id = ?", a.ID, res.AnioEscolar.ID).First(&m).Error
+		return m.CursoID, err == nil
+	}
+	if c, ok := cursoNuevo(promovido); !ok || c != c2.ID {
+		t.Errorf("promovido: curso %v", c)
+	}
+	if c, ok := cursoNuevo(repitente); !ok || c != c1.ID {
+		t.Errorf("repitente: curso %v", c)
+	}
+	if _, ok := cursoNuevo(egresado); ok {
+		t.Error("egresado con matricula en el ano nuevo")
+	}
+	var eg models.Alumno
+	db.First(&eg, "id = ?", egresado.ID)
+	if eg.Activo {
+		t.Error("egresado sigue activo")
+	}
+	var p models.Alumno
+	db.First(&p, "id = ?", promovido.ID)
+	if p.CursoID != c2.ID {
+		t.Error("Alumno.CursoID del promovido no se actualizo")
+	}
+
+	var copias int64
+	db.Model(&models.Horario{}).Where("curso_id = ? AND anio_escolar_id = ?", c1.ID, res.AnioEscolar.ID).Count(&copias)
+	if copias != 1 {
+		t.Errorf("horarios copiados: %d", copias)
+	}
+	var vigentes int64
+	db.Model(&models.Horario{}).Where("id = ?", horario.ID).Count(&vigentes)
+	if vigentes != 0 {
+		t.Error("el horario del ano cerrado no se archivo")
+	}
+
+	if _, err := Rollover(db, viejo.ID, Opciones{}); !errors.Is(err, ErrAnioCerrado) {
+		t.Errorf("segundo cierre: err = %v", err)
+	}
+}
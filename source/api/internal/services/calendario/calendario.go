@@ -0,0 +1,281 @@
+package calendario
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Tipo informado para los dias sin entrada en el calendario
+const (
+	TipoNormal      = "normal"
+	TipoFinDeSemana = "fin_de_semana"
+	TipoFueraAnio   = "fuera_anio_escolar"
+)
+
+// Maximo de dias que se retrocede al buscar dias lectivos (evita recorrer sin fin si no hay clases)
+const maxRetroceso = 400
+
+// Dia describe si un dia es lectivo para un curso y con que horario.
+type Dia struct {
+	Fecha   string `json:"fecha"` // YYYY-MM-DD
+	Lectivo bool   `json:"lectivo"`
+	Tipo    string `json:"tipo"`
+	Motivo  string `json:"motivo,omitempty"`
+	// Dia de la semana cuyo horario rige (1..5); 0 si no es lectivo
+	DiaSemanaHorario int `json:"dia_semana_horario,omitempty"`
+	// Bloques que se dictan; nil = todos
+	Bloques []int `json:"bloques,omitempty"`
+}
+
+// SeDicta indica si el bloque numero se dicta ese dia
+func (d Dia) SeDicta(numero int) bool {
+	if !d.Lectivo {
+		return false
+	}
+	if d.Bloques == nil {
+		return true
+	}
+	for _, b := range d.Bloques {
+		if b == numero {
+			return true
+		}
+	}
+	return false
+}
+
+// Calendario vista en memoria de las entradas y anos escolares de un rango de fechas.
+type Calendario struct {
+	entradas []models.CalendarioEntrada
+	anios    []models.AnioEscolar
+}
+
+// Cargar lee las entradas que tocan [desde, hasta] y los anos escolares configurados.
+func Cargar(db *gorm.DB, desde, hasta time.Time) (*Calendario, error) {
+	c := &Calendario{}
+	if err := db.Where("fecha_inicio <= ? AND fecha_fin >= ?", hasta.Format("2006-01-02"), desde.Format("2006-01-02")).
+		Find(&c.entradas).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Find(&c.anios).Error; err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Dia resuelve un dia para un curso (nil = establecimiento). Las entradas del curso tienen
+// prioridad sobre las generales y, dentro del mismo alcance, un dia no lectivo gana.
+func (c *Calendario) Dia(fecha time.Time, cursoID *uuid.UUID) Dia {
+	f := fecha.Format("2006-01-02")
+	dow := models.DiaSemanaDeFecha(fecha)
+	d := Dia{Fecha: f, Lectivo: dow <= 5, Tipo: TipoNormal}
+	if dow <= 5 {
+		d.DiaSemanaHorario = dow
+	} else {
+		d.Tipo = TipoFinDeSemana
+	}
+
+	if !c.enAnioEscolar(f) {
+		return Dia{Fecha: f, Tipo: TipoFueraAnio}
+	}
+
+	var elegida *models.CalendarioEntrada
+	prioridad := 0
+	for i := range c.entradas {
+		e := &c.entradas[i]
+		if f < e.FechaInicio.Format("2006-01-02") || f > e.FechaFin.Format("2006-01-02") {
+			continue
+		}
+		p := 1
+		if e.CursoID != nil {
+			if cursoID == nil || *e.CursoID != *cursoID {
+				continue
+			}
+			p = 3
+		}
+		if e.EsNoLectivo() {
+			p++
+		}
+		if p > prioridad {
+			elegida, prioridad = e, p
+		}
+	}
+	if elegida == nil {
+		return d
+	}
+
+	d.Tipo = elegida.Tipo
+	d.Motivo = elegida.Nombre
+	if elegida.EsNoLectivo() {
+		d.Lectivo = false
+		d.DiaSemanaHorario = 0
+		return d
+	}
+	d.Lectivo = true
+	if elegida.DiaSemanaHorario != nil {
+		d.DiaSemanaHorario = *elegida.DiaSemanaHorario
+	}
+	if d.DiaSemanaHorario < 1 || d.DiaSemanaHorario > 5 {
+		// Recuperativo sin horario asignado: no hay bloques que dictar
+		d.Lectivo = false
+		d.DiaSemanaHorario = 0
+		return d
+	}
+	d.Bloques = elegida.NumerosBloque()
+	return d
+}
+
+// DiasHorario retorna los dias de la semana (1..5) cuyo horario rige en la fecha: el del curso, o con
+// cursoID nil la union del establecimiento y de los cursos con entradas propias ese dia.
+func (c *Calendario) DiasHorario(fecha time.Time, cursoID *uuid.UUID) []int {
+	if cursoID != nil {
+		if d := c.Dia(fecha, cursoID); d.Lectivo {
+			return []int{d.DiaSemanaHorario}
+		}
+		return nil
+	}
+	var out []int
+	agregar := func(d Dia) {
+		if !d.Lectivo {
+			return
+		}
+		for _, v := range out {
+			if v == d.DiaSemanaHorario {
+				return
+			}
+		}
+		out = append(out, d.DiaSemanaHorario)
+	}
+	agregar(c.Dia(fecha, nil))
+	f := fecha.Format("2006-01-02")
+	for i := range c.entradas {
+		e := &c.entradas[i]
+		if e.CursoID != nil && f >= e.FechaInicio.Format("2006-01-02") && f <= e.FechaFin.Format("2006-01-02") {
+			agregar(c.Dia(fecha, e.CursoID))
+		}
+	}
+	return out
+}
+
+// enAnioEscolar: sin anos configurados todo el ano cuenta; si hay, la fecha debe caer en alguno
+func (c *Calendario) enAnioEscolar(f string) bool {
+	if len(c.anios) == 0 {
+		return true
+	}
+	for _, a := range c.anios {
+		if f >= a.FechaInicio.Format("2006-01-02") && f <= a.FechaFin.Format("2006-01-02") {
+			return true
+		}
+	}
+	return false
+}
+
+// Dias resuelve cada dia de [desde, hasta]
+func (c *Calendario) Dias(desde, hasta time.Time, cursoID *uuid.UUID) []Dia {
+	var out []Dia
+	for d := desde; !d.After(hasta); d = d.AddDate(0, 0, 1) {
+		out = append(out, c.Dia(d, cursoID))
+	}
+	return out
+}
+
+// DiasLectivos retorna las fechas lectivas de [desde, hasta]
+func (c *Calendario) DiasLectivos(desde, hasta time.Time, cursoID *uuid.UUID) []time.Time {
+	var out []time.Time
+	for d := desde; !d.After(hasta); d = d.AddDate(0, 0, 1) {
+		if c.Dia(d, cursoID).Lectivo {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// Retroceder retorna el instante que queda n dias lectivos antes de t, saltando los no lectivos
+// (ej. n=1 un lunes => el viernes anterior a la misma hora).
+func (c *Calendario) Retroceder(t time.Time, n int, cursoID *uuid.UUID) time.Time {
+	out := t
+	for i := 0; n > 0 && i < maxRetroceso; i++ {
+		out = out.AddDate(0, 0, -1)
+		if c.Dia(out, cursoID).Lectivo {
+			n--
+		}
+	}
+	return out
+}
+
+// DiaDe resuelve un solo dia consultando la base
+func DiaDe(db *gorm.DB, fecha time.Time, cursoID *uuid.UUID) (Dia, error) {
+	c, err := Cargar(db, fecha, fecha)
+	if err != nil {
+		return Dia{}, err
+	}
+	return c.Dia(fecha, cursoID), nil
+}
+
+// DiasLectivos retorna las fechas lectivas de [desde, hasta] consultando la base
+func DiasLectivos(db *gorm.DB, desde, hasta time.Time, cursoID *uuid.UUID) ([]time.Time, error) {
+	c, err := Cargar(db, desde, hasta)
+	if err != nil {
+		return nil, err
+	}
+	return c.DiasLectivos(desde, hasta, cursoID), nil
+}
+
+// InicioVentana retorna el inicio de una ventana de n dias lectivos que termina en t
+func InicioVentana(db *gorm.DB, t time.Time, n int, cursoID *uuid.UUID) (time.Time, error) {
+	// Margen para fines de semana, feriados y vacaciones dentro de la ventana
+	c, err := Cargar(db, t.AddDate(0, 0, -(n*2+90)), t)
+	if err != nil {
+		return t, err
+	}
+	return c.Retroceder(t, n, cursoID), nil
+}
+
+// Cache mantiene cargado el calendario de los ultimos maxRetroceso dias para evaluar muchas ventanas
+// sin ir a la base en cada una. Se recarga al cambiar el dia o al vencer ttl (toma ediciones recientes).
+type Cache struct {
+	db  *gorm.DB
+	ttl time.Duration
+
+	mu      sync.Mutex
+	dia     string
+	cargado time.Time
+	cal     *Calendario
+}
+
+// NewCache crea un cache vacio; la primera consulta carga el calendario
+func NewCache(db *gorm.DB, ttl time.Duration) *Cache {
+	return &Cache{db: db, ttl: ttl}
+}
+
+// calendario retorna la vista vigente para el dia de t, recargandola si corresponde
+func (c *Cache) calendario(t time.Time) (*Calendario, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	dia := t.Format("2006-01-02")
+	if c.cal != nil && c.dia == dia && time.Since(c.cargado) < c.ttl {
+		return c.cal, nil
+	}
+	cal, err := Cargar(c.db, t.AddDate(0, 0, -maxRetroceso), t)
+	if err != nil {
+		return nil, err
+	}
+	c.cal, c.dia, c.cargado = cal, dia, time.Now()
+	return cal, nil
+}
+
+// InicioVentana como la funcion del paquete, pero usando el calendario en cache.
+// Un t de otro dia que el actual se resuelve consultando la base.
+func (c *Cache) InicioVentana(t time.Time, n int, cursoID *uuid.UUID) (time.Time, error) {
+	if t.Format("2006-01-02") != time.Now().In(t.Location()).Format("2006-01-02") {
+		return InicioVentana(c.db, t, n, cursoID)
+	}
+	cal, err := c.calendario(t)
+	if err != nil {
+		return t, err
+	}
+	return cal.Retroceder(t, n, cursoID), nil
+}
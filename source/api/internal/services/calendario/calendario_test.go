@@ -0,0 +1,151 @@
+package calendario
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/testutil"
+)
+
+func TestDiaPrioridad(t *testing.T) {
+	curso := uuid.New()
+	otro := uuid.New()
+	martes := 2
+	entrada := func(tipo, nombre, desde, hasta string, cursoID *uuid.UUID) models.CalendarioEntrada {
+		return models.CalendarioEntrada{Tipo: tipo, Nombre: nombre, FechaInicio: fecha(desde), FechaFin: fecha(hasta), CursoID: cursoID}
+	}
+	recuperativo := entrada(models.CalendarioRecuperativo, "Recupera martes", "2026-04-04", "2026-04-04", nil)
+	recuperativo.DiaSemanaHorario = &martes
+	especial := entrada(models.CalendarioJornadaEspecial, "Jornada corta", "2026-04-09", "2026-04-09", &curso)
+	especial.Bloques = json.RawMessage(`[1,2]`)
+
+	c := &Calendario{
+		anios: []models.AnioEscolar{{Anio: 2026, FechaInicio: fecha("2026-03-02"), FechaFin: fecha("2026-12-18")}},
+		entradas: []models.CalendarioEntrada{
+			entrada(models.CalendarioFeriado, "Feriado", "2026-04-03", "2026-04-03", nil),
+			// Curso gana a lo general: clase recuperativa del curso en un feriado
+			entrada(models.CalendarioJornadaEspecial, "General", "2026-04-06", "2026-04-06", nil),
+			entrada(models.CalendarioSuspension, "Suspension curso", "2026-04-06", "2026-04-06", &curso),
+			// Mismo alcance: no lectivo gana
+			entrada(models.CalendarioJornadaEspecial, "Especial", "2026-04-07", "2026-04-07", nil),
+			entrada(models.CalendarioSuspension, "Corte de luz", "2026-04-07", "2026-04-07", nil),
+			// Curso gana aunque lo general sea no lectivo
+			entrada(models.CalendarioVacaciones, "Vacaciones", "2026-04-08", "2026-04-08", nil),
+			entrada(models.CalendarioJornadaEspecial, "Salida pedagogica", "2026-04-08", "2026-04-08", &curso),
+			recuperativo,
+			entrada(models.CalendarioRecuperativo, "Recuperativo sin dia", "2026-04-11", "2026-04-11", nil),
+			especial,
+		},
+	}
+
+	cases := []struct {
+		name    string
+		fecha   string
+		curso   *uuid.UUID
+		lectivo bool
+		tipo    string
+		motivo  string
+		dia     int
+		bloques []int
+	}{
+		{"dia normal", "2026-04-02", &curso, true, TipoNormal, "", 4, nil},
+		{"fin de semana", "2026-04-05", &curso, false, TipoFinDeSemana, "", 0, nil},
+		{"fuera del ano escolar", "2026-02-02", &curso, false, TipoFueraAnio, "", 0, nil},
+		{"feriado general", "2026-04-03", &curso, false, models.CalendarioFeriado, "Feriado", 0, nil},
+		{"curso sobre general", "2026-04-06", &curso, false, models.CalendarioSuspension, "Suspension curso", 0, nil},
+		{"entrada de otro curso no aplica", "2026-04-06", &otro, true, models.CalendarioJornadaEspecial, "General", 1, nil},
+		{"establecimiento ignora entradas de curso", "2026-04-06", nil, true, models.CalendarioJornadaEspecial, "General", 1, nil},
+		{"no lectivo gana en mismo alcance", "2026-04-07", &curso, false, models.CalendarioSuspension, "Corte de luz", 0, nil},
+		{"curso gana a vacaciones generales", "2026-04-08", &curso, true, models.CalendarioJornadaEspecial, "Salida pedagogica", 3, nil},
+		{"recuperativo en sabado", "2026-04-04", &curso, true, models.CalendarioRecuperativo, "Recupera martes", 2, nil},
+		{"recuperativo sin dia", "2026-04-11", &curso, false, models.CalendarioRecuperativo, "Recuperativo sin dia", 0, nil},
+		{"jornada especial con bloques", "2026-04-09", &curso, true, models.CalendarioJornadaEspecial, "Jornada corta", 4, []int{1, 2}},
+	}
+	for _, tc := range cases {
+		d := c.Dia(fecha(tc.fecha), tc.curso)
+		if d.Lectivo != tc.lectivo || d.Tipo != tc.tipo || d.Motivo != tc.motivo || d.DiaSemanaHorario != tc.dia || !reflect.DeepEqual(d.Bloques, tc.bloques) {
+			t.Errorf("%s: %+v", tc.name, d)
+		}
+	}
+
+	if d := c.Dia(fecha("2026-04-09"), &curso); d.SeDicta(3) || !d.SeDicta(2) {
+		t.Errorf("SeDicta: %+v", d)
+	}
+}
+
+func TestRetroceder(t *testing.T) {
+	c := &Calendario{entradas: []models.CalendarioEntrada{
+		{Tipo: models.CalendarioFeriado, FechaInicio: fecha("2026-04-03"), FechaFin: fecha("2026-04-03")},
+	}}
+	// lunes 6 - 1 dia lectivo: salta fin de semana y el feriado del viernes
+	if got := c.Retroceder(fecha("2026-04-06"), 1, nil); got.Format("2006-01-02") != "2026-04-02" {
+		t.Errorf("Retroceder = %s", got.Format("2006-01-02"))
+	}
+	if got := c.Retroceder(fecha("2026-04-06"), 0, nil); !got.Equal(fecha("2026-04-06")) {
+		t.Errorf("n=0 debe retornar t: %s", got)
+	}
+}
+
+// La cache carga una vez por dia: una entrada creada despues no se ve hasta vencer el ttl
+func TestCacheInicioVentana(t *testing.T) {
+	db := testutil.DB(t)
+	c := NewCache(db, time.Hour)
+	ahora := time.Now()
+
+	primero, err := c.InicioVentana(ahora, 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	desde := ahora.AddDate(0, 0, -10)
+	if err := db.Create(&models.CalendarioEntrada{Tipo: models.CalendarioSuspension, Nombre: "Test", FechaInicio: desde, FechaFin: ahora.AddDate(0, 0, -1)}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := c.InicioVentana(ahora, 3, nil); !got.Equal(primero) {
+		t.Errorf("cache recargada antes del ttl: %s vs %s", got, primero)
+	}
+	directo, err := InicioVentana(db, ahora, 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !directo.Before(desde) {
+		t.Errorf("la suspension debe empujar la ventana antes de %s: %s", desde, directo)
+	}
+
+	c.ttl = 0
+	if got, _ := c.InicioVentana(ahora, 3, nil); !got.Equal(directo) {
+		t.Errorf("tras vencer el ttl: %s, esperaba %s", got, directo)
+	}
+}
+
+func TestDiasHorario(t *testing.T) {
+	curso := uuid.New()
+	martes := 2
+	rec := models.CalendarioEntrada{Tipo: models.CalendarioRecuperativo, FechaInicio: fecha("2026-04-04"), FechaFin: fecha("2026-04-04"), CursoID: &curso, DiaSemanaHorario: &martes}
+	c := &Calendario{entradas: []models.CalendarioEntrada{
+		rec,
+		{Tipo: models.CalendarioSuspension, FechaInicio: fecha("2026-04-07"), FechaFin: fecha("2026-04-07"), CursoID: &curso},
+	}}
+
+	cases := []struct {
+		name  string
+		fecha string
+		curso *uuid.UUID
+		want  []int
+	}{
+		{"dia normal", "2026-04-06", nil, []int{1}},
+		{"sabado con recuperativo de un curso", "2026-04-04", nil, []int{2}},
+		{"sabado del curso", "2026-04-04", &curso, []int{2}},
+		{"suspension solo del curso", "2026-04-07", nil, []int{2}},
+		{"curso suspendido", "2026-04-07", &curso, nil},
+		{"domingo", "2026-04-05", nil, nil},
+	}
+	for _, tc := range cases {
+		if got := c.DiasHorario(fecha(tc.fecha), tc.curso); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: %v, esperaba %v", tc.name, got, tc.want)
+		}
+	}
+}
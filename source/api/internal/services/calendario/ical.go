@@ -0,0 +1,236 @@
+package calendario
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/school-monitoring/backend/internal/models"
+)
+
+// Dominio usado en los UID generados al exportar entradas creadas a mano
+const dominioUID = "calendario.school-monitoring"
+
+// EventoICal evento de dia completo leido de un archivo iCalendar (RFC 5545)
+type EventoICal struct {
+	UID         string
+	Nombre      string
+	Descripcion string
+	Categorias  []string
+	Inicio      time.Time
+	Fin         time.Time // inclusivo
+}
+
+// LeerICal extrae los VEVENT de un iCalendar. Solo importa fechas (los eventos con hora
+// se toman como el dia completo). Los eventos sin DTSTART se omiten.
+func LeerICal(r io.Reader) ([]EventoICal, error) {
+	lineas, err := desplegar(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []EventoICal
+	var ev *EventoICal
+	finExclusivo := false
+	for _, l := range lineas {
+		nombre, params, valor := propiedad(l)
+		switch {
+		case nombre == "BEGIN" && strings.EqualFold(valor, "VEVENT"):
+			ev = &EventoICal{}
+			finExclusivo = false
+		case nombre == "END" && strings.EqualFold(valor, "VEVENT"):
+			if ev != nil && !ev.Inicio.IsZero() {
+				if ev.Fin.IsZero() {
+					ev.Fin = ev.Inicio
+				} else if finExclusivo && ev.Fin.After(ev.Inicio) {
+					// DTEND de un evento de dia completo es exclusivo
+					ev.Fin = ev.Fin.AddDate(0, 0, -1)
+				}
+				if ev.Fin.Before(ev.Inicio) {
+					ev.Fin = ev.Inicio
+				}
+				out = append(out, *ev)
+			}
+			ev = nil
+		case ev == nil:
+			continue
+		case nombre == "UID":
+			ev.UID = valor
+		case nombre == "SUMMARY":
+			ev.Nombre = desescapar(valor)
+		case nombre == "DESCRIPTION":
+			ev.Descripcion = desescapar(valor)
+		case nombre == "CATEGORIES":
+			for _, c := range strings.Split(valor, ",") {
+				if c = strings.TrimSpace(desescapar(c)); c != "" {
+					ev.Categorias = append(ev.Categorias, c)
+				}
+			}
+		case nombre == "DTSTART":
+			t, _, err := fechaICal(params, valor)
+			if err != nil {
+				return nil, fmt.Errorf("DTSTART invalido %q: %w", valor, err)
+			}
+			ev.Inicio = t
+		case nombre == "DTEND":
+			t, soloFecha, err := fechaICal(params, valor)
+			if err != nil {
+				return nil, fmt.Errorf("DTEND invalido %q: %w", valor, err)
+			}
+			ev.Fin = t
+			finExclusivo = soloFecha
+		}
+	}
+	return out, nil
+}
+
+// TipoDeCategorias busca un tipo de calendario valido entre las categorias (o retorna def)
+func TipoDeCategorias(categorias []string, def string) string {
+	for _, c := range categorias {
+		t := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(c), " ", "_"))
+		if models.EsTipoCalendarioValido(t) {
+			return t
+		}
+	}
+	return def
+}
+
+// EscribirICal genera un iCalendar con una entrada por evento de dia completo
+func EscribirICal(w io.Writer, nombreCalendario string, entradas []models.CalendarioEntrada) error {
+	bw := bufio.NewWriter(w)
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	escribir := func(l string) {
+		plegar(bw, l)
+	}
+	escribir("BEGIN:VCALENDAR")
+	escribir("VERSION:2.0")
+	escribir("PRODID:-//School Monitoring//Calendario Escolar//ES")
+	escribir("CALSCALE:GREGORIAN")
+	escribir("METHOD:PUBLISH")
+	escribir("X-WR-CALNAME:" + escapar(nombreCalendario))
+	for _, e := range entradas {
+		uid := e.UID
+		if uid == "" {
+			uid = e.ID.String() + "@" + dominioUID
+		}
+		escribir("BEGIN:VEVENT")
+		escribir("UID:" + escapar(uid))
+		escribir("DTSTAMP:" + stamp)
+		escribir("DTSTART;VALUE=DATE:" + e.FechaInicio.Format("20060102"))
+		escribir("DTEND;VALUE=DATE:" + e.FechaFin.AddDate(0, 0, 1).Format("20060102"))
+		escribir("SUMMARY:" + escapar(e.Nombre))
+		if e.Descripcion != "" {
+			escribir("DESCRIPTION:" + escapar(e.Descripcion))
+		}
+		escribir("CATEGORIES:" + strings.ToUpper(e.Tipo))
+		if e.EsNoLectivo() {
+			escribir("TRANSP:OPAQUE")
+		} else {
+			escribir("TRANSP:TRANSPARENT")
+		}
+		escribir("END:VEVENT")
+	}
+	escribir("END:VCALENDAR")
+	return bw.Flush()
+}
+
+// desplegar une las lineas plegadas (continuaciones que empiezan con espacio o tab)
+func desplegar(r io.Reader) ([]string, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	var out []string
+	for sc.Scan() {
+		l := strings.TrimRight(sc.Text(), "\r")
+		if (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(out) > 0 {
+			out[len(out)-1] += l[1:]
+			continue
+		}
+		if l != "" {
+			out = append(out, l)
+		}
+	}
+	return out, sc.Err()
+}
+
+// propiedad separa "NOMBRE;PARAM=X:valor"
+func propiedad(l string) (string, map[string]string, string) {
+	i := strings.Index(l, ":")
+	if i < 0 {
+		return strings.ToUpper(l), nil, ""
+	}
+	cab, valor := l[:i], l[i+1:]
+	partes := strings.Split(cab, ";")
+	params := map[string]string{}
+	for _, p := range partes[1:] {
+		if kv := strings.SplitN(p, "=", 2); len(kv) == 2 {
+			params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return strings.ToUpper(partes[0]), params, valor
+}
+
+// fechaICal interpreta DATE (20250918) o DATE-TIME (20250918T080000[Z]); retorna si era solo fecha
+func fechaICal(params map[string]string, v string) (time.Time, bool, error) {
+	v = strings.TrimSpace(v)
+	if params["VALUE"] == "DATE" || len(v) == 8 {
+		t, err := time.Parse("20060102", v)
+		return t, true, err
+	}
+	loc := time.Local
+	if tz := params["TZID"]; tz != "" {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		}
+	}
+	var t time.Time
+	var err error
+	if strings.HasSuffix(v, "Z") {
+		t, err = time.Parse("20060102T150405Z", v)
+		t = t.In(time.Local)
+	} else {
+		t, err = time.ParseInLocation("20060102T150405", v, loc)
+	}
+	if err != nil {
+		return t, false, err
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), false, nil
+}
+
+func escapar(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return r.Replace(s)
+}
+
+func desescapar(s string) string {
+	r := strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+	return r.Replace(s)
+}
+
+// plegar escribe la linea cortandola a 75 octetos sin partir caracteres UTF-8
+func plegar(w *bufio.Writer, l string) {
+	const max = 75
+	primera := true
+	for len(l) > 0 {
+		limite := max
+		if !primera {
+			limite = max - 1
+		}
+		if len(l) <= limite {
+			limite = len(l)
+		} else {
+			for limite > 0 && l[limite]&0xC0 == 0x80 {
+				limite--
+			}
+		}
+		if !primera {
+			w.WriteString(" ")
+		}
+		w.WriteString(l[:limite])
+		w.WriteString("\r\n")
+		l = l[limite:]
+		primera = false
+	}
+}
@@ -0,0 +1,123 @@
+package calendario
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+)
+
+func fecha(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func TestICalIdaYVuelta(t *testing.T) {
+	entradas := []models.CalendarioEntrada{
+		{ID: uuid.New(), Tipo: models.CalendarioFeriado, Nombre: "Fiestas Patrias; desfile, acto", FechaInicio: fecha("2026-09-18"), FechaFin: fecha("2026-09-19"),
+			Descripcion: "Linea 1\nLinea 2 con \\ barra"},
+		{ID: uuid.New(), UID: "vacaciones-2026@mineduc.cl", Tipo: models.CalendarioVacaciones, FechaInicio: fecha("2026-07-06"), FechaFin: fecha("2026-07-17"),
+			Nombre: "Vacaciones de invierno con un nombre bastante largo para obligar a plegar la linea en mas de un tramo: ñandú, corazón"},
+		{ID: uuid.New(), Tipo: models.CalendarioJornadaEspecial, Nombre: "Jornada", FechaInicio: fecha("2026-05-04"), FechaFin: fecha("2026-05-04")},
+	}
+
+	var buf bytes.Buffer
+	if err := EscribirICal(&buf, "Calendario, 2026", entradas); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(l) > 75 {
+			t.Errorf("linea de %d octetos sin plegar: %q", len(l), l)
+		}
+	}
+
+	eventos, err := LeerICal(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(eventos) != len(entradas) {
+		t.Fatalf("eventos: %d, esperaba %d", len(eventos), len(entradas))
+	}
+	for i, e := range entradas {
+		ev := eventos[i]
+		if ev.Nombre != e.Nombre || ev.Descripcion != e.Descripcion {
+			t.Errorf("%d: texto %q / %q", i, ev.Nombre, ev.Descripcion)
+		}
+		// DTEND exclusivo al escribir, inclusivo al leer
+		if !ev.Inicio.Equal(e.FechaInicio) || !ev.Fin.Equal(e.FechaFin) {
+			t.Errorf("%d: rango %s..%s, esperaba %s..%s", i, ev.Inicio.Format("2006-01-02"), ev.Fin.Format("2006-01-02"),
+				e.FechaInicio.Format("2006-01-02"), e.FechaFin.Format("2006-01-02"))
+		}
+		if TipoDeCategorias(ev.Categorias, "") != e.Tipo {
+			t.Errorf("%d: categorias %v", i, ev.Categorias)
+		}
+	}
+	if eventos[0].UID != entradas[0].ID.String()+"@"+dominioUID || eventos[1].UID != entradas[1].UID {
+		t.Errorf("UID: %q, %q", eventos[0].UID, eventos[1].UID)
+	}
+}
+
+func TestLeerICal(t *testing.T) {
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"UID:a",
+		"SUMMARY:Feriado pleg",
+		" ado en dos lineas", // el espacio inicial de la continuacion se descarta
+		"DTSTART;VALUE=DATE:20260501",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:b",
+		"SUMMARY:Con hora",
+		"DTSTART;TZID=America/Santiago:20260921T080000",
+		"DTEND;TZID=America/Santiago:20260921T180000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:c",
+		"SUMMARY:Rango de dia completo",
+		"DTSTART:20260706",
+		"DTEND:20260718",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:d",
+		"SUMMARY:Sin fecha se omite",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	eventos, err := LeerICal(strings.NewReader(ics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		uid, nombre, inicio, fin string
+	}{
+		{"a", "Feriado plegado en dos lineas", "2026-05-01", "2026-05-01"}, // sin DTEND: un dia
+		{"b", "Con hora", "2026-09-21", "2026-09-21"},                      // DTEND con hora no es exclusivo
+		{"c", "Rango de dia completo", "2026-07-06", "2026-07-17"},
+	}
+	if len(eventos) != len(cases) {
+		t.Fatalf("eventos: %+v", eventos)
+	}
+	for i, tc := range cases {
+		ev := eventos[i]
+		if ev.UID != tc.uid || ev.Nombre != tc.nombre || ev.Inicio.Format("2006-01-02") != tc.inicio || ev.Fin.Format("2006-01-02") != tc.fin {
+			t.Errorf("%s: %+v", tc.uid, ev)
+		}
+	}
+
+	if _, err := LeerICal(strings.NewReader("BEGIN:VEVENT\r\nDTSTART:2026XX01\r\nEND:VEVENT\r\n")); err == nil {
+		t.Error("DTSTART invalido aceptado")
+	}
+}
+
+func TestEscaparICal(t *testing.T) {
+	for _, s := range []string{`a;b,c`, `barra \ final\`, "dos\nlineas", `\n literal`} {
+		if got := desescapar(escapar(s)); got != s {
+			t.Errorf("%q -> %q -> %q", s, escapar(s), got)
+		}
+	}
+}
@@ -0,0 +1,447 @@
+// Package esquema valida Evento.Datos contra el JSON Schema declarado por su Concepto.
+//
+// Soporta el subconjunto de JSON Schema que usan los formularios dinamicos:
+// type, properties, required, additionalProperties (bool), enum, const, minimum, maximum,
+// minLength, maxLength, pattern, format (date, date-time, time, uuid, email), items, minItems, maxItems.
+// title, description, default y demas palabras de presentacion se aceptan y se ignoran.
+package esquema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrorCampo error de validacion de un campo (ruta con puntos e indices, ej. "testigos[0].nombre")
+type ErrorCampo struct {
+	Campo string `json:"campo"`
+	Error string `json:"error"`
+}
+
+// Esquema JSON Schema compilado
+type Esquema struct {
+	raiz *nodo
+}
+
+type nodo struct {
+	tipos       []string
+	propiedades map[string]*nodo
+	requeridos  []string
+	adicionales *bool
+	enum        []interface{}
+	constante   interface{}
+	tieneConst  bool
+	minimo      *float64
+	maximo      *float64
+	minLargo    *int
+	maxLargo    *int
+	patron      *regexp.Regexp
+	formato     string
+	items       *nodo
+	minItems    *int
+	maxItems    *int
+}
+
+var tiposValidos = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true, "integer": true, "boolean": true, "null": true,
+}
+
+var formatosValidos = map[string]bool{
+	"date": true, "date-time": true, "time": true, "uuid": true, "email": true,
+}
+
+// Compilar valida el esquema y lo prepara para validar datos. La raiz debe ser de tipo object.
+func Compilar(raw json.RawMessage) (*Esquema, error) {
+	var def map[string]interface{}
+	if err := json.Unmarshal(raw, &def); err != nil {
+		return nil, errors.New("schema must be a JSON object")
+	}
+	raiz, err := compilarNodo(def, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(raiz.tipos) != 1 || raiz.tipos[0] != "object" {
+		return nil, errors.New(`schema root must have "type": "object"`)
+	}
+	return &Esquema{raiz: raiz}, nil
+}
+
+func compilarNodo(def map[string]interface{}, ruta string) (*nodo, error) {
+	n := &nodo{}
+	mal := func(clave, detalle string) error {
+		return fmt.Errorf("%s: %q %s", rutaEsquema(ruta), clave, detalle)
+	}
+
+	switch t := def["type"].(type) {
+	case nil:
+	case string:
+		n.tipos = []string{t}
+	case []interface{}:
+		for _, v := range t {
+			s, ok := v.(string)
+			if !ok {
+				return nil, mal("type", "must be a string or array of strings")
+			}
+			n.tipos = append(n.tipos, s)
+		}
+	default:
+		return nil, mal("type", "must be a string or array of strings")
+	}
+	for _, t := range n.tipos {
+		if !tiposValidos[t] {
+			return nil, mal("type", "has unknown type "+t)
+		}
+	}
+
+	if v, ok := def["properties"]; ok {
+		props, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, mal("properties", "must be an object")
+		}
+		n.propiedades = make(map[string]*nodo, len(props))
+		for nombre, p := range props {
+			pd, ok := p.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: property %q must be an object", rutaEsquema(ruta), nombre)
+			}
+			hijo, err := compilarNodo(pd, unir(ruta, nombre))
+			if err != nil {
+				return nil, err
+			}
+			n.propiedades[nombre] = hijo
+		}
+	}
+	if v, ok := def["required"]; ok {
+		lista, ok := v.([]interface{})
+		if !ok {
+			return nil, mal("required", "must be an array of strings")
+		}
+		for _, r := range lista {
+			s, ok := r.(string)
+			if !ok {
+				return nil, mal("required", "must be an array of strings")
+			}
+			n.requeridos = append(n.requeridos, s)
+		}
+	}
+	if v, ok := def["additionalProperties"]; ok {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, mal("additionalProperties", "must be a boolean")
+		}
+		n.adicionales = &b
+	}
+	if v, ok := def["enum"]; ok {
+		lista, ok := v.([]interface{})
+		if !ok || len(lista) == 0 {
+			return nil, mal("enum", "must be a non-empty array")
+		}
+		n.enum = lista
+	}
+	if v, ok := def["const"]; ok {
+		n.constante, n.tieneConst = v, true
+	}
+
+	var err error
+	if n.minimo, err = numero(def, "minimum"); err != nil {
+		return nil, mal("minimum", err.Error())
+	}
+	if n.maximo, err = numero(def, "maximum"); err != nil {
+		return nil, mal("maximum", err.Error())
+	}
+	if n.minLargo, err = entero(def, "minLength"); err != nil {
+		return nil, mal("minLength", err.Error())
+	}
+	if n.maxLargo, err = entero(def, "maxLength"); err != nil {
+		return nil, mal("maxLength", err.Error())
+	}
+	if n.minItems, err = entero(def, "minItems"); err != nil {
+		return nil, mal("minItems", err.Error())
+	}
+	if n.maxItems, err = entero(def, "maxItems"); err != nil {
+		return nil, mal("maxItems", err.Error())
+	}
+	if v, ok := def["pattern"]; ok {
+		s, ok := v.(string)
+		if !ok {
+			return nil, mal("pattern", "must be a string")
+		}
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, mal("pattern", "is not a valid regular expression")
+		}
+		n.patron = re
+	}
+	if v, ok := def["format"]; ok {
+		s, ok := v.(string)
+		if !ok || !formatosValidos[s] {
+			return nil, mal("format", "must be one of date, date-time, time, uuid, email")
+		}
+		n.formato = s
+	}
+	if v, ok := def["items"]; ok {
+		id, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, mal("items", "must be an object")
+		}
+		if n.items, err = compilarNodo(id, ruta+"[]"); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// Validar valida los datos; datos vacios o null se tratan como {}. Retorna nil si son validos.
+func (e *Esquema) Validar(datos json.RawMessage) []ErrorCampo {
+	var v interface{} = map[string]interface{}{}
+	if d := bytes.TrimSpace(datos); len(d) > 0 && !bytes.Equal(d, []byte("null")) {
+		dec := json.NewDecoder(bytes.NewReader(d))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return []ErrorCampo{{Campo: "", Error: "must be valid JSON"}}
+		}
+	}
+	var errs []ErrorCampo
+	e.raiz.validar(v, "", &errs)
+	return errs
+}
+
+// Validar compila el esquema y valida los datos. Sin esquema no hay restricciones.
+func Validar(schema json.RawMessage, datos json.RawMessage) ([]ErrorCampo, error) {
+	if len(bytes.TrimSpace(schema)) == 0 || bytes.Equal(bytes.TrimSpace(schema), []byte("null")) {
+		return nil, nil
+	}
+	e, err := Compilar(schema)
+	if err != nil {
+		return nil, err
+	}
+	return e.Validar(datos), nil
+}
+
+func (n *nodo) validar(v interface{}, ruta string, errs *[]ErrorCampo) {
+	agregar := func(msg string) {
+		*errs = append(*errs, ErrorCampo{Campo: ruta, Error: msg})
+	}
+
+	if len(n.tipos) > 0 {
+		ok := false
+		for _, t := range n.tipos {
+			if esTipo(v, t) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			agregar("must be of type " + strings.Join(n.tipos, " or "))
+			return
+		}
+	}
+	if n.tieneConst && !igual(v, n.constante) {
+		agregar(fmt.Sprintf("must be %v", n.constante))
+	}
+	if n.enum != nil {
+		ok := false
+		for _, op := range n.enum {
+			if igual(v, op) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			opciones := make([]string, len(n.enum))
+			for i, op := range n.enum {
+				opciones[i] = fmt.Sprint(op)
+			}
+			agregar("must be one of: " + strings.Join(opciones, ", "))
+		}
+	}
+
+	switch x := v.(type) {
+	case json.Number:
+		f, _ := x.Float64()
+		if n.minimo != nil && f < *n.minimo {
+			agregar(fmt.Sprintf("must be >= %v", *n.minimo))
+		}
+		if n.maximo != nil && f > *n.maximo {
+			agregar(fmt.Sprintf("must be <= %v", *n.maximo))
+		}
+	case string:
+		largo := len([]rune(x))
+		if n.minLargo != nil && largo < *n.minLargo {
+			agregar(fmt.Sprintf("must have at least %d characters", *n.minLargo))
+		}
+		if n.maxLargo != nil && largo > *n.maxLargo {
+			agregar(fmt.Sprintf("must have at most %d characters", *n.maxLargo))
+		}
+		if n.patron != nil && !n.patron.MatchString(x) {
+			agregar("does not match the expected pattern")
+		}
+		if n.formato != "" && !formatoValido(n.formato, x) {
+			agregar("must be a valid " + n.formato)
+		}
+	case []interface{}:
+		if n.minItems != nil && len(x) < *n.minItems {
+			agregar(fmt.Sprintf("must have at least %d items", *n.minItems))
+		}
+		if n.maxItems != nil && len(x) > *n.maxItems {
+			agregar(fmt.Sprintf("must have at most %d items", *n.maxItems))
+		}
+		if n.items != nil {
+			for i, item := range x {
+				n.items.validar(item, fmt.Sprintf("%s[%d]", ruta, i), errs)
+			}
+		}
+	case map[string]interface{}:
+		for _, r := range n.requeridos {
+			if val, ok := x[r]; !ok || val == nil {
+				*errs = append(*errs, ErrorCampo{Campo: unir(ruta, r), Error: "is required"})
+			}
+		}
+		claves := make([]string, 0, len(x))
+		for k := range x {
+			claves = append(claves, k)
+		}
+		sort.Strings(claves)
+		for _, k := range claves {
+			if p, ok := n.propiedades[k]; ok {
+				if x[k] != nil || len(p.tipos) > 0 {
+					p.validar(x[k], unir(ruta, k), errs)
+				}
+			} else if n.adicionales != nil && !*n.adicionales {
+				*errs = append(*errs, ErrorCampo{Campo: unir(ruta, k), Error: "is not allowed"})
+			}
+		}
+	}
+}
+
+func esTipo(v interface{}, tipo string) bool {
+	switch tipo {
+	case "null":
+		return v == nil
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(json.Number)
+		return ok
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return false
+		}
+		if _, err := n.Int64(); err == nil {
+			return true
+		}
+		f, err := n.Float64()
+		return err == nil && f == float64(int64(f))
+	case "array":
+		_, ok := v.([]interface{})
+		return ok
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	}
+	return false
+}
+
+// igual compara un valor de los datos (numeros json.Number) con uno del esquema (numeros float64)
+func igual(dato, esperado interface{}) bool {
+	a, err1 := json.Marshal(normalizar(dato))
+	b, err2 := json.Marshal(normalizar(esperado))
+	return err1 == nil && err2 == nil && bytes.Equal(a, b)
+}
+
+func normalizar(v interface{}) interface{} {
+	switch x := v.(type) {
+	case json.Number:
+		f, _ := x.Float64()
+		return f
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i := range x {
+			out[i] = normalizar(x[i])
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k := range x {
+			out[k] = normalizar(x[k])
+		}
+		return out
+	}
+	return v
+}
+
+var reEmail = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+func formatoValido(formato, s string) bool {
+	switch formato {
+	case "date":
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "time":
+		if _, err := time.Parse("15:04:05", s); err == nil {
+			return true
+		}
+		_, err := time.Parse("15:04", s)
+		return err == nil
+	case "uuid":
+		_, err := uuid.Parse(s)
+		return err == nil
+	case "email":
+		return reEmail.MatchString(s)
+	}
+	return true
+}
+
+func numero(def map[string]interface{}, clave string) (*float64, error) {
+	v, ok := def[clave]
+	if !ok {
+		return nil, nil
+	}
+	f, ok := v.(float64)
+	if !ok {
+		return nil, errors.New("must be a number")
+	}
+	return &f, nil
+}
+
+func entero(def map[string]interface{}, clave string) (*int, error) {
+	v, ok := def[clave]
+	if !ok {
+		return nil, nil
+	}
+	f, ok := v.(float64)
+	if !ok || f < 0 || f != float64(int(f)) {
+		return nil, errors.New("must be a non-negative integer")
+	}
+	n := int(f)
+	return &n, nil
+}
+
+func unir(ruta, campo string) string {
+	if ruta == "" {
+		return campo
+	}
+	return ruta + "." + campo
+}
+
+func rutaEsquema(ruta string) string {
+	if ruta == "" {
+		return "schema"
+	}
+	return "schema." + ruta
+}
@@ -0,0 +1,131 @@
+package esquema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompilarInvalido(t *testing.T) {
+	cases := []struct {
+		name   string
+		schema string
+		error  string
+	}{
+		{"no es objeto", `[1]`, "must be a JSON object"},
+		{"raiz sin tipo", `{"properties": {}}`, "root must have"},
+		{"raiz no objeto", `{"type": "string"}`, "root must have"},
+		{"tipo desconocido", `{"type": "object", "properties": {"a": {"type": "fecha"}}}`, `schema.a: "type" has unknown type fecha`},
+		{"tipo no string", `{"type": "object", "properties": {"a": {"type": 1}}}`, `"type" must be`},
+		{"propiedad no objeto", `{"type": "object", "properties": {"a": 1}}`, `property "a" must be an object`},
+		{"required no lista", `{"type": "object", "required": "a"}`, `"required" must be an array`},
+		{"required con numero", `{"type": "object", "required": [1]}`, `"required" must be an array`},
+		{"enum vacio", `{"type": "object", "properties": {"a": {"enum": []}}}`, `"enum" must be a non-empty array`},
+		{"minimum texto", `{"type": "object", "properties": {"a": {"minimum": "1"}}}`, `"minimum" must be a number`},
+		{"maxLength negativo", `{"type": "object", "properties": {"a": {"maxLength": -1}}}`, `"maxLength" must be a non-negative integer`},
+		{"minItems decimal", `{"type": "object", "properties": {"a": {"minItems": 1.5}}}`, `"minItems" must be a non-negative integer`},
+		{"pattern invalido", `{"type": "object", "properties": {"a": {"pattern": "("}}}`, `"pattern" is not a valid regular expression`},
+		{"format desconocido", `{"type": "object", "properties": {"a": {"format": "rut"}}}`, `"format" must be one of`},
+		{"additionalProperties objeto", `{"type": "object", "additionalProperties": {}}`, `"additionalProperties" must be a boolean`},
+		{"items anidado invalido", `{"type": "object", "properties": {"l": {"type": "array", "items": {"type": "x"}}}}`, `schema.l[]: "type"`},
+	}
+	for _, tc := range cases {
+		_, err := Compilar(json.RawMessage(tc.schema))
+		if err == nil || !strings.Contains(err.Error(), tc.error) {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+	}
+}
+
+const schemaFormulario = `{
+	"type": "object",
+	"required": ["gravedad", "fecha"],
+	"additionalProperties": false,
+	"properties": {
+		"gravedad": {"type": "string", "enum": ["leve", "grave"]},
+		"fecha": {"type": "string", "format": "date"},
+		"hora": {"type": "string", "format": "time"},
+		"minutos": {"type": "integer", "minimum": 0, "maximum": 120},
+		"nota": {"type": ["string", "null"], "maxLength": 5},
+		"codigo": {"type": "string", "pattern": "^[A-Z]{3}$"},
+		"version": {"const": 2},
+		"lugar": {
+			"type": "object",
+			"required": ["sala"],
+			"properties": {"sala": {"type": "string", "minLength": 1}, "piso": {"type": "integer"}}
+		},
+		"testigos": {
+			"type": "array",
+			"minItems": 1,
+			"maxItems": 2,
+			"items": {"type": "object", "required": ["nombre"], "properties": {"nombre": {"type": "string"}, "email": {"format": "email"}}}
+		}
+	}
+}`
+
+func TestValidar(t *testing.T) {
+	e, err := Compilar(json.RawMessage(schemaFormulario))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := `"gravedad": "leve", "fecha": "2026-03-02"`
+	cases := []struct {
+		name  string
+		datos string
+		want  []ErrorCampo
+	}{
+		{"minimo valido", `{` + base + `}`, nil},
+		{"completo valido", `{` + base + `, "hora": "08:15", "minutos": 30, "nota": null, "codigo": "ABC", "version": 2,
+			"lugar": {"sala": "A1", "piso": 2}, "testigos": [{"nombre": "Ana", "email": "ana@colegio.cl"}]}`, nil},
+		{"entero escrito como decimal", `{` + base + `, "minutos": 30.0}`, nil},
+		{"requeridos faltantes", `{}`, []ErrorCampo{{"gravedad", "is required"}, {"fecha", "is required"}}},
+		{"requerido null", `{"gravedad": null, "fecha": "2026-03-02"}`, []ErrorCampo{{"gravedad", "is required"}, {"gravedad", "must be of type string"}}},
+		{"tipo incorrecto", `{"gravedad": 1, "fecha": "2026-03-02"}`, []ErrorCampo{{"gravedad", "must be of type string"}}},
+		{"enum", `{"gravedad": "media", "fecha": "2026-03-02"}`, []ErrorCampo{{"gravedad", "must be one of: leve, grave"}}},
+		{"formato fecha", `{"gravedad": "leve", "fecha": "02-03-2026"}`, []ErrorCampo{{"fecha", "must be a valid date"}}},
+		{"formato hora", `{` + base + `, "hora": "8h"}`, []ErrorCampo{{"hora", "must be a valid time"}}},
+		{"entero con decimales", `{` + base + `, "minutos": 1.5}`, []ErrorCampo{{"minutos", "must be of type integer"}}},
+		{"bajo el minimo", `{` + base + `, "minutos": -1}`, []ErrorCampo{{"minutos", "must be >= 0"}}},
+		{"sobre el maximo", `{` + base + `, "minutos": 121}`, []ErrorCampo{{"minutos", "must be <= 120"}}},
+		{"largo maximo en runas", `{` + base + `, "nota": "ñandú"}`, nil},
+		{"largo maximo", `{` + base + `, "nota": "123456"}`, []ErrorCampo{{"nota", "must have at most 5 characters"}}},
+		{"patron", `{` + base + `, "codigo": "abc"}`, []ErrorCampo{{"codigo", "does not match the expected pattern"}}},
+		{"const", `{` + base + `, "version": 1}`, []ErrorCampo{{"version", "must be 2"}}},
+		{"propiedad adicional", `{` + base + `, "extra": 1}`, []ErrorCampo{{"extra", "is not allowed"}}},
+		{"objeto anidado", `{` + base + `, "lugar": {"sala": "", "piso": "2"}}`,
+			[]ErrorCampo{{"lugar.piso", "must be of type integer"}, {"lugar.sala", "must have at least 1 characters"}}},
+		{"objeto anidado sin requerido", `{` + base + `, "lugar": {}}`, []ErrorCampo{{"lugar.sala", "is required"}}},
+		{"arreglo vacio", `{` + base + `, "testigos": []}`, []ErrorCampo{{"testigos", "must have at least 1 items"}}},
+		{"arreglo largo", `{` + base + `, "testigos": [{"nombre": "a"}, {"nombre": "b"}, {"nombre": "c"}]}`,
+			[]ErrorCampo{{"testigos", "must have at most 2 items"}}},
+		{"items", `{` + base + `, "testigos": [{"nombre": "a"}, {"email": "no-es-email"}]}`,
+			[]ErrorCampo{{"testigos[1].nombre", "is required"}, {"testigos[1].email", "must be a valid email"}}},
+		{"no es objeto", `[1]`, []ErrorCampo{{"", "must be of type object"}}},
+		{"json invalido", `{`, []ErrorCampo{{"", "must be valid JSON"}}},
+	}
+	for _, tc := range cases {
+		got := e.Validar(json.RawMessage(tc.datos))
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: %v, esperaba %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// Datos vacios o null equivalen a {} y un concepto sin esquema no restringe
+func TestValidarSinDatosNiEsquema(t *testing.T) {
+	for _, datos := range []string{"", "null", "  "} {
+		errs, err := Validar(json.RawMessage(`{"type": "object", "required": ["a"]}`), json.RawMessage(datos))
+		if err != nil || len(errs) != 1 || errs[0].Campo != "a" {
+			t.Errorf("datos %q: %v %v", datos, errs, err)
+		}
+	}
+	for _, schema := range []string{"", "null"} {
+		if errs, err := Validar(json.RawMessage(schema), json.RawMessage(`{"x": 1}`)); errs != nil || err != nil {
+			t.Errorf("schema %q: %v %v", schema, errs, err)
+		}
+	}
+	if _, err := Validar(json.RawMessage(`{"type": "array"}`), nil); err == nil {
+		t.Error("schema invalido aceptado")
+	}
+}
@@ -0,0 +1,188 @@
+package eventbus
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/school-monitoring/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Topicos publicados por el orquestador
+const (
+	TopicEventoCreado  = "evento_creado"
+	TopicEventoCerrado = "evento_cerrado"
+)
+
+const (
+	pollInterval = time.Second
+	batchSize    = 20
+	leaseTTL     = 2 * time.Minute
+	maxIntentos  = 8
+)
+
+// Handler procesa un mensaje dentro de la transaccion que lo marca como procesado.
+// Debe ser idempotente: un mensaje puede entregarse mas de una vez.
+type Handler func(tx *gorm.DB, msg *models.EventoOutbox) error
+
+// Bus es un bus interno de eventos respaldado por la tabla eventos_outbox.
+type Bus struct {
+	db       *gorm.DB
+	workers  int
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+	wake     chan struct{}
+}
+
+// New crea el bus. La cantidad de workers se configura con EVENTBUS_WORKERS (default 4).
+func New(db *gorm.DB) *Bus {
+	workers := 4
+	if v, err := strconv.Atoi(os.Getenv("EVENTBUS_WORKERS")); err == nil && v > 0 {
+		workers = v
+	}
+	return &Bus{
+		db:       db,
+		workers:  workers,
+		handlers: map[string][]Handler{},
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Subscribe registra un handler para un topico.
+func (b *Bus) Subscribe(topico string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[topico] = append(b.handlers[topico], h)
+}
+
+// Publish encola un mensaje dentro de la transaccion del llamador (se entrega solo si esta hace commit).
+func (b *Bus) Publish(tx *gorm.DB, msg models.EventoOutbox) error {
+	msg.Estado = models.EventoOutboxPendiente
+	return tx.Create(&msg).Error
+}
+
+// Wake despierta a los workers sin esperar el siguiente poll (llamar despues del commit).
+func (b *Bus) Wake() {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run inicia los workers y bloquea hasta stop.
+func (b *Bus) Run(stop <-chan struct{}) {
+	var wg sync.WaitGroup
+	for i := 0; i < b.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.worker(stop)
+		}()
+	}
+	wg.Wait()
+}
+
+func (b *Bus) worker(stop <-chan struct{}) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-b.wake:
+		}
+		// Drenar mientras haya trabajo
+		for b.processBatch() == batchSize {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+		}
+	}
+}
+
+// claim toma un lote de mensajes (pendientes o con lease vencido) usando SKIP LOCKED,
+// de modo que varios workers/instancias no procesen el mismo mensaje a la vez.
+func (b *Bus) claim() ([]models.EventoOutbox, error) {
+	now := time.Now()
+	var items []models.EventoOutbox
+	err := b.db.Raw(`
+		UPDATE eventos_outbox SET estado = ?, bloqueado_hasta = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM eventos_outbox
+			WHERE (estado = ? AND (siguiente_intento_en IS NULL OR siguiente_intento_en <= ?))
+			   OR (estado = ? AND bloqueado_hasta < ?)
+			ORDER BY created_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		models.EventoOutboxProcesando, now.Add(leaseTTL), now,
+		models.EventoOutboxPendiente, now,
+		models.EventoOutboxProcesando, now,
+		batchSize,
+	).Scan(&items).Error
+	return items, err
+}
+
+func (b *Bus) processBatch() int {
+	items, err := b.claim()
+	if err != nil {
+		log.Printf("eventbus: error claiming messages: %v", err)
+		return 0
+	}
+	for i := range items {
+		b.process(&items[i])
+	}
+	return len(items)
+}
+
+func (b *Bus) process(msg *models.EventoOutbox) {
+	b.mu.RLock()
+	hs := b.handlers[msg.Topico]
+	b.mu.RUnlock()
+
+	err := b.db.Transaction(func(tx *gorm.DB) error {
+		for _, h := range hs {
+			if err := h(tx, msg); err != nil {
+				return err
+			}
+		}
+		now := time.Now()
+		return tx.Model(&models.EventoOutbox{}).Where("id = ?", msg.ID).Updates(map[string]interface{}{
+			"estado":          models.EventoOutboxProcesado,
+			"intentos":        msg.Intentos + 1,
+			"procesado_en":    now,
+			"bloqueado_hasta": nil,
+			"ultimo_error":    "",
+		}).Error
+	})
+	if err == nil {
+		return
+	}
+
+	log.Printf("eventbus: %s %s: %v", msg.Topico, msg.ID, err)
+	intentos := msg.Intentos + 1
+	updates := map[string]interface{}{
+		"intentos":        intentos,
+		"bloqueado_hasta": nil,
+		"ultimo_error":    fmt.Sprint(err),
+	}
+	if intentos >= maxIntentos {
+		updates["estado"] = models.EventoOutboxError
+		updates["siguiente_intento_en"] = nil
+	} else {
+		updates["estado"] = models.EventoOutboxPendiente
+		updates["siguiente_intento_en"] = time.Now().Add(time.Duration(1<<uint(intentos)) * time.Second)
+	}
+	if err := b.db.Model(&models.EventoOutbox{}).Where("id = ?", msg.ID).Updates(updates).Error; err != nil {
+		log.Printf("eventbus: error saving failed message %s: %v", msg.ID, err)
+	}
+}
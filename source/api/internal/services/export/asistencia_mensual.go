@@ -0,0 +1,195 @@
+package export
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/calendario"
+	"gorm.io/gorm"
+)
+
+// Codigos de la matriz mensual (libro de clases)
+var codigoEstadoDiario = map[string]string{
+	models.EstadoPresente:    "P",
+	models.EstadoAusente:     "A",
+	models.EstadoJustificado: "J",
+}
+
+// DiasHabiles retorna los dias lectivos del mes segun el calendario escolar
+// (lunes a viernes menos feriados, suspensiones y vacaciones; mas recuperativos).
+func DiasHabiles(db *gorm.DB, anio int, mes time.Month, cursoID *uuid.UUID) ([]time.Time, error) {
+	desde := time.Date(anio, mes, 1, 0, 0, 0, 0, time.UTC)
+	return calendario.DiasLectivos(db, desde, desde.AddDate(0, 1, -1), cursoID)
+}
+
+// AsistenciaMensual escribe la matriz alumno x dia del mes desde la asistencia diaria consolidada,
+// con totales y porcentaje por alumno y por curso. Alumnos y registros se leen con cursores
+// ordenados de la misma forma, por lo que el uso de memoria no depende del tamano del colegio.
+// Codigos: P presente, A ausente, J justificado; T/R marcan atraso/retiro (ej. "PT").
+func AsistenciaMensual(db *gorm.DB, t TablaWriter, anio int, mes time.Month, cursoID *uuid.UUID) error {
+	dias, err := DiasHabiles(db, anio, mes, cursoID)
+	if err != nil {
+		return err
+	}
+	desde := time.Date(anio, mes, 1, 0, 0, 0, 0, time.UTC)
+	hasta := desde.AddDate(0, 1, -1)
+
+	header := []interface{}{"Curso", "N", "RUT", "Apellido", "Nombre"}
+	for _, d := range dias {
+		header = append(header, fmt.Sprintf("%02d", d.Day()))
+	}
+	header = append(header, "Presentes", "Ausentes", "Justificados", "Atrasos", "Dias registrados", "% Asistencia")
+	if err := t.WriteRow(header); err != nil {
+		return err
+	}
+
+	var cursos []models.Curso
+	q := db.Order("nivel, nombre")
+	if cursoID != nil {
+		q = q.Where("id = ?", *cursoID)
+	}
+	if err := q.Find(&cursos).Error; err != nil {
+		return err
+	}
+
+	for _, curso := range cursos {
+		if err := matrizCurso(db, t, curso, dias, desde, hasta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type totalesDia struct {
+	presentes, registrados int
+}
+
+func matrizCurso(db *gorm.DB, t TablaWriter, curso models.Curso, dias []time.Time, desde, hasta time.Time) error {
+	indiceDia := map[string]int{}
+	for i, d := range dias {
+		indiceDia[d.Format("2006-01-02")] = i
+	}
+
+	// Alumnos del curso + alumnos con registros del mes en el curso (ej. trasladados)
+	enCurso := db.Model(&models.AsistenciaDiaria{}).Select("alumno_id").
+		Where("curso_id = ? AND fecha >= ? AND fecha <= ?", curso.ID, desde, hasta)
+	alumnosRows, err := db.Model(&models.Alumno{}).
+		Where("curso_id = ? OR id IN (?)", curso.ID, enCurso).
+		Order("apellido, nombre, id").Rows()
+	if err != nil {
+		return err
+	}
+	defer alumnosRows.Close()
+
+	diariaRows, err := db.Model(&models.AsistenciaDiaria{}).
+		Select("asistencias_diarias.*").
+		Joins("JOIN alumnos ON alumnos.id = asistencias_diarias.alumno_id").
+		Where("alumnos.deleted_at IS NULL AND asistencias_diarias.curso_id = ? AND asistencias_diarias.fecha >= ? AND asistencias_diarias.fecha <= ?", curso.ID, desde, hasta).
+		Order("alumnos.apellido, alumnos.nombre, alumnos.id, asistencias_diarias.fecha").Rows()
+	if err != nil {
+		return err
+	}
+	defer diariaRows.Close()
+
+	var pendiente *models.AsistenciaDiaria
+	siguiente := func() (*models.AsistenciaDiaria, error) {
+		if pendiente != nil {
+			d := pendiente
+			pendiente = nil
+			return d, nil
+		}
+		if !diariaRows.Next() {
+			return nil, diariaRows.Err()
+		}
+		var d models.AsistenciaDiaria
+		if err := db.ScanRows(diariaRows, &d); err != nil {
+			return nil, err
+		}
+		return &d, nil
+	}
+
+	porDia := make([]totalesDia, len(dias))
+	var cursoPresentes, cursoRegistrados int
+	n := 0
+	for alumnosRows.Next() {
+		var a models.Alumno
+		if err := db.ScanRows(alumnosRows, &a); err != nil {
+			return err
+		}
+		n++
+
+		celdas := make([]interface{}, len(dias))
+		var presentes, ausentes, justificados, atrasos, registrados int
+		for {
+			d, err := siguiente()
+			if err != nil {
+				return err
+			}
+			if d == nil {
+				break
+			}
+			if d.AlumnoID != a.ID {
+				pendiente = d
+				break
+			}
+			i, ok := indiceDia[d.Fecha.Format("2006-01-02")]
+			if !ok {
+				continue // registro en fin de semana: fuera de la matriz
+			}
+			codigo := codigoEstadoDiario[d.Estado]
+			if d.Atraso {
+				codigo += "T"
+				atrasos++
+			}
+			if d.Retiro {
+				codigo += "R"
+			}
+			celdas[i] = codigo
+			registrados++
+			porDia[i].registrados++
+			switch d.Estado {
+			case models.EstadoPresente:
+				presentes++
+				porDia[i].presentes++
+			case models.EstadoJustificado:
+				justificados++
+			default:
+				ausentes++
+			}
+		}
+		cursoPresentes += presentes
+		cursoRegistrados += registrados
+
+		row := []interface{}{curso.Nombre, n, a.Rut, a.Apellido, a.Nombre}
+		row = append(row, celdas...)
+		row = append(row, presentes, ausentes, justificados, atrasos, registrados, porcentaje(presentes, registrados))
+		if err := t.WriteRow(row); err != nil {
+			return err
+		}
+	}
+	if err := alumnosRows.Err(); err != nil {
+		return err
+	}
+
+	// Fila de totales del curso: % de asistencia por dia y del mes
+	total := []interface{}{curso.Nombre, nil, nil, "TOTAL CURSO", nil}
+	for _, td := range porDia {
+		if td.registrados == 0 {
+			total = append(total, nil)
+			continue
+		}
+		total = append(total, porcentaje(td.presentes, td.registrados))
+	}
+	total = append(total, cursoPresentes, nil, nil, nil, cursoRegistrados, porcentaje(cursoPresentes, cursoRegistrados))
+	return t.WriteRow(total)
+}
+
+func porcentaje(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(n)*1000/float64(total)) / 10
+}
@@ -0,0 +1,215 @@
+package export
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/pdf"
+)
+
+const margen = 36.0
+
+// Columna de una tabla PDF
+type Columna struct {
+	Titulo  string
+	Ancho   float64 // proporcion relativa al ancho util
+	Derecha bool
+}
+
+// informe agrega sobre pdf.Documento el encabezado institucional, pie de pagina,
+// cursor vertical y tablas con salto de pagina.
+type informe struct {
+	doc     *pdf.Documento
+	est     models.Establecimiento
+	titulo  string
+	sub     string
+	y       float64
+	r, g, b int
+}
+
+func nuevoInforme(est models.Establecimiento, tamano pdf.Tamano, titulo, sub string) *informe {
+	doc := pdf.Nuevo(tamano)
+	doc.Titulo = titulo
+	doc.Autor = est.Nombre
+	in := &informe{doc: doc, est: est, titulo: titulo, sub: sub}
+	in.r, in.g, in.b = colorRGB(est.ColorPrimario)
+	in.pagina()
+	return in
+}
+
+func (in *informe) ancho() float64 { return in.doc.Tamano().Ancho - 2*margen }
+
+func (in *informe) limite() float64 { return in.doc.Tamano().Alto - margen - 20 }
+
+// pagina abre una pagina nueva con el encabezado institucional
+func (in *informe) pagina() {
+	d := in.doc
+	d.NuevaPagina()
+	w := d.Tamano().Ancho
+
+	d.ColorRelleno(in.r, in.g, in.b)
+	d.Rect(0, 0, w, 8, true)
+
+	d.Fuente(true, 13)
+	d.Texto(margen, margen+8, in.est.Nombre)
+	d.Fuente(false, 8)
+	d.ColorRelleno(90, 90, 90)
+	linea := in.est.Direccion
+	if in.est.Comuna != "" {
+		linea = unir(linea, in.est.Comuna, ", ")
+	}
+	if in.est.RBD != "" {
+		linea = unir(linea, "RBD "+in.est.RBD, " - ")
+	}
+	d.Texto(margen, margen+20, linea)
+	if in.est.Lema != "" {
+		d.TextoDerecha(w-margen, margen+8, in.est.Lema)
+	}
+
+	d.ColorRelleno(in.r, in.g, in.b)
+	d.Fuente(true, 12)
+	d.Texto(margen, margen+42, in.titulo)
+	d.ColorRelleno(0, 0, 0)
+	if in.sub != "" {
+		d.Fuente(false, 9)
+		d.Texto(margen, margen+55, in.sub)
+	}
+	d.ColorLinea(in.r, in.g, in.b)
+	d.Linea(margen, margen+62, w-margen, margen+62, 0.8)
+	d.ColorLinea(0, 0, 0)
+	in.y = margen + 78
+}
+
+// asegurar salta de pagina si no quedan alto puntos
+func (in *informe) asegurar(alto float64) bool {
+	if in.y+alto > in.limite() {
+		in.pagina()
+		return true
+	}
+	return false
+}
+
+// parrafo escribe texto con salto de linea automatico
+func (in *informe) parrafo(s string, negrita bool, size float64) {
+	in.doc.Fuente(negrita, size)
+	for _, l := range in.doc.Partir(s, in.ancho()) {
+		in.asegurar(size + 4)
+		in.doc.Texto(margen, in.y, l)
+		in.y += size + 4
+	}
+}
+
+// campo escribe "Etiqueta: valor"
+func (in *informe) campo(etiqueta, valor string) {
+	in.asegurar(14)
+	in.doc.Fuente(true, 9)
+	in.doc.Texto(margen, in.y, etiqueta+":")
+	in.doc.Fuente(false, 9)
+	in.doc.Texto(margen+110, in.y, valor)
+	in.y += 14
+}
+
+func (in *informe) espacio(h float64) { in.y += h }
+
+// tabla dibuja filas con encabezado repetido en cada pagina
+func (in *informe) tabla(cols []Columna, filas [][]string, size float64) {
+	total := 0.0
+	for _, c := range cols {
+		total += c.Ancho
+	}
+	anchos := make([]float64, len(cols))
+	for i, c := range cols {
+		anchos[i] = c.Ancho / total * in.ancho()
+	}
+	alto := size + 6
+
+	encabezado := func() {
+		d := in.doc
+		d.ColorRelleno(in.r, in.g, in.b)
+		d.Rect(margen, in.y, in.ancho(), alto, true)
+		d.ColorRelleno(255, 255, 255)
+		d.Fuente(true, size)
+		x := margen
+		for i, c := range cols {
+			in.celda(x, anchos[i], c.Titulo, c.Derecha, alto)
+			x += anchos[i]
+		}
+		d.ColorRelleno(0, 0, 0)
+		in.y += alto
+	}
+
+	in.asegurar(2 * alto)
+	encabezado()
+	for n, fila := range filas {
+		if in.asegurar(alto) {
+			encabezado()
+		}
+		d := in.doc
+		if n%2 == 1 {
+			d.ColorRelleno(242, 242, 242)
+			d.Rect(margen, in.y, in.ancho(), alto, true)
+			d.ColorRelleno(0, 0, 0)
+		}
+		d.Fuente(false, size)
+		x := margen
+		for i := range cols {
+			v := ""
+			if i < len(fila) {
+				v = fila[i]
+			}
+			in.celda(x, anchos[i], v, cols[i].Derecha, alto)
+			x += anchos[i]
+		}
+		in.y += alto
+	}
+	in.doc.ColorLinea(200, 200, 200)
+	in.doc.Linea(margen, in.y, margen+in.ancho(), in.y, 0.5)
+	in.doc.ColorLinea(0, 0, 0)
+	in.y += 6
+}
+
+func (in *informe) celda(x, ancho float64, v string, derecha bool, alto float64) {
+	v = in.doc.Recortar(v, ancho-4)
+	base := in.y + alto - 4
+	if derecha {
+		in.doc.TextoDerecha(x+ancho-2, base, v)
+	} else {
+		in.doc.Texto(x+2, base, v)
+	}
+}
+
+// cerrar escribe el pie "Pagina n de N" y la fecha de emision en todas las paginas
+func (in *informe) cerrar() *pdf.Documento {
+	d := in.doc
+	n := d.Paginas()
+	emitido := "Emitido el " + time.Now().Format("02-01-2006 15:04")
+	for i := 1; i <= n; i++ {
+		d.IrAPagina(i)
+		d.ColorRelleno(120, 120, 120)
+		d.Fuente(false, 7)
+		y := d.Tamano().Alto - margen + 10
+		d.Texto(margen, y, emitido)
+		d.TextoDerecha(d.Tamano().Ancho-margen, y, fmt.Sprintf("Página %d de %d", i, n))
+	}
+	return d
+}
+
+func colorRGB(hex string) (int, int, int) {
+	if len(hex) != 7 || hex[0] != '#' {
+		return 31, 78, 121
+	}
+	v, err := strconv.ParseUint(hex[1:], 16, 32)
+	if err != nil {
+		return 31, 78, 121
+	}
+	return int(v >> 16 & 0xFF), int(v >> 8 & 0xFF), int(v & 0xFF)
+}
+
+func unir(a, b, sep string) string {
+	if a == "" {
+		return b
+	}
+	return a + sep + b
+}
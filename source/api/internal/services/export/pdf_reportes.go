@@ -0,0 +1,354 @@
+package export
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/pdf"
+	"gorm.io/gorm"
+)
+
+var meses = [...]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"}
+
+func fechaLarga(t time.Time) string {
+	return fmt.Sprintf("%d de %s de %d", t.Day(), meses[t.Month()-1], t.Year())
+}
+
+// tablaMemoria acumula filas en memoria (reportes PDF acotados a un curso)
+type tablaMemoria struct {
+	filas [][]interface{}
+}
+
+func (t *tablaMemoria) WriteRow(celdas []interface{}) error {
+	t.filas = append(t.filas, append([]interface{}(nil), celdas...))
+	return nil
+}
+
+func (t *tablaMemoria) Close() error { return nil }
+
+// PDFAsistenciaCurso planilla mensual de asistencia de un curso (hoja horizontal)
+func PDFAsistenciaCurso(db *gorm.DB, curso models.Curso, anio int, mes time.Month) (*pdf.Documento, error) {
+	var t tablaMemoria
+	if err := AsistenciaMensual(db, &t, anio, mes, &curso.ID); err != nil {
+		return nil, err
+	}
+
+	est := models.ObtenerEstablecimiento(db)
+	in := nuevoInforme(est, pdf.A4.Horizontal(),
+		"Planilla de asistencia mensual - "+curso.Nombre,
+		fmt.Sprintf("%s %d. P: presente, A: ausente, J: justificado, T: atraso, R: retiro.", meses[mes-1], anio))
+
+	// Columnas de la matriz: Curso | N | RUT | Apellido | Nombre | dias... | totales (6)
+	header := t.filas[0]
+	nDias := len(header) - 5 - 6
+	cols := []Columna{{Titulo: "N", Ancho: 2, Derecha: true}, {Titulo: "RUT", Ancho: 7}, {Titulo: "Alumno", Ancho: 14}}
+	for i := 0; i < nDias; i++ {
+		cols = append(cols, Columna{Titulo: celdaTexto(header[5+i]), Ancho: 2})
+	}
+	cols = append(cols,
+		Columna{Titulo: "P", Ancho: 2.5, Derecha: true},
+		Columna{Titulo: "A", Ancho: 2.5, Derecha: true},
+		Columna{Titulo: "J", Ancho: 2.5, Derecha: true},
+		Columna{Titulo: "T", Ancho: 2.5, Derecha: true},
+		Columna{Titulo: "Reg.", Ancho: 3, Derecha: true},
+		Columna{Titulo: "%", Ancho: 3.5, Derecha: true},
+	)
+
+	var filas [][]string
+	for _, f := range t.filas[1:] {
+		fila := []string{celdaTexto(f[1]), celdaTexto(f[2]), unir(celdaTexto(f[3]), celdaTexto(f[4]), " ")}
+		for _, c := range f[5:] {
+			fila = append(fila, celdaTexto(c))
+		}
+		filas = append(filas, fila)
+	}
+	in.tabla(cols, filas, 6.5)
+	return in.cerrar(), nil
+}
+
+// PDFCertificadoAsistencia certificado de asistencia de un alumno en un rango de fechas
+func PDFCertificadoAsistencia(db *gorm.DB, alumno models.Alumno, desde, hasta time.Time) (*pdf.Documento, error) {
+	type fila struct {
+		Mes          string
+		Presentes    int
+		Ausentes     int
+		Justificados int
+		Registrados  int
+	}
+	var porMes []fila
+	if err := db.Model(&models.AsistenciaDiaria{}).
+		Select("to_char(fecha, 'YYYY-MM') as mes, "+
+			"SUM(CASE WHEN estado = ? THEN 1 ELSE 0 END) as presentes, "+
+			"SUM(CASE WHEN estado = ? THEN 1 ELSE 0 END) as ausentes, "+
+			"SUM(CASE WHEN estado = ? THEN 1 ELSE 0 END) as justificados, "+
+			"COUNT(*) as registrados", models.EstadoPresente, models.EstadoAusente, models.EstadoJustificado).
+		Where("alumno_id = ? AND fecha >= ? AND fecha <= ?", alumno.ID, desde, hasta).
+		Group("mes").Order("mes").
+		Scan(&porMes).Error; err != nil {
+		return nil, err
+	}
+
+	var presentes, registrados int
+	for _, m := range porMes {
+		presentes += m.Presentes
+		registrados += m.Registrados
+	}
+
+	est := models.ObtenerEstablecimiento(db)
+	in := nuevoInforme(est, pdf.A4, "Certificado de asistencia", "")
+
+	curso := ""
+	if alumno.Curso != nil {
+		curso = alumno.Curso.Nombre
+	}
+	director := est.Director
+	if director == "" {
+		director = "La Dirección"
+	}
+	rbd := ""
+	if est.RBD != "" {
+		rbd = ", RBD " + est.RBD
+	}
+	in.espacio(10)
+	in.parrafo(fmt.Sprintf(
+		"%s de %s%s, certifica que %s %s, RUT %s, es alumno(a) regular del curso %s y registra, entre el %s y el %s, "+
+			"un %.1f%% de asistencia (%d de %d días registrados).",
+		director, est.Nombre, rbd, alumno.Nombre, alumno.Apellido, alumno.Rut, curso,
+		fechaLarga(desde), fechaLarga(hasta), porcentaje(presentes, registrados), presentes, registrados), false, 11)
+	in.espacio(8)
+	in.parrafo("Se extiende el presente certificado a petición del interesado para los fines que estime convenientes.", false, 11)
+	in.espacio(16)
+
+	if len(porMes) > 0 {
+		var filas [][]string
+		for _, m := range porMes {
+			t, _ := time.Parse("2006-01", m.Mes)
+			filas = append(filas, []string{
+				fmt.Sprintf("%s %d", meses[t.Month()-1], t.Year()),
+				fmt.Sprint(m.Presentes), fmt.Sprint(m.Ausentes), fmt.Sprint(m.Justificados), fmt.Sprint(m.Registrados),
+				fmt.Sprintf("%.1f%%", porcentaje(m.Presentes, m.Registrados)),
+			})
+		}
+		in.tabla([]Columna{
+			{Titulo: "Mes", Ancho: 4},
+			{Titulo: "Presentes", Ancho: 2, Derecha: true},
+			{Titulo: "Ausentes", Ancho: 2, Derecha: true},
+			{Titulo: "Justificados", Ancho: 2, Derecha: true},
+			{Titulo: "Días registrados", Ancho: 2.5, Derecha: true},
+			{Titulo: "% Asistencia", Ancho: 2.5, Derecha: true},
+		}, filas, 9)
+	}
+
+	// Firma
+	in.asegurar(90)
+	in.espacio(60)
+	x := in.doc.Tamano().Ancho / 2
+	in.doc.Linea(x-90, in.y, x+90, in.y, 0.6)
+	in.doc.Fuente(true, 10)
+	in.doc.TextoCentrado(x, in.y+14, director)
+	in.doc.Fuente(false, 9)
+	in.doc.TextoCentrado(x, in.y+26, est.Nombre)
+	in.y += 40
+	in.doc.Fuente(false, 9)
+	lugar := est.Comuna
+	if lugar != "" {
+		lugar += ", "
+	}
+	in.doc.Texto(margen, in.y, lugar+fechaLarga(time.Now()))
+
+	return in.cerrar(), nil
+}
+
+// PDFHistorialAlumno historial de eventos (incidentes, inasistencias, etc.) de un alumno
+func PDFHistorialAlumno(db *gorm.DB, alumno models.Alumno, desde, hasta time.Time) (*pdf.Documento, error) {
+	var eventos []models.Evento
+	if err := db.Preload("Concepto").Preload("Usuario").Preload("Notas").
+		Where("alumno_id = ? AND created_at >= ? AND created_at < ?", alumno.ID, desde, hasta.AddDate(0, 0, 1)).
+		Order("created_at").Find(&eventos).Error; err != nil {
+		return nil, err
+	}
+
+	curso := ""
+	if alumno.Curso != nil {
+		curso = alumno.Curso.Nombre
+	}
+	est := models.ObtenerEstablecimiento(db)
+	in := nuevoInforme(est, pdf.A4, "Historial de eventos del alumno",
+		fmt.Sprintf("%s %s (RUT %s) - %s. Periodo %s al %s.", alumno.Nombre, alumno.Apellido, alumno.Rut, curso,
+			desde.Format("02-01-2006"), hasta.Format("02-01-2006")))
+
+	porConcepto := map[string]int{}
+	orden := []string{}
+	var filas [][]string
+	for _, e := range eventos {
+		concepto := "-"
+		if e.Concepto != nil {
+			concepto = e.Concepto.Nombre
+		}
+		if _, ok := porConcepto[concepto]; !ok {
+			orden = append(orden, concepto)
+		}
+		porConcepto[concepto]++
+
+		estado := "Activo"
+		if !e.Activo {
+			estado = "Cerrado"
+			if e.MotivoCierre != "" {
+				estado += " (" + e.MotivoCierre + ")"
+			}
+		}
+		registrado := e.Origen
+		if e.Usuario != nil {
+			registrado = e.Usuario.Nombre
+		}
+		filas = append(filas, []string{
+			e.CreatedAt.Format("02-01-2006 15:04"), concepto, registrado, estado, fmt.Sprint(len(e.Notas)),
+		})
+	}
+
+	in.parrafo("Resumen", true, 10)
+	if len(orden) == 0 {
+		in.parrafo("Sin eventos registrados en el periodo.", false, 9)
+	}
+	for _, c := range orden {
+		in.campo(c, fmt.Sprint(porConcepto[c]))
+	}
+	in.espacio(8)
+
+	if len(filas) > 0 {
+		in.tabla([]Columna{
+			{Titulo: "Fecha", Ancho: 3},
+			{Titulo: "Concepto", Ancho: 3},
+			{Titulo: "Registrado por", Ancho: 3.5},
+			{Titulo: "Estado", Ancho: 3.5},
+			{Titulo: "Notas", Ancho: 1.2, Derecha: true},
+		}, filas, 8)
+
+		// Detalle de notas (seguimiento) por evento
+		for _, e := range eventos {
+			if len(e.Notas) == 0 {
+				continue
+			}
+			concepto := "-"
+			if e.Concepto != nil {
+				concepto = e.Concepto.Nombre
+			}
+			in.espacio(4)
+			in.parrafo(fmt.Sprintf("%s del %s", concepto, e.CreatedAt.Format("02-01-2006 15:04")), true, 9)
+			for _, n := range e.Notas {
+				in.parrafo(n.CreatedAt.Format("02-01-2006 15:04")+": "+n.Texto, false, 8)
+			}
+		}
+	}
+	return in.cerrar(), nil
+}
+
+// PDFResolucionAlertas reporte de alertas creadas en el periodo y su resolucion
+func PDFResolucionAlertas(db *gorm.DB, desde, hasta time.Time, cursoID *uuid.UUID) (*pdf.Documento, error) {
+	q := db.Where("created_at >= ? AND created_at < ?", desde, hasta.AddDate(0, 0, 1))
+	if cursoID != nil {
+		q = q.Where("curso_id = ?", *cursoID)
+	}
+	var alertas []models.Alerta
+	if err := q.Order("created_at").Find(&alertas).Error; err != nil {
+		return nil, err
+	}
+
+	// Nombres de alumnos involucrados
+	ids := []uuid.UUID{}
+	for _, a := range alertas {
+		if a.AlumnoID != nil {
+			ids = append(ids, *a.AlumnoID)
+		}
+	}
+	nombres := map[uuid.UUID]string{}
+	if len(ids) > 0 {
+		var alumnos []models.Alumno
+		db.Where("id IN ?", ids).Find(&alumnos)
+		for _, a := range alumnos {
+			nombres[a.ID] = a.NombreCompleto()
+		}
+	}
+
+	type resumen struct {
+		total, cerradas int
+		horas           float64
+	}
+	porPrioridad := map[string]*resumen{}
+	var general resumen
+	var filas [][]string
+	for _, a := range alertas {
+		r := porPrioridad[a.Prioridad]
+		if r == nil {
+			r = &resumen{}
+			porPrioridad[a.Prioridad] = r
+		}
+		r.total++
+		general.total++
+
+		cerrada, horas := "-", "-"
+		if a.Estado == models.AlertaCerrada && a.CerradoEn != nil {
+			h := a.CerradoEn.Sub(a.CreatedAt).Hours()
+			r.cerradas++
+			r.horas += h
+			general.cerradas++
+			general.horas += h
+			cerrada = a.CerradoEn.Format("02-01-2006 15:04")
+			horas = fmt.Sprintf("%.1f", h)
+		}
+		alumno := "-"
+		if a.AlumnoID != nil {
+			alumno = nombres[*a.AlumnoID]
+		}
+		filas = append(filas, []string{a.CreatedAt.Format("02-01-2006 15:04"), a.Titulo, a.Prioridad, alumno, a.Estado, cerrada, horas})
+	}
+
+	est := models.ObtenerEstablecimiento(db)
+	in := nuevoInforme(est, pdf.A4.Horizontal(), "Reporte de resolución de alertas",
+		fmt.Sprintf("Periodo %s al %s.", desde.Format("02-01-2006"), hasta.Format("02-01-2006")))
+
+	promedio := func(r resumen) string {
+		if r.cerradas == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.1f h", r.horas/float64(r.cerradas))
+	}
+	in.campo("Alertas", fmt.Sprint(general.total))
+	in.campo("Cerradas", fmt.Sprintf("%d (%.1f%%)", general.cerradas, porcentaje(general.cerradas, general.total)))
+	in.campo("Tiempo medio", promedio(general))
+	in.espacio(6)
+
+	var resumenFilas [][]string
+	for _, p := range []string{"critica", "alta", "media", "baja"} {
+		if r := porPrioridad[p]; r != nil {
+			resumenFilas = append(resumenFilas, []string{p, fmt.Sprint(r.total), fmt.Sprint(r.cerradas), fmt.Sprint(r.total - r.cerradas), promedio(*r)})
+		}
+	}
+	if len(resumenFilas) > 0 {
+		in.tabla([]Columna{
+			{Titulo: "Prioridad", Ancho: 3},
+			{Titulo: "Total", Ancho: 2, Derecha: true},
+			{Titulo: "Cerradas", Ancho: 2, Derecha: true},
+			{Titulo: "Abiertas", Ancho: 2, Derecha: true},
+			{Titulo: "Tiempo medio de resolución", Ancho: 4, Derecha: true},
+		}, resumenFilas, 9)
+		in.espacio(8)
+	}
+
+	if len(filas) > 0 {
+		in.tabla([]Columna{
+			{Titulo: "Creada", Ancho: 3},
+			{Titulo: "Alerta", Ancho: 7},
+			{Titulo: "Prioridad", Ancho: 2},
+			{Titulo: "Alumno", Ancho: 5},
+			{Titulo: "Estado", Ancho: 2},
+			{Titulo: "Cerrada", Ancho: 3},
+			{Titulo: "Horas", Ancho: 1.5, Derecha: true},
+		}, filas, 8)
+	} else {
+		in.parrafo("Sin alertas en el periodo.", false, 9)
+	}
+	return in.cerrar(), nil
+}
@@ -0,0 +1,82 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Formatos de exportacion soportados
+const (
+	FormatoCSV  = "csv"
+	FormatoXLSX = "xlsx"
+)
+
+// TablaWriter escribe filas de una tabla de forma incremental (streaming).
+// Las celdas pueden ser string, int, int64 o float64.
+type TablaWriter interface {
+	WriteRow(celdas []interface{}) error
+	Close() error
+}
+
+// NuevaTabla crea un writer para el formato pedido.
+func NuevaTabla(formato string, w io.Writer, hoja string) (TablaWriter, error) {
+	switch formato {
+	case FormatoCSV:
+		return newCSV(w), nil
+	case FormatoXLSX:
+		return NewXLSX(w, hoja)
+	}
+	return nil, fmt.Errorf("formato no soportado: %s", formato)
+}
+
+// ContentType retorna el MIME del formato
+func ContentType(formato string) string {
+	if formato == FormatoXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+type csvTabla struct {
+	w   *csv.Writer
+	row []string
+}
+
+func newCSV(w io.Writer) *csvTabla {
+	// BOM para que Excel abra el UTF-8 (tildes, ñ) correctamente
+	_, _ = w.Write([]byte("\xEF\xBB\xBF"))
+	cw := csv.NewWriter(w)
+	cw.Comma = ';'
+	return &csvTabla{w: cw}
+}
+
+func (t *csvTabla) WriteRow(celdas []interface{}) error {
+	t.row = t.row[:0]
+	for _, c := range celdas {
+		t.row = append(t.row, celdaTexto(c))
+	}
+	return t.w.Write(t.row)
+}
+
+func (t *csvTabla) Close() error {
+	t.w.Flush()
+	return t.w.Error()
+}
+
+func celdaTexto(c interface{}) string {
+	switch v := c.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', 1, 64)
+	}
+	return fmt.Sprint(c)
+}
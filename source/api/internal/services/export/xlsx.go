@@ -0,0 +1,114 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// XLSXWriter genera un libro XLSX de una hoja sin dependencias externas.
+// Las filas se escriben directo al zip, por lo que no se mantiene la planilla en memoria.
+type XLSXWriter struct {
+	zw   *zip.Writer
+	hoja *bufio.Writer
+	fila int
+}
+
+// NewXLSX escribe las partes fijas del libro y abre la hoja para escritura incremental.
+func NewXLSX(w io.Writer, nombreHoja string) (*XLSXWriter, error) {
+	if nombreHoja == "" {
+		nombreHoja = "Hoja1"
+	}
+	zw := zip.NewWriter(w)
+	partes := []struct{ nombre, contenido string }{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="` + escaparXML(recortarHoja(nombreHoja)) + `" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+	}
+	for _, p := range partes {
+		f, err := zw.Create(p.nombre)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, p.contenido); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	hoja := bufio.NewWriter(f)
+	hoja.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return &XLSXWriter{zw: zw, hoja: hoja}, nil
+}
+
+// WriteRow agrega una fila; los numeros se escriben como celdas numericas.
+func (x *XLSXWriter) WriteRow(celdas []interface{}) error {
+	x.fila++
+	r := strconv.Itoa(x.fila)
+	x.hoja.WriteString(`<row r="` + r + `">`)
+	for i, c := range celdas {
+		ref := columna(i) + r
+		switch v := c.(type) {
+		case nil:
+			continue
+		case int, int64:
+			x.hoja.WriteString(`<c r="` + ref + `"><v>` + celdaTexto(v) + `</v></c>`)
+		case float64:
+			x.hoja.WriteString(`<c r="` + ref + `"><v>` + strconv.FormatFloat(v, 'f', -1, 64) + `</v></c>`)
+		default:
+			s := celdaTexto(v)
+			if s == "" {
+				continue
+			}
+			x.hoja.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t>` + escaparXML(s) + `</t></is></c>`)
+		}
+	}
+	_, err := x.hoja.WriteString(`</row>`)
+	return err
+}
+
+// Close cierra la hoja y el zip.
+func (x *XLSXWriter) Close() error {
+	x.hoja.WriteString(`</sheetData></worksheet>`)
+	if err := x.hoja.Flush(); err != nil {
+		return err
+	}
+	return x.zw.Close()
+}
+
+// columna convierte un indice 0-based a letra de columna (0 -> A, 26 -> AA)
+func columna(i int) string {
+	s := ""
+	for i >= 0 {
+		s = string(rune('A'+i%26)) + s
+		i = i/26 - 1
+	}
+	return s
+}
+
+func escaparXML(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// Excel limita el nombre de hoja a 31 caracteres y prohibe algunos simbolos
+func recortarHoja(s string) string {
+	s = strings.NewReplacer("/", "-", "\\", "-", "?", "", "*", "", "[", "(", "]", ")", ":", "-").Replace(s)
+	if r := []rune(s); len(r) > 31 {
+		s = string(r[:31])
+	}
+	return s
+}
@@ -52,6 +52,17 @@ func cleanup(db *gorm.DB) {
 			log.Printf("retention: notification_outboxes: %v", err)
 		}
 	}
+	if outboxDays > 0 {
+		cut := now.AddDate(0, 0, -outboxDays)
+		// Solo mensajes del bus ya procesados
+		if err := db.Where("estado = ? AND created_at < ?", models.EventoOutboxProcesado, cut).Delete(&models.EventoOutbox{}).Error; err != nil {
+			log.Printf("retention: eventos_outbox: %v", err)
+		}
+	}
+	// Claves de idempotencia vencidas (el TTL se fija al crearlas)
+	if err := db.Where("expira_en < ?", now).Delete(&models.IdempotenciaClave{}).Error; err != nil {
+		log.Printf("retention: idempotencia_claves: %v", err)
+	}
 	if alertDays > 0 {
 		cut := now.AddDate(0, 0, -alertDays)
 		// Solo limpiar cerradas antiguas
@@ -0,0 +1,272 @@
+package monitor
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Ubicacion actual del alumno: estado temporal activo o, si no hay, la ultima asistencia del dia
+const (
+	UbicacionSala        = "sala"
+	UbicacionBano        = models.EstadoTemporalBano
+	UbicacionEnfermeria  = models.EstadoTemporalEnfermeria
+	UbicacionSOS         = models.EstadoTemporalSOS
+	UbicacionAusente     = "ausente"
+	UbicacionRetirado    = "retirado"
+	UbicacionSinRegistro = "sin_registro"
+)
+
+// Alumno estado del alumno en el monitor de inspectoria
+type Alumno struct {
+	AlumnoID       string          `json:"alumno_id"`
+	Nombre         string          `json:"nombre"`
+	Apellido       string          `json:"apellido"`
+	CursoID        string          `json:"curso_id"`
+	Curso          string          `json:"curso"`
+	CasoEspecial   bool            `json:"caso_especial"`
+	Ubicacion      string          `json:"ubicacion"`
+	EstadoTemporal *EstadoTemporal `json:"estado_temporal,omitempty"`
+	// Excedido: el estado temporal supera la duracion maxima de su tipo (destacar en el monitor)
+	Excedido        bool               `json:"excedido"`
+	Asistencia      []AsistenciaBloque `json:"asistencia"` // hoy, por bloque
+	EventosAbiertos []EventoAbierto    `json:"eventos_abiertos"`
+}
+
+type EstadoTemporal struct {
+	ID              string    `json:"id"`
+	Tipo            string    `json:"tipo"`
+	Inicio          time.Time `json:"inicio"`
+	TranscurridoMin int       `json:"transcurrido_min"`
+	MaximoMin       int       `json:"maximo_min"`
+}
+
+type AsistenciaBloque struct {
+	HorarioID  string `json:"horario_id"`
+	Numero     int    `json:"numero"`
+	HoraInicio string `json:"hora_inicio"`
+	Estado     string `json:"estado"`
+}
+
+type EventoAbierto struct {
+	ID       string    `json:"id"`
+	Concepto string    `json:"concepto"` // codigo
+	Nombre   string    `json:"nombre"`
+	Color    string    `json:"color"`
+	Desde    time.Time `json:"desde"`
+}
+
+// FiltroAlumnos filtros del listado por alumno (vacios = todos)
+type FiltroAlumnos struct {
+	CursoID      string
+	Ubicacion    string
+	SoloExcedido bool
+}
+
+// Incluye indica si el alumno pasa el filtro
+func (f FiltroAlumnos) Incluye(a Alumno) bool {
+	return (f.CursoID == "" || a.CursoID == f.CursoID) &&
+		(f.Ubicacion == "" || a.Ubicacion == f.Ubicacion) &&
+		(!f.SoloExcedido || a.Excedido)
+}
+
+// SnapshotAlumnos estado por alumno. Version crece con cada monitor_alumnos_actualizados.
+type SnapshotAlumnos struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	Version   uint64    `json:"version"`
+	Alumnos   []Alumno  `json:"alumnos"`
+}
+
+// CalcularAlumnos arma el estado de los alumnos activos de los cursos indicados (todos si cursoIDs es nil).
+func CalcularAlumnos(db *gorm.DB, now time.Time, cursoIDs []uuid.UUID) ([]Alumno, error) {
+	porCurso := func(q *gorm.DB) *gorm.DB {
+		if cursoIDs != nil {
+			return q.Where("alumnos.curso_id IN ?", cursoIDs)
+		}
+		return q
+	}
+
+	type alRow struct {
+		ID           uuid.UUID
+		Nombre       string
+		Apellido     string
+		CursoID      uuid.UUID
+		Curso        string
+		CasoEspecial bool
+	}
+	var alRows []alRow
+	if err := porCurso(db.Table("alumnos")).
+		Select("alumnos.id, alumnos.nombre, alumnos.apellido, alumnos.curso_id, cursos.nombre as curso, alumnos.caso_especial").
+		Joins("JOIN cursos ON cursos.id = alumnos.curso_id").
+		Where("alumnos.activo = true AND alumnos.deleted_at IS NULL").
+		Scan(&alRows).Error; err != nil {
+		return nil, err
+	}
+	if len(alRows) == 0 {
+		return []Alumno{}, nil
+	}
+
+	// Estados temporales activos
+	var estados []models.EstadoTemporal
+	if err := porCurso(db.Joins("JOIN alumnos ON alumnos.id = estado_temporals.alumno_id")).
+		Where("estado_temporals.fin IS NULL").
+		Order("estado_temporals.inicio").
+		Find(&estados).Error; err != nil {
+		return nil, err
+	}
+	etByAlumno := map[uuid.UUID]models.EstadoTemporal{}
+	for _, et := range estados {
+		// Con mas de uno activo prima SOS; si no, el mas reciente
+		if prev, ok := etByAlumno[et.AlumnoID]; ok && prev.Tipo == models.EstadoTemporalSOS {
+			continue
+		}
+		etByAlumno[et.AlumnoID] = et
+	}
+
+	// Asistencia del dia por bloque
+	type asRow struct {
+		AlumnoID   uuid.UUID
+		HorarioID  uuid.UUID
+		Estado     string
+		Numero     int
+		HoraInicio string
+	}
+	var asRows []asRow
+	if err := porCurso(db.Table("asistencias")).
+		Select("asistencias.alumno_id, asistencias.horario_id, asistencias.estado, bloque_horarios.numero, bloque_horarios.hora_inicio").
+		Joins("JOIN alumnos ON alumnos.id = asistencias.alumno_id").
+		Joins("JOIN horarios ON horarios.id = asistencias.horario_id").
+		Joins("JOIN bloque_horarios ON bloque_horarios.id = horarios.bloque_id").
+		Where("asistencias.fecha = ? AND asistencias.deleted_at IS NULL", now.Format("2006-01-02")).
+		Order("bloque_horarios.hora_inicio, bloque_horarios.numero").
+		Scan(&asRows).Error; err != nil {
+		return nil, err
+	}
+	asByAlumno := map[uuid.UUID][]AsistenciaBloque{}
+	for _, r := range asRows {
+		asByAlumno[r.AlumnoID] = append(asByAlumno[r.AlumnoID], AsistenciaBloque{
+			HorarioID:  r.HorarioID.String(),
+			Numero:     r.Numero,
+			HoraInicio: r.HoraInicio,
+			Estado:     r.Estado,
+		})
+	}
+
+	// Eventos abiertos del alumno
+	type evRow struct {
+		ID        uuid.UUID
+		AlumnoID  uuid.UUID
+		Codigo    string
+		Nombre    string
+		Color     string
+		CreatedAt time.Time
+	}
+	var evRows []evRow
+	if err := porCurso(db.Table("eventos")).
+		Select("eventos.id, eventos.alumno_id, conceptos.codigo, conceptos.nombre, conceptos.color, eventos.created_at").
+		Joins("JOIN alumnos ON alumnos.id = eventos.alumno_id").
+		Joins("JOIN conceptos ON conceptos.id = eventos.concepto_id").
+		Where("eventos.activo = true AND eventos.deleted_at IS NULL").
+		Order("eventos.created_at").
+		Scan(&evRows).Error; err != nil {
+		return nil, err
+	}
+	evByAlumno := map[uuid.UUID][]EventoAbierto{}
+	for _, r := range evRows {
+		evByAlumno[r.AlumnoID] = append(evByAlumno[r.AlumnoID], EventoAbierto{
+			ID:       r.ID.String(),
+			Concepto: r.Codigo,
+			Nombre:   r.Nombre,
+			Color:    r.Color,
+			Desde:    r.CreatedAt,
+		})
+	}
+
+	est := models.ObtenerEstablecimiento(db)
+	out := make([]Alumno, 0, len(alRows))
+	for _, r := range alRows {
+		a := Alumno{
+			AlumnoID:        r.ID.String(),
+			Nombre:          r.Nombre,
+			Apellido:        r.Apellido,
+			CursoID:         r.CursoID.String(),
+			Curso:           r.Curso,
+			CasoEspecial:    r.CasoEspecial,
+			Asistencia:      asByAlumno[r.ID],
+			EventosAbiertos: evByAlumno[r.ID],
+		}
+		if a.Asistencia == nil {
+			a.Asistencia = []AsistenciaBloque{}
+		}
+		if a.EventosAbiertos == nil {
+			a.EventosAbiertos = []EventoAbierto{}
+		}
+		if et, ok := etByAlumno[r.ID]; ok {
+			a.EstadoTemporal = &EstadoTemporal{
+				ID:        et.ID.String(),
+				Tipo:      et.Tipo,
+				Inicio:    et.Inicio,
+				MaximoMin: est.DuracionMaxEstadoTemporal(et.Tipo),
+			}
+		}
+		a.Ubicacion = ubicacion(a)
+		ActualizarTiempo(&a, now)
+		out = append(out, a)
+	}
+	ordenarAlumnos(out)
+	return out, nil
+}
+
+// ActualizarTiempo recalcula el tiempo transcurrido del estado temporal y si excede su maximo
+func ActualizarTiempo(a *Alumno, now time.Time) {
+	a.Excedido = false
+	et := a.EstadoTemporal
+	if et == nil {
+		return
+	}
+	et.TranscurridoMin = int(now.Sub(et.Inicio) / time.Minute)
+	if et.TranscurridoMin < 0 {
+		et.TranscurridoMin = 0
+	}
+	a.Excedido = et.MaximoMin > 0 && now.Sub(et.Inicio) > time.Duration(et.MaximoMin)*time.Minute
+}
+
+func ubicacion(a Alumno) string {
+	if a.EstadoTemporal != nil {
+		return a.EstadoTemporal.Tipo
+	}
+	if n := len(a.Asistencia); n > 0 {
+		// Un retiro en cualquier bloque del dia deja al alumno fuera del colegio
+		for _, as := range a.Asistencia {
+			if as.Estado == models.EstadoRetiro {
+				return UbicacionRetirado
+			}
+		}
+		switch a.Asistencia[n-1].Estado {
+		case models.EstadoAusente, models.EstadoJustificado:
+			return UbicacionAusente
+		}
+		return UbicacionSala
+	}
+	return UbicacionSinRegistro
+}
+
+// ordenarAlumnos por curso y apellido (como la nomina)
+func ordenarAlumnos(out []Alumno) {
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Curso != b.Curso {
+			return a.Curso < b.Curso
+		}
+		if a.Apellido != b.Apellido {
+			return a.Apellido < b.Apellido
+		}
+		if a.Nombre != b.Nombre {
+			return a.Nombre < b.Nombre
+		}
+		return a.AlumnoID < b.AlumnoID
+	})
+}
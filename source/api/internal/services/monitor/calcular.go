@@ -0,0 +1,230 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/agenda"
+	"gorm.io/gorm"
+)
+
+// Curso estado del curso en el monitor de inspectoria
+type Curso struct {
+	CursoID                  string     `json:"curso_id"`
+	Nombre                   string     `json:"nombre"`
+	Nivel                    string     `json:"nivel"`
+	SalaSemaforo             string     `json:"sala_semaforo"`     // verde, amarillo, rojo, gris
+	ProfesorSemaforo         string     `json:"profesor_semaforo"` // verde, amarillo, gris
+	EventosActivos           int        `json:"eventos_activos"`
+	EstadosTemporalesActivos int        `json:"estados_temporales_activos"`
+	AtrasosHoy               int        `json:"atrasos_hoy"`
+	RetirosHoy               int        `json:"retiros_hoy"`
+	UltimaAsistenciaEn       *time.Time `json:"ultima_asistencia_en,omitempty"`
+	// Bloque en curso segun calendario y excepciones (reemplazo, cambio de sala, cancelado)
+	BloqueActual         *Bloque `json:"bloque_actual,omitempty"`
+	BloquesSinAsistencia int     `json:"bloques_sin_asistencia"`
+}
+
+type Bloque struct {
+	HorarioID      string `json:"horario_id"`
+	Numero         int    `json:"numero"`
+	Asignatura     string `json:"asignatura,omitempty"`
+	ProfesorID     string `json:"profesor_id"`
+	ProfesorNombre string `json:"profesor_nombre,omitempty"`
+	Sala           string `json:"sala,omitempty"`
+	Reemplazo      bool   `json:"reemplazo"`
+	Cancelado      bool   `json:"cancelado"`
+}
+
+// Snapshot estado completo del monitor. Version crece con cada cambio difundido por WS.
+type Snapshot struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	Version   uint64    `json:"version"`
+	Cursos    []Curso   `json:"cursos"`
+}
+
+// Calcular arma el estado de los cursos indicados (todos si cursoIDs es nil) directo desde la DB.
+func Calcular(db *gorm.DB, now time.Time, cursoIDs []uuid.UUID) ([]Curso, error) {
+	porCurso := func(q *gorm.DB, col string) *gorm.DB {
+		if cursoIDs != nil {
+			return q.Where(col+" IN ?", cursoIDs)
+		}
+		return q
+	}
+
+	var cursos []models.Curso
+	if err := porCurso(db, "id").Order("nivel, nombre").Find(&cursos).Error; err != nil {
+		return nil, err
+	}
+	if len(cursos) == 0 {
+		return []Curso{}, nil
+	}
+
+	// Eventos activos agrupados por curso y color de semaforo del concepto
+	type evAgg struct {
+		CursoID string
+		Color   string
+		Cnt     int
+	}
+	var evRows []evAgg
+	if err := porCurso(db.Table("eventos"), "eventos.curso_id").
+		Select("curso_id as curso_id, conceptos.color as color, COUNT(*) as cnt").
+		Joins("JOIN conceptos ON eventos.concepto_id = conceptos.id").
+		Where("eventos.activo = true AND eventos.curso_id IS NOT NULL AND eventos.deleted_at IS NULL").
+		Group("curso_id, conceptos.color").
+		Scan(&evRows).Error; err != nil {
+		return nil, err
+	}
+	byCurso := map[string]map[string]int{}
+	for _, row := range evRows {
+		if _, ok := byCurso[row.CursoID]; !ok {
+			byCurso[row.CursoID] = map[string]int{}
+		}
+		byCurso[row.CursoID][row.Color] += row.Cnt
+	}
+
+	// Estados temporales activos agrupados por curso
+	type stAgg struct {
+		CursoID string
+		Cnt     int
+	}
+	var stRows []stAgg
+	if err := porCurso(db.Table("estado_temporals"), "alumnos.curso_id").
+		Select("alumnos.curso_id as curso_id, COUNT(*) as cnt").
+		Joins("JOIN alumnos ON estado_temporals.alumno_id = alumnos.id").
+		Where("estado_temporals.fin IS NULL").
+		Group("alumnos.curso_id").
+		Scan(&stRows).Error; err != nil {
+		return nil, err
+	}
+	stByCurso := map[string]int{}
+	for _, row := range stRows {
+		stByCurso[row.CursoID] = row.Cnt
+	}
+
+	// Atrasos y retiros del dia por curso (alumnos distintos)
+	type arAgg struct {
+		CursoID string
+		Estado  string
+		Cnt     int
+	}
+	var arRows []arAgg
+	if err := porCurso(db.Table("asistencias"), "alumnos.curso_id").
+		Select("alumnos.curso_id as curso_id, asistencias.estado as estado, COUNT(DISTINCT asistencias.alumno_id) as cnt").
+		Joins("JOIN alumnos ON asistencias.alumno_id = alumnos.id").
+		Where("asistencias.fecha = ? AND asistencias.estado IN ? AND asistencias.deleted_at IS NULL",
+			now.Format("2006-01-02"), []string{models.EstadoAtraso, models.EstadoRetiro}).
+		Group("alumnos.curso_id, asistencias.estado").
+		Scan(&arRows).Error; err != nil {
+		return nil, err
+	}
+	atrasosByCurso := map[string]int{}
+	retirosByCurso := map[string]int{}
+	for _, row := range arRows {
+		if row.Estado == models.EstadoAtraso {
+			atrasosByCurso[row.CursoID] = row.Cnt
+		} else {
+			retirosByCurso[row.CursoID] = row.Cnt
+		}
+	}
+
+	// Estado curso (presencia profesor)
+	var estados []models.CursoEstado
+	if err := porCurso(db, "curso_id").Find(&estados).Error; err != nil {
+		return nil, err
+	}
+	ceByCurso := map[string]*models.CursoEstado{}
+	for i := range estados {
+		ceByCurso[estados[i].CursoID.String()] = &estados[i]
+	}
+
+	actualByCurso, pendByCurso, err := agendaDelDia(db, now, cursoIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	umbrales := UmbralesDe(models.ObtenerEstablecimiento(db))
+	out := make([]Curso, 0, len(cursos))
+	for _, c := range cursos {
+		cid := c.ID.String()
+		colores := byCurso[cid]
+		evtCount := 0
+		for _, n := range colores {
+			evtCount += n
+		}
+		stCount := stByCurso[cid]
+
+		var last *time.Time
+		if ce := ceByCurso[cid]; ce != nil {
+			last = ce.UltimaAsistenciaEn
+		}
+
+		out = append(out, Curso{
+			CursoID: cid,
+			Nombre:  c.Nombre,
+			Nivel:   c.Nivel,
+			// Sala: color mas grave de los conceptos con eventos activos; profesor: umbrales del establecimiento
+			SalaSemaforo:             SemaforoSala(colores, stCount, last != nil),
+			ProfesorSemaforo:         SemaforoProfesor(last, now, umbrales),
+			EventosActivos:           evtCount,
+			EstadosTemporalesActivos: stCount,
+			AtrasosHoy:               atrasosByCurso[cid],
+			RetirosHoy:               retirosByCurso[cid],
+			UltimaAsistenciaEn:       last,
+			BloqueActual:             actualByCurso[cid],
+			BloquesSinAsistencia:     pendByCurso[cid],
+		})
+	}
+	return out, nil
+}
+
+// agendaDelDia bloque en curso y bloques terminados sin asistencia (hoy) por curso
+func agendaDelDia(db *gorm.DB, now time.Time, cursoIDs []uuid.UUID) (map[string]*Bloque, map[string]int, error) {
+	hora := now.Format("15:04")
+	filtros := []agenda.Filtro{{}}
+	if cursoIDs != nil {
+		filtros = filtros[:0]
+		for i := range cursoIDs {
+			filtros = append(filtros, agenda.Filtro{CursoID: &cursoIDs[i]})
+		}
+	}
+
+	actual := map[string]*Bloque{}
+	pend := map[string]int{}
+	for _, f := range filtros {
+		bloques, err := agenda.DelDia(db, now, f)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, hh := range bloques {
+			if hh.Bloque == nil || hora < hh.Bloque.HoraInicio || hora >= hh.Bloque.HoraFin {
+				continue
+			}
+			mb := &Bloque{
+				HorarioID:  hh.ID.String(),
+				Numero:     hh.Bloque.Numero,
+				ProfesorID: hh.ProfesorID.String(),
+				Sala:       hh.Sala,
+				Reemplazo:  hh.ProfesorTitularID != nil,
+				Cancelado:  agenda.Cancelado(hh),
+			}
+			if hh.Asignatura != nil {
+				mb.Asignatura = hh.Asignatura.Nombre
+			}
+			if hh.Profesor != nil {
+				mb.ProfesorNombre = hh.Profesor.Nombre
+			}
+			actual[hh.CursoID.String()] = mb
+		}
+
+		pendientes, err := agenda.Pendientes(db, now, f, hora)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, hh := range pendientes {
+			pend[hh.CursoID.String()]++
+		}
+	}
+	return actual, pend, nil
+}
@@ -0,0 +1,548 @@
+package monitor
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Mensajes WS del monitor
+const (
+	// TipoCursoActualizado campos de un curso que cambiaron (payload CursoDiff)
+	TipoCursoActualizado = "monitor_curso_actualizado"
+	// TipoAlumnosActualizados cambio el estado de alumnos de un curso (payload AlumnosDiff)
+	TipoAlumnosActualizados = "monitor_alumnos_actualizados"
+)
+
+const (
+	// espera para agrupar rafagas de eventos (ej. creacion masiva) en un solo recalculo
+	debounce = 300 * time.Millisecond
+	// segunda pasada por los cursos recalculados: cubre transacciones que aun no hacian commit
+	revision = 5 * time.Second
+	// bloque en curso, bloques pendientes, semaforo del profesor y estados temporales excedidos dependen de la hora
+	intervaloAgenda = time.Minute
+)
+
+// Tipos de mensaje del orquestador que cambian el estado de un curso
+var tiposObservados = []string{
+	"evento_creado", "evento_cerrado", "evento_reabierto",
+	"estado_temporal_", "asistencia_", "correccion_asistencia_", "justificacion_",
+}
+
+// CursoDiff payload de monitor_curso_actualizado. Cambios usa los nombres JSON de Curso;
+// un campo en null dejo de tener valor. Un curso nuevo llega con todos sus campos.
+type CursoDiff struct {
+	Version   uint64                 `json:"version"`
+	CursoID   string                 `json:"curso_id"`
+	Cambios   map[string]interface{} `json:"cambios,omitempty"`
+	Eliminado bool                   `json:"eliminado,omitempty"`
+}
+
+// AlumnosDiff payload de monitor_alumnos_actualizados: solo avisa que cambiaron alumnos del curso
+// (ubicacion, asistencia, eventos, paso a excedido, entrada o salida del curso). El WS no esta autenticado,
+// asi que no lleva datos de alumnos: el cliente vuelve a pedir GET /monitor/alumnos?curso_id= (protegido).
+// El tiempo transcurrido por si solo no genera mensaje: el cliente lo calcula desde estado_temporal.inicio.
+type AlumnosDiff struct {
+	Version uint64 `json:"version"`
+	CursoID string `json:"curso_id"`
+}
+
+// Estado mantiene en memoria el semaforo de cada curso y el estado de cada alumno. Se carga completo al iniciar y luego solo
+// recalcula los cursos afectados por los mensajes del orquestador (Observar). Cada cambio se difunde
+// como monitor_curso_actualizado / monitor_alumnos_actualizados; si un cliente ve un salto de version
+// debe volver a pedir el snapshot correspondiente.
+// Una reconciliacion completa periodica (MONITOR_RECONCILIAR_MIN, default 5) corrige lo que no pasa
+// por el orquestador (cursos nuevos, cambios de color de conceptos, transacciones revertidas).
+type Estado struct {
+	db        *gorm.DB
+	notificar func(tipo string, payload interface{})
+
+	mu             sync.RWMutex
+	cursos         map[string]Curso
+	version        uint64
+	porAlumno      map[string]Alumno
+	versionAlumnos uint64
+	actualizado    time.Time
+	dia            string
+	listo          bool
+	// diffs generados con mu tomado; se envian al soltarlo (liberar) para no bloquear lectores en notificar
+	salida []mensaje
+
+	colaMu     sync.Mutex
+	sucios     map[uuid.UUID]bool
+	alumnos    map[uuid.UUID]bool
+	revisiones map[uuid.UUID]bool
+	wake       chan struct{}
+}
+
+// mensaje diff pendiente de difundir
+type mensaje struct {
+	tipo    string
+	payload interface{}
+}
+
+// NewEstado crea el estado del monitor; notificar difunde los diffs (ej. Orchestrator.Notify)
+func NewEstado(db *gorm.DB, notificar func(tipo string, payload interface{})) *Estado {
+	return &Estado{
+		db:         db,
+		notificar:  notificar,
+		cursos:     map[string]Curso{},
+		porAlumno:  map[string]Alumno{},
+		sucios:     map[uuid.UUID]bool{},
+		alumnos:    map[uuid.UUID]bool{},
+		revisiones: map[uuid.UUID]bool{},
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Observar recibe los mensajes del orquestador y marca el curso afectado. No bloquea ni consulta la DB
+// (puede llamarse dentro de una transaccion); el recalculo lo hace Run.
+func (e *Estado) Observar(tipo string, payload interface{}) {
+	if !observado(tipo) {
+		return
+	}
+	// Los payloads son modelos o mapas: basta con curso_id o alumno_id
+	var ref struct {
+		CursoID  string `json:"curso_id"`
+		AlumnoID string `json:"alumno_id"`
+	}
+	b, err := json.Marshal(payload)
+	if err != nil || json.Unmarshal(b, &ref) != nil {
+		return
+	}
+	e.colaMu.Lock()
+	if id, err := uuid.Parse(ref.CursoID); err == nil {
+		e.sucios[id] = true
+	} else if id, err := uuid.Parse(ref.AlumnoID); err == nil {
+		e.alumnos[id] = true
+	}
+	e.colaMu.Unlock()
+	e.despertar()
+}
+
+// Snapshot retorna el estado en memoria (o lo calcula desde la DB si aun no se carga)
+func (e *Estado) Snapshot() (Snapshot, error) {
+	e.mu.RLock()
+	if e.listo {
+		out := Snapshot{UpdatedAt: e.actualizado, Version: e.version, Cursos: ordenados(e.cursos)}
+		e.mu.RUnlock()
+		return out, nil
+	}
+	e.mu.RUnlock()
+
+	now := time.Now()
+	cursos, err := Calcular(e.db, now, nil)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	return Snapshot{UpdatedAt: now, Cursos: cursos}, nil
+}
+
+// Alumnos retorna el estado por alumno en memoria (o lo calcula desde la DB si aun no se carga)
+func (e *Estado) Alumnos(f FiltroAlumnos) (SnapshotAlumnos, error) {
+	e.mu.RLock()
+	if e.listo {
+		out := SnapshotAlumnos{UpdatedAt: e.actualizado, Version: e.versionAlumnos, Alumnos: []Alumno{}}
+		for _, a := range e.porAlumno {
+			if f.Incluye(a) {
+				out.Alumnos = append(out.Alumnos, a)
+			}
+		}
+		e.mu.RUnlock()
+		ordenarAlumnos(out.Alumnos)
+		return out, nil
+	}
+	e.mu.RUnlock()
+	return CalcularSnapshotAlumnos(e.db, f)
+}
+
+// CalcularSnapshotAlumnos estado por alumno directo desde la DB (sin estado en memoria)
+func CalcularSnapshotAlumnos(db *gorm.DB, f FiltroAlumnos) (SnapshotAlumnos, error) {
+	now := time.Now()
+	var ids []uuid.UUID
+	if f.CursoID != "" {
+		id, err := uuid.Parse(f.CursoID)
+		if err != nil {
+			return SnapshotAlumnos{UpdatedAt: now, Alumnos: []Alumno{}}, nil
+		}
+		ids = []uuid.UUID{id}
+	}
+	alumnos, err := CalcularAlumnos(db, now, ids)
+	if err != nil {
+		return SnapshotAlumnos{}, err
+	}
+	out := SnapshotAlumnos{UpdatedAt: now, Alumnos: []Alumno{}}
+	for _, a := range alumnos {
+		if f.Incluye(a) {
+			out.Alumnos = append(out.Alumnos, a)
+		}
+	}
+	return out, nil
+}
+
+// Run carga el estado y lo mantiene al dia hasta que se cierre stop
+func (e *Estado) Run(stop <-chan struct{}) {
+	reconciliar := 5 * time.Minute
+	if v, err := strconv.Atoi(os.Getenv("MONITOR_RECONCILIAR_MIN")); err == nil && v > 0 {
+		reconciliar = time.Duration(v) * time.Minute
+	}
+
+	e.recargar()
+	agendaT := time.NewTicker(intervaloAgenda)
+	defer agendaT.Stop()
+	reconciliarT := time.NewTicker(reconciliar)
+	defer reconciliarT.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-e.wake:
+			select {
+			case <-stop:
+				return
+			case <-time.After(debounce):
+			}
+			e.procesarCola()
+		case <-agendaT.C:
+			if time.Now().Format("2006-01-02") != e.diaCargado() {
+				// Cambio de dia: atrasos/retiros y agenda parten de cero
+				e.recargar()
+			} else {
+				e.refrescarAgenda()
+			}
+		case <-reconciliarT.C:
+			e.recargar()
+		}
+	}
+}
+
+// recargar recalcula todos los cursos y difunde las diferencias con lo que habia en memoria
+func (e *Estado) recargar() {
+	now := time.Now()
+	cursos, err := Calcular(e.db, now, nil)
+	if err != nil {
+		log.Printf("monitor: error loading state: %v", err)
+		return
+	}
+	alumnos, err := CalcularAlumnos(e.db, now, nil)
+	if err != nil {
+		log.Printf("monitor: error loading students: %v", err)
+		return
+	}
+	e.mu.Lock()
+	defer e.liberar()
+	vistos := make(map[string]bool, len(cursos))
+	for _, c := range cursos {
+		vistos[c.CursoID] = true
+		e.aplicar(c)
+	}
+	for id := range e.cursos {
+		if !vistos[id] {
+			e.eliminar(id)
+		}
+	}
+	e.aplicarAlumnos(alumnos, func(Alumno) bool { return true })
+	e.actualizado = now
+	e.dia = now.Format("2006-01-02")
+	e.listo = true
+}
+
+// procesarCola recalcula solo los cursos marcados por Observar (y los de revision)
+func (e *Estado) procesarCola() {
+	e.colaMu.Lock()
+	sucios, alumnosSucios, revisiones := e.sucios, e.alumnos, e.revisiones
+	e.sucios, e.alumnos, e.revisiones = map[uuid.UUID]bool{}, map[uuid.UUID]bool{}, map[uuid.UUID]bool{}
+	e.colaMu.Unlock()
+
+	if len(alumnosSucios) > 0 {
+		var ids []uuid.UUID
+		if err := e.db.Model(&models.Alumno{}).Where("id IN ?", claves(alumnosSucios)).Pluck("curso_id", &ids).Error; err != nil {
+			log.Printf("monitor: error resolving students: %v", err)
+		}
+		for _, id := range ids {
+			if id != uuid.Nil {
+				sucios[id] = true
+			}
+		}
+	}
+	if len(sucios) == 0 && len(revisiones) == 0 {
+		return
+	}
+	ids := claves(sucios)
+	for id := range revisiones {
+		if !sucios[id] {
+			ids = append(ids, id)
+		}
+	}
+
+	now := time.Now()
+	cursos, err := Calcular(e.db, now, ids)
+	var alumnos []Alumno
+	if err == nil {
+		alumnos, err = CalcularAlumnos(e.db, now, ids)
+	}
+	if err != nil {
+		log.Printf("monitor: error updating courses: %v", err)
+		// Se reintentan en la proxima pasada
+		e.colaMu.Lock()
+		for _, id := range ids {
+			e.sucios[id] = true
+		}
+		e.colaMu.Unlock()
+		return
+	}
+
+	e.mu.Lock()
+	vistos := make(map[string]bool, len(cursos))
+	for _, c := range cursos {
+		vistos[c.CursoID] = true
+		e.aplicar(c)
+	}
+	for _, id := range ids {
+		if !vistos[id.String()] {
+			e.eliminar(id.String())
+		}
+	}
+	alcance := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		alcance[id.String()] = true
+	}
+	e.aplicarAlumnos(alumnos, func(a Alumno) bool { return alcance[a.CursoID] })
+	e.actualizado = now
+	e.liberar()
+
+	if len(sucios) > 0 {
+		pendientes := claves(sucios)
+		time.AfterFunc(revision, func() {
+			e.colaMu.Lock()
+			for _, id := range pendientes {
+				e.revisiones[id] = true
+			}
+			e.colaMu.Unlock()
+			e.despertar()
+		})
+	}
+}
+
+// refrescarAgenda recalcula lo que depende de la hora (bloque actual, pendientes, profesor, estados
+// temporales excedidos) sin tocar conteos
+func (e *Estado) refrescarAgenda() {
+	now := time.Now()
+	actual, pend, err := agendaDelDia(e.db, now, nil)
+	if err != nil {
+		log.Printf("monitor: error loading schedule: %v", err)
+		return
+	}
+	est := models.ObtenerEstablecimiento(e.db)
+	umbrales := UmbralesDe(est)
+
+	e.mu.Lock()
+	defer e.liberar()
+	for _, c := range e.cursos {
+		c.BloqueActual = actual[c.CursoID]
+		c.BloquesSinAsistencia = pend[c.CursoID]
+		c.ProfesorSemaforo = SemaforoProfesor(c.UltimaAsistenciaEn, now, umbrales)
+		e.aplicar(c)
+	}
+	var temporales []Alumno
+	for _, a := range e.porAlumno {
+		if a.EstadoTemporal == nil {
+			continue
+		}
+		et := *a.EstadoTemporal
+		et.MaximoMin = est.DuracionMaxEstadoTemporal(et.Tipo)
+		a.EstadoTemporal = &et
+		ActualizarTiempo(&a, now)
+		temporales = append(temporales, a)
+	}
+	e.aplicarAlumnos(temporales, nil)
+	e.actualizado = now
+}
+
+// aplicar guarda el curso y difunde los campos que cambiaron. Requiere e.mu tomado.
+func (e *Estado) aplicar(nuevo Curso) {
+	anterior, existia := e.cursos[nuevo.CursoID]
+	e.cursos[nuevo.CursoID] = nuevo
+	if !e.listo {
+		// Carga inicial: los clientes toman el snapshot completo
+		return
+	}
+	var cambios map[string]interface{}
+	if existia {
+		cambios = diferencias(anterior, nuevo)
+	} else {
+		cambios = aMapa(nuevo)
+	}
+	if len(cambios) == 0 {
+		return
+	}
+	e.version++
+	e.difundir(CursoDiff{Version: e.version, CursoID: nuevo.CursoID, Cambios: cambios})
+}
+
+// aplicarAlumnos guarda el estado de los alumnos y avisa, por curso, si alguno cambio. Los alumnos en
+// memoria que cumplen alcance y no vienen en nuevos se eliminan (nil: no elimina). Requiere e.mu tomado.
+func (e *Estado) aplicarAlumnos(nuevos []Alumno, alcance func(Alumno) bool) {
+	cambiados := map[string]bool{}
+	vistos := make(map[string]bool, len(nuevos))
+	for _, a := range nuevos {
+		vistos[a.AlumnoID] = true
+		anterior, existia := e.porAlumno[a.AlumnoID]
+		e.porAlumno[a.AlumnoID] = a
+		if existia && firmaAlumno(anterior) == firmaAlumno(a) {
+			continue
+		}
+		if existia && anterior.CursoID != a.CursoID {
+			// Cambio de curso: sale de la lista del curso anterior
+			cambiados[anterior.CursoID] = true
+		}
+		cambiados[a.CursoID] = true
+	}
+	if alcance != nil {
+		for id, a := range e.porAlumno {
+			if !vistos[id] && alcance(a) {
+				delete(e.porAlumno, id)
+				cambiados[a.CursoID] = true
+			}
+		}
+	}
+
+	if !e.listo {
+		return
+	}
+	cursoIDs := make([]string, 0, len(cambiados))
+	for id := range cambiados {
+		cursoIDs = append(cursoIDs, id)
+	}
+	sort.Strings(cursoIDs)
+	for _, id := range cursoIDs {
+		e.versionAlumnos++
+		e.salida = append(e.salida, mensaje{TipoAlumnosActualizados, AlumnosDiff{Version: e.versionAlumnos, CursoID: id}})
+	}
+}
+
+// eliminar quita un curso que ya no existe. Requiere e.mu tomado.
+func (e *Estado) eliminar(id string) {
+	if _, ok := e.cursos[id]; !ok {
+		return
+	}
+	delete(e.cursos, id)
+	e.version++
+	e.difundir(CursoDiff{Version: e.version, CursoID: id, Eliminado: true})
+}
+
+// difundir encola el diff del curso. Requiere e.mu tomado.
+func (e *Estado) difundir(d CursoDiff) {
+	e.salida = append(e.salida, mensaje{TipoCursoActualizado, d})
+}
+
+// liberar suelta e.mu y envia los diffs encolados, en orden de version. Solo Run modifica el estado,
+// asi que los envios no se intercalan.
+func (e *Estado) liberar() {
+	salida := e.salida
+	e.salida = nil
+	e.mu.Unlock()
+	if e.notificar == nil {
+		return
+	}
+	for _, m := range salida {
+		e.notificar(m.tipo, m.payload)
+	}
+}
+
+func (e *Estado) despertar() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (e *Estado) diaCargado() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.dia
+}
+
+func observado(tipo string) bool {
+	if tipo == TipoCursoActualizado {
+		return false
+	}
+	for _, p := range tiposObservados {
+		if strings.HasPrefix(tipo, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// diferencias campos JSON de nuevo distintos a los de anterior (null si el campo desaparece)
+func diferencias(anterior, nuevo Curso) map[string]interface{} {
+	a, n := aMapa(anterior), aMapa(nuevo)
+	out := map[string]interface{}{}
+	for k, v := range n {
+		if !reflect.DeepEqual(a[k], v) {
+			out[k] = v
+		}
+	}
+	for k := range a {
+		if _, ok := n[k]; !ok {
+			out[k] = nil
+		}
+	}
+	return out
+}
+
+// firmaAlumno compara estados de alumno sin el tiempo transcurrido (cambia cada minuto)
+func firmaAlumno(a Alumno) string {
+	if a.EstadoTemporal != nil {
+		et := *a.EstadoTemporal
+		et.TranscurridoMin = 0
+		a.EstadoTemporal = &et
+	}
+	b, _ := json.Marshal(a)
+	return string(b)
+}
+
+func aMapa(c Curso) map[string]interface{} {
+	b, _ := json.Marshal(c)
+	out := map[string]interface{}{}
+	_ = json.Unmarshal(b, &out)
+	return out
+}
+
+// ordenados los cursos como el listado de cursos (nivel, nombre)
+func ordenados(cursos map[string]Curso) []Curso {
+	out := make([]Curso, 0, len(cursos))
+	for _, c := range cursos {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Nivel != out[j].Nivel {
+			return out[i].Nivel < out[j].Nivel
+		}
+		if out[i].Nombre != out[j].Nombre {
+			return out[i].Nombre < out[j].Nombre
+		}
+		return out[i].CursoID < out[j].CursoID
+	})
+	return out
+}
+
+func claves(m map[uuid.UUID]bool) []uuid.UUID {
+	out := make([]uuid.UUID, 0, len(m))
+	for id := range m {
+		out = append(out, id)
+	}
+	return out
+}
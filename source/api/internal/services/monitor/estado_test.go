@@ -0,0 +1,140 @@
+package monitor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiferencias(t *testing.T) {
+	ultima := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	base := Curso{CursoID: "c1", Nombre: "1A", SalaSemaforo: "verde", EventosActivos: 1, UltimaAsistenciaEn: &ultima,
+		BloqueActual: &Bloque{HorarioID: "h1", Numero: 1, ProfesorID: "p1"}}
+
+	cambiado := base
+	cambiado.SalaSemaforo = "rojo"
+	cambiado.EventosActivos = 2
+	otroBloque := *base.BloqueActual
+	otroBloque.Reemplazo = true
+	conReemplazo := base
+	conReemplazo.BloqueActual = &otroBloque
+	sinBloque := base
+	sinBloque.BloqueActual = nil
+
+	cases := []struct {
+		name   string
+		nuevo  Curso
+		claves []string
+	}{
+		{"igual", base, nil},
+		{"campos simples", cambiado, []string{"eventos_activos", "sala_semaforo"}},
+		{"objeto anidado", conReemplazo, []string{"bloque_actual"}},
+		{"campo que desaparece", sinBloque, []string{"bloque_actual"}},
+	}
+	for _, tc := range cases {
+		got := diferencias(base, tc.nuevo)
+		var claves []string
+		for k := range got {
+			claves = append(claves, k)
+		}
+		if len(claves) != len(tc.claves) {
+			t.Errorf("%s: %v", tc.name, got)
+			continue
+		}
+		for _, k := range tc.claves {
+			if _, ok := got[k]; !ok {
+				t.Errorf("%s: falta %s en %v", tc.name, k, got)
+			}
+		}
+	}
+	if got := diferencias(base, sinBloque); got["bloque_actual"] != nil {
+		t.Errorf("un campo que desaparece va en null: %v", got)
+	}
+	if got := diferencias(base, cambiado); got["sala_semaforo"] != "rojo" || got["eventos_activos"] != float64(2) {
+		t.Errorf("valores: %v", got)
+	}
+}
+
+// Los diffs se envian despues de soltar el lock, en orden de version
+func TestEstadoDifundeSinLock(t *testing.T) {
+	var e *Estado
+	var recibidos []mensaje
+	e = NewEstado(nil, func(tipo string, payload interface{}) {
+		if !e.mu.TryLock() {
+			t.Errorf("%s: notificar con el lock tomado", tipo)
+		} else {
+			e.mu.Unlock()
+		}
+		recibidos = append(recibidos, mensaje{tipo, payload})
+	})
+
+	// Carga inicial: no se difunde nada
+	e.mu.Lock()
+	e.aplicar(Curso{CursoID: "c1", SalaSemaforo: "verde"})
+	e.aplicarAlumnos([]Alumno{{AlumnoID: "a1", CursoID: "c1", Ubicacion: UbicacionSala}}, nil)
+	e.listo = true
+	e.liberar()
+	if len(recibidos) != 0 {
+		t.Fatalf("carga inicial difundida: %v", recibidos)
+	}
+
+	e.mu.Lock()
+	e.aplicar(Curso{CursoID: "c1", SalaSemaforo: "rojo"})
+	e.aplicar(Curso{CursoID: "c2", SalaSemaforo: "verde"})
+	e.eliminar("c2")
+	e.aplicarAlumnos([]Alumno{{AlumnoID: "a1", CursoID: "c2", Ubicacion: UbicacionBano}}, nil)
+	if len(recibidos) != 0 {
+		t.Fatal("se difundio con el lock tomado")
+	}
+	e.liberar()
+
+	var versiones []uint64
+	for _, m := range recibidos[:3] {
+		d := m.payload.(CursoDiff)
+		versiones = append(versiones, d.Version)
+	}
+	if !reflect.DeepEqual(versiones, []uint64{1, 2, 3}) || !recibidos[2].payload.(CursoDiff).Eliminado {
+		t.Fatalf("diffs de curso: %+v", recibidos)
+	}
+	// Cambio de curso: sale de c1 y entra en c2
+	if len(recibidos) != 5 {
+		t.Fatalf("mensajes: %d", len(recibidos))
+	}
+	sale, entra := recibidos[3].payload.(AlumnosDiff), recibidos[4].payload.(AlumnosDiff)
+	if sale.CursoID != "c1" || entra.CursoID != "c2" {
+		t.Fatalf("diffs de alumnos: %+v %+v", sale, entra)
+	}
+	if sale.Version != 1 || entra.Version != 2 {
+		t.Fatalf("versiones de alumnos: %d %d", sale.Version, entra.Version)
+	}
+}
+
+// El tiempo transcurrido por si solo no genera mensaje
+func TestAplicarAlumnosIgnoraTranscurrido(t *testing.T) {
+	var recibidos int
+	e := NewEstado(nil, func(string, interface{}) { recibidos++ })
+	et := &EstadoTemporal{ID: "t1", Tipo: "bano", TranscurridoMin: 1, MaximoMin: 10}
+	e.mu.Lock()
+	e.listo = true
+	e.aplicarAlumnos([]Alumno{{AlumnoID: "a1", CursoID: "c1", EstadoTemporal: et}}, nil)
+	e.liberar()
+	recibidos = 0
+
+	mas := *et
+	mas.TranscurridoMin = 5
+	e.mu.Lock()
+	e.aplicarAlumnos([]Alumno{{AlumnoID: "a1", CursoID: "c1", EstadoTemporal: &mas}}, nil)
+	e.liberar()
+	if recibidos != 0 {
+		t.Fatalf("mensajes por tiempo transcurrido: %d", recibidos)
+	}
+
+	otro := mas
+	otro.Tipo = "enfermeria"
+	e.mu.Lock()
+	e.aplicarAlumnos([]Alumno{{AlumnoID: "a1", CursoID: "c1", EstadoTemporal: &otro}}, func(a Alumno) bool { return a.CursoID == "c1" })
+	e.liberar()
+	if recibidos != 1 {
+		t.Fatalf("cambio de estado: %d mensajes", recibidos)
+	}
+}
@@ -0,0 +1,62 @@
+// Package monitor calcula los semaforos del monitor de inspectoria.
+package monitor
+
+import (
+	"time"
+
+	"github.com/school-monitoring/backend/internal/models"
+)
+
+// Umbrales del semaforo de presencia del profesor (configurables por establecimiento)
+type Umbrales struct {
+	ProfesorVerde    time.Duration
+	ProfesorAmarillo time.Duration
+}
+
+// UmbralesDe toma los umbrales del establecimiento (defaults si no estan configurados)
+func UmbralesDe(e models.Establecimiento) Umbrales {
+	verde, amarillo := e.MonitorProfesorVerdeMin, e.MonitorProfesorAmarilloMin
+	if verde <= 0 {
+		verde = models.UmbralProfesorVerdeMin
+	}
+	if amarillo <= verde {
+		amarillo = verde + (models.UmbralProfesorAmarilloMin - models.UmbralProfesorVerdeMin)
+	}
+	return Umbrales{
+		ProfesorVerde:    time.Duration(verde) * time.Minute,
+		ProfesorAmarillo: time.Duration(amarillo) * time.Minute,
+	}
+}
+
+// SemaforoProfesor segun el tiempo desde la ultima asistencia registrada en el curso (gris si no hay o es antigua)
+func SemaforoProfesor(ultima *time.Time, now time.Time, u Umbrales) string {
+	if ultima == nil {
+		return models.SemaforoGris
+	}
+	switch d := now.Sub(*ultima); {
+	case d <= u.ProfesorVerde:
+		return models.SemaforoVerde
+	case d <= u.ProfesorAmarillo:
+		return models.SemaforoAmarillo
+	}
+	return models.SemaforoGris
+}
+
+// SemaforoSala el color mas grave entre los conceptos con eventos activos (conteo por color de concepto).
+// Sin eventos, estados temporales ni asistencia registrada la sala queda gris (sin datos).
+func SemaforoSala(eventosPorColor map[string]int, estadosTemporales int, conAsistencia bool) string {
+	total := 0
+	for _, n := range eventosPorColor {
+		total += n
+	}
+	if total == 0 && estadosTemporales == 0 && !conAsistencia {
+		return models.SemaforoGris
+	}
+	switch {
+	case eventosPorColor[models.SemaforoRojo] > 0:
+		return models.SemaforoRojo
+	case eventosPorColor[models.SemaforoAmarillo] > 0:
+		return models.SemaforoAmarillo
+	}
+	return models.SemaforoVerde
+}
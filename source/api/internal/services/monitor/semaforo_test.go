@@ -0,0 +1,72 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/school-monitoring/backend/internal/models"
+)
+
+func TestUmbralesDe(t *testing.T) {
+	cases := []struct {
+		name            string
+		verde, amarillo int
+		wantV, wantA    int
+	}{
+		{"configurados", 30, 60, 30, 60},
+		{"sin configurar", 0, 0, models.UmbralProfesorVerdeMin, models.UmbralProfesorAmarilloMin},
+		{"amarillo menor que verde", 100, 50, 100, 100 + models.UmbralProfesorAmarilloMin - models.UmbralProfesorVerdeMin},
+	}
+	for _, tc := range cases {
+		u := UmbralesDe(models.Establecimiento{MonitorProfesorVerdeMin: tc.verde, MonitorProfesorAmarilloMin: tc.amarillo})
+		if u.ProfesorVerde != time.Duration(tc.wantV)*time.Minute || u.ProfesorAmarillo != time.Duration(tc.wantA)*time.Minute {
+			t.Errorf("%s: %+v", tc.name, u)
+		}
+	}
+}
+
+func TestSemaforoProfesor(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	u := Umbrales{ProfesorVerde: 75 * time.Minute, ProfesorAmarillo: 150 * time.Minute}
+	hace := func(d time.Duration) *time.Time { t := now.Add(-d); return &t }
+	cases := []struct {
+		name   string
+		ultima *time.Time
+		want   string
+	}{
+		{"sin registro", nil, models.SemaforoGris},
+		{"reciente", hace(10 * time.Minute), models.SemaforoVerde},
+		{"limite verde", hace(75 * time.Minute), models.SemaforoVerde},
+		{"amarillo", hace(76 * time.Minute), models.SemaforoAmarillo},
+		{"limite amarillo", hace(150 * time.Minute), models.SemaforoAmarillo},
+		{"antiguo", hace(151 * time.Minute), models.SemaforoGris},
+	}
+	for _, tc := range cases {
+		if got := SemaforoProfesor(tc.ultima, now, u); got != tc.want {
+			t.Errorf("%s: %s", tc.name, got)
+		}
+	}
+}
+
+func TestSemaforoSala(t *testing.T) {
+	cases := []struct {
+		name          string
+		eventos       map[string]int
+		temporales    int
+		conAsistencia bool
+		want          string
+	}{
+		{"sin datos", nil, 0, false, models.SemaforoGris},
+		{"solo asistencia", nil, 0, true, models.SemaforoVerde},
+		{"estado temporal", nil, 1, false, models.SemaforoVerde},
+		{"evento verde", map[string]int{models.SemaforoVerde: 2}, 0, false, models.SemaforoVerde},
+		{"amarillo", map[string]int{models.SemaforoVerde: 2, models.SemaforoAmarillo: 1}, 0, true, models.SemaforoAmarillo},
+		{"rojo gana", map[string]int{models.SemaforoAmarillo: 3, models.SemaforoRojo: 1}, 0, true, models.SemaforoRojo},
+		{"conteos en cero", map[string]int{models.SemaforoRojo: 0}, 0, false, models.SemaforoGris},
+	}
+	for _, tc := range cases {
+		if got := SemaforoSala(tc.eventos, tc.temporales, tc.conAsistencia); got != tc.want {
+			t.Errorf("%s: %s", tc.name, got)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package orchestrator
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/school-monitoring/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// coincideDatos evalua los filtros de la regla sobre Evento.Datos (misma semantica que filtroDatos en SQL)
+func coincideDatos(datos json.RawMessage, filtros []models.FiltroDatos) bool {
+	if len(filtros) == 0 {
+		return true
+	}
+	var raiz interface{}
+	if len(bytes.TrimSpace(datos)) > 0 {
+		if err := json.Unmarshal(datos, &raiz); err != nil {
+			return false
+		}
+	}
+	for _, f := range filtros {
+		v, ok := valorEnRuta(raiz, f.Ruta())
+		if !coincideFiltro(f, v, ok) {
+			return false
+		}
+	}
+	return true
+}
+
+func valorEnRuta(v interface{}, ruta []string) (interface{}, bool) {
+	for _, campo := range ruta {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = obj[campo]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+func coincideFiltro(f models.FiltroDatos, v interface{}, existe bool) bool {
+	switch f.Operador {
+	case models.FiltroExiste:
+		return existe && v != nil
+	case models.FiltroIgual:
+		return existe && mismoJSON(v, f.Valor)
+	case models.FiltroDistinto:
+		return !existe || !mismoJSON(v, f.Valor)
+	case models.FiltroEn:
+		lista, _ := f.Valor.([]interface{})
+		for _, op := range lista {
+			if existe && mismoJSON(v, op) {
+				return true
+			}
+		}
+		return false
+	case models.FiltroMayor, models.FiltroMayorIgual, models.FiltroMenor, models.FiltroMenorIgual:
+		cmp, ok := comparar(v, f.Valor)
+		if !existe || !ok {
+			return false
+		}
+		switch f.Operador {
+		case models.FiltroMayor:
+			return cmp > 0
+		case models.FiltroMayorIgual:
+			return cmp >= 0
+		case models.FiltroMenor:
+			return cmp < 0
+		default:
+			return cmp <= 0
+		}
+	}
+	return false
+}
+
+// comparar solo entre numeros o entre textos (como jsonb_typeof en SQL)
+func comparar(a, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(x, y), true
+	}
+	return 0, false
+}
+
+func mismoJSON(a, b interface{}) bool {
+	x, err1 := json.Marshal(a)
+	y, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && bytes.Equal(x, y)
+}
+
+// filtroDatos agrega los filtros a una consulta sobre eventos (jsonb). Campo ya validado (FiltroDatos.Validar);
+// ruta y valores van como parametros.
+func filtroDatos(filtros []models.FiltroDatos) func(*gorm.DB) *gorm.DB {
+	return func(q *gorm.DB) *gorm.DB {
+		for _, f := range filtros {
+			ruta := "{" + strings.Join(f.Ruta(), ",") + "}"
+			valor, _ := json.Marshal(f.Valor)
+			switch f.Operador {
+			case models.FiltroExiste:
+				q = q.Where("COALESCE(jsonb_typeof(datos #> ?::text[]), 'null') <> 'null'", ruta)
+			case models.FiltroIgual:
+				q = q.Where("datos #> ?::text[] = ?::jsonb", ruta, string(valor))
+			case models.FiltroDistinto:
+				q = q.Where("datos #> ?::text[] IS DISTINCT FROM ?::jsonb", ruta, string(valor))
+			case models.FiltroEn:
+				// Igualdad exacta con algun elemento (@> aceptaria sub-arreglos y sub-objetos)
+				q = q.Where("datos #> ?::text[] IN (SELECT jsonb_array_elements(?::jsonb))", ruta, string(valor))
+			case models.FiltroMayor, models.FiltroMayorIgual, models.FiltroMenor, models.FiltroMenorIgual:
+				if n, ok := f.Valor.(float64); ok {
+					q = q.Where("(CASE WHEN jsonb_typeof(datos #> ?::text[]) = 'number' THEN (datos #>> ?::text[])::numeric END) "+f.Operador+" ?", ruta, ruta, n)
+				} else {
+					// Orden por bytes, igual que strings.Compare (la collation de la base puede diferir)
+					q = q.Where("(CASE WHEN jsonb_typeof(datos #> ?::text[]) = 'string' THEN datos #>> ?::text[] END) COLLATE \"C\" "+f.Operador+" ?", ruta, ruta, f.Valor)
+				}
+			default:
+				q = q.Where("1 = 0")
+			}
+		}
+		return q
+	}
+}
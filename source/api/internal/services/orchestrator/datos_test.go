@@ -0,0 +1,128 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/testutil"
+)
+
+func TestCoincideDatos(t *testing.T) {
+	datos := json.RawMessage(`{"sev": "grave", "n": 3, "u": {"sala": "A1"}, "nulo": null}`)
+	cases := []struct {
+		filtro string
+		want   bool
+	}{
+		{`{"campo": "sev", "operador": "==", "valor": "grave"}`, true},
+		{`{"campo": "n", "operador": "==", "valor": 3.0}`, true},
+		{`{"campo": "u", "operador": "==", "valor": {"sala": "A1"}}`, true},
+		{`{"campo": "u.sala", "operador": "!=", "valor": "A1"}`, false},
+		{`{"campo": "falta", "operador": "!=", "valor": "A1"}`, true},
+		{`{"campo": "nulo", "operador": "existe"}`, false},
+		{`{"campo": "u.sala", "operador": "existe"}`, true},
+		{`{"campo": "sev", "operador": "in", "valor": ["leve", "grave"]}`, true},
+		{`{"campo": "u", "operador": "in", "valor": [{"sala": "A1", "piso": 1}]}`, false},
+		{`{"campo": "n", "operador": ">", "valor": 2}`, true},
+		{`{"campo": "n", "operador": ">", "valor": "2"}`, false},
+		{`{"campo": "sev", "operador": "<", "valor": "h"}`, true},
+	}
+	for _, tc := range cases {
+		var f models.FiltroDatos
+		if err := json.Unmarshal([]byte(tc.filtro), &f); err != nil {
+			t.Fatal(err)
+		}
+		if got := coincideDatos(datos, []models.FiltroDatos{f}); got != tc.want {
+			t.Errorf("%s: %v", tc.filtro, got)
+		}
+	}
+	if !coincideDatos(nil, nil) || coincideDatos(json.RawMessage(`{`), []models.FiltroDatos{{Campo: "a", Operador: models.FiltroExiste}}) {
+		t.Error("sin filtros coincide siempre; datos invalidos nunca")
+	}
+}
+
+// coincideDatos (evento que dispara) y filtroDatos (eventos contados en SQL) deben dar lo mismo
+// para cada operador, incluidos tipos mezclados, nulos, anidados y textos con mayusculas.
+func TestFiltroDatosCoincideConSQL(t *testing.T) {
+	db := testutil.DB(t)
+	concepto := models.Concepto{Codigo: "TEST_" + uuid.NewString()[:8], Nombre: "Test"}
+	if err := db.Create(&concepto).Error; err != nil {
+		t.Fatal(err)
+	}
+	documentos := []string{
+		`{"sev": "grave", "n": 3, "f": "2026-03-02", "u": {"sala": "A1"}, "tags": ["x", "y"], "nulo": null}`,
+		`{"sev": "leve", "n": 3.5, "f": "2026-03-10", "u": {"sala": "b2"}}`,
+		`{"sev": "Grave", "n": "3", "u": {}}`,
+		`{}`,
+		`{"sev": ["grave"], "n": 10, "u": {"sala": "A1", "piso": 1}}`,
+		`{"u": {"sala": {"nombre": "A1"}}, "obj": {"a": 1, "b": 2}, "tags": ["x"]}`,
+	}
+	eventos := make([]models.Evento, len(documentos))
+	for i, d := range documentos {
+		eventos[i] = models.Evento{ConceptoID: &concepto.ID, Origen: models.OrigenSistema, Datos: json.RawMessage(d)}
+		if err := db.Create(&eventos[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	filtros := []string{
+		`{"campo": "sev", "operador": "existe"}`,
+		`{"campo": "nulo", "operador": "existe"}`,
+		`{"campo": "u.sala", "operador": "existe"}`,
+		`{"campo": "u.piso", "operador": "existe"}`,
+		`{"campo": "sev", "operador": "==", "valor": "grave"}`,
+		`{"campo": "n", "operador": "==", "valor": 3}`,
+		`{"campo": "u", "operador": "==", "valor": {"sala": "A1"}}`,
+		`{"campo": "tags", "operador": "==", "valor": ["x", "y"]}`,
+		`{"campo": "nulo", "operador": "==", "valor": null}`,
+		`{"campo": "u.sala", "operador": "==", "valor": "A1"}`,
+		`{"campo": "sev", "operador": "!=", "valor": "grave"}`,
+		`{"campo": "n", "operador": "!=", "valor": 3}`,
+		`{"campo": "nulo", "operador": "!=", "valor": null}`,
+		`{"campo": "u.sala", "operador": "!=", "valor": "A1"}`,
+		`{"campo": "sev", "operador": "in", "valor": ["grave", "leve"]}`,
+		`{"campo": "n", "operador": "in", "valor": [3, 10]}`,
+		`{"campo": "sev", "operador": "in", "valor": [["grave"]]}`,
+		`{"campo": "sev", "operador": "in", "valor": [["grave", "x"]]}`,
+		`{"campo": "tags", "operador": "in", "valor": [["x", "y", "z"]]}`,
+		`{"campo": "obj", "operador": "in", "valor": [{"a": 1}]}`,
+		`{"campo": "nulo", "operador": "in", "valor": [null]}`,
+		`{"campo": "n", "operador": ">", "valor": 3}`,
+		`{"campo": "n", "operador": ">=", "valor": 3}`,
+		`{"campo": "n", "operador": "<", "valor": 10}`,
+		`{"campo": "n", "operador": "<=", "valor": 3.5}`,
+		`{"campo": "n", "operador": ">", "valor": "2"}`,
+		`{"campo": "f", "operador": ">=", "valor": "2026-03-05"}`,
+		`{"campo": "f", "operador": "<", "valor": "2026-03-05"}`,
+		`{"campo": "sev", "operador": ">", "valor": "a"}`,
+		`{"campo": "sev", "operador": "<", "valor": "h"}`,
+		`{"campo": "u.sala", "operador": ">", "valor": "B"}`,
+		`{"campo": "u.sala", "operador": "<=", "valor": "a"}`,
+	}
+	for _, raw := range filtros {
+		var f models.FiltroDatos
+		if err := json.Unmarshal([]byte(raw), &f); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Validar(); err != nil {
+			t.Fatalf("%s: %v", raw, err)
+		}
+		fs := []models.FiltroDatos{f}
+
+		var ids []uuid.UUID
+		if err := db.Model(&models.Evento{}).Where("concepto_id = ?", concepto.ID).
+			Scopes(filtroDatos(fs)).Pluck("id", &ids).Error; err != nil {
+			t.Fatalf("%s: %v", raw, err)
+		}
+		enSQL := map[uuid.UUID]bool{}
+		for _, id := range ids {
+			enSQL[id] = true
+		}
+		for i, e := range eventos {
+			if got := coincideDatos(e.Datos, fs); got != enSQL[e.ID] {
+				t.Errorf("%s sobre %s: go=%v sql=%v", raw, documentos[i], got, enSQL[e.ID])
+			}
+		}
+	}
+}
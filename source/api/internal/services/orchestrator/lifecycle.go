@@ -0,0 +1,197 @@
+package orchestrator
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/eventbus"
+	"gorm.io/gorm"
+)
+
+// Ciclo de vida de eventos: abrir (crear) -> anotar -> cerrar -> reabrir.
+// Toda transicion pasa por aqui para dejar auditoria, broadcast y disparar reglas.
+
+// ErrNotaVacia se retorna al anotar un evento sin texto.
+var ErrNotaVacia = errors.New("la nota no puede estar vacia")
+
+// CreateEventoTx crea (abre) un evento dentro de una transaccion ya existente.
+func (o *Orchestrator) CreateEventoTx(tx *gorm.DB, evt *models.Evento, usuarioID *uuid.UUID) error {
+	if err := tx.Create(evt).Error; err != nil {
+		return err
+	}
+
+	_ = models.CrearAuditoria(tx, "eventos", evt.ID, models.AuditoriaInsert, nil, evt, usuarioID)
+
+	// Cargar relaciones para payload (best-effort)
+	tx.Preload("Concepto").Preload("Alumno").Preload("Curso").First(evt, "id = ?", evt.ID)
+	o.broadcast("evento_creado", evt)
+
+	return o.dispararReglas(tx, evt, eventbus.TopicEventoCreado, models.DisparadorCreado, usuarioID)
+}
+
+// CreateEvento crea un evento, registra auditoria y dispara reglas+acciones.
+func (o *Orchestrator) CreateEvento(evt *models.Evento, usuarioID *uuid.UUID) error {
+	err := o.db.Transaction(func(tx *gorm.DB) error {
+		return o.CreateEventoTx(tx, evt, usuarioID)
+	})
+	if err == nil {
+		o.Flush()
+	}
+	return err
+}
+
+// CloseEventoTx cierra el evento dentro de una transaccion ya existente.
+// Si ya estaba cerrado lo retorna sin cambios.
+func (o *Orchestrator) CloseEventoTx(tx *gorm.DB, eventoID uuid.UUID, usuarioID uuid.UUID, motivo string) (*models.Evento, error) {
+	var out models.Evento
+	if err := tx.First(&out, "id = ?", eventoID).Error; err != nil {
+		return nil, err
+	}
+	if !out.Activo {
+		return &out, nil
+	}
+	before := out
+	out.Cerrar(usuarioID, motivo)
+	if err := tx.Save(&out).Error; err != nil {
+		return nil, err
+	}
+	_ = models.CrearAuditoria(tx, "eventos", out.ID, models.AuditoriaUpdate, &before, &out, &usuarioID)
+	tx.Preload("Concepto").Preload("Alumno").Preload("Curso").First(&out, "id = ?", out.ID)
+	o.broadcast("evento_cerrado", &out)
+
+	if err := o.dispararReglas(tx, &out, eventbus.TopicEventoCerrado, models.DisparadorCerrado, &usuarioID); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CloseEvento cierra el evento (si esta activo), registra auditoria y broadcast.
+func (o *Orchestrator) CloseEvento(eventoID uuid.UUID, usuarioID uuid.UUID, motivo string) (*models.Evento, error) {
+	var out *models.Evento
+	err := o.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		out, err = o.CloseEventoTx(tx, eventoID, usuarioID, motivo)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	o.Flush()
+	return out, nil
+}
+
+// ReopenEventoTx reabre un evento cerrado dentro de una transaccion ya existente.
+// Si ya estaba activo lo retorna sin cambios.
+func (o *Orchestrator) ReopenEventoTx(tx *gorm.DB, eventoID uuid.UUID, usuarioID uuid.UUID) (*models.Evento, error) {
+	var out models.Evento
+	if err := tx.First(&out, "id = ?", eventoID).Error; err != nil {
+		return nil, err
+	}
+	if out.Activo {
+		return &out, nil
+	}
+	before := out
+	out.Reabrir(usuarioID)
+	if err := tx.Save(&out).Error; err != nil {
+		return nil, err
+	}
+	_ = models.CrearAuditoria(tx, "eventos", out.ID, models.AuditoriaUpdate, &before, &out, &usuarioID)
+	tx.Preload("Concepto").Preload("Alumno").Preload("Curso").First(&out, "id = ?", out.ID)
+	o.broadcast("evento_reabierto", &out)
+	return &out, nil
+}
+
+// ReopenEvento reabre un evento cerrado, registra auditoria y broadcast.
+func (o *Orchestrator) ReopenEvento(eventoID uuid.UUID, usuarioID uuid.UUID) (*models.Evento, error) {
+	var out *models.Evento
+	err := o.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		out, err = o.ReopenEventoTx(tx, eventoID, usuarioID)
+		return err
+	})
+	return out, err
+}
+
+// AnnotateEvento agrega una nota a un evento (activo o cerrado).
+func (o *Orchestrator) AnnotateEvento(eventoID uuid.UUID, usuarioID uuid.UUID, texto string) (*models.EventoNota, error) {
+	texto = strings.TrimSpace(texto)
+	if texto == "" {
+		return nil, ErrNotaVacia
+	}
+	nota := models.EventoNota{
+		EventoID:  eventoID,
+		Texto:     texto,
+		UsuarioID: usuarioID,
+	}
+	err := o.db.Transaction(func(tx *gorm.DB) error {
+		var evt models.Evento
+		if err := tx.First(&evt, "id = ?", eventoID).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&nota).Error; err != nil {
+			return err
+		}
+		_ = models.CrearAuditoria(tx, "evento_notas", nota.ID, models.AuditoriaInsert, nil, &nota, &usuarioID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// El WS es publico: solo se avisa que hay una nota nueva, el texto se pide a GET /eventos/{id}/notas
+	o.broadcast("evento_anotado", map[string]string{"evento_id": eventoID.String()})
+	return &nota, nil
+}
+
+// Flush despierta a los workers del bus; llamar despues de hacer commit de cambios hechos con las variantes *Tx.
+func (o *Orchestrator) Flush() {
+	if o.bus != nil {
+		o.bus.Wake()
+	}
+}
+
+// dispararReglas publica la transicion en el bus (reglas fuera del request) o, sin bus, evalua en linea.
+// Un cierre viaja con su cerrado_en y motivo: el worker evalua ese cierre aunque el evento se reabra despues.
+func (o *Orchestrator) dispararReglas(tx *gorm.DB, evt *models.Evento, topico, disparador string, usuarioID *uuid.UUID) error {
+	if o.bus != nil {
+		msg := models.EventoOutbox{Topico: topico, EventoID: evt.ID, UsuarioID: usuarioID}
+		if disparador == models.DisparadorCerrado {
+			msg.CerradoEn = evt.CerradoEn
+			msg.MotivoCierre = evt.MotivoCierre
+		}
+		return o.bus.Publish(tx, msg)
+	}
+	return o.evaluar(tx, evt, disparador, usuarioID)
+}
+
+// onEventoCreado consume evento_creado desde el bus y evalua reglas del evento.
+func (o *Orchestrator) onEventoCreado(tx *gorm.DB, msg *models.EventoOutbox) error {
+	return o.onTransicion(tx, msg, models.DisparadorCreado)
+}
+
+// onEventoCerrado consume evento_cerrado desde el bus y evalua reglas de cierre.
+func (o *Orchestrator) onEventoCerrado(tx *gorm.DB, msg *models.EventoOutbox) error {
+	return o.onTransicion(tx, msg, models.DisparadorCerrado)
+}
+
+func (o *Orchestrator) onTransicion(tx *gorm.DB, msg *models.EventoOutbox, disparador string) error {
+	var evt models.Evento
+	if err := tx.First(&evt, "id = ?", msg.EventoID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Evento eliminado antes de procesarse: nada que evaluar
+			return nil
+		}
+		return err
+	}
+	if disparador == models.DisparadorCerrado {
+		// Reglas de cierre: con el cierre publicado, no con la fila actual (pudo reabrirse o cerrarse otra vez)
+		if msg.CerradoEn == nil {
+			return nil
+		}
+		evt.Activo = false
+		evt.CerradoEn = msg.CerradoEn
+		evt.MotivoCierre = msg.MotivoCierre
+	}
+	return o.evaluar(tx, &evt, disparador, msg.UsuarioID)
+}
@@ -0,0 +1,81 @@
+package orchestrator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/eventbus"
+	"github.com/school-monitoring/backend/internal/testutil"
+	"gorm.io/gorm"
+)
+
+// cerrar -> reabrir -> cerrar: cada mensaje de cierre lleva su propio cerrado_en y motivo
+func TestCierrePublicaSnapshot(t *testing.T) {
+	db := testutil.DB(t)
+	o := New(db, nil, eventbus.New(db))
+
+	concepto := models.Concepto{Codigo: "TEST_" + uuid.NewString()[:8], Nombre: "Test"}
+	if err := db.Create(&concepto).Error; err != nil {
+		t.Fatal(err)
+	}
+	evt := models.Evento{ConceptoID: &concepto.ID, Origen: "sistema", Activo: true}
+	if err := db.Create(&evt).Error; err != nil {
+		t.Fatal(err)
+	}
+	usuario := nuevoUsuario(t, db)
+
+	if _, err := o.CloseEventoTx(db, evt.ID, usuario, models.MotivoCierreResuelto); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, err := o.ReopenEventoTx(db, evt.ID, usuario); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.CloseEventoTx(db, evt.ID, usuario, models.MotivoCierreManual); err != nil {
+		t.Fatal(err)
+	}
+
+	var msgs []models.EventoOutbox
+	db.Where("evento_id = ? AND topico = ?", evt.ID, eventbus.TopicEventoCerrado).Order("created_at").Find(&msgs)
+	if len(msgs) != 2 {
+		t.Fatalf("esperaba 2 mensajes de cierre, hay %d", len(msgs))
+	}
+	if msgs[0].CerradoEn == nil || msgs[1].CerradoEn == nil || msgs[0].CerradoEn.Equal(*msgs[1].CerradoEn) {
+		t.Fatalf("cerrado_en por mensaje: %v %v", msgs[0].CerradoEn, msgs[1].CerradoEn)
+	}
+	if msgs[0].MotivoCierre != models.MotivoCierreResuelto || msgs[1].MotivoCierre != models.MotivoCierreManual {
+		t.Fatalf("motivos: %q %q", msgs[0].MotivoCierre, msgs[1].MotivoCierre)
+	}
+}
+
+// La nota no viaja por el WS publico: solo el evento_id
+func TestAnotarDifundeSoloEvento(t *testing.T) {
+	db := testutil.DB(t)
+	o := New(db, nil, nil)
+	var tipo string
+	var payload interface{}
+	o.Observar(func(t string, p interface{}) { tipo, payload = t, p })
+
+	evt := models.Evento{Origen: "sistema", Activo: true}
+	if err := db.Create(&evt).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.AnnotateEvento(evt.ID, nuevoUsuario(t, db), "texto privado"); err != nil {
+		t.Fatal(err)
+	}
+	m, ok := payload.(map[string]string)
+	if tipo != "evento_anotado" || !ok || len(m) != 1 || m["evento_id"] != evt.ID.String() {
+		t.Fatalf("broadcast: %s %#v", tipo, payload)
+	}
+}
+
+func nuevoUsuario(t *testing.T, db *gorm.DB) uuid.UUID {
+	t.Helper()
+	u := models.Usuario{Email: uuid.NewString()[:8] + "@test.cl", PasswordHash: "x", Nombre: "Test", Rol: models.RolProfesor}
+	if err := db.Create(&u).Error; err != nil {
+		t.Fatal(err)
+	}
+	return u.ID
+}
@@ -2,25 +2,52 @@ package orchestrator
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/calendario"
+	"github.com/school-monitoring/backend/internal/services/eventbus"
 	"github.com/school-monitoring/backend/internal/websocket"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Orchestrator centraliza: registrar eventos -> evaluar reglas -> ejecutar acciones -> auditar -> broadcast
 type Orchestrator struct {
 	db  *gorm.DB
 	hub *websocket.Hub
+	bus *eventbus.Bus
+	// Calendario para ventanas en dias lectivos; evita cargarlo en cada evaluacion de regla
+	cal *calendario.Cache
+
+	obsMu        sync.RWMutex
+	observadores []Observador
 }
 
-func New(db *gorm.DB, hub *websocket.Hub) *Orchestrator {
-	return &Orchestrator{db: db, hub: hub}
+// Observador recibe cada mensaje que difunde el orquestador (ej. estado en memoria del monitor).
+// Se llama en linea, a veces dentro de una transaccion sin commit: no debe bloquear.
+type Observador func(tipo string, payload interface{})
+
+// New crea el orquestador. Con bus != nil la evaluacion de reglas sale del request:
+// el evento se publica en la outbox y la evaluan los workers del bus.
+func New(db *gorm.DB, hub *websocket.Hub, bus *eventbus.Bus) *Orchestrator {
+	o := &Orchestrator{db: db, hub: hub, bus: bus, cal: calendario.NewCache(db, 5*time.Minute)}
+	if bus != nil {
+		bus.Subscribe(eventbus.TopicEventoCreado, o.onEventoCreado)
+		bus.Subscribe(eventbus.TopicEventoCerrado, o.onEventoCerrado)
+	}
+	return o
 }
 
+// errEjecucionDuplicada indica que otra entrega del mismo mensaje ya ejecuto la accion.
+var errEjecucionDuplicada = errors.New("ejecucion duplicada")
+
 type BroadcastEnvelope struct {
 	Type      string      `json:"type"`
 	Timestamp time.Time   `json:"ts"`
@@ -28,6 +55,13 @@ type BroadcastEnvelope struct {
 }
 
 func (o *Orchestrator) broadcast(t string, payload interface{}) {
+	o.obsMu.RLock()
+	observadores := o.observadores
+	o.obsMu.RUnlock()
+	for _, obs := range observadores {
+		obs(t, payload)
+	}
+
 	if o.hub == nil {
 		return
 	}
@@ -43,105 +77,97 @@ func (o *Orchestrator) Notify(t string, payload interface{}) {
 	o.broadcast(t, payload)
 }
 
-// CreateEventoTx crea un evento dentro de una transaccion ya existente.
-func (o *Orchestrator) CreateEventoTx(tx *gorm.DB, evt *models.Evento, usuarioID *uuid.UUID) error {
-	if err := tx.Create(evt).Error; err != nil {
-		return err
-	}
-
-	_ = models.CrearAuditoria(tx, "eventos", evt.ID, models.AuditoriaInsert, nil, evt, usuarioID)
-
-	// Cargar relaciones para payload (best-effort)
-	tx.Preload("Concepto").Preload("Alumno").Preload("Curso").First(evt, "id = ?", evt.ID)
-	o.broadcast("evento_creado", evt)
-
-	return o.EvaluateAndExecute(tx, evt, usuarioID)
-}
-
-// CreateEvento crea un evento, registra auditoria y dispara reglas+acciones.
-func (o *Orchestrator) CreateEvento(evt *models.Evento, usuarioID *uuid.UUID) error {
-	return o.db.Transaction(func(tx *gorm.DB) error {
-		return o.CreateEventoTx(tx, evt, usuarioID)
-	})
+// Observar registra un observador de los mensajes difundidos.
+func (o *Orchestrator) Observar(obs Observador) {
+	o.obsMu.Lock()
+	defer o.obsMu.Unlock()
+	o.observadores = append(o.observadores, obs)
 }
 
-// CloseEventoTx cierra el evento dentro de una transaccion ya existente.
-func (o *Orchestrator) CloseEventoTx(tx *gorm.DB, eventoID uuid.UUID, usuarioID uuid.UUID) (*models.Evento, error) {
-	var out models.Evento
-	if err := tx.First(&out, "id = ?", eventoID).Error; err != nil {
-		return nil, err
-	}
-	if !out.Activo {
-		return &out, nil
-	}
-	before := out
-	out.Cerrar(usuarioID)
-	if err := tx.Save(&out).Error; err != nil {
-		return nil, err
-	}
-	_ = models.CrearAuditoria(tx, "eventos", out.ID, models.AuditoriaUpdate, &before, &out, &usuarioID)
-	tx.Preload("Concepto").Preload("Alumno").Preload("Curso").First(&out, "id = ?", out.ID)
-	o.broadcast("evento_cerrado", &out)
-	return &out, nil
-}
-
-// CloseEvento cierra el evento (si esta activo), registra auditoria y broadcast.
-func (o *Orchestrator) CloseEvento(eventoID uuid.UUID, usuarioID uuid.UUID) (*models.Evento, error) {
-	var out models.Evento
-	err := o.db.Transaction(func(tx *gorm.DB) error {
-		_, err := o.CloseEventoTx(tx, eventoID, usuarioID)
-		if err != nil {
-			return err
-		}
-		return nil
-	})
-	return &out, err
+// EvaluateAndExecute evalua reglas activas (disparador "creado") del concepto del evento y ejecuta acciones si corresponde.
+func (o *Orchestrator) EvaluateAndExecute(tx *gorm.DB, evt *models.Evento, usuarioID *uuid.UUID) error {
+	return o.evaluar(tx, evt, models.DisparadorCreado, usuarioID)
 }
 
-// EvaluateAndExecute evalua reglas activas del concepto del evento y ejecuta acciones si corresponde.
-func (o *Orchestrator) EvaluateAndExecute(tx *gorm.DB, evt *models.Evento, usuarioID *uuid.UUID) error {
+// evaluar corre las reglas del disparador indicado.
+// Cada regla corre en su propio savepoint: una regla con error no invalida la transaccion que la contiene.
+func (o *Orchestrator) evaluar(tx *gorm.DB, evt *models.Evento, disparador string, usuarioID *uuid.UUID) error {
 	if evt.ConceptoID == nil || *evt.ConceptoID == uuid.Nil {
 		// Evento sin concepto_id: no hay reglas que evaluar (compat DB vieja)
 		return nil
 	}
 	var reglas []models.Regla
 	if err := tx.Preload("Accion").
-		Where("concepto_id = ? AND activo = ?", *evt.ConceptoID, true).
+		Where("concepto_id = ? AND activo = ? AND disparador = ?", *evt.ConceptoID, true, disparador).
 		Find(&reglas).Error; err != nil {
 		return err
 	}
 
-	for _, regla := range reglas {
-		ok, scopeKey, winStart, winEnd, detail, err := o.evalRegla(tx, &regla, evt)
+	for i := range reglas {
+		regla := &reglas[i]
+		err := tx.Transaction(func(stx *gorm.DB) error {
+			return o.procesarRegla(stx, regla, evt, usuarioID)
+		})
+		if errors.Is(err, errEjecucionDuplicada) {
+			continue
+		}
 		if err != nil {
 			// Registrar auditoria de error sin cortar todo
+			log.Printf("orchestrator: regla %s (evento %s): %v", regla.ID, evt.ID, err)
 			d, _ := json.Marshal(map[string]string{"error": err.Error()})
 			_ = models.CrearAuditoria(tx, "reglas", regla.ID, models.AuditoriaUpdate, nil, json.RawMessage(d), usuarioID)
-			continue
-		}
-		if !ok {
-			continue
 		}
+	}
+	return nil
+}
 
-		// Deduplicacion v2: por regla+accion+scope+ventana (si aplica). Fallback a regla+evento si no hay scope/ventana.
-		var count int64
-		q := tx.Model(&models.AccionEjecucion{}).
-			Where("regla_id = ? AND accion_id = ?", regla.ID, regla.AccionID)
-		if scopeKey != "" && winStart != nil && winEnd != nil {
-			q = q.Where("scope_key = ? AND ventana_inicio = ? AND ventana_fin = ?", scopeKey, *winStart, *winEnd)
-		} else {
-			q = q.Where("evento_id = ?", evt.ID)
-		}
-		q.Count(&count)
-		if count > 0 {
-			continue
-		}
+// procesarRegla evalua la condicion, deduplica y ejecuta la accion de una regla.
+func (o *Orchestrator) procesarRegla(tx *gorm.DB, regla *models.Regla, evt *models.Evento, usuarioID *uuid.UUID) error {
+	ok, scopeKey, winStart, winEnd, detail, err := o.evalRegla(tx, regla, evt)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
 
-		if err := o.executeAccion(tx, &regla, evt, scopeKey, winStart, winEnd, detail, usuarioID); err != nil {
-			continue
+	// Serializa workers concurrentes que deduplican contra la misma regla+accion+scope+ventana (o evento):
+	// el lock se libera al terminar la transaccion, cuando la ejecucion del primero ya es visible.
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", claveDedup(regla, evt, scopeKey, winStart, winEnd)).Error; err != nil {
+		return err
+	}
+
+	// Idempotencia: una re-entrega del mismo evento no vuelve a ejecutar la accion.
+	var count int64
+	if err := tx.Model(&models.AccionEjecucion{}).
+		Where("clave = ?", claveEjecucion(regla, evt)).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	// Deduplicacion v2: por regla+accion+scope+ventana (si aplica). Fallback a regla+evento si no hay scope/ventana.
+	// Las ejecuciones con error tambien cuentan: se resuelven via reintento, no disparando de nuevo.
+	q := tx.Model(&models.AccionEjecucion{}).
+		Where("regla_id = ? AND accion_id = ?", regla.ID, regla.AccionID)
+	if scopeKey != "" && winStart != nil && winEnd != nil {
+		q = q.Where("scope_key = ? AND ventana_inicio = ? AND ventana_fin = ?", scopeKey, *winStart, *winEnd)
+		if err := q.Count(&count).Error; err != nil {
+			return err
+		}
+	} else if regla.Disparador != models.DisparadorCerrado {
+		// Sin ventana: una vez por evento. Las reglas de cierre solo deduplican por clave (incluye el cierre)
+		if err := q.Where("evento_id = ?", evt.ID).Count(&count).Error; err != nil {
+			return err
 		}
 	}
-	return nil
+	if count > 0 {
+		return nil
+	}
+
+	return o.executeAccion(tx, regla, evt, scopeKey, winStart, winEnd, detail, usuarioID)
 }
 
 func (o *Orchestrator) evalRegla(tx *gorm.DB, regla *models.Regla, evt *models.Evento) (bool, string, *time.Time, *time.Time, map[string]interface{}, error) {
@@ -155,6 +181,34 @@ func (o *Orchestrator) evalRegla(tx *gorm.DB, regla *models.Regla, evt *models.E
 		"condicion": cond,
 	}
 
+	// Reglas de cierre: filtrar por motivo
+	if regla.Disparador == models.DisparadorCerrado && len(cond.MotivosCierre) > 0 {
+		detail["motivo_cierre"] = evt.MotivoCierre
+		match := false
+		for _, m := range cond.MotivosCierre {
+			if m == evt.MotivoCierre {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false, "", nil, nil, detail, nil
+		}
+	}
+
+	// Filtros sobre Datos: el evento que dispara debe cumplirlos
+	if len(cond.Datos) > 0 {
+		detail["filtros_datos"] = cond.Datos
+		if !coincideDatos(evt.Datos, cond.Datos) {
+			return false, "", nil, nil, detail, nil
+		}
+	}
+
+	// Regla tipo siempre: dispara en cada evento (dedup por evento)
+	if cond.Tipo == "siempre" {
+		return true, "", nil, nil, detail, nil
+	}
+
 	// Regla tipo caso_especial: si el alumno es caso especial, inhibir disparo (por defecto)
 	if cond.Tipo == "caso_especial" {
 		if evt.AlumnoID == nil {
@@ -172,6 +226,34 @@ func (o *Orchestrator) evalRegla(tx *gorm.DB, regla *models.Regla, evt *models.E
 		return true, "alumno:"+alumno.ID.String(), nil, nil, detail, nil
 	}
 
+	// Regla tipo riesgo: compara el ultimo puntaje de riesgo calculado del alumno
+	// (campo "puntaje" por defecto, o "porcentaje_asistencia")
+	if cond.Tipo == "riesgo" {
+		if evt.AlumnoID == nil {
+			return false, "", nil, nil, detail, nil
+		}
+		var r models.RiesgoAlumno
+		if err := tx.First(&r, "alumno_id = ?", *evt.AlumnoID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return false, "", nil, nil, detail, nil
+			}
+			return false, "", nil, nil, detail, err
+		}
+		valor := r.Puntaje
+		if cond.Campo == "porcentaje_asistencia" {
+			if r.PorcentajeAsistencia == nil {
+				return false, "", nil, nil, detail, nil
+			}
+			valor = *r.PorcentajeAsistencia
+		}
+		detail["riesgo_puntaje"] = r.Puntaje
+		detail["riesgo_nivel"] = r.Nivel
+		detail["porcentaje_asistencia"] = r.PorcentajeAsistencia
+		ok, err := compararValor(cond.Operador, valor, float64(cond.Valor))
+		// Una ejecucion por alumno y calculo de riesgo
+		return ok, "alumno:" + evt.AlumnoID.String(), &r.CalculadoEn, &r.CalculadoEn, detail, err
+	}
+
 	if cond.Tipo != "cantidad" {
 		// Tipos no implementados aun (tiempo, etc.)
 		return false, "", nil, nil, detail, nil
@@ -198,15 +280,27 @@ func (o *Orchestrator) evalRegla(tx *gorm.DB, regla *models.Regla, evt *models.E
 		return false, "", nil, nil, detail, nil
 	}
 
-	// Ventana temporal (dias)
+	// Ventana temporal: dias lectivos (se saltan feriados, suspensiones y vacaciones) salvo dias_corridos
 	dias := cond.Dias
 	if dias <= 0 {
 		dias = 1
 	}
-	since := time.Now().AddDate(0, 0, -dias)
 	until := time.Now()
+	since := until.AddDate(0, 0, -dias)
+	if !cond.DiasCorridos {
+		var cursoCal *uuid.UUID
+		if scope == "curso" {
+			cursoCal = evt.CursoID
+		}
+		if t, err := o.cal.InicioVentana(until, dias, cursoCal); err == nil {
+			since = t
+		} else {
+			log.Printf("orchestrator: calendario no disponible, ventana en dias corridos: %v", err)
+		}
+	}
 	detail["since"] = since
 	detail["until"] = until
+	detail["dias_corridos"] = cond.DiasCorridos
 	detail["distinct_dias"] = cond.DistinctDias
 
 	// Construir scope key y consulta
@@ -222,7 +316,7 @@ func (o *Orchestrator) evalRegla(tx *gorm.DB, regla *models.Regla, evt *models.E
 			// distinct days de ocurrencias
 			type row struct{ D string }
 			var rows []row
-			if err := tx.Model(&models.Evento{}).
+			if err := tx.Model(&models.Evento{}).Scopes(filtroDatos(cond.Datos)).
 				Select("DATE(created_at) as d").
 				Where("concepto_id = ? AND curso_id = ? AND created_at >= ?", conceptoID, *evt.CursoID, since).
 				Group("DATE(created_at)").
@@ -231,7 +325,7 @@ func (o *Orchestrator) evalRegla(tx *gorm.DB, regla *models.Regla, evt *models.E
 			}
 			n = int64(len(rows))
 		} else {
-			if err := tx.Model(&models.Evento{}).
+			if err := tx.Model(&models.Evento{}).Scopes(filtroDatos(cond.Datos)).
 				Where("concepto_id = ? AND curso_id = ? AND created_at >= ?", conceptoID, *evt.CursoID, since).
 				Count(&n).Error; err != nil {
 				return false, scopeKey, &since, &until, detail, err
@@ -246,7 +340,7 @@ func (o *Orchestrator) evalRegla(tx *gorm.DB, regla *models.Regla, evt *models.E
 		if cond.DistinctDias {
 			type row struct{ D string }
 			var rows []row
-			if err := tx.Model(&models.Evento{}).
+			if err := tx.Model(&models.Evento{}).Scopes(filtroDatos(cond.Datos)).
 				Select("DATE(created_at) as d").
 				Where("concepto_id = ? AND alumno_id = ? AND created_at >= ?", conceptoID, *evt.AlumnoID, since).
 				Group("DATE(created_at)").
@@ -255,7 +349,7 @@ func (o *Orchestrator) evalRegla(tx *gorm.DB, regla *models.Regla, evt *models.E
 			}
 			n = int64(len(rows))
 		} else {
-			if err := tx.Model(&models.Evento{}).
+			if err := tx.Model(&models.Evento{}).Scopes(filtroDatos(cond.Datos)).
 				Where("concepto_id = ? AND alumno_id = ? AND created_at >= ?", conceptoID, *evt.AlumnoID, since).
 				Count(&n).Error; err != nil {
 				return false, scopeKey, &since, &until, detail, err
@@ -277,6 +371,41 @@ func (o *Orchestrator) evalRegla(tx *gorm.DB, regla *models.Regla, evt *models.E
 	}
 }
 
+func compararValor(op string, a, b float64) (bool, error) {
+	switch op {
+	case ">=":
+		return a >= b, nil
+	case "<=":
+		return a <= b, nil
+	case ">":
+		return a > b, nil
+	case "<":
+		return a < b, nil
+	case "==":
+		return a == b, nil
+	}
+	return false, fmt.Errorf("operador no soportado: %s", op)
+}
+
+// claveEjecucion es la clave de idempotencia de una ejecucion: regla+accion+evento.
+func claveEjecucion(regla *models.Regla, evt *models.Evento) string {
+	clave := regla.ID.String() + ":" + regla.AccionID.String() + ":" + evt.ID.String()
+	// Un evento puede cerrarse varias veces (reabrir -> cerrar): cada cierre dispara sus reglas de cierre
+	if regla.Disparador == models.DisparadorCerrado && evt.CerradoEn != nil {
+		clave += ":cierre:" + strconv.FormatInt(evt.CerradoEn.UnixMicro(), 10)
+	}
+	return clave
+}
+
+// claveDedup identifica el grupo de deduplicacion v2 (mismo criterio que la consulta de procesarRegla)
+func claveDedup(regla *models.Regla, evt *models.Evento, scopeKey string, winStart, winEnd *time.Time) string {
+	base := "dedup:" + regla.ID.String() + ":" + regla.AccionID.String() + ":"
+	if scopeKey != "" && winStart != nil && winEnd != nil {
+		return base + scopeKey + ":" + winStart.UTC().Format(time.RFC3339Nano) + ":" + winEnd.UTC().Format(time.RFC3339Nano)
+	}
+	return base + "evento:" + claveEjecucion(regla, evt)
+}
+
 func (o *Orchestrator) executeAccion(tx *gorm.DB, regla *models.Regla, evt *models.Evento, scopeKey string, winStart, winEnd *time.Time, detail map[string]interface{}, usuarioID *uuid.UUID) error {
 	if regla.Accion == nil {
 		var accion models.Accion
@@ -287,29 +416,64 @@ func (o *Orchestrator) executeAccion(tx *gorm.DB, regla *models.Regla, evt *mode
 	}
 
 	detailBytes, _ := json.Marshal(detail)
+	clave := claveEjecucion(regla, evt)
 
 	exec := models.AccionEjecucion{
+		Clave:     &clave,
 		ReglaID:   regla.ID,
 		AccionID:  regla.AccionID,
 		EventoID:  evt.ID,
 		AlumnoID:  evt.AlumnoID,
 		CursoID:   evt.CursoID,
-		Resultado: "ok",
+		Resultado: models.EjecucionResultadoOK,
 		Detalle:   detailBytes,
 		ScopeKey:  scopeKey,
 		VentanaInicio: winStart,
 		VentanaFin:    winEnd,
 	}
-	if err := tx.Create(&exec).Error; err != nil {
-		return err
+
+	// Side effects en su propio savepoint: si fallan, la ejecucion queda registrada
+	// con resultado "error" y encolada para reintento.
+	if err := tx.Transaction(func(stx *gorm.DB) error {
+		return o.ejecutarEfectos(stx, regla, evt, detail, usuarioID)
+	}); err != nil {
+		exec.MarcarError(err)
+		exec.SiguienteIntentoEn = proximoIntento(exec.Intentos)
+	} else {
+		exec.MarcarOK()
+	}
+
+	// La clave es unica: si otra entrega concurrente ya la inserto, se revierten los side effects de esta.
+	res := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "clave"}}, DoNothing: true}).Create(&exec)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errEjecucionDuplicada
 	}
 
 	_ = models.CrearAuditoria(tx, "acciones_ejecuciones", exec.ID, models.AuditoriaInsert, nil, &exec, usuarioID)
 
-	// Ejecutar "side effects" (MVP+): alertas operativas persistentes + broadcast
-	if regla.Accion.Tipo == models.TipoAccionAlerta {
+	// Broadcast ejecucion (para trazabilidad realtime)
+	o.broadcast("accion_ejecutada", map[string]interface{}{
+		"regla":  regla,
+		"accion": regla.Accion,
+		"evento": evt,
+		"exec":   exec,
+	})
+
+	return nil
+}
+
+// ejecutarEfectos aplica los "side effects" (MVP+) de la accion: alertas operativas persistentes,
+// notificaciones en outbox y sus broadcast. Cualquier error se propaga para que la ejecucion quede en error.
+func (o *Orchestrator) ejecutarEfectos(tx *gorm.DB, regla *models.Regla, evt *models.Evento, detail map[string]interface{}, usuarioID *uuid.UUID) error {
+	switch regla.Accion.Tipo {
+	case models.TipoAccionAlerta:
 		var p models.ParametrosAlerta
-		_ = json.Unmarshal(regla.Accion.Parametros, &p)
+		if err := json.Unmarshal(regla.Accion.Parametros, &p); err != nil && len(regla.Accion.Parametros) > 0 {
+			return fmt.Errorf("parametros de alerta invalidos: %w", err)
+		}
 
 		prio := p.Prioridad
 		if prio == "" {
@@ -318,30 +482,36 @@ func (o *Orchestrator) executeAccion(tx *gorm.DB, regla *models.Regla, evt *mode
 
 		// Dedup por evento+accion (no crear 2 alertas por el mismo disparo)
 		var existing int64
-		tx.Model(&models.Alerta{}).Where("evento_id = ? AND accion_id = ? AND estado = ?", evt.ID, regla.AccionID, models.AlertaAbierta).Count(&existing)
-		if existing == 0 {
-			alerta := models.Alerta{
-				Codigo:    regla.Accion.Codigo,
-				Titulo:    regla.Accion.Nombre,
-				Prioridad: prio,
-				Estado:    models.AlertaAbierta,
-				CursoID:   evt.CursoID,
-				AlumnoID:  evt.AlumnoID,
-				EventoID:  &evt.ID,
-				ReglaID:   &regla.ID,
-				AccionID:  &regla.AccionID,
-				CreadoPor: usuarioID,
-			}
-			_ = tx.Create(&alerta).Error
-			_ = models.CrearAuditoria(tx, "alertas", alerta.ID, models.AuditoriaInsert, nil, &alerta, usuarioID)
-			o.broadcast("alerta_creada", alerta)
+		if err := tx.Model(&models.Alerta{}).Where("evento_id = ? AND accion_id = ? AND estado = ?", evt.ID, regla.AccionID, models.AlertaAbierta).Count(&existing).Error; err != nil {
+			return err
 		}
-	}
+		if existing > 0 {
+			return nil
+		}
+		alerta := models.Alerta{
+			Codigo:    regla.Accion.Codigo,
+			Titulo:    regla.Accion.Nombre,
+			Prioridad: prio,
+			Estado:    models.AlertaAbierta,
+			CursoID:   evt.CursoID,
+			AlumnoID:  evt.AlumnoID,
+			EventoID:  &evt.ID,
+			ReglaID:   &regla.ID,
+			AccionID:  &regla.AccionID,
+			CreadoPor: usuarioID,
+		}
+		if err := tx.Create(&alerta).Error; err != nil {
+			return fmt.Errorf("creando alerta: %w", err)
+		}
+		_ = models.CrearAuditoria(tx, "alertas", alerta.ID, models.AuditoriaInsert, nil, &alerta, usuarioID)
+		o.broadcast("alerta_creada", alerta)
 
-	// Notificaciones: outbox (asíncrono) + broadcast
-	if regla.Accion.Tipo == models.TipoAccionNotificacion {
+	case models.TipoAccionNotificacion:
+		// Notificaciones: outbox (asíncrono) + broadcast
 		var p models.ParametrosNotificacion
-		_ = json.Unmarshal(regla.Accion.Parametros, &p)
+		if err := json.Unmarshal(regla.Accion.Parametros, &p); err != nil && len(regla.Accion.Parametros) > 0 {
+			return fmt.Errorf("parametros de notificacion invalidos: %w", err)
+		}
 
 		dest := p.Destinatario
 		if dest == "" {
@@ -379,21 +549,12 @@ func (o *Orchestrator) executeAccion(tx *gorm.DB, regla *models.Regla, evt *mode
 			Estado:      models.NotificacionEstadoPendiente,
 			CreadoPor:   usuarioID,
 		}
-		if err := tx.Create(&item).Error; err == nil {
-			_ = models.CrearAuditoria(tx, "notification_outboxes", item.ID, models.AuditoriaInsert, nil, &item, usuarioID)
-			o.broadcast("notificacion_creada", item)
+		if err := tx.Create(&item).Error; err != nil {
+			return fmt.Errorf("encolando notificacion: %w", err)
 		}
+		_ = models.CrearAuditoria(tx, "notification_outboxes", item.ID, models.AuditoriaInsert, nil, &item, usuarioID)
+		o.broadcast("notificacion_creada", item)
 	}
 
-	// Broadcast ejecucion (para trazabilidad realtime)
-	o.broadcast("accion_ejecutada", map[string]interface{}{
-		"regla":  regla,
-		"accion": regla.Accion,
-		"evento": evt,
-		"exec":   exec,
-	})
-
 	return nil
 }
-
-
@@ -0,0 +1,71 @@
+package orchestrator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+)
+
+func TestClaveDedup(t *testing.T) {
+	regla := &models.Regla{ID: uuid.New(), AccionID: uuid.New()}
+	e1 := &models.Evento{ID: uuid.New()}
+	e2 := &models.Evento{ID: uuid.New()}
+	ini := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	fin := ini.AddDate(0, 0, 7)
+	otroFin := fin.AddDate(0, 0, 1)
+	// Misma ventana expresada en otra zona: mismo grupo
+	iniLocal := ini.In(time.FixedZone("CLT", -3*3600))
+
+	cases := []struct {
+		name  string
+		a, b  string
+		igual bool
+	}{
+		{"mismo scope y ventana, distinto evento",
+			claveDedup(regla, e1, "alumno:x", &ini, &fin), claveDedup(regla, e2, "alumno:x", &ini, &fin), true},
+		{"misma ventana en otra zona",
+			claveDedup(regla, e1, "alumno:x", &ini, &fin), claveDedup(regla, e1, "alumno:x", &iniLocal, &fin), true},
+		{"otro scope",
+			claveDedup(regla, e1, "alumno:x", &ini, &fin), claveDedup(regla, e1, "alumno:y", &ini, &fin), false},
+		{"otra ventana",
+			claveDedup(regla, e1, "alumno:x", &ini, &fin), claveDedup(regla, e1, "alumno:x", &ini, &otroFin), false},
+		{"sin ventana: por evento",
+			claveDedup(regla, e1, "alumno:x", nil, nil), claveDedup(regla, e2, "alumno:x", nil, nil), false},
+		{"sin ventana: mismo evento",
+			claveDedup(regla, e1, "", nil, nil), claveDedup(regla, e1, "alumno:x", nil, nil), true},
+	}
+	for _, tc := range cases {
+		if (tc.a == tc.b) != tc.igual {
+			t.Errorf("%s: %q vs %q", tc.name, tc.a, tc.b)
+		}
+	}
+}
+
+func TestClaveEjecucionCierres(t *testing.T) {
+	evt := &models.Evento{ID: uuid.New()}
+	creado := &models.Regla{ID: uuid.New(), AccionID: uuid.New(), Disparador: models.DisparadorCreado}
+	cerrado := &models.Regla{ID: uuid.New(), AccionID: uuid.New(), Disparador: models.DisparadorCerrado}
+
+	primero := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	evt.CerradoEn = &primero
+	k1 := claveEjecucion(cerrado, evt)
+	c1 := claveEjecucion(creado, evt)
+
+	// reabrir -> cerrar de nuevo
+	segundo := primero.Add(time.Hour)
+	evt.CerradoEn = &segundo
+	k2 := claveEjecucion(cerrado, evt)
+	c2 := claveEjecucion(creado, evt)
+
+	if k1 == k2 {
+		t.Error("cada cierre debe tener su propia clave para reglas de cierre")
+	}
+	if c1 != c2 {
+		t.Error("la clave de reglas de creacion no depende del cierre")
+	}
+	if claveEjecucion(cerrado, evt) != k2 {
+		t.Error("la re-entrega del mismo cierre debe dar la misma clave")
+	}
+}
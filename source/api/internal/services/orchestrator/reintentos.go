@@ -0,0 +1,155 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Cola de reintentos: backoff exponencial sobre acciones_ejecuciones con resultado "error".
+const (
+	maxIntentosEjecucion = 5
+	backoffEjecucion     = time.Minute
+)
+
+// ErrEjecucionNoFallida se retorna al intentar reintentar una ejecucion que no esta en error.
+var ErrEjecucionNoFallida = errors.New("la ejecucion no esta en estado error")
+
+// ErrEjecucionHuerfana se retorna cuando la regla, su accion o el evento de la ejecucion ya no existen.
+// La ejecucion queda agotada (sin siguiente intento) con el motivo en Detalle.
+var ErrEjecucionHuerfana = errors.New("la regla, accion o evento de la ejecucion ya no existe")
+
+// proximoIntento calcula el siguiente reintento automatico (nil si se agotaron los intentos).
+func proximoIntento(intentos int) *time.Time {
+	if intentos >= maxIntentosEjecucion {
+		return nil
+	}
+	if intentos < 1 {
+		intentos = 1
+	}
+	t := time.Now().Add(backoffEjecucion * time.Duration(1<<uint(intentos-1)))
+	return &t
+}
+
+// ReintentarEjecucion vuelve a aplicar los side effects de una ejecucion fallida.
+// No re-evalua la condicion de la regla: el disparo ya quedo registrado en la ejecucion original.
+func (o *Orchestrator) ReintentarEjecucion(execID uuid.UUID, usuarioID *uuid.UUID) (*models.AccionEjecucion, error) {
+	var exec models.AccionEjecucion
+	var regla models.Regla
+	var evt models.Evento
+	huerfana := false
+
+	err := o.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&exec, "id = ?", execID).Error; err != nil {
+			return err
+		}
+		if exec.Resultado != models.EjecucionResultadoError {
+			return ErrEjecucionNoFallida
+		}
+		before := exec
+
+		// Sin regla, accion o evento no hay nada que reintentar: se agota para que la cola no la tome de nuevo
+		err := tx.Preload("Accion").First(&regla, "id = ?", exec.ReglaID).Error
+		if err == nil && regla.Accion == nil {
+			err = gorm.ErrRecordNotFound
+		}
+		if err == nil {
+			err = tx.Unscoped().First(&evt, "id = ?", exec.EventoID).Error
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			huerfana = true
+			agotarEjecucion(&exec, ErrEjecucionHuerfana)
+			if err := tx.Save(&exec).Error; err != nil {
+				return err
+			}
+			_ = models.CrearAuditoria(tx, "acciones_ejecuciones", exec.ID, models.AuditoriaUpdate, &before, &exec, usuarioID)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var detail map[string]interface{}
+		_ = json.Unmarshal(exec.Detalle, &detail)
+
+		if err := tx.Transaction(func(stx *gorm.DB) error {
+			return o.ejecutarEfectos(stx, &regla, &evt, detail, usuarioID)
+		}); err != nil {
+			exec.MarcarError(err)
+			exec.SiguienteIntentoEn = proximoIntento(exec.Intentos)
+		} else {
+			exec.MarcarOK()
+		}
+
+		if err := tx.Save(&exec).Error; err != nil {
+			return err
+		}
+		_ = models.CrearAuditoria(tx, "acciones_ejecuciones", exec.ID, models.AuditoriaUpdate, &before, &exec, usuarioID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if huerfana {
+		return &exec, ErrEjecucionHuerfana
+	}
+
+	o.broadcast("accion_reintentada", map[string]interface{}{
+		"regla":  &regla,
+		"accion": regla.Accion,
+		"evento": &evt,
+		"exec":   &exec,
+	})
+	return &exec, nil
+}
+
+// agotarEjecucion registra un intento fallido sin reintento posterior y deja el motivo en Detalle
+func agotarEjecucion(exec *models.AccionEjecucion, motivo error) {
+	exec.MarcarError(motivo)
+	exec.SiguienteIntentoEn = nil
+	detalle := map[string]interface{}{}
+	_ = json.Unmarshal(exec.Detalle, &detalle)
+	if detalle == nil {
+		detalle = map[string]interface{}{}
+	}
+	detalle["error"] = motivo.Error()
+	detalle["agotada"] = true
+	exec.Detalle, _ = json.Marshal(detalle)
+}
+
+// RunReintentos procesa en background las ejecuciones fallidas cuyo siguiente intento ya vencio.
+func (o *Orchestrator) RunReintentos(stop <-chan struct{}) {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			o.reintentarPendientes()
+		}
+	}
+}
+
+func (o *Orchestrator) reintentarPendientes() {
+	var ids []uuid.UUID
+	if err := o.db.Model(&models.AccionEjecucion{}).
+		Where("resultado = ? AND siguiente_intento_en IS NOT NULL AND siguiente_intento_en <= ?", models.EjecucionResultadoError, time.Now()).
+		Order("siguiente_intento_en ASC").
+		Limit(50).
+		Pluck("id", &ids).Error; err != nil {
+		log.Printf("orchestrator: error fetching failed executions: %v", err)
+		return
+	}
+	for _, id := range ids {
+		if _, err := o.ReintentarEjecucion(id, nil); err != nil {
+			log.Printf("orchestrator: retry %s: %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/testutil"
+)
+
+func TestProximoIntento(t *testing.T) {
+	for intentos := 0; intentos < maxIntentosEjecucion; intentos++ {
+		if proximoIntento(intentos) == nil {
+			t.Fatalf("intentos=%d: esperaba siguiente intento", intentos)
+		}
+	}
+	if got := proximoIntento(maxIntentosEjecucion); got != nil {
+		t.Fatalf("intentos agotados: esperaba nil, got %v", got)
+	}
+}
+
+func TestAgotarEjecucion(t *testing.T) {
+	sig := time.Now()
+	exec := models.AccionEjecucion{
+		Resultado:          models.EjecucionResultadoError,
+		Intentos:           1,
+		Detalle:            json.RawMessage(`{"scope":"alumno"}`),
+		SiguienteIntentoEn: &sig,
+	}
+	agotarEjecucion(&exec, ErrEjecucionHuerfana)
+
+	if exec.SiguienteIntentoEn != nil {
+		t.Fatal("siguiente_intento_en debe quedar vacio")
+	}
+	if exec.Intentos != 2 || exec.Resultado != models.EjecucionResultadoError {
+		t.Fatalf("intentos=%d resultado=%s", exec.Intentos, exec.Resultado)
+	}
+	var d map[string]interface{}
+	if err := json.Unmarshal(exec.Detalle, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d["scope"] != "alumno" || d["error"] != ErrEjecucionHuerfana.Error() || d["agotada"] != true {
+		t.Fatalf("detalle: %v", d)
+	}
+}
+
+// Regla eliminada: la ejecucion queda agotada y deja de aparecer en la cola de reintentos
+func TestReintentarEjecucionReglaEliminada(t *testing.T) {
+	db := testutil.DB(t)
+	o := New(db, nil, nil)
+
+	concepto := models.Concepto{Codigo: "TEST_" + uuid.NewString()[:8], Nombre: "Test"}
+	accion := models.Accion{Codigo: "TEST_" + uuid.NewString()[:8], Nombre: "Test", Tipo: "registro"}
+	if err := db.Create(&concepto).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&accion).Error; err != nil {
+		t.Fatal(err)
+	}
+	regla := models.Regla{Nombre: "Test", ConceptoID: concepto.ID, AccionID: accion.ID, Condicion: json.RawMessage(`{"tipo":"siempre"}`)}
+	if err := db.Create(&regla).Error; err != nil {
+		t.Fatal(err)
+	}
+	evt := models.Evento{ConceptoID: &concepto.ID, Origen: "sistema"}
+	if err := db.Create(&evt).Error; err != nil {
+		t.Fatal(err)
+	}
+	vencido := time.Now().Add(-time.Minute)
+	exec := models.AccionEjecucion{
+		ReglaID:            regla.ID,
+		AccionID:           accion.ID,
+		EventoID:           evt.ID,
+		Resultado:          models.EjecucionResultadoError,
+		Intentos:           1,
+		SiguienteIntentoEn: &vencido,
+	}
+	if err := db.Create(&exec).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(&regla).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := o.ReintentarEjecucion(exec.ID, nil)
+	if !errors.Is(err, ErrEjecucionHuerfana) {
+		t.Fatalf("err = %v, esperaba ErrEjecucionHuerfana", err)
+	}
+	if got == nil || got.SiguienteIntentoEn != nil {
+		t.Fatalf("ejecucion retornada: %+v", got)
+	}
+
+	var saved models.AccionEjecucion
+	if err := db.First(&saved, "id = ?", exec.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if saved.SiguienteIntentoEn != nil || saved.Intentos != 2 || saved.UltimoError == "" {
+		t.Fatalf("guardada: siguiente=%v intentos=%d error=%q", saved.SiguienteIntentoEn, saved.Intentos, saved.UltimoError)
+	}
+	var pendientes int64
+	db.Model(&models.AccionEjecucion{}).
+		Where("id = ? AND siguiente_intento_en IS NOT NULL", exec.ID).
+		Count(&pendientes)
+	if pendientes != 0 {
+		t.Fatal("la ejecucion sigue en la cola de reintentos")
+	}
+}
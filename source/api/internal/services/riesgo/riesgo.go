@@ -0,0 +1,313 @@
+package riesgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Alumnos procesados por lote
+const loteAlumnos = 500
+
+// Peso de cada estado de caso en el factor casos (cerrado = 0)
+var pesoEstadoCaso = map[string]float64{
+	models.CasoAbierto:     1,
+	models.CasoDerivado:    0.8,
+	models.CasoSeguimiento: 0.6,
+}
+
+// Filtro acota el calculo (campos nil = todos los alumnos activos)
+type Filtro struct {
+	CursoID  *uuid.UUID
+	AlumnoID *uuid.UUID
+}
+
+// Calcular recalcula el puntaje de riesgo de los alumnos activos del filtro. Retorna cuantos se actualizaron.
+func Calcular(db *gorm.DB, f Filtro) (int, error) {
+	cfg := models.ObtenerConfiguracionRiesgo(db)
+	pesosConcepto := map[string]float64{}
+	if len(cfg.PesosConcepto) > 0 {
+		_ = json.Unmarshal(cfg.PesosConcepto, &pesosConcepto)
+	}
+
+	q := db.Model(&models.Alumno{}).Where("activo = ?", true)
+	if f.CursoID != nil {
+		q = q.Where("curso_id = ?", *f.CursoID)
+	}
+	if f.AlumnoID != nil {
+		q = q.Where("id = ?", *f.AlumnoID)
+	}
+
+	total := 0
+	var lote []models.Alumno
+	err := q.Order("id").FindInBatches(&lote, loteAlumnos, func(tx *gorm.DB, _ int) error {
+		n, err := calcularLote(db, cfg, pesosConcepto, lote)
+		total += n
+		return err
+	}).Error
+	return total, err
+}
+
+func calcularLote(db *gorm.DB, cfg models.ConfiguracionRiesgo, pesosConcepto map[string]float64, alumnos []models.Alumno) (int, error) {
+	if len(alumnos) == 0 {
+		return 0, nil
+	}
+	ids := make([]uuid.UUID, len(alumnos))
+	for i, a := range alumnos {
+		ids[i] = a.ID
+	}
+	ventana := cfg.VentanaDias
+	if ventana <= 0 {
+		ventana = 30
+	}
+	since := time.Now().AddDate(0, 0, -ventana)
+
+	// Asistencia diaria consolidada en la ventana
+	type asisRow struct {
+		AlumnoID    uuid.UUID
+		Presentes   int
+		Registrados int
+	}
+	var asis []asisRow
+	if err := db.Model(&models.AsistenciaDiaria{}).
+		Select("alumno_id, SUM(CASE WHEN estado = ? THEN 1 ELSE 0 END) as presentes, COUNT(*) as registrados", models.EstadoPresente).
+		Where("alumno_id IN ? AND fecha >= ?", ids, since.Format("2006-01-02")).
+		Group("alumno_id").Scan(&asis).Error; err != nil {
+		return 0, err
+	}
+	asisBy := map[uuid.UUID]asisRow{}
+	for _, r := range asis {
+		asisBy[r.AlumnoID] = r
+	}
+
+	// Eventos por concepto en la ventana
+	type evRow struct {
+		AlumnoID uuid.UUID
+		Codigo   string
+		Cnt      int
+	}
+	var evs []evRow
+	if err := db.Table("eventos").
+		Select("eventos.alumno_id, conceptos.codigo, COUNT(*) as cnt").
+		Joins("JOIN conceptos ON conceptos.id = eventos.concepto_id").
+		Where("eventos.deleted_at IS NULL AND eventos.alumno_id IN ? AND eventos.created_at >= ?", ids, since).
+		Group("eventos.alumno_id, conceptos.codigo").Scan(&evs).Error; err != nil {
+		return 0, err
+	}
+	evBy := map[uuid.UUID][]evRow{}
+	for _, r := range evs {
+		evBy[r.AlumnoID] = append(evBy[r.AlumnoID], r)
+	}
+
+	// Alertas abiertas
+	type cntRow struct {
+		AlumnoID uuid.UUID
+		Cnt      int
+	}
+	var als []cntRow
+	if err := db.Model(&models.Alerta{}).
+		Select("alumno_id, COUNT(*) as cnt").
+		Where("alumno_id IN ? AND estado = ?", ids, models.AlertaAbierta).
+		Group("alumno_id").Scan(&als).Error; err != nil {
+		return 0, err
+	}
+	alBy := map[uuid.UUID]int{}
+	for _, r := range als {
+		alBy[r.AlumnoID] = r.Cnt
+	}
+
+	// Casos no cerrados
+	var casos []models.Caso
+	if err := db.Select("alumno_id, estado").
+		Where("alumno_id IN ? AND estado <> ?", ids, models.CasoCerrado).
+		Find(&casos).Error; err != nil {
+		return 0, err
+	}
+	casoBy := map[uuid.UUID]string{}
+	for _, c := range casos {
+		if pesoEstadoCaso[c.Estado] > pesoEstadoCaso[casoBy[c.AlumnoID]] {
+			casoBy[c.AlumnoID] = c.Estado
+		}
+	}
+
+	now := time.Now()
+	out := make([]models.RiesgoAlumno, 0, len(alumnos))
+	for _, a := range alumnos {
+		d := datosAlumno{
+			Presentes:   asisBy[a.ID].Presentes,
+			Registrados: asisBy[a.ID].Registrados,
+			Alertas:     alBy[a.ID],
+			EstadoCaso:  casoBy[a.ID],
+		}
+		for _, e := range evBy[a.ID] {
+			d.Eventos = append(d.Eventos, conteoConcepto{Codigo: e.Codigo, Cnt: e.Cnt})
+		}
+		r := puntuar(cfg, pesosConcepto, ventana, d)
+		r.AlumnoID = a.ID
+		r.CursoID = a.CursoID
+		r.CalculadoEn = now
+		out = append(out, r)
+	}
+
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "alumno_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"curso_id", "puntaje", "nivel", "porcentaje_asistencia", "factores", "calculado_en", "updated_at"}),
+	}).Create(&out).Error; err != nil {
+		return 0, err
+	}
+	return len(out), nil
+}
+
+// conteoConcepto eventos de un concepto en la ventana
+type conteoConcepto struct {
+	Codigo string
+	Cnt    int
+}
+
+// datosAlumno insumos del puntaje de un alumno en la ventana
+type datosAlumno struct {
+	Presentes   int // dias presentes (asistencia diaria)
+	Registrados int // dias con asistencia diaria; 0 = sin datos
+	Eventos     []conteoConcepto
+	Alertas     int    // alertas abiertas
+	EstadoCaso  string // estado del caso no cerrado mas relevante ("" sin caso)
+}
+
+// puntuar calcula puntaje, nivel y factores explicados de un alumno
+func puntuar(cfg models.ConfiguracionRiesgo, pesosConcepto map[string]float64, ventana int, d datosAlumno) models.RiesgoAlumno {
+	sumaPesos := cfg.PesoAsistencia + cfg.PesoEventos + cfg.PesoAlertas + cfg.PesoCasos
+	if sumaPesos <= 0 {
+		sumaPesos = 1
+	}
+	aporte := func(peso, valor float64) float64 {
+		return redondear(peso / sumaPesos * valor * 100)
+	}
+
+	var factores []models.FactorRiesgo
+	var r models.RiesgoAlumno
+
+	// Asistencia: 0 con 100%, 1 al llegar al umbral (ej. 85%)
+	if d.Registrados > 0 {
+		pct := redondear(float64(d.Presentes) * 100 / float64(d.Registrados))
+		r.PorcentajeAsistencia = &pct
+		v := 1.0
+		if cfg.UmbralAsistencia < 100 {
+			v = limitar((100 - pct) / (100 - cfg.UmbralAsistencia))
+		}
+		factores = append(factores, models.FactorRiesgo{
+			Factor: "asistencia", Valor: redondear(v), Aporte: aporte(cfg.PesoAsistencia, v),
+			Detalle: fmt.Sprintf("%.1f%% de asistencia en los ultimos %d dias (%d de %d dias; umbral %.0f%%)", pct, ventana, d.Presentes, d.Registrados, cfg.UmbralAsistencia),
+		})
+	} else {
+		factores = append(factores, models.FactorRiesgo{Factor: "asistencia", Detalle: "Sin asistencia diaria registrada en la ventana"})
+	}
+
+	// Eventos ponderados por concepto
+	puntos := 0.0
+	detalle := ""
+	for _, e := range d.Eventos {
+		p, ok := pesosConcepto[e.Codigo]
+		if !ok {
+			p = 1
+		}
+		if p == 0 {
+			continue
+		}
+		puntos += p * float64(e.Cnt)
+		detalle = unir(detalle, fmt.Sprintf("%d %s (x%g)", e.Cnt, e.Codigo, p))
+	}
+	vEv := 0.0
+	if cfg.SaturacionEventos > 0 {
+		vEv = limitar(puntos / cfg.SaturacionEventos)
+	}
+	if detalle == "" {
+		detalle = "Sin eventos relevantes"
+	}
+	factores = append(factores, models.FactorRiesgo{Factor: "eventos", Valor: redondear(vEv), Aporte: aporte(cfg.PesoEventos, vEv), Detalle: detalle})
+
+	// Alertas abiertas
+	vAl := 0.0
+	if cfg.SaturacionAlertas > 0 {
+		vAl = limitar(float64(d.Alertas) / float64(cfg.SaturacionAlertas))
+	}
+	factores = append(factores, models.FactorRiesgo{Factor: "alertas", Valor: redondear(vAl), Aporte: aporte(cfg.PesoAlertas, vAl), Detalle: fmt.Sprintf("%d alertas abiertas", d.Alertas)})
+
+	// Casos
+	vCa := pesoEstadoCaso[d.EstadoCaso]
+	detCaso := "Sin caso activo"
+	if d.EstadoCaso != "" {
+		detCaso = "Caso en estado " + d.EstadoCaso
+	}
+	factores = append(factores, models.FactorRiesgo{Factor: "casos", Valor: vCa, Aporte: aporte(cfg.PesoCasos, vCa), Detalle: detCaso})
+
+	for _, f := range factores {
+		r.Puntaje += f.Aporte
+	}
+	r.Puntaje = redondear(r.Puntaje)
+	switch {
+	case r.Puntaje >= cfg.UmbralAlto:
+		r.Nivel = models.RiesgoAlto
+	case r.Puntaje >= cfg.UmbralMedio:
+		r.Nivel = models.RiesgoMedio
+	default:
+		r.Nivel = models.RiesgoBajo
+	}
+	r.Factores, _ = json.Marshal(factores)
+	return r
+}
+
+// RunNightly recalcula el riesgo de todos los alumnos una vez al dia (RIESGO_HORA, default 23),
+// despues de la consolidacion de asistencia diaria.
+func RunNightly(db *gorm.DB, stop <-chan struct{}) {
+	hora := 23
+	if n, err := strconv.Atoi(os.Getenv("RIESGO_HORA")); err == nil && n >= 0 && n <= 23 {
+		hora = n
+	}
+
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	ultimo := ""
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			hoy := now.Format("2006-01-02")
+			if now.Hour() < hora || ultimo == hoy {
+				continue
+			}
+			n, err := Calcular(db, Filtro{})
+			if err != nil {
+				log.Printf("riesgo: calculo %s: %v", hoy, err)
+				continue
+			}
+			log.Printf("riesgo: calculo %s (%d alumnos)", hoy, n)
+			ultimo = hoy
+		}
+	}
+}
+
+func limitar(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+func redondear(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+func unir(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + ", " + b
+}
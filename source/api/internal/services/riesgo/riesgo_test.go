@@ -0,0 +1,63 @@
+package riesgo
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/school-monitoring/backend/internal/models"
+)
+
+func TestPuntuar(t *testing.T) {
+	cfg := models.ConfiguracionRiesgoDefault() // pesos 40/25/20/15, umbral 85%, saturacion 10 eventos / 3 alertas
+	var pesos map[string]float64
+	if err := json.Unmarshal(cfg.PesosConcepto, &pesos); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		datos   datosAlumno
+		puntaje float64
+		nivel   string
+	}{
+		{"sin riesgo", datosAlumno{Presentes: 20, Registrados: 20}, 0, models.RiesgoBajo},
+		{"sin asistencia registrada", datosAlumno{}, 0, models.RiesgoBajo},
+		{"asistencia a medio camino del umbral", datosAlumno{Presentes: 37, Registrados: 40}, 20, models.RiesgoBajo},
+		{"asistencia en el umbral", datosAlumno{Presentes: 17, Registrados: 20}, 40, models.RiesgoMedio},
+		{"asistencia bajo el umbral no pasa del maximo", datosAlumno{Presentes: 5, Registrados: 20}, 40, models.RiesgoMedio},
+		{"eventos ponderados", datosAlumno{Presentes: 20, Registrados: 20, Eventos: []conteoConcepto{{"SOS", 2}}}, 15, models.RiesgoBajo},
+		{"concepto con peso 0 no suma", datosAlumno{Presentes: 20, Registrados: 20, Eventos: []conteoConcepto{{"INASISTENCIA", 9}}}, 0, models.RiesgoBajo},
+		{"concepto sin peso configurado vale 1", datosAlumno{Presentes: 20, Registrados: 20, Eventos: []conteoConcepto{{"OTRO", 4}}}, 10, models.RiesgoBajo},
+		{"eventos saturan", datosAlumno{Presentes: 20, Registrados: 20, Eventos: []conteoConcepto{{"DISCIPLINARIO", 5}}}, 25, models.RiesgoBajo},
+		{"caso derivado", datosAlumno{Presentes: 20, Registrados: 20, EstadoCaso: models.CasoDerivado}, 12, models.RiesgoBajo},
+		{"todo al maximo", datosAlumno{Presentes: 10, Registrados: 20, Eventos: []conteoConcepto{{"SOS", 4}}, Alertas: 3, EstadoCaso: models.CasoAbierto}, 100, models.RiesgoAlto},
+		{"umbral alto", datosAlumno{Presentes: 17, Registrados: 20, Alertas: 3}, 60, models.RiesgoAlto},
+	}
+	for _, tc := range cases {
+		r := puntuar(cfg, pesos, cfg.VentanaDias, tc.datos)
+		if r.Puntaje != tc.puntaje || r.Nivel != tc.nivel {
+			t.Errorf("%s: puntaje=%v nivel=%s, esperaba %v %s", tc.name, r.Puntaje, r.Nivel, tc.puntaje, tc.nivel)
+		}
+		var factores []models.FactorRiesgo
+		if err := json.Unmarshal(r.Factores, &factores); err != nil || len(factores) != 4 {
+			t.Errorf("%s: factores %s", tc.name, r.Factores)
+		}
+		if (tc.datos.Registrados > 0) != (r.PorcentajeAsistencia != nil) {
+			t.Errorf("%s: porcentaje_asistencia %v", tc.name, r.PorcentajeAsistencia)
+		}
+	}
+}
+
+// Los pesos se normalizan por su suma: duplicarlos no cambia el puntaje
+func TestPuntuarPesosNormalizados(t *testing.T) {
+	cfg := models.ConfiguracionRiesgoDefault()
+	d := datosAlumno{Presentes: 17, Registrados: 20, Alertas: 1}
+	base := puntuar(cfg, nil, 30, d).Puntaje
+	cfg.PesoAsistencia *= 2
+	cfg.PesoEventos *= 2
+	cfg.PesoAlertas *= 2
+	cfg.PesoCasos *= 2
+	if got := puntuar(cfg, nil, 30, d).Puntaje; got != base {
+		t.Fatalf("puntaje %v, esperaba %v", got, base)
+	}
+}
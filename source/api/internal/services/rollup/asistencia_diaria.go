@@ -0,0 +1,253 @@
+package rollup
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/school-monitoring/backend/internal/models"
+	"github.com/school-monitoring/backend/internal/services/calendario"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Politica define como se consolida la asistencia por bloque en un estado diario.
+type Politica struct {
+	Tipo       string `json:"tipo"`        // primer_bloque, minimo_bloques
+	MinBloques int    `json:"min_bloques"` // solo minimo_bloques
+}
+
+// PoliticaDesdeEnv lee la politica vigente (ASISTENCIA_DIARIA_POLITICA / ASISTENCIA_DIARIA_MIN_BLOQUES).
+func PoliticaDesdeEnv() Politica {
+	p := Politica{Tipo: models.PoliticaPrimerBloque, MinBloques: 1}
+	if v := strings.ToLower(strings.TrimSpace(os.Getenv("ASISTENCIA_DIARIA_POLITICA"))); v == models.PoliticaMinimoBloques {
+		p.Tipo = v
+	}
+	if n, err := strconv.Atoi(os.Getenv("ASISTENCIA_DIARIA_MIN_BLOQUES")); err == nil && n > 0 {
+		p.MinBloques = n
+	}
+	return p
+}
+
+// Filtro acota el recalculo (campos nil = sin filtro).
+type Filtro struct {
+	CursoID  *uuid.UUID
+	AlumnoID *uuid.UUID
+}
+
+// loteUpsert filas por INSERT ... ON CONFLICT
+const loteUpsert = 500
+
+// Recalcular consolida la asistencia diaria en el rango [desde, hasta] (fechas inclusivas) en una sola
+// transaccion. Las filas corregidas manualmente no se sobreescriben. Retorna cuantas filas se escribieron.
+func Recalcular(db *gorm.DB, desde, hasta time.Time, f Filtro) (int, error) {
+	escritas := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		n, err := recalcular(tx, desde, hasta, f)
+		escritas = n
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return escritas, nil
+}
+
+func recalcular(db *gorm.DB, desde, hasta time.Time, f Filtro) (int, error) {
+	pol := PoliticaDesdeEnv()
+
+	q := db.Table("asistencias").
+		Select("asistencias.alumno_id, horarios.curso_id, asistencias.fecha, asistencias.estado, bloque_horarios.numero").
+		Joins("JOIN horarios ON horarios.id = asistencias.horario_id").
+		Joins("JOIN bloque_horarios ON bloque_horarios.id = horarios.bloque_id").
+		Where("asistencias.deleted_at IS NULL AND asistencias.fecha >= ? AND asistencias.fecha <= ?",
+			desde.Format("2006-01-02"), hasta.Format("2006-01-02"))
+	if f.CursoID != nil {
+		q = q.Where("horarios.curso_id = ?", *f.CursoID)
+	}
+	if f.AlumnoID != nil {
+		q = q.Where("asistencias.alumno_id = ?", *f.AlumnoID)
+	}
+	var filas []bloqueDia
+	if err := q.Order("asistencias.fecha, asistencias.alumno_id, bloque_horarios.numero").Scan(&filas).Error; err != nil {
+		return 0, err
+	}
+
+	// Dias no lectivos (feriados, suspensiones, vacaciones) no cuentan: se descartan sus filas
+	cal, err := calendario.Cargar(db, desde, hasta)
+	if err != nil {
+		return 0, err
+	}
+	if err := limpiarNoLectivos(db, cal, desde, hasta, f); err != nil {
+		return 0, err
+	}
+
+	type clave struct {
+		AlumnoID uuid.UUID
+		Fecha    string
+	}
+	grupos := map[clave][]bloqueDia{}
+	orden := []clave{}
+	for _, r := range filas {
+		cursoID := r.CursoID
+		if !cal.Dia(r.Fecha, &cursoID).SeDicta(r.Numero) {
+			continue
+		}
+		k := clave{r.AlumnoID, r.Fecha.Format("2006-01-02")}
+		if _, ok := grupos[k]; !ok {
+			orden = append(orden, k)
+		}
+		grupos[k] = append(grupos[k], r)
+	}
+
+	if len(orden) == 0 {
+		return 0, nil
+	}
+	now := time.Now()
+	diarias := make([]models.AsistenciaDiaria, 0, len(orden))
+	for _, k := range orden {
+		d := consolidar(pol, grupos[k])
+		d.CalculadoEn = now
+		diarias = append(diarias, d)
+	}
+
+	// Upsert por (alumno_id, fecha): una fila corregida no se toca; una borrada (soft delete) se revive
+	res := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "alumno_id"}, {Name: "fecha"}},
+		DoUpdates: clause.AssignmentColumns([]string{"curso_id", "estado", "atraso", "retiro", "bloques_registrados",
+			"bloques_presentes", "politica", "calculado_en", "updated_at", "deleted_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "asistencias_diarias.corregido = ?", Vars: []interface{}{false}}}},
+	}).CreateInBatches(&diarias, loteUpsert)
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	return int(res.RowsAffected), nil
+}
+
+// limpiarNoLectivos elimina las filas diarias calculadas (no corregidas) de dias que ya no son lectivos
+func limpiarNoLectivos(db *gorm.DB, cal *calendario.Calendario, desde, hasta time.Time, f Filtro) error {
+	type fila struct {
+		ID      uuid.UUID
+		CursoID uuid.UUID
+		Fecha   time.Time
+	}
+	q := db.Model(&models.AsistenciaDiaria{}).Select("id, curso_id, fecha").
+		Where("corregido = ? AND fecha >= ? AND fecha <= ?", false, desde.Format("2006-01-02"), hasta.Format("2006-01-02"))
+	if f.CursoID != nil {
+		q = q.Where("curso_id = ?", *f.CursoID)
+	}
+	if f.AlumnoID != nil {
+		q = q.Where("alumno_id = ?", *f.AlumnoID)
+	}
+	var filas []fila
+	if err := q.Scan(&filas).Error; err != nil {
+		return err
+	}
+	var borrar []uuid.UUID
+	for _, r := range filas {
+		cursoID := r.CursoID
+		if !cal.Dia(r.Fecha, &cursoID).Lectivo {
+			borrar = append(borrar, r.ID)
+		}
+	}
+	if len(borrar) == 0 {
+		return nil
+	}
+	return db.Unscoped().Where("id IN ?", borrar).Delete(&models.AsistenciaDiaria{}).Error
+}
+
+// bloqueDia es un registro por bloque de un alumno en un dia
+type bloqueDia struct {
+	AlumnoID uuid.UUID
+	CursoID  uuid.UUID
+	Fecha    time.Time
+	Estado   string
+	Numero   int
+}
+
+// consolidar aplica la politica sobre los bloques (ordenados por numero) de un alumno en un dia.
+func consolidar(pol Politica, bloques []bloqueDia) models.AsistenciaDiaria {
+	d := models.AsistenciaDiaria{
+		AlumnoID:           bloques[0].AlumnoID,
+		CursoID:            bloques[0].CursoID,
+		Fecha:              bloques[0].Fecha,
+		Politica:           pol.Tipo,
+		BloquesRegistrados: len(bloques),
+	}
+	justificado := false
+	primeroPresente := false
+	for i, b := range bloques {
+		estado := b.Estado
+		asistio := estado == models.EstadoPresente || estado == models.EstadoAtraso || estado == models.EstadoRetiro
+		if asistio {
+			d.BloquesPresentes++
+			if i == 0 {
+				primeroPresente = true
+			}
+		}
+		switch estado {
+		case models.EstadoAtraso:
+			d.Atraso = true
+		case models.EstadoRetiro:
+			d.Retiro = true
+		case models.EstadoJustificado:
+			justificado = true
+		}
+	}
+
+	presente := primeroPresente
+	if pol.Tipo == models.PoliticaMinimoBloques {
+		presente = d.BloquesPresentes >= pol.MinBloques
+	}
+	switch {
+	case presente:
+		d.Estado = models.EstadoPresente
+	case justificado:
+		d.Estado = models.EstadoJustificado
+	default:
+		d.Estado = models.EstadoAusente
+	}
+	return d
+}
+
+// RunNightly recalcula la asistencia diaria una vez al dia (ASISTENCIA_DIARIA_HORA, default 22)
+// y al iniciar recupera el dia anterior por si el proceso estuvo detenido.
+func RunNightly(db *gorm.DB, stop <-chan struct{}) {
+	hora := 22
+	if n, err := strconv.Atoi(os.Getenv("ASISTENCIA_DIARIA_HORA")); err == nil && n >= 0 && n <= 23 {
+		hora = n
+	}
+
+	ayer := time.Now().AddDate(0, 0, -1)
+	if n, err := Recalcular(db, ayer, ayer, Filtro{}); err != nil {
+		log.Printf("rollup: asistencia diaria %s: %v", ayer.Format("2006-01-02"), err)
+	} else {
+		log.Printf("rollup: asistencia diaria %s (%d)", ayer.Format("2006-01-02"), n)
+	}
+
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	ultimo := ""
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			hoy := now.Format("2006-01-02")
+			if now.Hour() < hora || ultimo == hoy {
+				continue
+			}
+			if n, err := Recalcular(db, now, now, Filtro{}); err != nil {
+				log.Printf("rollup: asistencia diaria %s: %v", hoy, err)
+				continue
+			} else {
+				log.Printf("rollup: asistencia diaria %s (%d)", hoy, n)
+			}
+			ultimo = hoy
+		}
+	}
+}
@@ -0,0 +1,45 @@
+// Package testutil ayudas para tests que necesitan Postgres.
+package testutil
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/school-monitoring/backend/internal/database"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var (
+	once   sync.Once
+	base   *gorm.DB
+	errIni error
+)
+
+// DB abre TEST_DATABASE_URL (DSN de Postgres, migrado una vez por proceso) y retorna una transaccion
+// que se revierte al terminar el test. Sin TEST_DATABASE_URL el test se omite.
+// Las transacciones que abra el codigo bajo prueba quedan como savepoints dentro de esta.
+func DB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	once.Do(func() {
+		base, errIni = gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		if errIni == nil {
+			errIni = database.Migrar(base)
+		}
+	})
+	if errIni != nil {
+		t.Fatalf("test database: %v", errIni)
+	}
+	tx := base.Begin()
+	if tx.Error != nil {
+		t.Fatalf("test database: %v", tx.Error)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	return tx
+}